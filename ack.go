@@ -0,0 +1,90 @@
+package broadcast
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	defaultMaxAttempts     = 3
+	defaultRedeliveryDelay = time.Second
+)
+
+// DeadLetterHandler is called with a message that could not be delivered to an
+// acknowledging subscription after all attempts and the error of the last attempt.
+type DeadLetterHandler func(sub *Subscription, msg *Message, err error)
+
+// WithRedelivery sets how many times a message is delivered to an acknowledging
+// subscription before it is given up and how long to wait before the first redelivery.
+// The delay doubles after every attempt. Default is 3 attempts starting with 1 second.
+func WithRedelivery(maxAttempts int, delay time.Duration) Option {
+	return func(b *broadcaster) error {
+		if maxAttempts <= 0 {
+			return errors.New("max attempts must be positive")
+		}
+
+		if delay < 0 {
+			return errors.New("redelivery delay cannot be negative")
+		}
+
+		b.maxAttempts = maxAttempts
+		b.redeliveryDelay = delay
+		return nil
+	}
+}
+
+// WithDeadLetterHandler sets a function that is called with messages that
+// acknowledging subscriptions failed to process after all attempts.
+// By default such messages are discarded.
+func WithDeadLetterHandler(handler DeadLetterHandler) Option {
+	return func(b *broadcaster) error {
+		if handler == nil {
+			return errors.New("dead letter handler cannot be nil")
+		}
+
+		b.deadLetterHandler = handler
+		return nil
+	}
+}
+
+// SubscribeAck creates a subscription with at-least-once delivery.
+// A message is acknowledged when the callback returns nil, otherwise it is
+// delivered again after a delay until the redelivery attempts are exhausted.
+// A panicking callback counts as a failed attempt.
+func (b *broadcaster) SubscribeAck(callback func(data interface{}) error) *Subscription {
+	sub := b.newSubscription(nil)
+	sub.ackCallback = callback
+	b.subscribed(sub)
+
+	return sub
+}
+
+// redeliver schedules another attempt of a failed delivery and reports
+// whether it was scheduled.
+func (b *broadcaster) redeliver(s *Subscription, d delivery) bool {
+	d.attempts++
+	if d.attempts >= b.maxAttempts || s.isClosed() {
+		return false
+	}
+
+	delay := b.redeliveryDelay << uint(d.attempts-1)
+
+	b.clock.AfterFunc(delay, func() {
+		if s.isClosed() {
+			d.finish(false)
+			return
+		}
+
+		if !b.pool.doPriority(func() { b.deliver(s, d) }, d.msg.Priority) {
+			d.finish(false)
+		}
+	})
+
+	return true
+}
+
+func (b *broadcaster) deadLetter(s *Subscription, msg *Message, err error) {
+	if b.deadLetterHandler != nil {
+		b.deadLetterHandler(s, msg, err)
+	}
+}
@@ -0,0 +1,126 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRedelivery_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRedelivery(0, time.Second)(b); err == nil {
+		t.Fatalf("WithRedelivery(0, time.Second); expected an error")
+	}
+
+	if err := WithRedelivery(1, -time.Second)(b); err == nil {
+		t.Fatalf("WithRedelivery(1, -time.Second); expected an error")
+	}
+}
+
+func TestWithDeadLetterHandler_WithNilHandler(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithDeadLetterHandler(nil)(b); err == nil {
+		t.Fatalf("WithDeadLetterHandler(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_SubscribeAck_ShouldRedeliverUntilAcknowledged(t *testing.T) {
+	b, cancel, _ := New(WithRedelivery(3, time.Millisecond))
+	defer cancel()
+	var calls int32
+	b.SubscribeAck(func(_ interface{}) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("not yet")
+		}
+
+		return nil
+	})
+
+	delivered, err := b.ToAllSync(context.Background(), 1)
+
+	if err != nil || delivered != 1 {
+		t.Fatalf("ToAllSync returned (%d, %v); want (1, nil)", delivered, err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("callback was called %d times; want 3", got)
+	}
+}
+
+func TestBroadcaster_SubscribeAck_ShouldRedeliverPanickingCallback(t *testing.T) {
+	b, cancel, _ := New(WithRedelivery(2, time.Millisecond))
+	defer cancel()
+	var calls int32
+	b.SubscribeAck(func(_ interface{}) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("test panic")
+		}
+
+		return nil
+	})
+
+	delivered, _ := b.ToAllSync(context.Background(), 1)
+
+	if delivered != 1 {
+		t.Fatalf("ToAllSync delivered to %d subscriptions; want 1", delivered)
+	}
+}
+
+func TestBroadcaster_SubscribeAck_ShouldDeadLetterAfterMaxAttempts(t *testing.T) {
+	deadLetters := make(chan *Message, 1)
+	b, cancel, _ := New(
+		WithRedelivery(2, time.Millisecond),
+		WithDeadLetterHandler(func(_ *Subscription, msg *Message, err error) {
+			deadLetters <- msg
+		}),
+	)
+	defer cancel()
+	var calls int32
+	b.SubscribeAck(func(_ interface{}) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("failed")
+	})
+
+	delivered, _ := b.ToAllSync(context.Background(), "data")
+
+	if delivered != 0 {
+		t.Fatalf("ToAllSync delivered to %d subscriptions; want 0", delivered)
+	}
+
+	select {
+	case msg := <-deadLetters:
+		if msg.Data != "data" {
+			t.Fatalf("dead letter data is %v; want %v", msg.Data, "data")
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("dead letter handler was not called")
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("callback was called %d times; want 2", got)
+	}
+}
+
+func TestBroadcaster_SubscribeAck_ShouldStopRedeliveryAfterUnsubscribe(t *testing.T) {
+	b, cancel, _ := New(WithRedelivery(5, time.Millisecond*50))
+	defer cancel()
+	var calls int32
+	var subscription *Subscription
+	subscription = b.SubscribeAck(func(_ interface{}) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("failed")
+	})
+
+	b.ToAll(1)
+	<-time.After(time.Millisecond * 20)
+	b.Unsubscribe(subscription)
+	<-time.After(time.Millisecond * 200)
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("callback was called %d times; want 1", got)
+	}
+}
@@ -0,0 +1,90 @@
+package broadcast
+
+import (
+	"errors"
+	"time"
+)
+
+// Auditor receives a record of every message sent by this instance, e.g. to retain
+// what was broadcast to whom in regulated applications. See the audit package for
+// an Auditor writing JSON lines.
+type Auditor interface {
+	// Audit is called once per message. It is called on the go routine sending the message
+	// if the message is rejected or not delivered locally, otherwise on another go routine.
+	Audit(record AuditRecord)
+}
+
+// AuditRecord describes a message sent by this instance, its targets and the outcome
+// of its local deliveries. Deliveries on other instances are not part of the record.
+type AuditRecord struct {
+	ID                string
+	Timestamp         time.Time
+	Data              interface{}
+	Namespace         string
+	ToAll             bool
+	Rooms             []string
+	RoomPattern       string
+	Subscribers       []string
+	Except            []string
+	ExceptSubscribers []string
+	Sender            string
+	// Rejected is the error of the Authorizer if the message was not sent.
+	Rejected error
+	// Delivered holds the IDs of the local subscriptions that received the message.
+	Delivered []string
+	// Failed holds the error of every local subscription that didn't receive the message by subscription ID.
+	Failed map[string]error
+}
+
+// WithAuditor sets the Auditor that receives a record of every message sent by this instance
+// once its local deliveries are finished, including messages rejected by the Authorizer.
+// Messages dropped by middleware and messages received through the Dispatcher are not audited.
+func WithAuditor(auditor Auditor) Option {
+	return func(b *broadcaster) error {
+		if auditor == nil {
+			return errors.New("auditor cannot be nil")
+		}
+
+		b.auditor = auditor
+		return nil
+	}
+}
+
+// audited marks an accepted message to be audited once its local deliveries are finished.
+// A message without local deliveries is audited right away.
+func (b *broadcaster) audited(msg *Message) {
+	if b.auditor == nil {
+		return
+	}
+
+	if msg.remote {
+		b.audit(msg, nil, Receipt{}, nil)
+		return
+	}
+
+	msg.audited = true
+}
+
+// audit passes the record of a message to the Auditor.
+func (b *broadcaster) audit(msg *Message, rejected error, receipt Receipt, delivered []string) {
+	if b.auditor == nil {
+		return
+	}
+
+	b.auditor.Audit(AuditRecord{
+		ID:                msg.ID,
+		Timestamp:         msg.Timestamp,
+		Data:              msg.Data,
+		Namespace:         msg.Namespace,
+		ToAll:             msg.ToAll,
+		Rooms:             msg.Rooms,
+		RoomPattern:       msg.RoomPattern,
+		Subscribers:       msg.Subscribers,
+		Except:            msg.Except,
+		ExceptSubscribers: msg.ExceptSubscribers,
+		Sender:            msg.Sender,
+		Rejected:          rejected,
+		Delivered:         delivered,
+		Failed:            receipt.Failed,
+	})
+}
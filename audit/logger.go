@@ -0,0 +1,146 @@
+// Package audit writes the audit records of a broadcaster as JSON lines,
+// one object per message, e.g. to an append-only file:
+//
+//	logger, err := audit.OpenFile("broadcast.audit.jsonl")
+//	b, cancel, err := broadcast.New(broadcast.WithAuditor(logger))
+//	defer logger.Close()
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+// Logger implements broadcast.Auditor by writing every record as a line of JSON.
+type Logger struct {
+	mux    *sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	err    error
+}
+
+// NewLogger creates a Logger writing to w.
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{
+		mux: &sync.Mutex{},
+		w:   w,
+	}
+}
+
+// OpenFile creates a Logger appending to the named file, which is created if it doesn't exist.
+func OpenFile(name string) (*Logger, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLogger(f)
+	l.closer = f
+	return l, nil
+}
+
+// entry is the JSON representation of an audit record.
+type entry struct {
+	ID                string            `json:"id"`
+	Timestamp         time.Time         `json:"timestamp"`
+	Data              json.RawMessage   `json:"data"`
+	Namespace         string            `json:"namespace,omitempty"`
+	ToAll             bool              `json:"to_all,omitempty"`
+	Rooms             []string          `json:"rooms,omitempty"`
+	RoomPattern       string            `json:"room_pattern,omitempty"`
+	Subscribers       []string          `json:"subscribers,omitempty"`
+	Except            []string          `json:"except,omitempty"`
+	ExceptSubscribers []string          `json:"except_subscribers,omitempty"`
+	Sender            string            `json:"sender,omitempty"`
+	Rejected          string            `json:"rejected,omitempty"`
+	Delivered         []string          `json:"delivered"`
+	Failed            map[string]string `json:"failed,omitempty"`
+}
+
+// Audit writes the record as a line of JSON. Data that can't be encoded as JSON
+// is written as a string in its default format. Write errors are reported by Err.
+func (l *Logger) Audit(record broadcast.AuditRecord) {
+	e := entry{
+		ID:                record.ID,
+		Timestamp:         record.Timestamp,
+		Data:              encodeData(record.Data),
+		Namespace:         record.Namespace,
+		ToAll:             record.ToAll,
+		Rooms:             record.Rooms,
+		RoomPattern:       record.RoomPattern,
+		Subscribers:       record.Subscribers,
+		Except:            record.Except,
+		ExceptSubscribers: record.ExceptSubscribers,
+		Sender:            record.Sender,
+		Delivered:         record.Delivered,
+	}
+
+	if e.Delivered == nil {
+		e.Delivered = []string{}
+	}
+
+	if record.Rejected != nil {
+		e.Rejected = record.Rejected.Error()
+	}
+
+	if len(record.Failed) > 0 {
+		e.Failed = make(map[string]string, len(record.Failed))
+		for id, err := range record.Failed {
+			e.Failed[id] = err.Error()
+		}
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		l.fail(err)
+		return
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if _, err := l.w.Write(append(line, '\n')); err != nil && l.err == nil {
+		l.err = err
+	}
+}
+
+// Err returns the first error that occurred while writing records.
+func (l *Logger) Err() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.err
+}
+
+// Close closes the file of a Logger created with OpenFile.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+
+	return l.closer.Close()
+}
+
+func (l *Logger) fail(err error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+func encodeData(data interface{}) json.RawMessage {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(data))
+	}
+
+	return encoded
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+func TestLogger_Audit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(buf)
+
+	l.Audit(broadcast.AuditRecord{
+		ID:        "1",
+		Timestamp: time.Unix(0, 0).UTC(),
+		Data:      map[string]int{"count": 1},
+		Rooms:     []string{"chat"},
+		Delivered: []string{"a"},
+		Failed:    map[string]error{"b": errors.New("failed")},
+	})
+	l.Audit(broadcast.AuditRecord{ID: "2", Data: func() {}, Rejected: errors.New("forbidden")})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Audit() wrote %d lines; want 2", len(lines))
+	}
+
+	want := `{"id":"1","timestamp":"1970-01-01T00:00:00Z","data":{"count":1},"rooms":["chat"],"delivered":["a"],"failed":{"b":"failed"}}`
+	if lines[0] != want {
+		t.Fatalf("Audit() wrote %s; want %s", lines[0], want)
+	}
+
+	var e entry
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil || e.Rejected != "forbidden" || len(e.Data) == 0 {
+		t.Fatalf("Audit() wrote %s; want a rejected record with the data as a string", lines[1])
+	}
+
+	if err := l.Err(); err != nil {
+		t.Fatalf("Err() = %v; want nil", err)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := OpenFile(name)
+	if err != nil {
+		t.Fatalf("OpenFile() returned %v", err)
+	}
+	defer l.Close()
+	b, cancel, _ := broadcast.New(broadcast.WithAuditor(l), broadcast.WithSynchronousDelivery())
+	defer cancel()
+	b.Subscribe(func(_ interface{}) {})
+
+	b.ToAll("data")
+
+	var content []byte
+	for deadline := time.Now().Add(time.Second); len(content) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond * 10)
+		if content, err = os.ReadFile(name); err != nil {
+			t.Fatalf("ReadFile() returned %v", err)
+		}
+	}
+
+	if !strings.Contains(string(content), `"data":"data"`) || !strings.HasSuffix(string(content), "\n") {
+		t.Fatalf("OpenFile() logger wrote %q; want a line with the message", content)
+	}
+}
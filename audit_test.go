@@ -0,0 +1,89 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type auditRecorder struct {
+	records chan AuditRecord
+}
+
+func (a *auditRecorder) Audit(record AuditRecord) {
+	a.records <- record
+}
+
+func (a *auditRecorder) next(t *testing.T) AuditRecord {
+	select {
+	case record := <-a.records:
+		return record
+	case <-time.After(time.Second):
+		t.Fatalf("no audit record")
+		return AuditRecord{}
+	}
+}
+
+func TestBroadcaster_WithAuditor_ShouldRecordDeliveries(t *testing.T) {
+	auditor := &auditRecorder{records: make(chan AuditRecord, 1)}
+	b, cancel, _ := New(WithAuditor(auditor), WithRedelivery(1, 0), WithSynchronousDelivery())
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "chat")
+	failing := b.SubscribeAck(func(_ interface{}) error { return errors.New("failed") })
+	b.JoinRoom(failing, "chat")
+
+	b.ToRoom("data", "chat", "muted")
+
+	record := auditor.next(t)
+	if len(record.ID) == 0 || record.Timestamp.IsZero() || record.Data != "data" {
+		t.Fatalf("got record %+v; want the ID, timestamp and data of the message", record)
+	}
+
+	if len(record.Rooms) != 1 || record.Rooms[0] != "chat" || len(record.Except) != 1 || record.Except[0] != "muted" {
+		t.Fatalf("got record targeting %v except %v; want chat except muted", record.Rooms, record.Except)
+	}
+
+	if len(record.Delivered) != 1 || record.Delivered[0] != s.ID() || record.Failed[failing.ID()] == nil {
+		t.Fatalf("got delivered %v and failed %v; want %s delivered and %s failed", record.Delivered, record.Failed, s.ID(), failing.ID())
+	}
+}
+
+func TestBroadcaster_WithAuditor_ShouldRecordRejections(t *testing.T) {
+	auditor := &auditRecorder{records: make(chan AuditRecord, 1)}
+	b, cancel, _ := New(WithAuditor(auditor), WithAuthorizer(&roomAuthorizer{allowed: "allowed"}))
+	defer cancel()
+
+	b.ToAll("data")
+
+	if record := auditor.next(t); record.Rejected == nil || !record.ToAll {
+		t.Fatalf("got record %+v; want a rejected message to all", record)
+	}
+}
+
+func TestBroadcaster_WithAuditor_ShouldNotRecordReceivedMessages(t *testing.T) {
+	auditor := &auditRecorder{records: make(chan AuditRecord, 1)}
+	dispatcher := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithAuditor(auditor), WithDispatcher(dispatcher), WithSynchronousDelivery())
+	defer cancel()
+
+	dispatcher.received(&Message{ID: "remote", Origin: "other", Data: "data", ToAll: true})
+
+	select {
+	case record := <-auditor.records:
+		t.Fatalf("got record %+v of a received message", record)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	b.ToAllWithOptions("data", RemoteOnly())
+
+	if record := auditor.next(t); record.ID == "remote" || len(record.Delivered) != 0 {
+		t.Fatalf("got record %+v; want the remote only message without deliveries", record)
+	}
+}
+
+func TestWithAuditor_Nil(t *testing.T) {
+	if _, _, err := New(WithAuditor(nil)); err == nil {
+		t.Fatalf("WithAuditor(nil) should fail")
+	}
+}
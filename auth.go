@@ -0,0 +1,52 @@
+package broadcast
+
+import "errors"
+
+// Authorizer decides whether subscriptions may join rooms and messages may be sent,
+// e.g. to enforce per room access control lists in multi-tenant applications.
+type Authorizer interface {
+	// AuthorizeJoin returns an error if the subscription may not join the room.
+	AuthorizeJoin(sub *Subscription, room string) error
+	// AuthorizeSend returns an error if the message may not be sent.
+	// Messages received through the Dispatcher are not authorized again.
+	AuthorizeSend(msg *Message) error
+}
+
+// WithAuthorizer sets the Authorizer consulted by JoinRoom, JoinTree, JoinGroup and every send.
+// Sends that don't return an error drop rejected messages silently.
+// By default everything is allowed.
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(b *broadcaster) error {
+		if authorizer == nil {
+			return errors.New("authorizer cannot be nil")
+		}
+
+		b.authorizer = authorizer
+		return nil
+	}
+}
+
+// authorize returns the error of the Authorizer if a message may not be sent.
+// Replies sent with Request.Reply go to the private room of a request that was already authorized.
+func (b *broadcaster) authorize(msg *Message) error {
+	if b.authorizer == nil || msg.reply {
+		return nil
+	}
+
+	return b.authorizer.AuthorizeSend(msg)
+}
+
+// authorizeJoin returns the first error of the Authorizer if the subscription may not join all rooms.
+func (b *broadcaster) authorizeJoin(sub *Subscription, rooms []string) error {
+	if b.authorizer == nil {
+		return nil
+	}
+
+	for _, r := range rooms {
+		if err := b.authorizer.AuthorizeJoin(sub, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
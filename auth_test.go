@@ -0,0 +1,199 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithAuthorizer_WithNilAuthorizer(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithAuthorizer(nil)(b); err == nil {
+		t.Fatalf("WithAuthorizer(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_JoinRoom_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&roomAuthorizer{allowed: "allowed"}))
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+
+	if err := b.JoinRoom(s, "allowed", "forbidden"); err == nil {
+		t.Fatalf("JoinRoom of a forbidden room; expected an error")
+	}
+
+	if rooms := b.RoomsOf(s); len(rooms) != 1 {
+		t.Fatalf("subscription joined %v; want only the default room", rooms)
+	}
+
+	if err := b.JoinRoom(s, "allowed"); err != nil {
+		t.Fatalf("JoinRoom of an allowed room returned %v", err)
+	}
+}
+
+func TestBroadcaster_ToRoom_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&roomAuthorizer{allowed: "allowed"}), WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	s := b.Subscribe(func(_ interface{}) { calls++ })
+	b.JoinRoom(s, "allowed")
+
+	if err := b.ToRoom("data", "forbidden"); err == nil {
+		t.Fatalf("ToRoom to a forbidden room; expected an error")
+	}
+
+	if err := b.ToRoom("data", "allowed"); err != nil || calls != 1 {
+		t.Fatalf("ToRoom to an allowed room returned %v and delivered %d messages; want nil and 1", err, calls)
+	}
+}
+
+func TestBroadcaster_ToSubscriber(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	var target, other []interface{}
+	s := b.Subscribe(func(data interface{}) { target = append(target, data) })
+	b.Subscribe(func(data interface{}) { other = append(other, data) })
+
+	if err := b.ToSubscriber("data", s.ID()); err != nil {
+		t.Fatalf("ToSubscriber returned %v", err)
+	}
+
+	if len(target) != 1 || len(other) != 0 {
+		t.Fatalf("ToSubscriber delivered to %d targeted and %d other subscriptions; want 1 and 0", len(target), len(other))
+	}
+}
+
+func TestBroadcaster_ToSubscriber_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&roomAuthorizer{}), WithSynchronousDelivery())
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+
+	if err := b.ToSubscriber("data", s.ID()); err == nil {
+		t.Fatalf("ToSubscriber of a rejected message; expected an error")
+	}
+}
+
+// roomAuthorizer allows a single room.
+type roomAuthorizer struct {
+	allowed string
+}
+
+func (a *roomAuthorizer) AuthorizeJoin(_ *Subscription, room string) error {
+	if room == a.allowed || room == "default" {
+		return nil
+	}
+
+	return errors.New("forbidden")
+}
+
+func (a *roomAuthorizer) AuthorizeSend(msg *Message) error {
+	if len(msg.Rooms) == 1 && msg.Rooms[0] == a.allowed {
+		return nil
+	}
+
+	return errors.New("forbidden")
+}
+
+func TestBroadcaster_JoinTree_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&joinAuthorizer{allowed: "allowed"}), WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	s := b.Subscribe(func(_ interface{}) { calls++ })
+	b.LeaveRoom(s, "default")
+
+	if err := b.JoinTree(s, "allowed", "forbidden"); err == nil {
+		t.Fatalf("JoinTree of a forbidden tree; expected an error")
+	}
+
+	b.ToRoom("data", "forbidden/child")
+	b.ToRoom("data", "allowed/child")
+
+	if calls != 0 {
+		t.Fatalf("rejected JoinTree delivered %d messages; want 0", calls)
+	}
+
+	if err := b.JoinTree(s, "allowed"); err != nil {
+		t.Fatalf("JoinTree of an allowed tree returned %v", err)
+	}
+}
+
+func TestBroadcaster_JoinGroup_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&joinAuthorizer{allowed: "allowed"}), WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	s := b.Subscribe(func(_ interface{}) { calls++ })
+	b.LeaveRoom(s, "default")
+
+	if err := b.JoinGroup(s, "forbidden"); err == nil {
+		t.Fatalf("JoinGroup of a forbidden group; expected an error")
+	}
+
+	b.ToRoom("data", "forbidden")
+
+	if calls != 0 {
+		t.Fatalf("rejected JoinGroup delivered %d messages; want 0", calls)
+	}
+}
+
+func TestBroadcaster_JoinTree_Closed(t *testing.T) {
+	b, cancel, _ := New()
+	s := b.Subscribe(func(_ interface{}) {})
+	cancel()
+
+	if err := b.JoinTree(s, "tree"); err != ErrBroadcasterClosed {
+		t.Fatalf("JoinTree after cancel returned %v; want ErrBroadcasterClosed", err)
+	}
+
+	if err := b.JoinGroup(s, "group"); err != ErrBroadcasterClosed {
+		t.Fatalf("JoinGroup after cancel returned %v; want ErrBroadcasterClosed", err)
+	}
+}
+
+func TestBroadcaster_Send_WithAuthorizer_SpoofedReply(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&roomAuthorizer{allowed: "allowed"}), WithSynchronousDelivery())
+	defer cancel()
+	spoof := func(msg *Message) {
+		msg.Rooms = []string{"forbidden"}
+		msg.CorrelationID = "spoofed"
+	}
+
+	if err := b.Send("data", spoof); err == nil {
+		t.Fatalf("Send of a message posing as a reply; expected an error")
+	}
+}
+
+func TestBroadcaster_Request_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&roomAuthorizer{allowed: "allowed"}))
+	defer cancel()
+	s := b.Subscribe(func(data interface{}) {
+		if req, ok := data.(*Request); ok {
+			req.Reply("pong")
+		}
+	})
+	b.JoinRoom(s, "allowed")
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second)
+	defer cancelCtx()
+
+	if reply, err := b.Request(ctx, "ping", "allowed"); err != nil || reply != "pong" {
+		t.Fatalf("Request returned %v, %v; want pong and no error", reply, err)
+	}
+}
+
+// joinAuthorizer allows a single room to be joined and all sends.
+type joinAuthorizer struct {
+	allowed string
+}
+
+func (a *joinAuthorizer) AuthorizeJoin(_ *Subscription, room string) error {
+	if room == a.allowed || room == "default" {
+		return nil
+	}
+
+	return errors.New("forbidden")
+}
+
+func (a *joinAuthorizer) AuthorizeSend(_ *Message) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchDispatcher can be implemented by a MessageDispatcher to send several messages at once,
+// e.g. in a single broker round-trip. It is used when batching is enabled with WithDispatchBatching.
+type BatchDispatcher interface {
+	MessageDispatcher
+	// DispatchBatch sends the messages in order to an external service and returns an error if it failed.
+	DispatchBatch(msgs []*Message) error
+}
+
+// WithDispatchBatching collects the messages passed to the Dispatcher and sends them with
+// DispatchBatch once size messages are collected or the first of them waited for latency.
+// Remaining messages are sent when the broadcaster is canceled or drained. Failed batches
+// count every message as a failed dispatch in the Metrics. The Dispatcher needs to implement
+// BatchDispatcher and the contexts of the messages are not passed to it.
+func WithDispatchBatching(size int, latency time.Duration) Option {
+	return func(b *broadcaster) error {
+		if size <= 0 {
+			return errors.New("dispatch batch size must be positive")
+		}
+
+		if latency <= 0 {
+			return errors.New("dispatch batch latency must be positive")
+		}
+
+		b.batcher = &batcher{mux: &sync.Mutex{}, size: size, latency: latency}
+		return nil
+	}
+}
+
+// batcher collects dispatched messages until a batch is full or its latency passed.
+type batcher struct {
+	mux     *sync.Mutex
+	size    int
+	latency time.Duration
+	pending []*Message
+	timer   Timer
+}
+
+// batch adds a dispatched message to the current batch and sends the batch if it is full.
+// The message counts as in progress until its batch is sent, so Drain waits for it.
+func (b *broadcaster) batch(msg *Message) {
+	b.addPending(1)
+
+	c := b.batcher
+	c.mux.Lock()
+	c.pending = append(c.pending, msg)
+
+	// Once the broadcaster is draining, batches don't wait for their latency.
+	if len(c.pending) < c.size && atomic.LoadInt32(&b.draining) == 0 {
+		if len(c.pending) == 1 {
+			c.timer = b.clock.AfterFunc(c.latency, b.flushBatch)
+		}
+		c.mux.Unlock()
+		return
+	}
+
+	msgs := c.take()
+	c.mux.Unlock()
+
+	b.dispatchBatch(msgs)
+}
+
+// flushBatch sends the messages collected so far.
+func (b *broadcaster) flushBatch() {
+	c := b.batcher
+	c.mux.Lock()
+	msgs := c.take()
+	c.mux.Unlock()
+
+	b.dispatchBatch(msgs)
+}
+
+// take removes the collected messages and stops the latency timer.
+func (c *batcher) take() []*Message {
+	msgs := c.pending
+	c.pending = nil
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	return msgs
+}
+
+func (b *broadcaster) dispatchBatch(msgs []*Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	defer b.addPending(-int64(len(msgs)))
+
+	err := b.dispatcher.(BatchDispatcher).DispatchBatch(msgs)
+	if err != nil && b.metrics != nil {
+		for range msgs {
+			b.metrics.DispatchFailed()
+		}
+	}
+}
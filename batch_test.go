@@ -0,0 +1,91 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type batchDispatcher struct {
+	mockMessageDispatcher
+	mux     *sync.Mutex
+	batches [][]*Message
+}
+
+func newBatchDispatcher() *batchDispatcher {
+	return &batchDispatcher{mux: &sync.Mutex{}}
+}
+
+func (d *batchDispatcher) DispatchBatch(msgs []*Message) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.batches = append(d.batches, msgs)
+	return nil
+}
+
+func (d *batchDispatcher) sizes() []int {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	sizes := []int{}
+	for _, batch := range d.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func TestBroadcaster_WithDispatchBatching_ShouldSendFullBatches(t *testing.T) {
+	dispatcher := newBatchDispatcher()
+	b, cancel, _ := New(WithDispatcher(dispatcher), WithDispatchBatching(3, time.Hour), WithSynchronousDelivery())
+	defer cancel()
+
+	for i := 0; i < 7; i++ {
+		b.ToAll(i)
+	}
+
+	if sizes := dispatcher.sizes(); len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 3 {
+		t.Fatalf("Dispatched batches of %v; want two batches of 3", sizes)
+	}
+
+	if first := dispatcher.batches[0]; first[0].Data != 0 || first[2].Data != 2 {
+		t.Fatalf("First batch holds %v and %v; want 0 and 2 in order", first[0].Data, first[2].Data)
+	}
+}
+
+func TestBroadcaster_WithDispatchBatching_ShouldFlushAfterLatency(t *testing.T) {
+	dispatcher := newBatchDispatcher()
+	b, cancel, _ := New(WithDispatcher(dispatcher), WithDispatchBatching(100, time.Millisecond*20), WithSynchronousDelivery())
+	defer cancel()
+
+	b.ToAll("a")
+	b.ToAll("b")
+	time.Sleep(time.Millisecond * 100)
+
+	if sizes := dispatcher.sizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Fatalf("Dispatched batches of %v; want one batch of 2", sizes)
+	}
+}
+
+func TestBroadcaster_WithDispatchBatching_DrainShouldFlush(t *testing.T) {
+	dispatcher := newBatchDispatcher()
+	b, _, _ := New(WithDispatcher(dispatcher), WithDispatchBatching(100, time.Hour))
+
+	b.ToAll("a")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if err := b.Drain(ctx); err != nil {
+		t.Fatalf("Drain() returned %v; want the batch to be flushed", err)
+	}
+
+	if sizes := dispatcher.sizes(); len(sizes) != 1 || sizes[0] != 1 {
+		t.Fatalf("Dispatched batches of %v after Drain(); want one batch of 1", sizes)
+	}
+}
+
+func TestWithDispatchBatching_WithoutBatchDispatcher(t *testing.T) {
+	if _, _, err := New(WithDispatchBatching(10, time.Millisecond)); err == nil {
+		t.Fatalf("New() with batching and a Dispatcher without DispatchBatch should fail")
+	}
+}
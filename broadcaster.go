@@ -4,22 +4,79 @@
 package broadcast
 
 import (
+	"context"
 	"errors"
+	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/xid"
 )
 
 // Broadcaster defines all broadcast operations.
+// After the broadcaster is canceled, operations that return an error return ErrBroadcasterClosed.
 type Broadcaster interface {
 	Subscribe(func(interface{})) *Subscription
+	SubscribeAck(func(interface{}) error) *Subscription
+	SubscribeMessage(func(*Message)) *Subscription
+	SubscribeContext(func(ctx context.Context, data interface{})) *Subscription
+	SubscribeWithOptions(callback func(interface{}), options ...SubscribeOption) *Subscription
+	SubscribeWithID(id string, callback func(interface{})) (*Subscription, error)
+	SubscribeWebhook(url string, options ...WebhookOption) (*Subscription, error)
 	Unsubscribe(*Subscription)
-	JoinRoom(s *Subscription, rooms ...string)
+	JoinRoom(s *Subscription, rooms ...string) error
 	LeaveRoom(s *Subscription, rooms ...string)
-	ToAll(data interface{}, except ...string)
-	ToRoom(data interface{}, room string, except ...string)
+	JoinRoomAll(room string, subs ...*Subscription) error
+	MoveAll(fromRoom string, toRoom string) error
+	ClearRoom(room string)
+	JoinTree(s *Subscription, rooms ...string) error
+	LeaveTree(s *Subscription, rooms ...string)
+	JoinGroup(s *Subscription, groups ...string) error
+	LeaveGroup(s *Subscription, groups ...string)
+	Send(data interface{}, options ...SendOption) error
+	ToAll(data interface{}, except ...string) error
+	ToAllWithOptions(data interface{}, options ...SendOption) error
+	ToRoom(data interface{}, room string, except ...string) error
+	ToAllCtx(ctx context.Context, data interface{}, except ...string) error
+	ToRoomCtx(ctx context.Context, data interface{}, room string, except ...string) error
+	ToSubscriber(data interface{}, id string) error
+	ToRoomWithOptions(data interface{}, room string, options ...SendOption) error
+	ToRoomFrom(sub *Subscription, data interface{}, room string, except ...string) error
+	ToRooms(data interface{}, rooms []string, except ...string) error
+	ToRoomBatch(items []interface{}, room string, except ...string) error
+	ToMatching(data interface{}, match func(meta SubMeta) bool) error
+	ToRoomSample(data interface{}, room string, n int) error
+	ToRoomPercent(data interface{}, room string, percent float64) error
+	ToRoomKeyed(data interface{}, room string, key string) error
+	IdleSubscribers(threshold time.Duration) []string
+	SubscriptionStats(id string) (SubscriptionStats, bool)
+	ToRoomPattern(data interface{}, pattern string, except ...string) error
+	Request(ctx context.Context, data interface{}, room string) (interface{}, error)
+	Gather(ctx context.Context, data interface{}, room string) ([]Reply, error)
+	Replay(s *Subscription, room string, since time.Time) (int, error)
+	ReplaySince(s *Subscription, room string, id string) (int, error)
+	ToAllSync(ctx context.Context, data interface{}, except ...string) (int, error)
+	ToRoomSync(ctx context.Context, data interface{}, room string, except ...string) (int, error)
 	RoomsOf(s *Subscription) []string
+	Subscribers(room string) []string
+	LastSeq(room string) uint64
+	ForEachSubscriber(room string, fn func(info SubscriberInfo) bool)
+	CountSubscribers(room string) int
+	CountRooms() int
+	Stats() BroadcasterStats
+	CreateRoom(name string, meta map[string]string)
+	SetRoomTransformer(room string, transform Transformer)
+	OnDemand(room string, start func(), stop func())
+	Rooms(filter func(name string, info RoomInfo) bool) []RoomInfo
+	ClusterSubscribers(room string) []Member
+	Namespace(name string) (Broadcaster, error)
+	Publisher() Publisher
+	SubscriberAPI() Subscriber
+	Drain(ctx context.Context) error
+	Health(ctx context.Context) error
+	Export() Snapshot
+	Restore(snapshot Snapshot, resubscribe func(id string) func(interface{})) error
 	Done() <-chan struct{}
 }
 
@@ -39,6 +96,19 @@ func WithPoolSize(size int) Option {
 	}
 }
 
+// WithPoolMinSize sets the number of go routines that keep running after
+// the pool timeout instead of exiting. Default is 0.
+func WithPoolMinSize(size int) Option {
+	return func(b *broadcaster) error {
+		if size < 0 {
+			return errors.New("pool min size cannot be negative")
+		}
+
+		b.pool.min = int32(size)
+		return nil
+	}
+}
+
 // WithPoolTimeout sets the duration a go routine responsible for
 // sending messages to subscribers will linger after it is done with sending mesasges.
 // Default is 5 minutes.
@@ -70,25 +140,71 @@ func WithDefaultRoomName(name string) Option {
 	}
 }
 
+// WithSubscriberBuffer gives every subscription a buffer that can hold up to size messages.
+// Messages are delivered to a buffered subscription one at a time so a slow callback
+// occupies at most one pool go routine. The policy decides what happens when the buffer is full.
+// By default subscriptions are not buffered.
+func WithSubscriberBuffer(size int, policy OverflowPolicy) Option {
+	return func(b *broadcaster) error {
+		if size <= 0 {
+			return errors.New("subscriber buffer size must be positive")
+		}
+
+		if policy < OverflowBlock || policy > OverflowClose {
+			return errors.New("unknown overflow policy")
+		}
+
+		b.bufferSize = size
+		b.overflowPolicy = policy
+		return nil
+	}
+}
+
+// WithSynchronousDelivery makes ToAll and ToRoom block until all local subscriptions
+// have received the message and calls the Dispatcher on the sending go routine.
+// This is mostly useful for deterministic tests.
+func WithSynchronousDelivery() Option {
+	return func(b *broadcaster) error {
+		b.synchronous = true
+		return nil
+	}
+}
+
+// WithOrderedDelivery guarantees that every subscription receives the messages sent from
+// a single go routine in the order they were sent. Messages are queued per subscription on
+// the sending go routine and delivered one at a time, while different subscriptions still
+// receive messages in parallel. Subscriptions without a buffer set by WithSubscriberBuffer
+// get a buffer of 64 messages that blocks the sender when it is full.
+func WithOrderedDelivery() Option {
+	return func(b *broadcaster) error {
+		b.ordered = true
+		return nil
+	}
+}
+
 // CancelFunc represents a function used to cancel all go routines used by the Broadcaster.
 type CancelFunc func()
 
 // New creates a new Broadcaster.
 func New(options ...Option) (Broadcaster, CancelFunc, error) {
-	pool := &pool{
-		cancelc: make(chan struct{}),
-		tickets: make(chan struct{}, defaultPoolSize),
-		tasks:   make(chan func()),
-		timeout: defaultPoolTimeout,
-	}
 	var mux sync.RWMutex
 	b := &broadcaster{
-		pool:            pool,
-		rooms:           make(map[string]*room),
+		pool:            newPool(int(defaultPoolSize), defaultPoolTimeout),
+		rooms:           newRoomMap(defaultRoomShards),
+		trees:           make(map[string]*room),
+		groups:          make(map[string]*group),
+		retained:        make(map[string]*Message),
 		mux:             &mux,
 		dispatcher:      &noopDispatcher{},
 		defaultRoomName: "default",
+		roomSeparator:   defaultRoomSeparator,
+		instanceID:      xid.New().String(),
+		maxAttempts:     defaultMaxAttempts,
+		redeliveryDelay: defaultRedeliveryDelay,
 		done:            make(chan struct{}),
+		settled:         make(chan struct{}, 1),
+		cancelOnce:      &sync.Once{},
+		clock:           realClock{},
 	}
 
 	for _, option := range options {
@@ -99,32 +215,157 @@ func New(options ...Option) (Broadcaster, CancelFunc, error) {
 		}
 	}
 
-	b.dispatcher.Received(func(data interface{}, toAll bool, room string, except ...string) {
-		if toAll {
-			b.toAllLocal(data, except...)
-			return
+	if b.idleThreshold > 0 && b.heartbeatInterval == 0 {
+		return nil, nil, errors.New("idle eviction requires a heartbeat")
+	}
+
+	if int(b.pool.min) > cap(b.pool.tickets) {
+		return nil, nil, errors.New("pool min size cannot exceed pool size")
+	}
+
+	b.rooms.factory = b.roomFactory
+	b.pool.clock = b.clock
+	b.pool.drop = b.spilled
+	for _, p := range b.roomPools {
+		p.timeout = b.pool.timeout
+		p.maxPending = b.pool.maxPending
+		p.spillover = b.pool.spillover
+		p.clock = b.clock
+		p.drop = b.spilled
+	}
+	b.observePools()
+	for _, l := range b.roomLimiters {
+		l.setClock(b.clock)
+	}
+	if b.pacer != nil {
+		b.pacer.clock = b.clock
+	}
+
+	if d, ok := b.dispatcher.(MessageDispatcher); ok {
+		d.ReceivedMessage(b.receive)
+	} else {
+		b.dispatcher.Received(func(data interface{}, toAll bool, room string, except ...string) {
+			b.receive(&Message{Data: data, ToAll: toAll, Rooms: roomsOf(room), Except: except})
+		})
+	}
+
+	if _, ok := b.dispatcher.(BatchDispatcher); b.batcher != nil && !ok {
+		return nil, nil, errors.New("dispatch batching requires a BatchDispatcher")
+	}
+
+	if b.cluster != nil {
+		d, ok := b.dispatcher.(PresenceDispatcher)
+		if !ok {
+			return nil, nil, errors.New("cluster presence requires a PresenceDispatcher")
 		}
 
-		b.toRoomLocal(data, room, except...)
-	})
+		d.ReceivedPresence(b.receivePresence)
+		b.dispatchPresence(PresenceEvent{Instance: b.instanceID, Joined: true})
+	}
+
+	b.cancel = func() {
+		b.cancelOnce.Do(func() {
+			atomic.StoreInt32(&b.closed, 1)
+
+			if b.cluster != nil {
+				b.dispatchPresence(PresenceEvent{Instance: b.instanceID})
+			}
 
-	cancel := func() {
-		go func() {
-			b.pool.cancel()
-			close(b.done)
-		}()
+			if b.batcher != nil {
+				b.flushBatch()
+			}
+
+			b.stopDemands()
+
+			go func() {
+				b.cancelPools()
+				close(b.done)
+			}()
+		})
 	}
 
-	return b, cancel, nil
+	b.scheduleExpiry()
+	b.scheduleHeartbeat()
+
+	return b, b.cancel, nil
 }
 
 type broadcaster struct {
-	pool            *pool
-	mux             *sync.RWMutex
-	rooms           map[string]*room
-	dispatcher      Dispatcher
-	defaultRoomName string
-	done            chan struct{}
+	counters             counters
+	pool                 *pool
+	mux                  *sync.RWMutex
+	rooms                *roomMap
+	trees                map[string]*room
+	groups               map[string]*group
+	retainPatterns       []string
+	retained             map[string]*Message
+	store                Store
+	storeErrorHandler    func(err error)
+	historyTTL           time.Duration
+	dispatcher           Dispatcher
+	defaultRoomName      string
+	roomSeparator        string
+	done                 chan struct{}
+	cancel               CancelFunc
+	cancelOnce           *sync.Once
+	draining             int32
+	settled              chan struct{}
+	closed               int32
+	bufferSize           int
+	overflowPolicy       OverflowPolicy
+	watchdog             *watchdog
+	slowConsumerHook     func(s *Subscription, latency time.Duration)
+	errorHandler         ErrorHandler
+	panicLimit           int32
+	maxAttempts          int
+	redeliveryDelay      time.Duration
+	deadLetterHandler    DeadLetterHandler
+	synchronous          bool
+	ordered              bool
+	roomLimiters         map[string]*rateLimiter
+	roomPools            map[string]*pool
+	roomCapacities       map[string]int
+	maxSubscriptions     int64
+	rejectionHook        func(sub *Subscription, err error)
+	quotas               *quotas
+	quotaRejectionHook   func(producer string, msg *Message)
+	roomIdle             time.Duration
+	roomExpiry           RoomExpiry
+	roomExpiredHook      func(room string)
+	heartbeatInterval    time.Duration
+	heartbeatPayload     interface{}
+	idleThreshold        time.Duration
+	subscriberRateLimit  *rateLimit
+	conflations          map[string]ConflationKey
+	instanceID           string
+	dedupe               *deduper
+	idempotency          *deduper
+	cluster              *clusterView
+	roomCreatedHook      func(room string)
+	roomEmptiedHook      func(room string)
+	subscribeHook        func(sub *Subscription)
+	unsubscribeHook      func(sub *Subscription)
+	authorizer           Authorizer
+	auditor              Auditor
+	metrics              Metrics
+	tracer               Tracer
+	middleware           []Middleware
+	inboundMiddleware    []Middleware
+	transformers         map[string]Transformer
+	demands              map[string]*demand
+	replies              map[string]*Subscription
+	roomCodecs           map[string]*roomCodec
+	encryptor            Encryptor
+	compressor           Compressor
+	compressionThreshold int
+	payloadErrorHandler  func(err error)
+	cloner               func(data interface{}) interface{}
+	pacer                *pacer
+	roomFactory          func(name string) Room
+	batcher              *batcher
+	idGenerator          func() string
+	claimed              map[string]struct{}
+	clock                Clock
 }
 
 // Done returns a channel that is closed when all internal go routines exit.
@@ -135,48 +376,159 @@ func (b *broadcaster) Done() <-chan struct{} {
 // Subscribe creates a new subscription.
 // All subscriptions are added to the default room upon creation.
 func (b *broadcaster) Subscribe(callback func(interface{})) *Subscription {
+	sub := b.newSubscription(callback)
+	b.subscribed(sub)
+
+	return sub
+}
+
+// SubscribeMessage works like Subscribe but the callback receives the whole message,
+// including its ID and timestamp. The message is shared between subscriptions and must not be modified.
+func (b *broadcaster) SubscribeMessage(callback func(msg *Message)) *Subscription {
+	sub := b.newSubscription(nil)
+	sub.handler = callback
+	b.subscribed(sub)
+
+	return sub
+}
+
+// newSubscription creates a subscription that is not part of any room.
+func (b *broadcaster) newSubscription(callback func(interface{})) *Subscription {
 	sub := &Subscription{
-		id:       xid.New().String(),
-		callback: callback,
+		id:           b.nextID(),
+		callback:     callback,
+		lastDelivery: b.clock.Now().UnixNano(),
+	}
+
+	if b.bufferSize > 0 {
+		sub.queue = newQueue(b.bufferSize, b.overflowPolicy)
+	} else if b.ordered || len(b.conflations) > 0 {
+		sub.queue = newQueue(defaultQueueSize, OverflowBlock)
+	}
+
+	if len(b.conflations) > 0 {
+		sub.conflator = newConflator()
 	}
 
-	b.JoinRoom(sub, b.defaultRoomName)
+	if l := b.subscriberRateLimit; l != nil {
+		sub.limiter = newRateLimiter(l.rate, l.burst, l.policy, b.pool.do, b.clock)
+	}
 
 	return sub
 }
 
-// Unsubscribe removes a subscription from all rooms.
+// subscribed adds a new subscription to the default room and calls the subscribe hook.
+// It returns the error of a subscription that was rejected.
+func (b *broadcaster) subscribed(sub *Subscription) error {
+	sub.broadcaster = b
+	if b.isClosed() {
+		sub.closed = 1
+		return ErrBroadcasterClosed
+	}
+
+	if err := b.enter(sub, b.defaultRoomName); err != nil {
+		return err
+	}
+
+	if b.subscribeHook != nil {
+		b.subscribeHook(sub)
+	}
+
+	return nil
+}
+
+// Unsubscribe removes a subscription from all rooms, room trees and groups.
+// Buffered messages that were not delivered yet are discarded.
 func (b *broadcaster) Unsubscribe(s *Subscription) {
+	first := atomic.CompareAndSwapInt32(&s.closed, 0, 1)
+
+	if s.queue != nil {
+		s.queue.close()
+	}
+
+	emptied := []string{}
+	b.rooms.each(func(name string, room *room) bool {
+		if removed, empty := room.removeSubscription(s); removed {
+			b.announce(s, name, false)
+
+			if empty {
+				emptied = append(emptied, name)
+			}
+		}
+		return true
+	})
+
 	b.mux.RLock()
-	defer b.mux.RUnlock()
+	for _, tree := range b.trees {
+		tree.removeSubscription(s)
+	}
 
-	for _, room := range b.rooms {
-		room.removeSubscription(s)
+	for _, g := range b.groups {
+		g.members.removeSubscription(s)
+	}
+	b.mux.RUnlock()
+
+	b.roomsEmptied(emptied)
+
+	if !first {
+		return
+	}
+
+	b.unclaim(s.id)
+	b.measureSubscriptions(-1)
+	if b.unsubscribeHook != nil {
+		b.unsubscribeHook(s)
 	}
 }
 
 // JoinRoom adds a subscription to one or multiple rooms.
+// If a room retains its last message, the message is sent to the subscription right away.
 // Subsequent calls with the same room and subscription have no effect.
-func (b *broadcaster) JoinRoom(sub *Subscription, rooms ...string) {
+// If the Authorizer rejects any of the rooms, the subscription joins none of them and the error is returned.
+// If a room is full, the subscription joins the rooms before it and ErrRoomFull is returned, see WithRoomCapacity.
+// A closed subscription can't join rooms, ErrSubscriptionClosed is returned.
+func (b *broadcaster) JoinRoom(sub *Subscription, rooms ...string) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	if err := b.authorizeJoin(sub, rooms); err != nil {
+		return err
+	}
+
+	return b.joinRoom(sub, rooms...)
+}
+
+// joinRoom adds a subscription to the rooms in order and stops at the first room that is full.
+func (b *broadcaster) joinRoom(sub *Subscription, rooms ...string) error {
+	if sub.isClosed() {
+		return ErrSubscriptionClosed
+	}
+
 	for _, r := range rooms {
-		b.mux.RLock()
-		existingRoom := b.rooms[r]
-		b.mux.RUnlock()
+		var added, first, full bool
+		b.rooms.join(r, func(rm *room) {
+			added, first, full = rm.addSubscriptionWithin(sub, b.roomCapacities[r])
+		})
+		if full {
+			return roomFull(r)
+		}
 
-		if existingRoom == nil {
-			var roomMux sync.RWMutex
-			existingRoom = &room{
-				subscriptions: make(map[string]*Subscription),
-				mux:           &roomMux,
-			}
+		if !added {
+			continue
+		}
 
-			b.mux.Lock()
-			b.rooms[r] = existingRoom
-			b.mux.Unlock()
+		b.touchRooms([]string{r})
+
+		if first {
+			b.roomCreated(r)
 		}
 
-		existingRoom.addSubscription(sub)
+		b.announce(sub, r, true)
+		b.sendRetained(sub, r)
 	}
+
+	return nil
 }
 
 // LeaveRoom removes a subscription from a room.
@@ -184,94 +536,427 @@ func (b *broadcaster) JoinRoom(sub *Subscription, rooms ...string) {
 // Removing a subscription from the default room will prevent
 // the subscription from receiving messages when ToAll is called.
 func (b *broadcaster) LeaveRoom(sub *Subscription, rooms ...string) {
-	b.mux.RLock()
-	defer b.mux.RUnlock()
-
+	emptied := []string{}
 	for _, r := range rooms {
-		existingRoom := b.rooms[r]
+		existingRoom := b.rooms.get(r)
 		if existingRoom == nil {
 			continue
 		}
 
-		existingRoom.removeSubscription(sub)
+		if removed, empty := existingRoom.removeSubscription(sub); removed {
+			b.announce(sub, r, false)
+
+			if empty {
+				emptied = append(emptied, r)
+			}
+		}
 	}
+
+	b.roomsEmptied(emptied)
 }
 
 // ToAll sends a message to all subscriptions except the subscriptions
 // that are part of the rooms specified with "except".
 // ToAll won't send messages to the subscriptions manually removed from the default room.
-func (b *broadcaster) ToAll(data interface{}, except ...string) {
-	go b.dispatcher.Dispatch(data, true, "", except...)
-	b.toAllLocal(data, except...)
+func (b *broadcaster) ToAll(data interface{}, except ...string) error {
+	return b.publish(&Message{Data: data, ToAll: true, Except: except})
 }
 
-func (b *broadcaster) toAllLocal(data interface{}, except ...string) {
-	b.mux.RLock()
-	defaultRoom, ok := b.rooms[b.defaultRoomName]
-	if !ok {
+// ToAllWithOptions works like ToAll but the recipients are narrowed down with send options.
+func (b *broadcaster) ToAllWithOptions(data interface{}, options ...SendOption) error {
+	msg := newMessage(data, options...)
+	msg.ToAll = true
+	return b.publish(msg)
+}
+
+// ToAllSync works like ToAll but blocks until all local subscriptions have received
+// the message or the context is done. It returns the number of subscriptions whose callback
+// completed successfully and, if the context is done first, the context error.
+func (b *broadcaster) ToAllSync(ctx context.Context, data interface{}, except ...string) (int, error) {
+	return b.publishSync(ctx, &Message{Data: data, ToAll: true, Except: except})
+}
+
+// ToRoom sends a message to all subscriptions within a room except
+// the subscriptions that are part of the rooms specified with "except".
+// It returns the error of the Authorizer if the message is rejected.
+func (b *broadcaster) ToRoom(data interface{}, room string, except ...string) error {
+	return b.publish(&Message{Data: data, Rooms: []string{room}, Except: except})
+}
+
+// ToSubscriber sends a message to the subscription with the given ID, on this
+// or, if the Dispatcher implements MessageDispatcher, another instance.
+// It returns the error of the Authorizer if the message is rejected.
+func (b *broadcaster) ToSubscriber(data interface{}, id string) error {
+	return b.publish(&Message{Data: data, Subscribers: []string{id}})
+}
+
+// ToRoomWithOptions works like ToRoom but the recipients are narrowed down with send options.
+func (b *broadcaster) ToRoomWithOptions(data interface{}, room string, options ...SendOption) error {
+	msg := newMessage(data, options...)
+	msg.Rooms = []string{room}
+	return b.publish(msg)
+}
+
+// ToRooms sends a message to all subscriptions within any of the rooms except
+// the subscriptions that are part of the rooms specified with "except".
+// A subscription that is part of several of the rooms receives the message once.
+func (b *broadcaster) ToRooms(data interface{}, rooms []string, except ...string) error {
+	return b.publish(&Message{Data: data, Rooms: rooms, Except: except})
+}
+
+// ToRoomBatch sends several items to all subscriptions within a room as a single message,
+// so every subscription callback is called once with the []interface{} holding all items.
+// An empty batch is not sent.
+func (b *broadcaster) ToRoomBatch(items []interface{}, room string, except ...string) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	return b.publish(&Message{Data: items, Rooms: []string{room}, Except: except, Batch: true})
+}
+
+// ToRoomPattern sends a message to all subscriptions within the existing rooms whose name
+// matches the pattern, except the subscriptions that are part of the rooms specified with "except".
+// The pattern syntax is the one used by path.Match, e.g. "game:*:lobby".
+// A subscription that is part of several matching rooms receives the message once.
+func (b *broadcaster) ToRoomPattern(data interface{}, pattern string, except ...string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	return b.publish(&Message{Data: data, RoomPattern: pattern, Except: except})
+}
+
+// ToRoomSync works like ToRoom but blocks until all local subscriptions within the room
+// have received the message or the context is done. It returns the number of subscriptions
+// whose callback completed successfully and, if the context is done first, the context error.
+func (b *broadcaster) ToRoomSync(ctx context.Context, data interface{}, room string, except ...string) (int, error) {
+	return b.publishSync(ctx, &Message{Data: data, Rooms: []string{room}, Except: except})
+}
+
+// publish sends a message to other instances and local subscriptions, or only to one
+// of them with LocalOnly or RemoteOnly, unless the Authorizer rejects it.
+func (b *broadcaster) publish(msg *Message) error {
+	if msg.local && msg.remote {
+		return errLocalAndRemote
+	}
+
+	if err := b.accept(); err != nil {
+		return err
+	}
+	defer b.release()
+
+	return b.intercept(msg, func(msg *Message) error {
+		if err := b.permit(msg); err != nil {
+			b.audit(msg, err, Receipt{}, nil)
+			return err
+		}
+
+		if b.isRepeated(msg) {
+			return nil
+		}
+
+		span := b.traceSend(msg)
+		b.originate(msg)
+		if !msg.local {
+			b.dispatch(msg)
+		}
+		if !msg.remote {
+			b.deliverLocal(msg)
+		}
+		span.End(nil)
+		return nil
+	})
+}
+
+// stamp sets the ID and timestamp of a message that doesn't have them yet.
+func (b *broadcaster) stamp(msg *Message) {
+	if len(msg.ID) == 0 {
+		msg.ID = b.nextID()
+	}
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = b.clock.Now()
+	}
+}
+
+// publishSync works like publish but waits for the local deliveries.
+func (b *broadcaster) publishSync(ctx context.Context, msg *Message) (int, error) {
+	if msg.local && msg.remote {
+		return 0, errLocalAndRemote
+	}
+
+	if err := b.accept(); err != nil {
+		return 0, err
+	}
+	defer b.release()
+
+	delivered := 0
+	err := b.intercept(msg, func(msg *Message) error {
+		if err := b.permit(msg); err != nil {
+			b.audit(msg, err, Receipt{}, nil)
+			return err
+		}
+
+		if b.isRepeated(msg) {
+			return nil
+		}
+
+		span := b.traceSend(msg)
+		b.originate(msg)
+		b.dispatch(msg)
+		n, err := b.deliverLocalSync(ctx, msg)
+		delivered = n
+		span.End(err)
+		return err
+	})
+
+	return delivered, err
+}
+
+// publishLocal works like publish but doesn't pass the message to the Dispatcher.
+func (b *broadcaster) publishLocal(msg *Message) error {
+	if err := b.accept(); err != nil {
+		return err
+	}
+	defer b.release()
+
+	return b.intercept(msg, func(msg *Message) error {
+		if err := b.permit(msg); err != nil {
+			b.audit(msg, err, Receipt{}, nil)
+			return err
+		}
+
+		if b.isRepeated(msg) {
+			return nil
+		}
+
+		span := b.traceSend(msg)
+		b.originate(msg)
+		b.deliverLocal(msg)
+		span.End(nil)
+		return nil
+	})
+}
+
+// originate stamps a message sent by this broadcaster with its origin and remembers
+// the message ID so the message is not delivered again if a broker echoes it.
+func (b *broadcaster) originate(msg *Message) {
+	b.stamp(msg)
+	msg.Origin = b.instanceID
+	b.measureSent(msg)
+	b.audited(msg)
+	if !msg.remote {
+		b.sequence(msg)
+	}
+
+	if b.dedupe != nil {
+		b.dedupe.add(msg.ID, msg.Timestamp)
+	}
+}
+
+// receive delivers a message that arrived through the Dispatcher
+// unless it is an echo or a duplicate.
+func (b *broadcaster) receive(msg *Message) {
+	if b.accept() != nil {
 		return
 	}
-	b.mux.RUnlock()
+	defer b.release()
 
-	defaultRoom.mux.RLock()
-	defer defaultRoom.mux.RUnlock()
+	if b.isDuplicate(msg) {
+		return
+	}
 
-	for _, sub := range defaultRoom.subscriptions {
-		s := sub
-		b.pool.do(func() {
-			if b.isInRooms(s, except...) {
-				return
-			}
-			s.send(data)
-		})
+	data, err := b.unseal(msg.Data)
+	if err == nil {
+		data, err = b.decode(msg, data)
+	}
+	if err != nil {
+		b.payloadError(err)
+		return
+	}
+	msg.Data = data
+
+	err = chain(b.inboundMiddleware, func(msg *Message) error {
+		b.stamp(msg)
+		span := b.trace(SpanReceive, msg)
+		b.sequence(msg)
+		b.deliverLocal(msg)
+		span.End(nil)
+		return nil
+	})(msg)
+	if err != nil {
+		b.payloadError(err)
 	}
 }
 
-// ToRoom sends a message to all subscriptions within a room except
-// the subscriptions that are part of the rooms specified with "except".
-func (b *broadcaster) ToRoom(data interface{}, room string, except ...string) {
-	go b.dispatcher.Dispatch(data, false, room, except...)
-	b.toRoomLocal(data, room, except...)
+func (b *broadcaster) deliverLocal(msg *Message) {
+	if b.synchronous {
+		b.deliverLocalSync(context.Background(), msg)
+		return
+	}
+
+	t := b.track(msg)
+	b.fanOut(msg, t)
+	t.report()
 }
 
-func (b *broadcaster) toRoomLocal(data interface{}, room string, except ...string) {
-	b.mux.RLock()
-	defer b.mux.RUnlock()
+func (b *broadcaster) deliverLocalSync(ctx context.Context, msg *Message) (int, error) {
+	t := b.track(msg)
+	b.fanOut(msg, t)
+	t.report()
 
-	existingRoom := b.rooms[room]
-	if existingRoom == nil {
+	return t.wait(ctx)
+}
+
+func (b *broadcaster) dispatch(msg *Message) {
+	if b.synchronous {
+		b.dispatchMessage(msg)
 		return
 	}
 
-	defer existingRoom.mux.RUnlock()
-	existingRoom.mux.RLock()
+	b.addPending(1)
+	go func() {
+		defer b.release()
+		b.dispatchMessage(msg)
+	}()
+}
 
-	for _, sub := range existingRoom.subscriptions {
-		s := sub
-		b.pool.do(func() {
-			if b.isInRooms(s, except...) {
-				return
-			}
-			s.send(data)
-		})
+func (b *broadcaster) dispatchMessage(msg *Message) {
+	// The dispatched message carries the context of the dispatch span. It is a copy
+	// because local deliveries read the original concurrently.
+	span, carrier := b.startSpan(SpanDispatch, msg)
+	dispatched := *msg
+	dispatched.Trace = carrier
+	dispatched.receipt = nil
+	dispatched.audited = false
+
+	data, err := b.encode(&dispatched)
+	if err == nil {
+		data, err = b.seal(data)
+	}
+	if err != nil {
+		b.payloadError(err)
+		if b.metrics != nil {
+			b.metrics.DispatchFailed()
+		}
+		span.End(err)
+		return
+	}
+	dispatched.Data = data
+
+	if b.batcher != nil {
+		b.batch(&dispatched)
+		span.End(nil)
+		return
+	}
+
+	if d, ok := b.dispatcher.(ContextDispatcher); ok {
+		err = d.DispatchMessageContext(msg.Context(), &dispatched)
+		if err != nil && b.metrics != nil {
+			b.metrics.DispatchFailed()
+		}
+		span.End(err)
+		return
+	}
+
+	if d, ok := b.dispatcher.(FallibleDispatcher); ok {
+		err = d.TryDispatchMessage(&dispatched)
+		if err != nil && b.metrics != nil {
+			b.metrics.DispatchFailed()
+		}
+		span.End(err)
+		return
+	}
+
+	defer span.End(nil)
+
+	if d, ok := b.dispatcher.(MessageDispatcher); ok {
+		d.DispatchMessage(&dispatched)
+		return
+	}
+
+	if msg.ToAll {
+		b.dispatcher.Dispatch(data, true, "", msg.Except...)
+		return
+	}
+
+	// Dispatch can only target a single room. Excluding the rooms that were already
+	// dispatched keeps subscriptions in several target rooms from receiving duplicates.
+	rooms := b.targetRooms(msg)
+	for i, room := range rooms {
+		except := append(append([]string{}, msg.Except...), rooms[:i]...)
+		b.dispatcher.Dispatch(data, false, room, except...)
 	}
 }
 
-func (b *broadcaster) isInRooms(sub *Subscription, rooms ...string) bool {
-	b.mux.RLock()
-	defer b.mux.RUnlock()
+// isExcluded reports whether a message should not be delivered to a subscription.
+func (b *broadcaster) isExcluded(sub *Subscription, msg *Message) bool {
+	if len(msg.Namespace) > 0 && msg.Namespace != sub.namespace {
+		return true
+	}
+
+	if msg.match != nil && !msg.match(sub.meta) {
+		return true
+	}
+
+	if isSender(sub, msg) {
+		return true
+	}
+
+	for _, id := range msg.ExceptSubscribers {
+		if id == sub.id {
+			return true
+		}
+	}
+
+	return b.isInRooms(sub, msg.Except...)
+}
+
+// targetRooms returns the names of the rooms a message is sent to.
+// Patterns and cascades are resolved against the rooms that currently exist.
+func (b *broadcaster) targetRooms(msg *Message) []string {
+	if msg.ToAll {
+		return []string{b.defaultRoomName}
+	}
+
+	if len(msg.RoomPattern) == 0 && !msg.Cascade {
+		return msg.Rooms
+	}
+
+	rooms := append([]string{}, msg.Rooms...)
+	b.rooms.each(func(name string, _ *room) bool {
+		if len(msg.RoomPattern) > 0 {
+			if ok, _ := path.Match(msg.RoomPattern, name); ok {
+				rooms = append(rooms, name)
+				return true
+			}
+		}
+
+		if !msg.Cascade {
+			return true
+		}
 
+		for _, parent := range msg.Rooms {
+			if b.isDescendant(name, parent) {
+				rooms = append(rooms, name)
+				break
+			}
+		}
+		return true
+	})
+
+	return rooms
+}
+
+func (b *broadcaster) isInRooms(sub *Subscription, rooms ...string) bool {
 	for _, name := range rooms {
-		room := b.rooms[name]
+		room := b.rooms.get(name)
 		if room == nil {
 			continue
 		}
 
-		room.mux.RLock()
-		existingSub := room.subscriptions[sub.id]
-		room.mux.RUnlock()
-
-		if existingSub != nil {
+		if room.lookup(sub.id) != nil {
 			return true
 		}
 	}
@@ -281,22 +966,14 @@ func (b *broadcaster) isInRooms(sub *Subscription, rooms ...string) bool {
 
 // RoomsOf returns the rooms a given subscription belongs to.
 func (b *broadcaster) RoomsOf(s *Subscription) []string {
-	b.mux.RLock()
-	defer b.mux.RUnlock()
-
 	roomNames := []string{}
 
-	for name, room := range b.rooms {
-		room.mux.RLock()
-		_, ok := room.subscriptions[s.id]
-		room.mux.RUnlock()
-
-		if !ok {
-			continue
+	b.rooms.each(func(name string, room *room) bool {
+		if room.lookup(s.id) != nil {
+			roomNames = append(roomNames, name)
 		}
-
-		roomNames = append(roomNames, name)
-	}
+		return true
+	})
 
 	return roomNames
 }
@@ -1,6 +1,7 @@
 package broadcast
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -90,6 +91,26 @@ func TestWithPoolSize_WithNonPositiveSize(t *testing.T) {
 	}
 }
 
+func TestWithPoolMinSize(t *testing.T) {
+	b := createTestBroadcaster()
+	want := 5
+
+	WithPoolMinSize(want)(b)
+
+	got := int(b.pool.min)
+	if got != want {
+		t.Fatalf("WithPoolMinSize(%v); got pool with min size %v", want, got)
+	}
+}
+
+func TestNew_WithPoolMinSizeAbovePoolSize(t *testing.T) {
+	_, _, err := New(WithPoolSize(1), WithPoolMinSize(2))
+
+	if err == nil {
+		t.Fatalf("New should fail when the pool min size exceeds the pool size")
+	}
+}
+
 func TestWithPoolTimeout(t *testing.T) {
 	b := createTestBroadcaster()
 	want := time.Minute * 3
@@ -176,7 +197,7 @@ func TestBroadcaster_Subscribe_ShouldAddToDefaultRoom(t *testing.T) {
 
 	subscription := b.Subscribe(func(_ interface{}) {})
 
-	roomSubscription := b.rooms[b.defaultRoomName].subscriptions[subscription.ID()]
+	roomSubscription := b.rooms.get(b.defaultRoomName).lookup(subscription.ID())
 	if roomSubscription == nil {
 		t.Fatal("Subscribe should add the new subscription to the default room")
 	}
@@ -190,8 +211,8 @@ func TestBroadcaster_Unsubscribe(t *testing.T) {
 
 	b.Unsubscribe(subscription)
 
-	defaultRoomSubscription := b.rooms[b.defaultRoomName].subscriptions[subscription.ID()]
-	testRoomSubscription := b.rooms[testRoom].subscriptions[subscription.ID()]
+	defaultRoomSubscription := b.rooms.get(b.defaultRoomName).lookup(subscription.ID())
+	testRoomSubscription := b.rooms.get(testRoom).lookup(subscription.ID())
 
 	if defaultRoomSubscription != nil || testRoomSubscription != nil {
 		t.Fatal("Unsubscribe should remove subscription from all rooms")
@@ -214,17 +235,40 @@ func TestBroadcaster_JoinRoom(t *testing.T) {
 
 	b.JoinRoom(subscription, roomName)
 
-	room := b.rooms[roomName]
+	room := b.rooms.get(roomName)
 	if room == nil {
 		t.Fatal("JoinRoom didn't create new room")
 	}
 
-	roomSubscription := room.subscriptions[subscription.ID()]
+	roomSubscription := room.lookup(subscription.ID())
 	if roomSubscription == nil {
 		t.Fatal("JoinRoom didn't add subscription to room")
 	}
 }
 
+func TestBroadcaster_JoinRoom_AfterUnsubscribe(t *testing.T) {
+	b := createTestBroadcaster()
+	subscription := b.Subscribe(func(_ interface{}) {})
+	b.Unsubscribe(subscription)
+
+	joins := map[string]func() error{
+		"JoinRoom":    func() error { return b.JoinRoom(subscription, "test-room") },
+		"JoinRoomAll": func() error { return b.JoinRoomAll("test-room", subscription) },
+		"JoinTree":    func() error { return b.JoinTree(subscription, "test-tree") },
+		"JoinGroup":   func() error { return b.JoinGroup(subscription, "test-group") },
+	}
+
+	for name, join := range joins {
+		if err := join(); err != ErrSubscriptionClosed {
+			t.Fatalf("%s returned %v; want %v", name, err, ErrSubscriptionClosed)
+		}
+	}
+
+	if b.rooms.get("test-room") != nil || len(b.trees) > 0 || len(b.groups) > 0 {
+		t.Fatal("unsubscribed subscription should not join rooms, trees or groups")
+	}
+}
+
 func TestBroadcaster_LeaveRoom(t *testing.T) {
 	b := createTestBroadcaster()
 	subscription := b.Subscribe(func(_ interface{}) {})
@@ -233,8 +277,8 @@ func TestBroadcaster_LeaveRoom(t *testing.T) {
 
 	b.LeaveRoom(subscription, roomName)
 
-	room := b.rooms[roomName]
-	roomSubscription := room.subscriptions[subscription.ID()]
+	room := b.rooms.get(roomName)
+	roomSubscription := room.lookup(subscription.ID())
 	if roomSubscription != nil {
 		t.Fatal("LeaveRoom didn't remove subscription from room")
 	}
@@ -495,18 +539,19 @@ func TestBroadcaster_RoomsOf(t *testing.T) {
 }
 
 func createTestBroadcaster() *broadcaster {
-	pool := &pool{
-		tickets: make(chan struct{}, defaultPoolSize),
-		tasks:   make(chan func()),
-		timeout: defaultPoolTimeout,
-	}
+	pool := newPool(int(defaultPoolSize), defaultPoolTimeout)
 	var mux sync.RWMutex
 	b := &broadcaster{
 		pool:            pool,
-		rooms:           make(map[string]*room),
+		rooms:           newRoomMap(defaultRoomShards),
+		trees:           make(map[string]*room),
+		groups:          make(map[string]*group),
+		retained:        make(map[string]*Message),
 		mux:             &mux,
 		dispatcher:      &noopDispatcher{},
 		defaultRoomName: "default",
+		roomSeparator:   defaultRoomSeparator,
+		clock:           realClock{},
 	}
 
 	return b
@@ -543,3 +588,314 @@ func waitOrTimeout(done <-chan struct{}) {
 		return
 	}
 }
+
+func TestWithSubscriberBuffer(t *testing.T) {
+	b := createTestBroadcaster()
+
+	WithSubscriberBuffer(10, OverflowDropOldest)(b)
+	subscription := b.Subscribe(func(_ interface{}) {})
+
+	if subscription.queue == nil || cap(subscription.queue.items) != 10 {
+		t.Fatalf("WithSubscriberBuffer(10, OverflowDropOldest); subscription should have a buffer of 10")
+	}
+
+	if subscription.queue.policy != OverflowDropOldest {
+		t.Fatalf("WithSubscriberBuffer(10, OverflowDropOldest); got policy %v", subscription.queue.policy)
+	}
+}
+
+func TestWithSubscriberBuffer_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithSubscriberBuffer(0, OverflowBlock)(b); err == nil {
+		t.Fatalf("WithSubscriberBuffer(0, OverflowBlock); expected an error")
+	}
+
+	if err := WithSubscriberBuffer(1, OverflowPolicy(99))(b); err == nil {
+		t.Fatalf("WithSubscriberBuffer(1, 99); expected an error")
+	}
+}
+
+func TestBroadcaster_ToAll_WithSubscriberBufferShouldCountDropped(t *testing.T) {
+	b, cancel, _ := New(WithSubscriberBuffer(1, OverflowDropNewest))
+	defer cancel()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	subscription := b.Subscribe(func(_ interface{}) {
+		started <- struct{}{}
+		<-release
+	})
+
+	b.ToAll(1)
+	<-started
+	b.ToAll(2)
+	b.ToAll(3)
+	<-time.After(time.Millisecond * 200)
+	close(release)
+
+	if got := subscription.Dropped(); got != 1 {
+		t.Fatalf("Dropped() = %v; want 1", got)
+	}
+}
+
+func TestBroadcaster_ToAll_WithOverflowCloseShouldUnsubscribe(t *testing.T) {
+	b, cancel, _ := New(WithSubscriberBuffer(1, OverflowClose))
+	defer cancel()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	subscription := b.Subscribe(func(_ interface{}) {
+		started <- struct{}{}
+		<-release
+	})
+
+	b.ToAll(1)
+	<-started
+	b.ToAll(2)
+	b.ToAll(3)
+	<-time.After(time.Millisecond * 200)
+	close(release)
+
+	if rooms := b.RoomsOf(subscription); len(rooms) != 0 {
+		t.Fatalf("overflowing subscription should be unsubscribed; still in %v", rooms)
+	}
+}
+
+func TestBroadcaster_ToRoomWithOptions_ExceptSubscribers(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	room := "test-room"
+	excludedCalled := false
+	excluded := b.Subscribe(func(_ interface{}) {
+		excludedCalled = true
+	})
+	called := false
+	subscription := b.Subscribe(func(_ interface{}) {
+		called = true
+	})
+	b.JoinRoom(excluded, room)
+	b.JoinRoom(subscription, room)
+
+	b.ToRoomWithOptions(struct{}{}, room, ExceptSubscribers(excluded.ID()))
+
+	if excludedCalled {
+		t.Fatalf("ToRoomWithOptions sent data to excluded subscriber")
+	}
+
+	if !called {
+		t.Fatalf("ToRoomWithOptions did not send data to subscriber")
+	}
+}
+
+func TestBroadcaster_ToAllWithOptions_Except(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	called := false
+	subscription := b.Subscribe(func(_ interface{}) {
+		called = true
+	})
+	b.JoinRoom(subscription, "test-room")
+
+	b.ToAllWithOptions(struct{}{}, Except("test-room"))
+
+	if called {
+		t.Fatalf("ToAllWithOptions sent data to excluded subscriber")
+	}
+}
+
+func TestBroadcaster_ShouldUseMessageDispatcher(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+
+	b.ToAllWithOptions("data", ExceptSubscribers("other"))
+	msg := <-dispatcher.dispatched
+	dispatcher.received(&Message{Data: "data", ToAll: true})
+
+	if msg.Data != "data" || len(msg.ExceptSubscribers) != 1 {
+		t.Fatalf("DispatchMessage should receive the whole message; got %+v", msg)
+	}
+
+	if calls != 2 {
+		t.Fatalf("Message received through ReceivedMessage was not sent to subscribers")
+	}
+}
+
+type mockMessageDispatcher struct {
+	mockDispatcher
+	dispatched chan *Message
+	received   func(msg *Message)
+}
+
+func (d *mockMessageDispatcher) DispatchMessage(msg *Message) {
+	d.dispatched <- msg
+}
+
+func (d *mockMessageDispatcher) ReceivedMessage(callback func(msg *Message)) {
+	d.received = callback
+}
+
+func TestBroadcaster_ToRooms_ShouldDeliverOnce(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	subscription := b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+	b.JoinRoom(subscription, "room-a", "room-b")
+	otherCalled := false
+	other := b.Subscribe(func(_ interface{}) {
+		otherCalled = true
+	})
+	b.JoinRoom(other, "room-b")
+
+	b.ToRooms(struct{}{}, []string{"room-a", "room-b", "missing-room"})
+
+	if calls != 1 {
+		t.Fatalf("ToRooms sent data %v times to a subscriber in several rooms; want 1", calls)
+	}
+
+	if !otherCalled {
+		t.Fatalf("ToRooms did not send data to subscriber")
+	}
+}
+
+func TestBroadcaster_ToRooms_ShouldDispatchOncePerRoom(t *testing.T) {
+	excepts := [][]string{}
+	dispatcher := mockDispatcher{
+		dispatch: func(data interface{}, toAll bool, room string, except ...string) {
+			excepts = append(excepts, except)
+		},
+	}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithSynchronousDelivery())
+	defer cancel()
+
+	b.ToRooms(struct{}{}, []string{"room-a", "room-b"}, "excluded")
+
+	if len(excepts) != 2 {
+		t.Fatalf("ToRooms dispatched %v times; want 2", len(excepts))
+	}
+
+	if len(excepts[1]) != 2 || excepts[1][0] != "excluded" || excepts[1][1] != "room-a" {
+		t.Fatalf("ToRooms should exclude already dispatched rooms; got %v", excepts[1])
+	}
+}
+
+func TestBroadcaster_ToRoomPattern(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	var mux sync.Mutex
+	received := map[string]int{}
+	for _, room := range []string{"game:1:lobby", "game:2:lobby", "game:1:chat"} {
+		r := room
+		subscription := b.Subscribe(func(_ interface{}) {
+			mux.Lock()
+			defer mux.Unlock()
+			received[r]++
+		})
+		b.JoinRoom(subscription, r)
+	}
+
+	err := b.ToRoomPattern(struct{}{}, "game:*:lobby")
+
+	if err != nil {
+		t.Fatalf("ToRoomPattern returned error - %v, want nil error", err)
+	}
+
+	if received["game:1:lobby"] != 1 || received["game:2:lobby"] != 1 {
+		t.Fatalf("ToRoomPattern did not send data to matching rooms; got %v", received)
+	}
+
+	if received["game:1:chat"] != 0 {
+		t.Fatalf("ToRoomPattern sent data to a room that doesn't match")
+	}
+}
+
+func TestBroadcaster_ToRoomPattern_WithInvalidPattern(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+
+	err := b.ToRoomPattern(struct{}{}, "game:[")
+
+	if err == nil {
+		t.Fatalf("ToRoomPattern with invalid pattern should return an error")
+	}
+}
+
+func TestBroadcaster_ToRoomBatch_ShouldCallCallbackOnce(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	var received []*Message
+	subscription := b.SubscribeMessage(func(msg *Message) {
+		received = append(received, msg)
+	})
+	b.JoinRoom(subscription, "test-room")
+
+	b.ToRoomBatch([]interface{}{1, 2, 3}, "test-room")
+
+	if len(received) != 1 {
+		t.Fatalf("callback was called %d times; want 1", len(received))
+	}
+
+	items, ok := received[0].Data.([]interface{})
+	if !ok || len(items) != 3 || !received[0].Batch {
+		t.Fatalf("received %v; want a batch of 3 items", received[0].Data)
+	}
+}
+
+func TestBroadcaster_ToRoomBatch_WithEmptyItems(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	subscription := b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+	b.JoinRoom(subscription, "test-room")
+
+	b.ToRoomBatch(nil, "test-room")
+
+	if calls != 0 {
+		t.Fatalf("empty batch should not be sent")
+	}
+}
+
+func TestBroadcaster_AfterCancel_ShouldReturnErrBroadcasterClosed(t *testing.T) {
+	b, cancel, _ := New()
+	s := b.Subscribe(func(_ interface{}) {})
+	cancel()
+	<-b.Done()
+
+	if err := b.ToAll("data"); err != ErrBroadcasterClosed {
+		t.Fatalf("ToAll() returned %v; want ErrBroadcasterClosed", err)
+	}
+
+	if err := b.JoinRoom(s, "room"); err != ErrBroadcasterClosed {
+		t.Fatalf("JoinRoom() returned %v; want ErrBroadcasterClosed", err)
+	}
+
+	if _, err := b.ToRoomSync(context.Background(), "data", "room"); err != ErrBroadcasterClosed {
+		t.Fatalf("ToRoomSync() returned %v; want ErrBroadcasterClosed", err)
+	}
+
+	if _, err := b.Replay(s, "room", time.Time{}); err != ErrBroadcasterClosed {
+		t.Fatalf("Replay() returned %v; want ErrBroadcasterClosed", err)
+	}
+
+	if err := b.Drain(context.Background()); err != ErrBroadcasterClosed {
+		t.Fatalf("Drain() returned %v; want ErrBroadcasterClosed", err)
+	}
+}
+
+func TestBroadcaster_Subscribe_AfterCancel(t *testing.T) {
+	b, cancel, _ := New()
+	cancel()
+
+	s := b.Subscribe(func(_ interface{}) {})
+
+	if !s.isClosed() || b.CountSubscribers("default") != 0 {
+		t.Fatalf("Subscribe() after cancel returned an open subscription")
+	}
+}
@@ -0,0 +1,202 @@
+// Package broadcasttest provides helpers for testing code that uses a Broadcaster
+// without sleeping: a broadcaster that delivers synchronously, a Dispatcher that
+// records dispatched messages, subscriptions that record the data they receive
+// and a Clock that tests advance explicitly.
+package broadcasttest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+// New creates a Broadcaster with synchronous delivery, so every subscription has received
+// a message when ToAll or ToRoom returns. The broadcaster is canceled when the test ends.
+func New(t testing.TB, options ...broadcast.Option) broadcast.Broadcaster {
+	t.Helper()
+
+	options = append([]broadcast.Option{broadcast.WithSynchronousDelivery()}, options...)
+	b, cancel, err := broadcast.New(options...)
+	if err != nil {
+		t.Fatalf("broadcasttest.New: %v", err)
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		<-b.Done()
+	})
+
+	return b
+}
+
+// Dispatcher is a broadcast.MessageDispatcher that records all dispatched messages
+// and lets tests deliver messages as if they were received from another instance.
+type Dispatcher struct {
+	mux      *sync.Mutex
+	messages []*broadcast.Message
+	received func(msg *broadcast.Message)
+}
+
+// NewDispatcher creates a new recording Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{mux: &sync.Mutex{}}
+}
+
+// Dispatch records a message dispatched through the single room interface.
+func (d *Dispatcher) Dispatch(data interface{}, toAll bool, room string, except ...string) {
+	msg := &broadcast.Message{Data: data, ToAll: toAll, Except: except}
+	if len(room) > 0 {
+		msg.Rooms = []string{room}
+	}
+
+	d.DispatchMessage(msg)
+}
+
+// Received is not used since Dispatcher implements ReceivedMessage.
+func (d *Dispatcher) Received(callback func(data interface{}, toAll bool, room string, except ...string)) {
+}
+
+// DispatchMessage records a dispatched message.
+func (d *Dispatcher) DispatchMessage(msg *broadcast.Message) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.messages = append(d.messages, msg)
+}
+
+// ReceivedMessage stores the callback used by Deliver.
+func (d *Dispatcher) ReceivedMessage(callback func(msg *broadcast.Message)) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.received = callback
+}
+
+// Messages returns the messages dispatched so far in the order they were dispatched.
+func (d *Dispatcher) Messages() []*broadcast.Message {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	messages := make([]*broadcast.Message, len(d.messages))
+	copy(messages, d.messages)
+	return messages
+}
+
+// Reset discards the recorded messages.
+func (d *Dispatcher) Reset() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.messages = nil
+}
+
+// Deliver passes a message to the broadcaster as if another instance had dispatched it.
+// It has no effect until the Dispatcher is passed to a broadcaster.
+func (d *Dispatcher) Deliver(msg *broadcast.Message) {
+	d.mux.Lock()
+	received := d.received
+	d.mux.Unlock()
+
+	if received != nil {
+		received(msg)
+	}
+}
+
+// Recorder is a subscription that records the data it receives.
+type Recorder struct {
+	Subscription *broadcast.Subscription
+	mux          *sync.Mutex
+	received     []interface{}
+	signal       chan struct{}
+}
+
+// Subscribe creates a subscription that records the data it receives.
+func Subscribe(b broadcast.Broadcaster, rooms ...string) *Recorder {
+	r := &Recorder{mux: &sync.Mutex{}, signal: make(chan struct{}, 1)}
+	r.Subscription = b.Subscribe(r.record)
+	if len(rooms) > 0 {
+		b.JoinRoom(r.Subscription, rooms...)
+	}
+
+	return r
+}
+
+func (r *Recorder) record(data interface{}) {
+	r.mux.Lock()
+	r.received = append(r.received, data)
+	r.mux.Unlock()
+
+	select {
+	case r.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Received returns the data received so far that was not taken by ExpectDelivery.
+func (r *Recorder) Received() []interface{} {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	received := make([]interface{}, len(r.received))
+	copy(received, r.received)
+	return received
+}
+
+// next removes and returns the oldest received data.
+func (r *Recorder) next() (interface{}, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if len(r.received) == 0 {
+		return nil, false
+	}
+
+	data := r.received[0]
+	r.received = r.received[1:]
+	return data, true
+}
+
+// ExpectDelivery waits until the subscription receives data and returns the oldest data
+// it received. The test fails if nothing is received within the timeout.
+func ExpectDelivery(t testing.TB, r *Recorder, timeout time.Duration) interface{} {
+	t.Helper()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		if data, ok := r.next(); ok {
+			return data
+		}
+
+		select {
+		case <-r.signal:
+		case <-deadline.C:
+			t.Fatalf("subscription %s received nothing within %v", r.Subscription.ID(), timeout)
+			return nil
+		}
+	}
+}
+
+// ExpectNoDelivery fails the test if the subscription receives data within the timeout.
+func ExpectNoDelivery(t testing.TB, r *Recorder, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		if data, ok := r.next(); ok {
+			t.Fatalf("subscription %s received %v; want nothing", r.Subscription.ID(), data)
+			return
+		}
+
+		select {
+		case <-r.signal:
+		case <-deadline.C:
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package broadcasttest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+func TestNew_ShouldDeliverSynchronously(t *testing.T) {
+	b := New(t)
+	r := Subscribe(b, "test-room")
+
+	b.ToRoom("data", "test-room")
+
+	received := r.Received()
+	if len(received) != 1 || received[0] != "data" {
+		t.Fatalf("Received() = %v; want [data] right after ToRoom", received)
+	}
+}
+
+func TestDispatcher_ShouldRecordDispatchedMessages(t *testing.T) {
+	d := NewDispatcher()
+	b := New(t, broadcast.WithDispatcher(d))
+
+	b.ToRoom("data", "test-room")
+
+	messages := d.Messages()
+	if len(messages) != 1 || messages[0].Data != "data" || len(messages[0].Rooms) != 1 || messages[0].Rooms[0] != "test-room" {
+		t.Fatalf("Messages() = %v; want the message sent to test-room", messages)
+	}
+
+	d.Reset()
+	if messages := d.Messages(); len(messages) != 0 {
+		t.Fatalf("Messages() = %v after Reset; want none", messages)
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	d := NewDispatcher()
+	b := New(t, broadcast.WithDispatcher(d))
+	r := Subscribe(b, "test-room")
+
+	d.Deliver(&broadcast.Message{Data: "remote", Rooms: []string{"test-room"}})
+
+	if data := ExpectDelivery(t, r, time.Second); data != "remote" {
+		t.Fatalf("ExpectDelivery returned %v; want remote", data)
+	}
+}
+
+func TestExpectDelivery_ShouldReturnDataInOrder(t *testing.T) {
+	b := New(t)
+	r := Subscribe(b)
+
+	b.ToAll("first")
+	b.ToAll("second")
+
+	if data := ExpectDelivery(t, r, time.Second); data != "first" {
+		t.Fatalf("ExpectDelivery returned %v; want first", data)
+	}
+	if data := ExpectDelivery(t, r, time.Second); data != "second" {
+		t.Fatalf("ExpectDelivery returned %v; want second", data)
+	}
+	ExpectNoDelivery(t, r, time.Millisecond*10)
+}
@@ -0,0 +1,128 @@
+package broadcasttest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+// Clock is a broadcast.Clock that only moves when Advance is called.
+type Clock struct {
+	mux    *sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// NewClock creates a Clock set to the given time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{mux: &sync.Mutex{}, now: now}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.now
+}
+
+// NewTimer creates a timer that fires once the clock is advanced by d.
+func (c *Clock) NewTimer(d time.Duration) broadcast.Timer {
+	t := &timer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc creates a timer that calls f once the clock is advanced by d.
+// The function runs on the go routine calling Advance.
+func (c *Clock) AfterFunc(d time.Duration, f func()) broadcast.Timer {
+	t := &timer{clock: c, f: f}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d and fires the timers that are due, in the order of their deadlines.
+func (c *Clock) Advance(d time.Duration) {
+	c.mux.Lock()
+	end := c.now.Add(d)
+	c.mux.Unlock()
+
+	for {
+		c.mux.Lock()
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].deadline.Before(c.timers[j].deadline)
+		})
+
+		if len(c.timers) == 0 || c.timers[0].deadline.After(end) {
+			c.now = end
+			c.mux.Unlock()
+			return
+		}
+
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		if t.deadline.After(c.now) {
+			c.now = t.deadline
+		}
+		now := c.now
+		c.mux.Unlock()
+
+		t.fire(now)
+	}
+}
+
+// remove removes a timer and reports whether it was waiting.
+// It must be called with the lock held.
+func (c *Clock) remove(t *timer) bool {
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	c        chan time.Time
+	f        func()
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mux.Lock()
+	defer t.clock.mux.Unlock()
+
+	return t.clock.remove(t)
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mux.Lock()
+	defer t.clock.mux.Unlock()
+
+	active := t.clock.remove(t)
+	t.deadline = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+
+	return active
+}
+
+func (t *timer) fire(now time.Time) {
+	if t.f != nil {
+		t.f()
+		return
+	}
+
+	select {
+	case t.c <- now:
+	default:
+	}
+}
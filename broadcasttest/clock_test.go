@@ -0,0 +1,65 @@
+package broadcasttest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+func TestClock_Advance_ShouldFireDueTimersInOrder(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+	fired := []time.Duration{}
+	c.AfterFunc(time.Minute*2, func() { fired = append(fired, c.Now().Sub(start)) })
+	c.AfterFunc(time.Minute, func() { fired = append(fired, c.Now().Sub(start)) })
+	c.AfterFunc(time.Minute*5, func() { fired = append(fired, c.Now().Sub(start)) })
+
+	c.Advance(time.Minute * 3)
+
+	if len(fired) != 2 || fired[0] != time.Minute || fired[1] != time.Minute*2 {
+		t.Fatalf("timers fired at %v; want [1m 2m]", fired)
+	}
+	if got := c.Now().Sub(start); got != time.Minute*3 {
+		t.Fatalf("Now() is %v after start; want 3m", got)
+	}
+}
+
+func TestClock_NewTimer_Stop(t *testing.T) {
+	c := NewClock(time.Now())
+	timer := c.NewTimer(time.Second)
+
+	if !timer.Stop() {
+		t.Fatalf("Stop should report that the timer was active")
+	}
+	c.Advance(time.Minute)
+
+	select {
+	case <-timer.C():
+		t.Fatalf("stopped timer should not fire")
+	default:
+	}
+}
+
+func TestClock_WithDedupeWindow(t *testing.T) {
+	c := NewClock(time.Now())
+	d := NewDispatcher()
+	b := New(t, broadcast.WithDispatcher(d), broadcast.WithClock(c), broadcast.WithDedupeWindow(time.Minute))
+	r := Subscribe(b, "test-room")
+	msg := func() *broadcast.Message {
+		return &broadcast.Message{ID: "message", Data: "data", Rooms: []string{"test-room"}}
+	}
+
+	d.Deliver(msg())
+	d.Deliver(msg())
+	if received := r.Received(); len(received) != 1 {
+		t.Fatalf("Received() = %v; want the duplicate to be suppressed", received)
+	}
+
+	c.Advance(time.Minute * 2)
+	d.Deliver(msg())
+
+	if received := r.Received(); len(received) != 2 {
+		t.Fatalf("Received() = %v; want the message again after the window", received)
+	}
+}
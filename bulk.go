@@ -0,0 +1,245 @@
+package broadcast
+
+// JoinRoomAll adds the subscriptions to a room with a single membership change,
+// e.g. to rebalance thousands of subscriptions at once. Subscriptions that are already
+// part of the room are skipped. If the Authorizer rejects any of the subscriptions, none of
+// them joins the room and the error is returned. If the room is full, the subscriptions that
+// fit join it in order and ErrRoomFull is returned, see WithRoomCapacity.
+// If any of the subscriptions is closed, none of them joins the room and ErrSubscriptionClosed is returned.
+func (b *broadcaster) JoinRoomAll(room string, subs ...*Subscription) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	for _, s := range subs {
+		if s.isClosed() {
+			return ErrSubscriptionClosed
+		}
+	}
+
+	if err := b.authorizeJoinAll(room, subs); err != nil {
+		return err
+	}
+
+	return b.joinRoomAll(room, subs)
+}
+
+// MoveAll moves all subscriptions of a room to another room with a single membership change
+// of each room, e.g. when a game shard closes. Subscriptions that are already part of the target
+// room only leave the source room. Authorization and capacity work like with JoinRoomAll,
+// subscriptions that don't fit into the target room stay in the source room.
+func (b *broadcaster) MoveAll(fromRoom string, toRoom string) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	from := b.rooms.get(fromRoom)
+	if from == nil || fromRoom == toRoom {
+		return nil
+	}
+
+	subs := from.snapshot()
+	if err := b.authorizeJoinAll(toRoom, subs); err != nil {
+		return err
+	}
+
+	err := b.joinRoomAll(toRoom, subs)
+
+	to := b.room(toRoom)
+	moved := make([]*Subscription, 0, len(subs))
+	for _, s := range subs {
+		if to.lookup(s.id) != nil {
+			moved = append(moved, s)
+		}
+	}
+	b.leaveRoomAll(fromRoom, moved)
+
+	return err
+}
+
+// ClearRoom removes all subscriptions from a room with a single membership change.
+// The subscriptions stay part of their other rooms.
+func (b *broadcaster) ClearRoom(room string) {
+	b.leaveRoomAll(room, nil)
+}
+
+func (b *broadcaster) authorizeJoinAll(room string, subs []*Subscription) error {
+	if b.authorizer == nil {
+		return nil
+	}
+
+	for _, s := range subs {
+		if err := b.authorizer.AuthorizeJoin(s, room); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// joinRoomAll adds the subscriptions to the room and stops when the room is full.
+func (b *broadcaster) joinRoomAll(name string, subs []*Subscription) error {
+	var added []*Subscription
+	var first, full bool
+	b.rooms.join(name, func(r *room) {
+		added, first, full = r.addSubscriptions(subs, b.roomCapacities[name])
+	})
+
+	if len(added) > 0 {
+		b.touchRooms([]string{name})
+
+		if first {
+			b.roomCreated(name)
+		}
+	}
+
+	for _, s := range added {
+		b.announce(s, name, true)
+		b.sendRetained(s, name)
+	}
+
+	if full {
+		return roomFull(name)
+	}
+
+	return nil
+}
+
+// leaveRoomAll removes the subscriptions, or all subscriptions if subs is nil, from the room.
+func (b *broadcaster) leaveRoomAll(name string, subs []*Subscription) {
+	r := b.rooms.get(name)
+	if r == nil {
+		return
+	}
+
+	removed, emptied := r.removeSubscriptions(subs)
+	for _, s := range removed {
+		b.announce(s, name, false)
+	}
+
+	if emptied {
+		b.roomsEmptied([]string{name})
+	}
+}
+
+// addSubscriptions adds the subscriptions that are not part of the room yet while the room
+// holds fewer than capacity subscriptions. It returns the added subscriptions and reports
+// whether they are the first subscriptions of the room and whether any didn't fit.
+// Zero capacity is unlimited.
+func (r *room) addSubscriptions(subs []*Subscription, capacity int) (added []*Subscription, first bool, full bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	empty := r.members.Len() == 0
+	if m, ok := r.members.(*memberSet); ok {
+		added, full = m.addAll(subs, capacity)
+	} else {
+		for _, s := range subs {
+			if r.members.Lookup(s.id) != nil {
+				continue
+			}
+
+			if capacity > 0 && r.members.Len() >= capacity {
+				full = true
+				break
+			}
+
+			if r.members.Add(s) {
+				added = append(added, s)
+			}
+		}
+	}
+
+	return added, empty && len(added) > 0, full
+}
+
+// removeSubscriptions removes the subscriptions, or all subscriptions if subs is nil,
+// from the room. It returns the removed subscriptions and reports whether the room
+// became empty.
+func (r *room) removeSubscriptions(subs []*Subscription) (removed []*Subscription, emptied bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if m, ok := r.members.(*memberSet); ok {
+		removed = m.removeAll(subs)
+	} else {
+		if subs == nil {
+			subs = append([]*Subscription(nil), r.members.Subscriptions()...)
+		}
+
+		for _, s := range subs {
+			if r.members.Remove(s) {
+				removed = append(removed, s)
+			}
+		}
+	}
+
+	return removed, len(removed) > 0 && r.members.Len() == 0
+}
+
+// addAll adds the subscriptions under a single lock, see addSubscriptions.
+func (m *memberSet) addAll(subs []*Subscription, capacity int) (added []*Subscription, full bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, s := range subs {
+		if m.byID[s.id] != nil {
+			continue
+		}
+
+		if capacity > 0 && len(m.list) >= capacity {
+			full = true
+			break
+		}
+
+		m.add(s)
+		added = append(added, s)
+	}
+
+	return added, full
+}
+
+// removeAll removes the subscriptions, or all subscriptions if subs is nil, under a single lock.
+func (m *memberSet) removeAll(subs []*Subscription) (removed []*Subscription) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if subs == nil {
+		removed = m.list
+		m.byID = make(map[string]*Subscription)
+		m.list = nil
+		m.snapshot.Store(noSnapshot)
+		return removed
+	}
+
+	ids := make(map[string]bool, len(subs))
+	for _, s := range subs {
+		if m.byID[s.id] != nil {
+			ids[s.id] = true
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return m.remove(ids)
+}
+
+func (n *namespace) JoinRoomAll(room string, subs ...*Subscription) error {
+	for _, s := range subs {
+		if !n.owns(s) {
+			return ErrForeignSubscription
+		}
+	}
+
+	return n.broadcaster.JoinRoomAll(n.room(room), subs...)
+}
+
+func (n *namespace) MoveAll(fromRoom string, toRoom string) error {
+	return n.broadcaster.MoveAll(n.room(fromRoom), n.room(toRoom))
+}
+
+func (n *namespace) ClearRoom(room string) {
+	n.broadcaster.ClearRoom(n.room(room))
+}
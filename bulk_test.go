@@ -0,0 +1,147 @@
+package broadcast
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func subscribeMany(b Broadcaster, n int) []*Subscription {
+	subs := make([]*Subscription, n)
+	for i := range subs {
+		subs[i] = b.Subscribe(func(_ interface{}) {})
+	}
+
+	return subs
+}
+
+func TestBroadcaster_JoinRoomAll(t *testing.T) {
+	var created []string
+	b, cancel, _ := New(WithRoomCreatedHook(func(room string) { created = append(created, room) }))
+	defer cancel()
+	subs := subscribeMany(b, 3)
+	b.JoinRoom(subs[0], "room")
+	created = nil
+
+	if err := b.JoinRoomAll("room", subs...); err != nil {
+		t.Fatalf("JoinRoomAll() returned %v", err)
+	}
+
+	if got := b.Subscribers("room"); len(got) != 3 {
+		t.Fatalf("Room has subscribers %v; want 3", got)
+	}
+
+	b.JoinRoomAll("new", subs...)
+	if len(created) != 1 || created[0] != "new" {
+		t.Fatalf("Room created hook was called for %v; want [new]", created)
+	}
+}
+
+func TestBroadcaster_JoinRoomAll_ShouldStopWhenFull(t *testing.T) {
+	b, cancel, _ := New(WithRoomCapacity("room", 2))
+	defer cancel()
+	subs := subscribeMany(b, 3)
+
+	if err := b.JoinRoomAll("room", subs...); !errors.Is(err, ErrRoomFull) {
+		t.Fatalf("JoinRoomAll() returned %v; want ErrRoomFull", err)
+	}
+
+	if got := b.Subscribers("room"); len(got) != 2 {
+		t.Fatalf("Room has subscribers %v; want the first 2", got)
+	}
+}
+
+func TestBroadcaster_JoinRoomAll_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithAuthorizer(&roomAuthorizer{allowed: "allowed"}))
+	defer cancel()
+	subs := subscribeMany(b, 2)
+
+	if err := b.JoinRoomAll("forbidden", subs...); err == nil {
+		t.Fatalf("JoinRoomAll() with a rejected room should fail")
+	}
+
+	if got := b.Subscribers("forbidden"); len(got) != 0 {
+		t.Fatalf("Rejected room has subscribers %v", got)
+	}
+}
+
+func TestBroadcaster_MoveAll(t *testing.T) {
+	var emptied []string
+	b, cancel, _ := New(WithRoomEmptiedHook(func(room string) { emptied = append(emptied, room) }))
+	defer cancel()
+	subs := subscribeMany(b, 3)
+	b.JoinRoomAll("shard-1", subs...)
+	b.JoinRoom(subs[0], "shard-2")
+
+	if err := b.MoveAll("shard-1", "shard-2"); err != nil {
+		t.Fatalf("MoveAll() returned %v", err)
+	}
+
+	if got := b.Subscribers("shard-1"); len(got) != 0 {
+		t.Fatalf("Source room has subscribers %v; want none", got)
+	}
+
+	got := b.Subscribers("shard-2")
+	want := []string{subs[0].ID(), subs[1].ID(), subs[2].ID()}
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("Target room has subscribers %v; want %v", got, want)
+	}
+
+	if len(emptied) != 1 || emptied[0] != "shard-1" {
+		t.Fatalf("Room emptied hook was called for %v; want [shard-1]", emptied)
+	}
+}
+
+func TestBroadcaster_MoveAll_ShouldKeepSubscriptionsThatDontFit(t *testing.T) {
+	b, cancel, _ := New(WithRoomCapacity("small", 1))
+	defer cancel()
+	subs := subscribeMany(b, 2)
+	b.JoinRoomAll("large", subs...)
+
+	if err := b.MoveAll("large", "small"); !errors.Is(err, ErrRoomFull) {
+		t.Fatalf("MoveAll() returned %v; want ErrRoomFull", err)
+	}
+
+	if len(b.Subscribers("small")) != 1 || len(b.Subscribers("large")) != 1 {
+		t.Fatalf("Rooms have %v and %v; want one subscription each", b.Subscribers("small"), b.Subscribers("large"))
+	}
+}
+
+func TestBroadcaster_ClearRoom(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	subs := subscribeMany(b, 2)
+	b.JoinRoomAll("room", subs...)
+
+	b.ClearRoom("room")
+	b.ClearRoom("missing")
+
+	if got := b.Subscribers("room"); len(got) != 0 {
+		t.Fatalf("Room has subscribers %v after ClearRoom(); want none", got)
+	}
+
+	if rooms := subs[0].Rooms(); len(rooms) != 1 || rooms[0] != "default" {
+		t.Fatalf("Subscription is part of %v; want only the default room", rooms)
+	}
+}
+
+func TestNamespace_JoinRoomAll_ShouldRejectForeignSubscriptions(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	n := namespaceOf(t, b, "tenant")
+	own := n.Subscribe(func(_ interface{}) {})
+	foreign := b.Subscribe(func(_ interface{}) {})
+
+	if err := n.JoinRoomAll("room", own, foreign); err != ErrForeignSubscription {
+		t.Fatalf("JoinRoomAll() returned %v; want ErrForeignSubscription", err)
+	}
+
+	n.JoinRoomAll("room", own)
+	n.MoveAll("room", "other")
+
+	if got := n.Subscribers("other"); len(got) != 1 || got[0] != own.ID() {
+		t.Fatalf("Namespace room has subscribers %v; want %s", got, own.ID())
+	}
+}
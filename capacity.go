@@ -0,0 +1,117 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrRoomFull is returned when a subscription can't join a room that reached its capacity, see WithRoomCapacity.
+var ErrRoomFull = errors.New("room is full")
+
+// ErrTooManySubscriptions is returned when a subscription can't be created because
+// the broadcaster reached its subscription limit, see WithMaxSubscriptions.
+var ErrTooManySubscriptions = errors.New("too many subscriptions")
+
+// WithRoomCapacity limits how many subscriptions can join a room. JoinRoom returns ErrRoomFull
+// for a full room. If the default room is limited, subscriptions that don't fit are rejected,
+// see WithRejectionHook.
+func WithRoomCapacity(room string, max int) Option {
+	return func(b *broadcaster) error {
+		if len(room) == 0 {
+			return errors.New("limited room name cannot be empty")
+		}
+
+		if max <= 0 {
+			return errors.New("room capacity must be positive")
+		}
+
+		if b.roomCapacities == nil {
+			b.roomCapacities = make(map[string]int)
+		}
+
+		b.roomCapacities[room] = max
+		return nil
+	}
+}
+
+// WithMaxSubscriptions limits how many subscriptions can exist at the same time.
+// Subscriptions created over the limit are rejected, see WithRejectionHook.
+// SubscribeWithID returns ErrTooManySubscriptions instead.
+func WithMaxSubscriptions(max int) Option {
+	return func(b *broadcaster) error {
+		if max <= 0 {
+			return errors.New("max subscriptions must be positive")
+		}
+
+		b.maxSubscriptions = int64(max)
+		return nil
+	}
+}
+
+// WithRejectionHook sets a function that is called with subscriptions that were rejected
+// because of WithMaxSubscriptions or the capacity of the default room, and the reason.
+// Rejected subscriptions are closed and never receive messages.
+func WithRejectionHook(hook func(sub *Subscription, err error)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("rejection hook cannot be nil")
+		}
+
+		b.rejectionHook = hook
+		return nil
+	}
+}
+
+// admit counts a new subscription unless the subscription limit is reached.
+func (b *broadcaster) admit() error {
+	if b.maxSubscriptions == 0 {
+		b.measureSubscriptions(1)
+		return nil
+	}
+
+	for {
+		count := atomic.LoadInt64(&b.counters.subscriptions)
+		if count >= b.maxSubscriptions {
+			return ErrTooManySubscriptions
+		}
+
+		if atomic.CompareAndSwapInt64(&b.counters.subscriptions, count, count+1) {
+			if b.metrics != nil {
+				b.metrics.SubscriptionsChanged(1)
+			}
+			return nil
+		}
+	}
+}
+
+// reject closes a subscription that was not admitted and calls the rejection hook.
+func (b *broadcaster) reject(sub *Subscription, err error) {
+	atomic.StoreInt32(&sub.closed, 1)
+	b.unclaim(sub.id)
+
+	if b.rejectionHook != nil {
+		b.rejectionHook(sub, err)
+	}
+}
+
+// enter admits a new subscription and adds it to its default room.
+func (b *broadcaster) enter(sub *Subscription, defaultRoom string) error {
+	if err := b.admit(); err != nil {
+		b.reject(sub, err)
+		return err
+	}
+
+	if err := b.joinRoom(sub, defaultRoom); err != nil {
+		b.measureSubscriptions(-1)
+		b.reject(sub, err)
+		return err
+	}
+
+	return nil
+}
+
+// roomFull returns the error of a subscription that can't join a full room.
+func roomFull(room string) error {
+	return fmt.Errorf("room %s: %w", room, ErrRoomFull)
+}
@@ -0,0 +1,99 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithRoomCapacity_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomCapacity("", 1)(b); err == nil {
+		t.Fatalf("WithRoomCapacity should fail with an empty room name")
+	}
+
+	if err := WithRoomCapacity("test-room", 0)(b); err == nil {
+		t.Fatalf("WithRoomCapacity should fail with a non-positive capacity")
+	}
+}
+
+func TestBroadcaster_JoinRoom_WithFullRoom(t *testing.T) {
+	b := createTestBroadcaster()
+	WithRoomCapacity("test-room", 1)(b)
+	first := b.Subscribe(func(_ interface{}) {})
+	second := b.Subscribe(func(_ interface{}) {})
+
+	if err := b.JoinRoom(first, "test-room"); err != nil {
+		t.Fatalf("JoinRoom returned %v; want nil", err)
+	}
+
+	if err := b.JoinRoom(second, "test-room"); !errors.Is(err, ErrRoomFull) {
+		t.Fatalf("JoinRoom returned %v; want %v", err, ErrRoomFull)
+	}
+
+	if err := b.JoinRoom(first, "test-room"); err != nil {
+		t.Fatalf("JoinRoom of a member returned %v; want nil", err)
+	}
+
+	b.LeaveRoom(first, "test-room")
+	if err := b.JoinRoom(second, "test-room"); err != nil {
+		t.Fatalf("JoinRoom after a member left returned %v; want nil", err)
+	}
+}
+
+func TestBroadcaster_Subscribe_WithMaxSubscriptions(t *testing.T) {
+	b := createTestBroadcaster()
+	WithMaxSubscriptions(1)(b)
+	var rejected error
+	WithRejectionHook(func(_ *Subscription, err error) {
+		rejected = err
+	})(b)
+	first := b.Subscribe(func(_ interface{}) {})
+
+	second := b.Subscribe(func(_ interface{}) {})
+
+	if !second.isClosed() || rejected != ErrTooManySubscriptions {
+		t.Fatalf("subscription over the limit should be closed and rejected with %v; got %v", ErrTooManySubscriptions, rejected)
+	}
+
+	if _, err := b.SubscribeWithID("user-1", func(_ interface{}) {}); err != ErrTooManySubscriptions {
+		t.Fatalf("SubscribeWithID returned %v; want %v", err, ErrTooManySubscriptions)
+	}
+
+	b.Unsubscribe(second)
+	b.Unsubscribe(first)
+	if _, err := b.SubscribeWithID("user-1", func(_ interface{}) {}); err != nil {
+		t.Fatalf("SubscribeWithID after unsubscribing returned %v; want nil", err)
+	}
+}
+
+func TestBroadcaster_JoinRoom_WithRejectedSubscription(t *testing.T) {
+	b := createTestBroadcaster()
+	WithMaxSubscriptions(1)(b)
+	b.Subscribe(func(_ interface{}) {})
+	received := false
+	rejected := b.Subscribe(func(_ interface{}) {
+		received = true
+	})
+
+	if err := b.JoinRoom(rejected, "test-room"); err != ErrSubscriptionClosed {
+		t.Fatalf("JoinRoom returned %v; want %v", err, ErrSubscriptionClosed)
+	}
+
+	b.ToRoom("message", "test-room")
+	if received || b.CountSubscribers("test-room") != 0 {
+		t.Fatal("rejected subscription should not join rooms or receive messages")
+	}
+}
+
+func TestBroadcaster_Subscribe_WithFullDefaultRoom(t *testing.T) {
+	b := createTestBroadcaster()
+	WithRoomCapacity(b.defaultRoomName, 1)(b)
+	b.Subscribe(func(_ interface{}) {})
+
+	s := b.Subscribe(func(_ interface{}) {})
+
+	if !s.isClosed() || b.Stats().Subscriptions != 1 {
+		t.Fatalf("subscription that doesn't fit the default room should be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+package broadcast
+
+import (
+	"errors"
+	"time"
+)
+
+// Clock provides the time to the broadcaster. It is used for message timestamps and TTLs,
+// history TTLs, deduplication windows, rate limits, redelivery delays, slow consumer
+// detection and pool timeouts, so tests can replace wall-clock time with a fake clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a Timer that sends the current time on its channel after d.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f after d and returns a Timer that can cancel the call.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event created by a Clock, see time.Timer.
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires.
+	// It is nil for timers created with AfterFunc.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing and reports whether it was stopped before it fired.
+	Stop() bool
+	// Reset changes the timer to fire after d and reports whether it had been active.
+	Reset(d time.Duration) bool
+}
+
+// WithClock sets the Clock used by the broadcaster. Default is the wall clock.
+func WithClock(clock Clock) Option {
+	return func(b *broadcaster) error {
+		if clock == nil {
+			return errors.New("clock cannot be nil")
+		}
+
+		b.clock = clock
+		return nil
+	}
+}
+
+// realClock is the Clock based on the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
@@ -0,0 +1,35 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithClock_WithNilClock(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithClock(nil)(b); err == nil {
+		t.Fatalf("WithClock(nil); expected an error")
+	}
+}
+
+func TestNew_WithClockShouldSetPoolClock(t *testing.T) {
+	clock := realClock{}
+	br, cancel, _ := New(WithClock(clock), WithRoomPool("test-room", 1))
+	defer cancel()
+	b := br.(*broadcaster)
+
+	if b.pool.clock != clock || b.roomPools["test-room"].clock != clock {
+		t.Fatalf("New should pass the clock to all pools")
+	}
+}
+
+func TestRealClock_NewTimer(t *testing.T) {
+	timer := realClock{}.NewTimer(time.Millisecond)
+
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Fatalf("timer didn't fire")
+	}
+}
@@ -0,0 +1,96 @@
+package broadcast
+
+import (
+	"errors"
+	"reflect"
+)
+
+// WithPayloadCloner passes every subscription callback its own copy of the payload created
+// by the cloner, so subscriptions can modify payloads without racing each other.
+// DeepCopy can be used as the cloner. By default all subscriptions receive the same payload.
+func WithPayloadCloner(cloner func(data interface{}) interface{}) Option {
+	return func(b *broadcaster) error {
+		if cloner == nil {
+			return errors.New("payload cloner cannot be nil")
+		}
+
+		b.cloner = cloner
+		return nil
+	}
+}
+
+// DeepCopy returns a copy of the value that shares no pointers, maps or slices with it.
+// Unexported struct fields, channels and functions are copied shallowly and cyclic values are not supported.
+func DeepCopy(data interface{}) interface{} {
+	if data == nil {
+		return nil
+	}
+
+	return deepCopy(reflect.ValueOf(data)).Interface()
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(deepCopy(iter.Key()), deepCopy(iter.Value()))
+		}
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// clone returns the message with a copy of its payload if a cloner is set.
+func (b *broadcaster) clone(msg *Message) *Message {
+	if b.cloner == nil {
+		return msg
+	}
+
+	copied := *msg
+	copied.Data = b.cloner(msg.Data)
+	return &copied
+}
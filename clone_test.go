@@ -0,0 +1,73 @@
+package broadcast
+
+import (
+	"reflect"
+	"testing"
+)
+
+type clonePayload struct {
+	Name   string
+	Tags   []string
+	Counts map[string]int
+	Child  *clonePayload
+	Any    interface{}
+	hidden int
+}
+
+func TestDeepCopy(t *testing.T) {
+	original := &clonePayload{
+		Name:   "root",
+		Tags:   []string{"a"},
+		Counts: map[string]int{"a": 1},
+		Child:  &clonePayload{Name: "child"},
+		Any:    []int{1},
+		hidden: 7,
+	}
+
+	copied := DeepCopy(original).(*clonePayload)
+
+	if !reflect.DeepEqual(original, copied) {
+		t.Fatalf("DeepCopy() = %+v; want %+v", copied, original)
+	}
+
+	copied.Tags[0] = "b"
+	copied.Counts["a"] = 2
+	copied.Child.Name = "changed"
+	copied.Any.([]int)[0] = 2
+
+	if original.Tags[0] != "a" || original.Counts["a"] != 1 || original.Child.Name != "child" || original.Any.([]int)[0] != 1 {
+		t.Fatalf("Changing the copy changed the original to %+v", original)
+	}
+
+	if DeepCopy(nil) != nil {
+		t.Fatalf("DeepCopy(nil) should return nil")
+	}
+}
+
+func TestBroadcaster_WithPayloadCloner_ShouldPassCopies(t *testing.T) {
+	b, cancel, _ := New(WithPayloadCloner(DeepCopy), WithSynchronousDelivery())
+	defer cancel()
+	received := []map[string]int{}
+	for i := 0; i < 2; i++ {
+		b.Subscribe(func(data interface{}) {
+			m := data.(map[string]int)
+			m["count"]++
+			received = append(received, m)
+		})
+	}
+	payload := map[string]int{"count": 0}
+
+	b.ToAll(payload)
+
+	if len(received) != 2 || received[0]["count"] != 1 || received[1]["count"] != 1 || payload["count"] != 0 {
+		t.Fatalf("Subscriptions received %v from payload %v; want separate copies", received, payload)
+	}
+}
+
+func TestWithPayloadCloner_WithNilCloner(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithPayloadCloner(nil)(b); err == nil {
+		t.Fatalf("WithPayloadCloner(nil); expected an error")
+	}
+}
@@ -0,0 +1,14 @@
+module github.com/go-broadcast/broadcast/cmd/broadcastd
+
+go 1.16
+
+require (
+	github.com/go-broadcast/broadcast v0.0.0
+	github.com/go-broadcast/broadcast/prombroadcast v0.0.0
+	github.com/prometheus/client_golang v1.12.2
+)
+
+replace (
+	github.com/go-broadcast/broadcast => ../../
+	github.com/go-broadcast/broadcast/prombroadcast => ../../prombroadcast
+)
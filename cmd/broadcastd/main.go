@@ -0,0 +1,138 @@
+// Command broadcastd is an example chat server built on the broadcast package. Clients chat
+// in rooms over WebSocket, messages can be posted over HTTP and room members, metrics and
+// health are served for operators, see server for the routes.
+//
+// The broadcaster is configured with the BROADCAST_* environment variables, see
+// broadcast.ConfigFromEnv. With -redis, instances share messages through a Redis stream:
+//
+//	broadcastd -addr :8080 -redis localhost:6379 -group $(hostname)
+//
+// The Redis stream dispatcher doesn't share presence, so members only lists the
+// subscriptions of the instance serving the request.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+	"github.com/go-broadcast/broadcast/dispatchers"
+	"github.com/go-broadcast/broadcast/prombroadcast"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const shutdownTimeout = time.Second * 15
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to serve HTTP on")
+	redisAddr := flag.String("redis", "", "address of the Redis server, messages stay local if empty")
+	stream := flag.String("stream", "broadcast", "Redis stream shared by the instances")
+	group := flag.String("group", "", "consumer group of the instance, defaults to the host name")
+	origins := flag.String("origins", "", "comma separated hosts of other sites whose pages may open WebSockets")
+	flag.Parse()
+
+	if err := run(*addr, *redisAddr, *stream, *group, splitList(*origins)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(addr, redisAddr, stream, group string, origins []string) error {
+	cfg, err := broadcast.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+
+	registry := prometheus.NewRegistry()
+	metrics, err := prombroadcast.NewMetrics(registry)
+	if err != nil {
+		return err
+	}
+	cfg.Options = append(cfg.Options, broadcast.WithMetrics(metrics))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var dispatcher *dispatchers.RedisStreamDispatcher
+	if len(redisAddr) > 0 {
+		if len(group) == 0 {
+			if group, err = os.Hostname(); err != nil {
+				return err
+			}
+		}
+
+		client := newRedisClient(redisAddr)
+		defer client.Close()
+
+		dispatcher, err = dispatchers.NewRedisStreamDispatcher(client, stream, group, dispatchers.WithStreamErrorHandler(func(err error) {
+			log.Printf("redis stream: %v", err)
+		}))
+		if err != nil {
+			return err
+		}
+
+		cfg.Dispatcher = dispatcher
+		if len(cfg.InstanceID) == 0 {
+			cfg.InstanceID = group
+		}
+	}
+
+	b, cancel, err := broadcast.NewFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	runCtx, stopRun := context.WithCancel(context.Background())
+	defer stopRun()
+	if dispatcher != nil {
+		go func() {
+			if err := dispatcher.Run(runCtx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("redis stream stopped: %v", err)
+			}
+		}()
+	}
+
+	srv := &http.Server{Addr: addr, Handler: newServer(b, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), origins...)}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	log.Printf("serving on %s", addr)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Stop taking requests, then deliver the accepted messages before the dispatcher
+	// stops reading. Shutdown doesn't wait for hijacked WebSocket connections.
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutting down: %v", err)
+	}
+
+	return b.Drain(shutdownCtx)
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
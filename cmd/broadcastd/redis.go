@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-broadcast/broadcast/dispatchers"
+)
+
+const (
+	payloadField     = "payload"
+	redisDialTimeout = time.Second * 5
+	redisIOTimeout   = time.Second * 10
+	maxIdleConns     = 4
+)
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisClient implements dispatchers.StreamClient with the Redis protocol. The dispatcher
+// blocks in XREADGROUP while messages are added, so every command takes its own connection
+// from a small free list instead of sharing one.
+//
+// The client only knows the commands of the dispatcher and supports neither AUTH nor TLS,
+// so the example has no dependencies. Production servers should implement StreamClient
+// with a maintained Redis client instead.
+type redisClient struct {
+	addr string
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	mux  *sync.Mutex
+	idle []*redisConn
+}
+
+var _ dispatchers.StreamClient = (*redisClient)(nil)
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newRedisClient(addr string) *redisClient {
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	return &redisClient{addr: addr, dial: dialer.DialContext, mux: &sync.Mutex{}}
+}
+
+// CreateGroup runs XGROUP CREATE and ignores a group that already exists.
+func (c *redisClient) CreateGroup(ctx context.Context, stream, group, start string) error {
+	_, err := c.do(ctx, 0, "XGROUP", "CREATE", stream, group, start, "MKSTREAM")
+	if e, ok := err.(redisError); ok && strings.HasPrefix(string(e), "BUSYGROUP") {
+		return nil
+	}
+
+	return err
+}
+
+// Add runs XADD with an approximate MAXLEN if maxLen is positive.
+func (c *redisClient) Add(ctx context.Context, stream string, maxLen int64, payload []byte) error {
+	args := []string{"XADD", stream}
+	if maxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.FormatInt(maxLen, 10))
+	}
+	args = append(args, "*", payloadField, string(payload))
+
+	_, err := c.do(ctx, 0, args...)
+	return err
+}
+
+// ReadGroup runs XREADGROUP. Entries without a payload field are returned
+// with an empty payload, so the dispatcher acknowledges them.
+func (c *redisClient) ReadGroup(ctx context.Context, stream, group, consumer, id string, count int64, block time.Duration) ([]dispatchers.StreamEntry, error) {
+	args := []string{"XREADGROUP", "GROUP", group, consumer, "COUNT", strconv.FormatInt(count, 10)}
+	if id == ">" {
+		args = append(args, "BLOCK", strconv.FormatInt(block.Milliseconds(), 10))
+	}
+	args = append(args, "STREAMS", stream, id)
+
+	reply, err := c.do(ctx, block, args...)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+
+	return parseStreamReply(reply)
+}
+
+// Ack runs XACK.
+func (c *redisClient) Ack(ctx context.Context, stream, group string, ids ...string) error {
+	_, err := c.do(ctx, 0, append([]string{"XACK", stream, group}, ids...)...)
+	return err
+}
+
+// Close closes the idle connections.
+func (c *redisClient) Close() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for _, rc := range c.idle {
+		rc.conn.Close()
+	}
+	c.idle = nil
+}
+
+// do runs a command on a free connection. The block duration extends the I/O deadline
+// of blocking commands. Connections with an I/O error are discarded.
+func (c *redisClient) do(ctx context.Context, block time.Duration, args ...string) (interface{}, error) {
+	rc, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(redisIOTimeout + block)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	rc.conn.SetDeadline(deadline)
+
+	// A canceled context interrupts the command. The watcher exits before the
+	// connection is released, so it can't change the deadline of the next command.
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			rc.conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	reply, err := rc.roundTrip(args)
+	close(stop)
+	<-stopped
+	if _, ok := err.(redisError); err != nil && !ok {
+		rc.conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	c.release(rc)
+	return reply, err
+}
+
+func (c *redisClient) conn(ctx context.Context) (*redisConn, error) {
+	c.mux.Lock()
+	if n := len(c.idle); n > 0 {
+		rc := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mux.Unlock()
+		return rc, nil
+	}
+	c.mux.Unlock()
+
+	conn, err := c.dial(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redisConn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func (c *redisClient) release(rc *redisConn) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if len(c.idle) >= maxIdleConns {
+		rc.conn.Close()
+		return
+	}
+	c.idle = append(c.idle, rc)
+}
+
+// roundTrip writes a command as an array of bulk strings and reads the reply.
+func (rc *redisConn) roundTrip(args []string) (interface{}, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(rc.conn, sb.String()); err != nil {
+		return nil, err
+	}
+
+	return readReply(rc.r)
+}
+
+// readReply reads a reply. Simple strings are returned as string, bulk strings as []byte,
+// integers as int64, arrays as []interface{} and nil replies as nil.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	kind, value := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, redisError(value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				// An error reply within an array doesn't end the array.
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", kind)
+	}
+}
+
+// parseStreamReply converts the reply of XREADGROUP, [[stream, [[id, [field, value, ...]], ...]]],
+// into stream entries.
+func parseStreamReply(reply interface{}) ([]dispatchers.StreamEntry, error) {
+	malformed := errors.New("malformed stream reply")
+
+	streams, ok := reply.([]interface{})
+	if !ok {
+		return nil, malformed
+	}
+
+	entries := []dispatchers.StreamEntry{}
+	for _, s := range streams {
+		stream, ok := s.([]interface{})
+		if !ok || len(stream) != 2 {
+			return nil, malformed
+		}
+
+		items, ok := stream[1].([]interface{})
+		if !ok {
+			return nil, malformed
+		}
+
+		for _, i := range items {
+			item, ok := i.([]interface{})
+			if !ok || len(item) != 2 {
+				return nil, malformed
+			}
+
+			id, ok := item[0].([]byte)
+			if !ok {
+				return nil, malformed
+			}
+
+			entry := dispatchers.StreamEntry{ID: string(id)}
+			// Entries that were deleted while pending have no fields.
+			fields, _ := item[1].([]interface{})
+			for f := 0; f+1 < len(fields); f += 2 {
+				if name, _ := fields[f].([]byte); string(name) == payloadField {
+					entry.Payload, _ = fields[f+1].([]byte)
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast/dispatchers"
+)
+
+// fakeRedis serves the stream commands used by redisClient from memory. Every group reads
+// all entries in order, pending entries are not tracked.
+type fakeRedis struct {
+	listener net.Listener
+	mux      *sync.Mutex
+	entries  [][2]string
+	groups   map[string]int
+	commands [][]string
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	f := &fakeRedis{listener: l, mux: &sync.Mutex{}, groups: make(map[string]int)}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+
+		items := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+
+		if _, err := conn.Write([]byte(f.handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) handle(args []string) string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.commands = append(f.commands, args)
+	switch args[0] {
+	case "XGROUP":
+		if _, ok := f.groups[args[3]]; ok {
+			return "-BUSYGROUP Consumer Group name already exists\r\n"
+		}
+		f.groups[args[3]] = len(f.entries)
+		return "+OK\r\n"
+	case "XADD":
+		id := fmt.Sprintf("%d-0", len(f.entries)+1)
+		f.entries = append(f.entries, [2]string{id, args[len(args)-1]})
+		return bulk(id)
+	case "XACK":
+		return ":" + strconv.Itoa(len(args)-3) + "\r\n"
+	case "XREADGROUP":
+		return f.readGroup(args)
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+// readGroup polls for new entries until the block duration passes.
+func (f *fakeRedis) readGroup(args []string) string {
+	group, id := args[2], args[len(args)-1]
+	if id == "0" {
+		return "*1\r\n*2\r\n" + bulk(args[len(args)-2]) + "*0\r\n"
+	}
+
+	block, _ := strconv.Atoi(args[7])
+	deadline := time.Now().Add(time.Duration(block) * time.Millisecond)
+	for f.groups[group] >= len(f.entries) {
+		if time.Now().After(deadline) {
+			return "*-1\r\n"
+		}
+
+		f.mux.Unlock()
+		time.Sleep(time.Millisecond * 5)
+		f.mux.Lock()
+	}
+
+	var sb strings.Builder
+	entries := f.entries[f.groups[group]:]
+	f.groups[group] = len(f.entries)
+	fmt.Fprintf(&sb, "*1\r\n*2\r\n%s*%d\r\n", bulk(args[len(args)-2]), len(entries))
+	for _, e := range entries {
+		fmt.Fprintf(&sb, "*2\r\n%s*2\r\n%s%s", bulk(e[0]), bulk(payloadField), bulk(e[1]))
+	}
+
+	return sb.String()
+}
+
+func (f *fakeRedis) commandsNamed(name string) [][]string {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	commands := [][]string{}
+	for _, c := range f.commands {
+		if c[0] == name {
+			commands = append(commands, c)
+		}
+	}
+
+	return commands
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func TestRedisClient_CreateGroup_ExistingGroup(t *testing.T) {
+	f := startFakeRedis(t)
+	c := newRedisClient(f.addr())
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := c.CreateGroup(context.Background(), "stream", "a", "$"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+}
+
+func TestRedisClient_AddAndReadGroup(t *testing.T) {
+	f := startFakeRedis(t)
+	c := newRedisClient(f.addr())
+	defer c.Close()
+	ctx := context.Background()
+	c.CreateGroup(ctx, "stream", "a", "$")
+
+	c.Add(ctx, "stream", 100, []byte(`{"Data":"one"}`))
+	c.Add(ctx, "stream", 0, []byte("two\r\nlines"))
+	entries, err := c.ReadGroup(ctx, "stream", "a", "a", ">", 10, time.Millisecond*50)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(entries) != 2 || string(entries[0].Payload) != `{"Data":"one"}` || string(entries[1].Payload) != "two\r\nlines" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	adds := f.commandsNamed("XADD")
+	if strings.Join(adds[0][:6], " ") != "XADD stream MAXLEN ~ 100 *" || adds[1][2] != "*" {
+		t.Fatalf("unexpected XADD commands %v", adds)
+	}
+}
+
+func TestRedisClient_ReadGroup_Timeout(t *testing.T) {
+	f := startFakeRedis(t)
+	c := newRedisClient(f.addr())
+	defer c.Close()
+	c.CreateGroup(context.Background(), "stream", "a", "$")
+
+	entries, err := c.ReadGroup(context.Background(), "stream", "a", "a", ">", 10, time.Millisecond*10)
+
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected no entries, got %v, %v", entries, err)
+	}
+}
+
+func TestRedisClient_ReadGroup_Canceled(t *testing.T) {
+	f := startFakeRedis(t)
+	c := newRedisClient(f.addr())
+	defer c.Close()
+	c.CreateGroup(context.Background(), "stream", "a", "$")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*20, cancel)
+
+	_, err := c.ReadGroup(ctx, "stream", "a", "a", ">", 10, time.Second*10)
+
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParseStreamReply_EntryWithoutFields(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{[]byte("stream"), []interface{}{
+			[]interface{}{[]byte("1-0"), nil},
+			[]interface{}{[]byte("2-0"), []interface{}{[]byte("other"), []byte("x"), []byte(payloadField), []byte("p")}},
+		}},
+	}
+
+	entries, err := parseStreamReply(reply)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].ID != "1-0" || entries[0].Payload != nil || string(entries[1].Payload) != "p" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+}
+
+func TestServer_ChatOverRedis(t *testing.T) {
+	f := startFakeRedis(t)
+	start := func(id string) *dispatchers.RedisStreamDispatcher {
+		d, err := dispatchers.NewRedisStreamDispatcher(newRedisClient(f.addr()), "chat", id,
+			dispatchers.WithStreamRead(10, time.Millisecond*50))
+		if err != nil {
+			t.Fatalf("failed to create dispatcher: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go d.Run(ctx)
+		t.Cleanup(cancel)
+		return d
+	}
+	a := startInstance(t, "a", start("a"))
+	b := startInstance(t, "b", start("b"))
+	waitFor(t, func() bool { return len(f.commandsNamed("XGROUP")) == 2 })
+
+	alice := dialWebsocket(t, a, "go", "alice")
+	bob := dialWebsocket(t, b, "go", "bob")
+	waitForMembers(t, a, "go", 1)
+	waitForMembers(t, b, "go", 1)
+
+	// The notice of the instance joining last may reach the other instance before its member joined.
+	alice.WriteText([]byte("hello"))
+	for {
+		e := readEvent(t, bob)
+		if e.Type == eventJoin {
+			continue
+		}
+
+		if e != (event{Type: eventMessage, Room: "go", User: "alice", Text: "hello"}) {
+			t.Fatalf("expected the message of alice, got %+v", e)
+		}
+		break
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+const (
+	defaultRoom    = "lobby"
+	maxPostMessage = 64 * 1024
+	healthTimeout  = time.Second * 2
+)
+
+// Event types sent to chat clients.
+const (
+	eventJoin    = "join"
+	eventLeave   = "leave"
+	eventMessage = "message"
+)
+
+// event is the payload of the chat. Events received from other instances are
+// decoded by the Dispatcher, so clients get them re-encoded from whatever the Dispatcher produced.
+type event struct {
+	Type string `json:"type"`
+	Room string `json:"room"`
+	User string `json:"user,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// server serves the chat over WebSocket and HTTP:
+//
+//	GET  /ws?room=lobby&user=alice  joins a room, text messages are sent to the room
+//	POST /rooms/{room}/messages     sends the request body to a room
+//	GET  /rooms/{room}/members      lists the subscriptions of a room on all known instances
+//	GET  /metrics                   metrics in the Prometheus text format
+//	GET  /healthz                   readiness of the broadcaster and its Dispatcher
+//
+// WebSocket requests from browsers are only accepted from pages served by the
+// same host or by one of the given origin hosts.
+type server struct {
+	broadcaster broadcast.Broadcaster
+	mux         *http.ServeMux
+	origins     []string
+}
+
+func newServer(b broadcast.Broadcaster, metrics http.Handler, origins ...string) *server {
+	s := &server{broadcaster: b, mux: http.NewServeMux(), origins: origins}
+	s.mux.HandleFunc("/ws", s.handleWebsocket)
+	s.mux.HandleFunc("/rooms/", s.handleRoom)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	if metrics != nil {
+		s.mux.Handle("/metrics", metrics)
+	}
+
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *server) handleWebsocket(w http.ResponseWriter, r *http.Request) {
+	room := r.URL.Query().Get("room")
+	if len(room) == 0 {
+		room = defaultRoom
+	}
+
+	user := r.URL.Query().Get("user")
+	if len(user) == 0 {
+		http.Error(w, "user is required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrade(w, r, s.origins)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub := s.broadcaster.SubscribeWithOptions(func(data interface{}) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("encoding event: %v", err)
+			return
+		}
+
+		if err := conn.WriteText(payload); err != nil {
+			// The read loop notices the closed connection and unsubscribes.
+			conn.conn.Close()
+		}
+	}, broadcast.WithMeta("user", user))
+	defer s.broadcaster.Unsubscribe(sub)
+
+	if err := s.broadcaster.JoinRoom(sub, room); err != nil {
+		log.Printf("joining room %q: %v", room, err)
+		return
+	}
+
+	s.broadcaster.ToRoomFrom(sub, event{Type: eventJoin, Room: room, User: user}, room)
+	defer s.broadcaster.ToRoomFrom(sub, event{Type: eventLeave, Room: room, User: user}, room)
+
+	for {
+		text, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		err = s.broadcaster.ToRoomFrom(sub, event{Type: eventMessage, Room: room, User: user, Text: string(text)}, room)
+		if err != nil {
+			log.Printf("sending to room %q: %v", room, err)
+		}
+	}
+}
+
+// handleRoom serves /rooms/{room}/messages and /rooms/{room}/members.
+func (s *server) handleRoom(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/rooms/"), "/")
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	room := parts[0]
+	switch {
+	case parts[1] == "messages" && r.Method == http.MethodPost:
+		s.postMessage(w, r, room)
+	case parts[1] == "members" && r.Method == http.MethodGet:
+		s.members(w, room)
+	case parts[1] == "messages" || parts[1] == "members":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *server) postMessage(w http.ResponseWriter, r *http.Request, room string) {
+	text, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPostMessage))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	user := r.URL.Query().Get("user")
+	if err := s.broadcaster.ToRoom(event{Type: eventMessage, Room: room, User: user, Text: string(text)}, room); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// members lists the subscriptions of the room. Without cluster presence only
+// the subscriptions of this instance are known.
+func (s *server) members(w http.ResponseWriter, room string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.broadcaster.ClusterSubscribers(room))
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	if err := s.broadcaster.Health(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+	"github.com/go-broadcast/broadcast/dispatchers"
+	"github.com/go-broadcast/broadcast/prombroadcast"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const testTimeout = time.Second * 5
+
+// startInstance serves a broadcaster linked to the other instances of the dispatcher.
+func startInstance(t *testing.T, id string, dispatcher broadcast.Dispatcher, options ...broadcast.Option) *httptest.Server {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	metrics, _ := prombroadcast.NewMetrics(registry)
+	options = append(options, broadcast.WithInstanceID(id), broadcast.WithMetrics(metrics))
+	if dispatcher != nil {
+		options = append(options, broadcast.WithDispatcher(dispatcher))
+	}
+
+	b, cancel, err := broadcast.New(options...)
+	if err != nil {
+		t.Fatalf("failed to create broadcaster: %v", err)
+	}
+
+	srv := httptest.NewServer(newServer(b, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+
+	return srv
+}
+
+// startCluster starts two instances linked with cluster presence.
+func startCluster(t *testing.T) (*httptest.Server, *httptest.Server) {
+	bridge := dispatchers.NewLocalBridge()
+	a := startInstance(t, "a", bridge.Dispatcher(), broadcast.WithClusterPresence())
+	b := startInstance(t, "b", bridge.Dispatcher(), broadcast.WithClusterPresence())
+	return a, b
+}
+
+// dialWebsocket connects a WebSocket client to the chat of the server.
+func dialWebsocket(t *testing.T, srv *httptest.Server, room, user string) *wsConn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET /ws?room=%s&user=%s HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\n"+
+		"Upgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: %s\r\n\r\n", room, user, key)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101, got %d", resp.StatusCode)
+	}
+
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", accept)
+	}
+
+	c := &wsConn{conn: conn, r: r, wmux: &sync.Mutex{}, client: true}
+	t.Cleanup(func() { conn.Close() })
+	return c
+}
+
+// readEvent reads the next event received by the client.
+func readEvent(t *testing.T, c *wsConn) event {
+	t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(testTimeout))
+	payload, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+
+	e := event{}
+	if err := json.Unmarshal(payload, &e); err != nil {
+		t.Fatalf("failed to decode event %q: %v", payload, err)
+	}
+
+	return e
+}
+
+// members returns the members of the room listed by the server.
+func members(t *testing.T, srv *httptest.Server, room string) []broadcast.Member {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + "/rooms/" + room + "/members")
+	if err != nil {
+		t.Fatalf("failed to get members: %v", err)
+	}
+	defer resp.Body.Close()
+
+	list := []broadcast.Member{}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode members: %v", err)
+	}
+
+	return list
+}
+
+// waitForMembers waits until the server lists count members of the room.
+func waitForMembers(t *testing.T, srv *httptest.Server, room string, count int) []broadcast.Member {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for {
+		list := members(t, srv, room)
+		if len(list) == count {
+			return list
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d members, got %v", count, list)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestServer_ChatAcrossInstances(t *testing.T) {
+	a, b := startCluster(t)
+	alice := dialWebsocket(t, a, "go", "alice")
+	waitForMembers(t, b, "go", 1)
+	bob := dialWebsocket(t, b, "go", "bob")
+
+	if e := readEvent(t, alice); e != (event{Type: eventJoin, Room: "go", User: "bob"}) {
+		t.Fatalf("expected bob to join, got %+v", e)
+	}
+
+	bob.WriteText([]byte("hi"))
+	if e := readEvent(t, alice); e != (event{Type: eventMessage, Room: "go", User: "bob", Text: "hi"}) {
+		t.Fatalf("expected the message of bob, got %+v", e)
+	}
+
+	alice.WriteText([]byte("hello"))
+	if e := readEvent(t, bob); e != (event{Type: eventMessage, Room: "go", User: "alice", Text: "hello"}) {
+		t.Fatalf("expected the message of alice, got %+v", e)
+	}
+
+	bob.Close()
+	if e := readEvent(t, alice); e != (event{Type: eventLeave, Room: "go", User: "bob"}) {
+		t.Fatalf("expected bob to leave, got %+v", e)
+	}
+}
+
+func TestServer_members_ClusterPresence(t *testing.T) {
+	a, b := startCluster(t)
+	dialWebsocket(t, a, "go", "alice")
+	dialWebsocket(t, b, "go", "bob")
+	dialWebsocket(t, b, "rust", "carol")
+
+	list := waitForMembers(t, a, "go", 2)
+
+	if list[0].Instance != "a" || list[1].Instance != "b" {
+		t.Fatalf("expected a member on each instance, got %v", list)
+	}
+
+	if rust := waitForMembers(t, a, "rust", 1); rust[0].Instance != "b" {
+		t.Fatalf("expected carol on instance b, got %v", rust)
+	}
+}
+
+func TestServer_postMessage(t *testing.T) {
+	a, b := startCluster(t)
+	bob := dialWebsocket(t, b, "go", "bob")
+	waitForMembers(t, a, "go", 1)
+
+	resp, err := http.Post(a.URL+"/rooms/go/messages?user=ops", "text/plain", strings.NewReader("deploying"))
+	if err != nil {
+		t.Fatalf("failed to post: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", resp.StatusCode)
+	}
+
+	if e := readEvent(t, bob); e != (event{Type: eventMessage, Room: "go", User: "ops", Text: "deploying"}) {
+		t.Fatalf("expected the posted message, got %+v", e)
+	}
+}
+
+func TestServer_handleRoom_UnknownRoutes(t *testing.T) {
+	srv := startInstance(t, "a", nil)
+
+	for path, status := range map[string]int{
+		"/rooms/go/history":   http.StatusNotFound,
+		"/rooms//messages":    http.StatusNotFound,
+		"/rooms/go/messages":  http.StatusMethodNotAllowed,
+		"/ws?room=go":         http.StatusBadRequest,
+		"/ws?room=go&user=al": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", path, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != status {
+			t.Fatalf("expected status %d for %s, got %d", status, path, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_metricsAndHealth(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, _ := prombroadcast.NewMetrics(registry)
+	b, cancel, _ := broadcast.New(broadcast.WithMetrics(metrics))
+	srv := httptest.NewServer(newServer(b, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	defer srv.Close()
+	b.ToRoom("hello", "go")
+
+	body := get(t, srv.URL+"/metrics", http.StatusOK)
+	if !strings.Contains(body, `broadcast_messages_sent_total{room=""} 1`+"\n") {
+		t.Fatalf("metrics do not contain the sent message:\n%s", body)
+	}
+
+	get(t, srv.URL+"/healthz", http.StatusOK)
+	cancel()
+	get(t, srv.URL+"/healthz", http.StatusServiceUnavailable)
+}
+
+func get(t *testing.T, url string, status int) string {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != status {
+		t.Fatalf("expected status %d for %s, got %d: %s", status, url, resp.StatusCode, body)
+	}
+
+	return string(body)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The server speaks the subset of RFC 6455 a chat needs: text and binary messages,
+// fragmented messages, ping, pong and close. Extensions are not negotiated, so frames
+// with reserved bits or unknown opcodes are protocol errors.
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xa
+)
+
+const (
+	websocketGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxWebsocketMessage = 64 * 1024
+	websocketWriteWait  = time.Second * 10
+	maxControlPayload   = 125
+)
+
+var (
+	errMessageTooLarge = errors.New("websocket message is too large")
+	errProtocol        = errors.New("websocket protocol error")
+)
+
+// wsConn is a WebSocket connection. Reads happen on a single go routine,
+// writes are serialized so subscription callbacks can write concurrently.
+type wsConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	wmux   *sync.Mutex
+	client bool
+}
+
+// upgrade performs the opening handshake of a WebSocket request.
+// Requests from browsers must come from an allowed origin, see checkOrigin.
+func upgrade(w http.ResponseWriter, r *http.Request, origins []string) (*wsConn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, errors.New("request is not a websocket upgrade")
+	}
+
+	if !checkOrigin(r, origins) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, errors.New("websocket origin is not allowed")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if len(key) == 0 {
+		http.Error(w, "missing websocket key", http.StatusBadRequest)
+		return nil, errors.New("request has no websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("response can't be hijacked")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &wsConn{conn: conn, r: rw.Reader, wmux: &sync.Mutex{}}, nil
+}
+
+// checkOrigin reports whether the Origin of the request is the host of the request or one
+// of the allowed hosts. Requests without an Origin don't come from a browser and are allowed,
+// browsers always send it, so pages of other sites can't use the cookies of the user to chat.
+func checkOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range origins {
+		if strings.EqualFold(u.Host, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ReadMessage returns the next text or binary message. It answers pings and
+// returns io.EOF when the peer closes the connection. Control frames may arrive
+// between the fragments of a message, data frames must not.
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return nil, io.EOF
+		default:
+			// A continuation must continue a message, a new message must not interrupt one.
+			if started != (opcode == opContinuation) {
+				return nil, errProtocol
+			}
+
+			if len(message)+len(payload) > maxWebsocketMessage {
+				return nil, errMessageTooLarge
+			}
+
+			started = true
+			message = append(message, payload...)
+			if fin {
+				return message, nil
+			}
+		}
+	}
+}
+
+// readFrame reads a frame and validates its header. Clients must mask their frames
+// and servers must not, control frames must be final and short.
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.r, header); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	if header[0]&0x70 != 0 || masked == c.client {
+		return false, 0, nil, errProtocol
+	}
+
+	switch opcode {
+	case opContinuation, opText, opBinary:
+	case opClose, opPing, opPong:
+		if !fin || length > maxControlPayload {
+			return false, 0, nil, errProtocol
+		}
+	default:
+		return false, 0, nil, errProtocol
+	}
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(c.r, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(c.r, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > maxWebsocketMessage {
+		return false, 0, nil, errMessageTooLarge
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(c.r, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		if masked {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteText sends a text message.
+func (c *wsConn) WriteText(payload []byte) error {
+	return c.writeFrame(opText, payload)
+}
+
+// writeFrame sends a single final frame. Clients mask their frames as RFC 6455 requires.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.wmux.Lock()
+	defer c.wmux.Unlock()
+
+	frame := []byte{0x80 | opcode}
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(n))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(n))
+	}
+
+	if c.client {
+		// A constant mask is enough for the test client, it doesn't talk to proxies.
+		mask := []byte{0x12, 0x34, 0x56, 0x78}
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(websocketWriteWait))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close sends a close frame and closes the connection.
+func (c *wsConn) Close() error {
+	c.writeFrame(opClose, nil)
+	return c.conn.Close()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// serverConn returns the server side of a WebSocket connection that reads the given frames.
+func serverConn(t *testing.T, frames ...[]byte) *wsConn {
+	t.Helper()
+
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	go func() {
+		for _, frame := range frames {
+			if _, err := client.Write(frame); err != nil {
+				return
+			}
+		}
+	}()
+
+	return &wsConn{conn: server, r: bufio.NewReader(server), wmux: &sync.Mutex{}}
+}
+
+// frame encodes a client frame with the given first header byte, masked with a zero mask.
+func frame(first byte, payload string) []byte {
+	return append([]byte{first, 0x80 | byte(len(payload)), 0, 0, 0, 0}, payload...)
+}
+
+func TestWsConn_ReadMessage_Fragmented(t *testing.T) {
+	c := serverConn(t, frame(opText, "hel"), frame(0x80|opPong, ""), frame(0x80|opContinuation, "lo"))
+
+	message, err := c.ReadMessage()
+
+	if err != nil || string(message) != "hello" {
+		t.Fatalf("ReadMessage() = %q, %v; want hello, nil", message, err)
+	}
+}
+
+func TestWsConn_ReadMessage_InvalidFrames(t *testing.T) {
+	tests := map[string][][]byte{
+		"unmasked":                  {{0x80 | opText, 2, 'h', 'i'}},
+		"reserved bits":             {frame(0xc0|opText, "hi")},
+		"unknown opcode":            {frame(0x83, "hi")},
+		"continuation first":        {frame(0x80|opContinuation, "hi")},
+		"message interrupted":       {frame(opText, "h"), frame(0x80|opText, "i")},
+		"fragmented control frame":  {frame(opPing, "")},
+		"control payload too large": {frame(0x80|opPing, string(make([]byte, maxControlPayload+1)))},
+	}
+
+	for name, frames := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := serverConn(t, frames...)
+
+			if _, err := c.ReadMessage(); err != errProtocol {
+				t.Fatalf("ReadMessage() returned %v; want %v", err, errProtocol)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := map[string]bool{
+		"":                        true,
+		"http://chat.example.com": true,
+		"https://app.example.com": true,
+		"https://evil.example":    false,
+		"::":                      false,
+	}
+
+	for origin, want := range tests {
+		r := httptest.NewRequest("GET", "http://chat.example.com/ws", nil)
+		if len(origin) > 0 {
+			r.Header.Set("Origin", origin)
+		}
+
+		if got := checkOrigin(r, []string{"app.example.com"}); got != want {
+			t.Fatalf("checkOrigin() with origin %q = %v; want %v", origin, got, want)
+		}
+	}
+}
+
+func TestUpgrade_WithForeignOrigin(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://chat.example.com/ws", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.Header.Set("Origin", "https://evil.example")
+	w := httptest.NewRecorder()
+
+	if _, err := upgrade(w, r, nil); err == nil || w.Code != http.StatusForbidden {
+		t.Fatalf("upgrade() = %v with status %d; want an error and %d", err, w.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,146 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Codec encodes payloads into bytes of a content type and decodes them, see WithRoomCodec.
+type Codec interface {
+	// ContentType identifies the encoding, e.g. "application/json" or "application/x-protobuf".
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes data into the value v points to.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is a Codec using encoding/json.
+type JSONCodec struct{}
+
+// ContentType returns "application/json".
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON into the value v points to.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// WithRoomCodec encodes the payloads of messages sent to a room with the codec before they
+// are passed to the Dispatcher, e.g. protobuf for telemetry rooms and JSON for chat rooms,
+// and sets the content type of the dispatched message. Received messages with the content
+// type are decoded into a new value of the type of value, or of the type value points to if it
+// is a pointer, so subscriptions of all instances receive the same Go type. A nil value decodes
+// into the generic types of the codec. Messages sent to all subscriptions use the codec of the
+// default room and a message sent to several rooms uses the codec of the first of them that has
+// one. All instances need the same option. Only a MessageDispatcher transfers the content type.
+// Local subscriptions receive the original payload.
+func WithRoomCodec(room string, codec Codec, value interface{}) Option {
+	return func(b *broadcaster) error {
+		if len(room) == 0 {
+			return errors.New("codec room name cannot be empty")
+		}
+
+		if codec == nil {
+			return errors.New("codec cannot be nil")
+		}
+
+		if len(codec.ContentType()) == 0 {
+			return errors.New("codec content type cannot be empty")
+		}
+
+		if b.roomCodecs == nil {
+			b.roomCodecs = make(map[string]*roomCodec)
+		}
+
+		b.roomCodecs[room] = &roomCodec{codec: codec, typ: reflect.TypeOf(value)}
+		return nil
+	}
+}
+
+// roomCodec is the codec of a room and the type received payloads are decoded into.
+type roomCodec struct {
+	codec Codec
+	typ   reflect.Type
+}
+
+// codecFor returns the codec of the first target room of a message that has one.
+func (b *broadcaster) codecFor(msg *Message) *roomCodec {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	if len(b.roomCodecs) == 0 {
+		return nil
+	}
+
+	if msg.ToAll {
+		return b.roomCodecs[b.defaultRoomName]
+	}
+
+	for _, room := range msg.Rooms {
+		if c := b.roomCodecs[room]; c != nil {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// encode encodes the payload of a dispatched message with the codec of its
+// room and sets its content type. Payloads without a codec are returned as they are.
+func (b *broadcaster) encode(msg *Message) (interface{}, error) {
+	c := b.codecFor(msg)
+	if c == nil {
+		return msg.Data, nil
+	}
+
+	payload, err := c.codec.Marshal(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.ContentType = c.codec.ContentType()
+	return payload, nil
+}
+
+// decode decodes the payload of a received message with the codec of its room.
+// Payloads without a content type are returned as they are.
+func (b *broadcaster) decode(msg *Message, data interface{}) (interface{}, error) {
+	if len(msg.ContentType) == 0 {
+		return data, nil
+	}
+
+	c := b.codecFor(msg)
+	if c == nil || c.codec.ContentType() != msg.ContentType {
+		return nil, fmt.Errorf("received payload has the unknown content type %q", msg.ContentType)
+	}
+
+	payload, ok := data.([]byte)
+	if !ok {
+		return nil, errors.New("received payload is not a byte slice")
+	}
+
+	if c.typ == nil {
+		var v interface{}
+		err := c.codec.Unmarshal(payload, &v)
+		return v, err
+	}
+
+	if c.typ.Kind() == reflect.Ptr {
+		v := reflect.New(c.typ.Elem())
+		err := c.codec.Unmarshal(payload, v.Interface())
+		return v.Interface(), err
+	}
+
+	v := reflect.New(c.typ)
+	err := c.codec.Unmarshal(payload, v.Interface())
+	return v.Elem().Interface(), err
+}
@@ -0,0 +1,90 @@
+package broadcast
+
+import (
+	"testing"
+)
+
+type telemetry struct {
+	Device string
+	Value  float64
+}
+
+func TestWithRoomCodec_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomCodec("", JSONCodec{}, nil)(b); err == nil {
+		t.Fatalf("WithRoomCodec with an empty room; expected an error")
+	}
+
+	if err := WithRoomCodec("room", nil, nil)(b); err == nil {
+		t.Fatalf("WithRoomCodec with a nil codec; expected an error")
+	}
+}
+
+func TestBroadcaster_WithRoomCodec(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(
+		WithDispatcher(&dispatcher),
+		WithSynchronousDelivery(),
+		WithRoomCodec("telemetry", JSONCodec{}, telemetry{}),
+		WithRoomCodec("devices", JSONCodec{}, &telemetry{}),
+	)
+	defer cancel()
+	received := []interface{}{}
+	s := b.Subscribe(func(data interface{}) {
+		received = append(received, data)
+	})
+	b.JoinRoom(s, "telemetry", "devices")
+
+	b.ToRoom(telemetry{Device: "a", Value: 1.5}, "telemetry")
+	dispatched := <-dispatcher.dispatched
+
+	if payload, ok := dispatched.Data.([]byte); !ok || string(payload) != `{"Device":"a","Value":1.5}` || dispatched.ContentType != "application/json" {
+		t.Fatalf("Dispatched message has the payload %v of %q; want JSON", dispatched.Data, dispatched.ContentType)
+	}
+
+	echo := *dispatched
+	echo.Origin = "other"
+	dispatcher.received(&echo)
+	echo = *dispatched
+	echo.Origin = "other"
+	echo.Rooms = []string{"devices"}
+	dispatcher.received(&echo)
+
+	if len(received) != 3 || received[0] != (telemetry{Device: "a", Value: 1.5}) || received[1] != (telemetry{Device: "a", Value: 1.5}) {
+		t.Fatalf("Subscription received %v; want the original and decoded telemetry", received)
+	}
+
+	if decoded, ok := received[2].(*telemetry); !ok || decoded.Device != "a" {
+		t.Fatalf("Subscription received %v; want a decoded *telemetry", received[2])
+	}
+}
+
+func TestBroadcaster_WithRoomCodec_UnknownContentType(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	var payloadErr error
+	b, cancel, _ := New(
+		WithDispatcher(&dispatcher),
+		WithSynchronousDelivery(),
+		WithRoomCodec("chat", JSONCodec{}, nil),
+		WithPayloadErrorHandler(func(err error) { payloadErr = err }),
+	)
+	defer cancel()
+	var received interface{}
+	s := b.Subscribe(func(data interface{}) {
+		received = data
+	})
+	b.JoinRoom(s, "chat")
+
+	dispatcher.received(&Message{Data: []byte{1}, Rooms: []string{"chat"}, ContentType: "application/x-protobuf"})
+
+	if payloadErr == nil || received != nil {
+		t.Fatalf("Message with an unknown content type was delivered as %v", received)
+	}
+
+	dispatcher.received(&Message{Data: []byte(`{"text":"hi"}`), Rooms: []string{"chat"}, ContentType: "application/json"})
+
+	if decoded, ok := received.(map[string]interface{}); !ok || decoded["text"] != "hi" {
+		t.Fatalf("Subscription received %v; want the generic JSON value", received)
+	}
+}
@@ -0,0 +1,119 @@
+package broadcast
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+)
+
+// Compressed payloads start with a byte telling whether the rest is compressed.
+const (
+	payloadPlain byte = iota
+	payloadCompressed
+)
+
+// Compressor compresses the payloads of messages passed to the Dispatcher
+// and decompresses the payloads of messages received through it.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// WithCompression compresses the payloads of messages that are at least threshold bytes
+// long before they are passed to the Dispatcher and decompresses them when they are received.
+// All instances need the same option. Payloads are converted like with WithEncryption, and
+// compressed before they are encrypted. Local subscriptions receive the original payload.
+func WithCompression(compressor Compressor, threshold int) Option {
+	return func(b *broadcaster) error {
+		if compressor == nil {
+			return errors.New("compressor cannot be nil")
+		}
+
+		if threshold < 0 {
+			return errors.New("compression threshold cannot be negative")
+		}
+
+		b.compressor = compressor
+		b.compressionThreshold = threshold
+		return nil
+	}
+}
+
+type gzipCompressor struct {
+	level int
+}
+
+// NewGzipCompressor returns a Compressor using gzip with the given level, see compress/gzip.
+func NewGzipCompressor(level int) (Compressor, error) {
+	if _, err := gzip.NewWriterLevel(ioutil.Discard, level); err != nil {
+		return nil, err
+	}
+
+	return &gzipCompressor{level: level}, nil
+}
+
+func (c *gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// compress compresses a payload that reaches the threshold.
+func (b *broadcaster) compress(payload []byte) ([]byte, error) {
+	if b.compressor == nil {
+		return payload, nil
+	}
+
+	if len(payload) < b.compressionThreshold {
+		return append([]byte{payloadPlain}, payload...), nil
+	}
+
+	compressed, err := b.compressor.Compress(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{payloadCompressed}, compressed...), nil
+}
+
+func (b *broadcaster) decompress(payload []byte) ([]byte, error) {
+	if b.compressor == nil {
+		return payload, nil
+	}
+
+	if len(payload) == 0 {
+		return nil, errors.New("received payload is empty")
+	}
+
+	switch payload[0] {
+	case payloadPlain:
+		return payload[1:], nil
+	case payloadCompressed:
+		return b.compressor.Decompress(payload[1:])
+	default:
+		return nil, errors.New("received payload has an unknown compression")
+	}
+}
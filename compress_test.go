@@ -0,0 +1,56 @@
+package broadcast
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestNewGzipCompressor_WithInvalidLevel(t *testing.T) {
+	if _, err := NewGzipCompressor(42); err == nil {
+		t.Fatalf("NewGzipCompressor(42); expected an error")
+	}
+}
+
+func TestWithCompression_WithNegativeThreshold(t *testing.T) {
+	b := createTestBroadcaster()
+	c, _ := NewGzipCompressor(gzip.DefaultCompression)
+
+	if err := WithCompression(c, -1)(b); err == nil {
+		t.Fatalf("WithCompression(c, -1); expected an error")
+	}
+}
+
+func TestBroadcaster_WithCompression(t *testing.T) {
+	c, _ := NewGzipCompressor(gzip.BestCompression)
+	e, _ := NewAESGCM(bytes.Repeat([]byte{1}, 16))
+	sender := &mockMessageDispatcher{dispatched: make(chan *Message, 2)}
+	receiver := &mockMessageDispatcher{}
+	options := []Option{WithCompression(c, 100), WithEncryption(e), WithSynchronousDelivery()}
+	b1, cancel1, _ := New(append(options, WithDispatcher(sender))...)
+	defer cancel1()
+	b2, cancel2, _ := New(append(options, WithDispatcher(receiver))...)
+	defer cancel2()
+	received := []interface{}{}
+	b2.Subscribe(func(data interface{}) {
+		received = append(received, data)
+	})
+	large := strings.Repeat("a", 1000)
+
+	b1.ToAll("small")
+	b1.ToAll(large)
+	small := <-sender.dispatched
+	compressed := <-sender.dispatched
+	size := len(compressed.Data.([]byte))
+	receiver.received(small)
+	receiver.received(compressed)
+
+	if size >= len(large) {
+		t.Fatalf("Dispatched payload has %d bytes; want less than %d", size, len(large))
+	}
+
+	if len(received) != 2 || received[0] != "small" || received[1] != large {
+		t.Fatalf("Receiving broadcaster delivered %d messages; want the small and the large one", len(received))
+	}
+}
@@ -0,0 +1,203 @@
+package broadcast
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config describes a broadcaster declaratively. Zero values keep the defaults of New.
+type Config struct {
+	// InstanceID is the ID put in the Origin of sent messages, see WithInstanceID.
+	InstanceID string
+	// PoolSize, PoolMinSize and PoolTimeout configure the pool, see WithPoolSize,
+	// WithPoolMinSize and WithPoolTimeout.
+	PoolSize    int
+	PoolMinSize int
+	PoolTimeout time.Duration
+	// DefaultRoom is the name of the default room, see WithDefaultRoomName.
+	DefaultRoom string
+	// SubscriberBuffer and OverflowPolicy give every subscription a buffer, see WithSubscriberBuffer.
+	SubscriberBuffer int
+	OverflowPolicy   OverflowPolicy
+	// SynchronousDelivery and OrderedDelivery change how messages are delivered,
+	// see WithSynchronousDelivery and WithOrderedDelivery.
+	SynchronousDelivery bool
+	OrderedDelivery     bool
+	// HistorySize and HistoryTTL keep recent messages, see WithHistory.
+	HistorySize int
+	HistoryTTL  time.Duration
+	// MaxSubscriptions limits the subscriptions, see WithMaxSubscriptions.
+	MaxSubscriptions int
+	// RoomCapacities limits the subscriptions of rooms by room name, see WithRoomCapacity.
+	RoomCapacities map[string]int
+	// SubscriberRate, SubscriberBurst and SubscriberRatePolicy limit the messages every
+	// subscription receives, see WithSubscriberRateLimit.
+	SubscriberRate       float64
+	SubscriberBurst      int
+	SubscriberRatePolicy RateLimitPolicy
+	// FanoutRate limits the deliveries per second, see WithFanoutRate.
+	FanoutRate float64
+	// Dispatcher passes messages to other instances, see WithDispatcher.
+	Dispatcher Dispatcher
+	// Options are applied after the options derived from the other fields,
+	// e.g. for hooks and settings Config doesn't cover.
+	Options []Option
+}
+
+// NewFromConfig creates a broadcaster configured by cfg, see New.
+func NewFromConfig(cfg Config) (Broadcaster, CancelFunc, error) {
+	return New(cfg.options()...)
+}
+
+// options converts the config into the options for New.
+func (cfg Config) options() []Option {
+	options := []Option{}
+	add := func(set bool, option Option) {
+		if set {
+			options = append(options, option)
+		}
+	}
+
+	add(len(cfg.InstanceID) > 0, WithInstanceID(cfg.InstanceID))
+	add(cfg.PoolSize != 0, WithPoolSize(cfg.PoolSize))
+	add(cfg.PoolMinSize != 0, WithPoolMinSize(cfg.PoolMinSize))
+	add(cfg.PoolTimeout != 0, WithPoolTimeout(cfg.PoolTimeout))
+	add(len(cfg.DefaultRoom) > 0, WithDefaultRoomName(cfg.DefaultRoom))
+	add(cfg.SubscriberBuffer != 0, WithSubscriberBuffer(cfg.SubscriberBuffer, cfg.OverflowPolicy))
+	add(cfg.SynchronousDelivery, WithSynchronousDelivery())
+	add(cfg.OrderedDelivery, WithOrderedDelivery())
+	add(cfg.HistorySize != 0, WithHistory(cfg.HistorySize, cfg.HistoryTTL))
+	add(cfg.MaxSubscriptions != 0, WithMaxSubscriptions(cfg.MaxSubscriptions))
+	for room, capacity := range cfg.RoomCapacities {
+		options = append(options, WithRoomCapacity(room, capacity))
+	}
+	add(cfg.SubscriberRate != 0, WithSubscriberRateLimit(cfg.SubscriberRate, cfg.SubscriberBurst, cfg.SubscriberRatePolicy))
+	add(cfg.FanoutRate != 0, WithFanoutRate(cfg.FanoutRate))
+	add(cfg.Dispatcher != nil, WithDispatcher(cfg.Dispatcher))
+
+	return append(options, cfg.Options...)
+}
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvInstanceID          = "BROADCAST_INSTANCE_ID"
+	EnvPoolSize            = "BROADCAST_POOL_SIZE"
+	EnvPoolMinSize         = "BROADCAST_POOL_MIN_SIZE"
+	EnvPoolTimeout         = "BROADCAST_POOL_TIMEOUT"
+	EnvDefaultRoom         = "BROADCAST_DEFAULT_ROOM"
+	EnvSubscriberBuffer    = "BROADCAST_SUBSCRIBER_BUFFER"
+	EnvOverflowPolicy      = "BROADCAST_OVERFLOW_POLICY"
+	EnvSynchronousDelivery = "BROADCAST_SYNCHRONOUS_DELIVERY"
+	EnvOrderedDelivery     = "BROADCAST_ORDERED_DELIVERY"
+	EnvHistorySize         = "BROADCAST_HISTORY_SIZE"
+	EnvHistoryTTL          = "BROADCAST_HISTORY_TTL"
+	EnvMaxSubscriptions    = "BROADCAST_MAX_SUBSCRIPTIONS"
+	EnvRoomCapacities      = "BROADCAST_ROOM_CAPACITIES"
+	EnvSubscriberRate      = "BROADCAST_SUBSCRIBER_RATE"
+	EnvSubscriberBurst     = "BROADCAST_SUBSCRIBER_BURST"
+	EnvFanoutRate          = "BROADCAST_FANOUT_RATE"
+)
+
+var overflowPolicies = map[string]OverflowPolicy{
+	"block":       OverflowBlock,
+	"drop-oldest": OverflowDropOldest,
+	"drop-newest": OverflowDropNewest,
+	"close":       OverflowClose,
+}
+
+// ConfigFromEnv reads a Config from the BROADCAST_* environment variables. Durations use the
+// syntax of time.ParseDuration, booleans the syntax of strconv.ParseBool, the overflow policy is
+// one of block, drop-oldest, drop-newest and close and room capacities are a comma separated list
+// of room=capacity pairs. Unset variables keep the defaults, the Dispatcher has to be set in code.
+func ConfigFromEnv() (Config, error) {
+	return configFromLookup(os.LookupEnv)
+}
+
+func configFromLookup(lookup func(key string) (string, bool)) (Config, error) {
+	cfg := Config{}
+	var err error
+	parse := func(key string, set func(value string) error) {
+		value, ok := lookup(key)
+		if !ok || len(value) == 0 || err != nil {
+			return
+		}
+
+		if e := set(value); e != nil {
+			err = fmt.Errorf("%s: %w", key, e)
+		}
+	}
+	integer := func(target *int) func(string) error {
+		return func(value string) (e error) {
+			*target, e = strconv.Atoi(value)
+			return e
+		}
+	}
+	float := func(target *float64) func(string) error {
+		return func(value string) (e error) {
+			*target, e = strconv.ParseFloat(value, 64)
+			return e
+		}
+	}
+	duration := func(target *time.Duration) func(string) error {
+		return func(value string) (e error) {
+			*target, e = time.ParseDuration(value)
+			return e
+		}
+	}
+	boolean := func(target *bool) func(string) error {
+		return func(value string) (e error) {
+			*target, e = strconv.ParseBool(value)
+			return e
+		}
+	}
+
+	parse(EnvInstanceID, func(value string) error {
+		cfg.InstanceID = value
+		return nil
+	})
+	parse(EnvPoolSize, integer(&cfg.PoolSize))
+	parse(EnvPoolMinSize, integer(&cfg.PoolMinSize))
+	parse(EnvPoolTimeout, duration(&cfg.PoolTimeout))
+	parse(EnvDefaultRoom, func(value string) error {
+		cfg.DefaultRoom = value
+		return nil
+	})
+	parse(EnvSubscriberBuffer, integer(&cfg.SubscriberBuffer))
+	parse(EnvOverflowPolicy, func(value string) error {
+		policy, ok := overflowPolicies[value]
+		if !ok {
+			return fmt.Errorf("unknown overflow policy %q", value)
+		}
+		cfg.OverflowPolicy = policy
+		return nil
+	})
+	parse(EnvSynchronousDelivery, boolean(&cfg.SynchronousDelivery))
+	parse(EnvOrderedDelivery, boolean(&cfg.OrderedDelivery))
+	parse(EnvHistorySize, integer(&cfg.HistorySize))
+	parse(EnvHistoryTTL, duration(&cfg.HistoryTTL))
+	parse(EnvMaxSubscriptions, integer(&cfg.MaxSubscriptions))
+	parse(EnvRoomCapacities, func(value string) error {
+		cfg.RoomCapacities = make(map[string]int)
+		for _, pair := range strings.Split(value, ",") {
+			parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("room capacity %q is not room=capacity", pair)
+			}
+
+			capacity, e := strconv.Atoi(parts[1])
+			if e != nil {
+				return e
+			}
+			cfg.RoomCapacities[parts[0]] = capacity
+		}
+		return nil
+	})
+	parse(EnvSubscriberRate, float(&cfg.SubscriberRate))
+	parse(EnvSubscriberBurst, integer(&cfg.SubscriberBurst))
+	parse(EnvFanoutRate, float(&cfg.FanoutRate))
+
+	return cfg, err
+}
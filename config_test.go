@@ -0,0 +1,87 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+func lookupFrom(env map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	cfg, err := configFromLookup(lookupFrom(map[string]string{
+		EnvPoolSize:            "20",
+		EnvPoolTimeout:         "30s",
+		EnvDefaultRoom:         "lobby",
+		EnvSubscriberBuffer:    "8",
+		EnvOverflowPolicy:      "drop-oldest",
+		EnvSynchronousDelivery: "true",
+		EnvHistorySize:         "5",
+		EnvRoomCapacities:      "small=1, large=100",
+		EnvFanoutRate:          "1000",
+	}))
+
+	if err != nil {
+		t.Fatalf("configFromLookup() returned %v", err)
+	}
+
+	if cfg.PoolSize != 20 || cfg.PoolTimeout != time.Second*30 || cfg.DefaultRoom != "lobby" || !cfg.SynchronousDelivery {
+		t.Fatalf("configFromLookup() = %+v; want the pool, default room and delivery settings", cfg)
+	}
+
+	if cfg.SubscriberBuffer != 8 || cfg.OverflowPolicy != OverflowDropOldest || cfg.HistorySize != 5 || cfg.FanoutRate != 1000 {
+		t.Fatalf("configFromLookup() = %+v; want the buffer, history and fan-out settings", cfg)
+	}
+
+	if len(cfg.RoomCapacities) != 2 || cfg.RoomCapacities["small"] != 1 || cfg.RoomCapacities["large"] != 100 {
+		t.Fatalf("configFromLookup() has room capacities %v; want small=1 and large=100", cfg.RoomCapacities)
+	}
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	for key, value := range map[string]string{
+		EnvPoolSize:       "many",
+		EnvPoolTimeout:    "5",
+		EnvOverflowPolicy: "ignore",
+		EnvRoomCapacities: "room",
+	} {
+		if _, err := configFromLookup(lookupFrom(map[string]string{key: value})); err == nil {
+			t.Fatalf("configFromLookup() with %s=%s should fail", key, value)
+		}
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	b, cancel, err := NewFromConfig(Config{
+		DefaultRoom:         "lobby",
+		SynchronousDelivery: true,
+		RoomCapacities:      map[string]int{"small": 1},
+		Options:             []Option{WithInstanceID("instance")},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig() returned %v", err)
+	}
+	defer cancel()
+	called := false
+	s := b.Subscribe(func(_ interface{}) { called = true })
+	b.ToRoom("data", "lobby")
+
+	if !called {
+		t.Fatalf("Subscription in the configured default room did not receive the message")
+	}
+
+	b.JoinRoom(s, "small")
+	if err := b.JoinRoom(b.Subscribe(func(_ interface{}) {}), "small"); err == nil {
+		t.Fatalf("JoinRoom() of a full room should fail")
+	}
+}
+
+func TestNewFromConfig_Invalid(t *testing.T) {
+	if _, _, err := NewFromConfig(Config{PoolSize: -1}); err == nil {
+		t.Fatalf("NewFromConfig() with a negative pool size should fail")
+	}
+}
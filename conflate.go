@@ -0,0 +1,129 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+)
+
+// ConflationKey returns the key of a message. Queued messages of
+// a conflated room with the same key replace each other.
+type ConflationKey func(data interface{}) string
+
+// WithConflation makes a room deliver only the latest message per key to subscriptions that are behind.
+// When a message is sent to the room while a message with the same key is still waiting in the buffer
+// of a subscription, the waiting message is replaced and counted by Subscription.Dropped.
+// Messages sent to all subscribers are conflated by the default room. Subscriptions without
+// a buffer set by WithSubscriberBuffer get a buffer of 64 messages that blocks the sender when it is full.
+func WithConflation(room string, key ConflationKey) Option {
+	return func(b *broadcaster) error {
+		if len(room) == 0 {
+			return errors.New("conflated room name cannot be empty")
+		}
+
+		if key == nil {
+			return errors.New("conflation key cannot be nil")
+		}
+
+		if b.conflations == nil {
+			b.conflations = make(map[string]ConflationKey)
+		}
+
+		b.conflations[room] = key
+		return nil
+	}
+}
+
+// conflator keeps track of the conflated deliveries waiting in a subscription queue.
+type conflator struct {
+	mux     *sync.Mutex
+	pending map[string]*conflated
+}
+
+// conflated is a queue slot holding the latest delivery of a key.
+type conflated struct {
+	key       string
+	delivery  delivery
+	conflator *conflator
+}
+
+func newConflator() *conflator {
+	return &conflator{
+		mux:     &sync.Mutex{},
+		pending: make(map[string]*conflated),
+	}
+}
+
+// add replaces the waiting delivery with the same key and returns it, or returns
+// a delivery holding a new slot that has to be queued.
+func (c *conflator) add(key string, d delivery) (delivery, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if slot := c.pending[key]; slot != nil {
+		replaced := slot.delivery
+		slot.delivery = d
+		return replaced, true
+	}
+
+	slot := &conflated{key: key, delivery: d, conflator: c}
+	c.pending[key] = slot
+
+	return delivery{msg: d.msg, conflated: slot}, false
+}
+
+// take removes the slot and returns its latest delivery.
+func (s *conflated) take() delivery {
+	s.conflator.mux.Lock()
+	defer s.conflator.mux.Unlock()
+
+	if s.conflator.pending[s.key] == s {
+		delete(s.conflator.pending, s.key)
+	}
+
+	return s.delivery
+}
+
+// current returns the delivery that a queue slot holds when it is taken out of the queue.
+func (d delivery) current() delivery {
+	if d.conflated == nil {
+		return d
+	}
+
+	return d.conflated.take()
+}
+
+// conflate replaces a delivery waiting in the subscription queue with the same key
+// and reports whether it did, otherwise it returns the delivery to queue.
+func (b *broadcaster) conflate(s *Subscription, d delivery) (delivery, bool) {
+	if s.conflator == nil {
+		return d, false
+	}
+
+	room, key := b.conflationKey(d.msg)
+	if key == nil {
+		return d, false
+	}
+
+	replaced, ok := s.conflator.add(room+"\x00"+key(d.msg.Data), d)
+	if ok {
+		b.dropped(s, 1)
+		replaced.finish(false)
+	}
+
+	return replaced, ok
+}
+
+// conflationKey returns the first target room of a message that is conflated and its key function.
+func (b *broadcaster) conflationKey(msg *Message) (string, ConflationKey) {
+	if msg.ToAll {
+		return b.defaultRoomName, b.conflations[b.defaultRoomName]
+	}
+
+	for _, room := range msg.Rooms {
+		if key := b.conflations[room]; key != nil {
+			return room, key
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,80 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithConflation_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithConflation("", conflationKey)(b); err == nil {
+		t.Fatalf("WithConflation with empty room; expected an error")
+	}
+
+	if err := WithConflation("room", nil)(b); err == nil {
+		t.Fatalf("WithConflation with nil key; expected an error")
+	}
+}
+
+func TestBroadcaster_ToRoom_WithConflationShouldDeliverLatestPerKey(t *testing.T) {
+	b, cancel, _ := New(WithConflation("ticks", conflationKey), WithOrderedDelivery())
+	defer cancel()
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	received := make(chan interface{}, 10)
+	subscription := b.Subscribe(func(data interface{}) {
+		started <- struct{}{}
+		<-release
+		received <- data
+	})
+	b.JoinRoom(subscription, "ticks")
+
+	b.ToRoom("a1", "ticks")
+	<-started
+	for _, tick := range []string{"a2", "b1", "a3"} {
+		b.ToRoom(tick, "ticks")
+	}
+	close(release)
+
+	for _, want := range []string{"a1", "a3", "b1"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("received %v; want %v", got, want)
+			}
+		case <-time.After(time.Second * 3):
+			t.Fatalf("message %v was not received", want)
+		}
+	}
+
+	if subscription.Dropped() != 1 {
+		t.Fatalf("subscription dropped %d messages; want 1", subscription.Dropped())
+	}
+}
+
+func TestBroadcaster_ToRoom_WithConflationShouldNotConflateOtherRooms(t *testing.T) {
+	b, cancel, _ := New(WithConflation("ticks", conflationKey), WithOrderedDelivery())
+	defer cancel()
+	release := make(chan struct{})
+	received := make(chan interface{}, 10)
+	subscription := b.Subscribe(func(data interface{}) {
+		<-release
+		received <- data
+	})
+	b.JoinRoom(subscription, "other")
+
+	for _, tick := range []string{"a1", "a2", "a3"} {
+		b.ToRoom(tick, "other")
+	}
+	close(release)
+	<-time.After(time.Millisecond * 100)
+
+	if len(received) != 3 {
+		t.Fatalf("received %d messages; want 3", len(received))
+	}
+}
+
+func conflationKey(data interface{}) string {
+	return data.(string)[:1]
+}
@@ -0,0 +1,96 @@
+package broadcast
+
+import "context"
+
+// ContextDispatcher can be implemented by a MessageDispatcher to receive the context of
+// the messages it sends, e.g. to cancel a broker call or to transfer request metadata.
+// When implemented, DispatchMessageContext is used instead of TryDispatchMessage and
+// DispatchMessage and its errors are reported to the Metrics. On the receiving side,
+// a Dispatcher passes a context to the broadcaster with Message.WithContext.
+type ContextDispatcher interface {
+	MessageDispatcher
+	// DispatchMessageContext sends a message to an external service and returns an error if it failed.
+	DispatchMessageContext(ctx context.Context, msg *Message) error
+}
+
+// WithContext sets the context of a message. It is passed to middleware, a ContextDispatcher and
+// the callbacks of SubscribeContext. Deliveries that didn't start before the context is done are dropped,
+// retained and replayed deliveries of the message don't carry the context.
+func WithContext(ctx context.Context) SendOption {
+	return func(msg *Message) {
+		msg.ctx = ctx
+	}
+}
+
+// Context returns the context of the message or, if it has none, the background context.
+func (m *Message) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+
+	return m.ctx
+}
+
+// WithContext returns a shallow copy of the message with its context changed to ctx.
+func (m *Message) WithContext(ctx context.Context) *Message {
+	copied := *m
+	copied.ctx = ctx
+	return &copied
+}
+
+// detached returns the message without its context. Retained and stored messages are
+// delivered after the send returned, so the context of the send must not drop them.
+func (m *Message) detached() *Message {
+	if m.ctx == nil {
+		return m
+	}
+
+	copied := *m
+	copied.ctx = nil
+	return &copied
+}
+
+// canceled reports whether the context of the message is done.
+func (m *Message) canceled() bool {
+	return m.ctx != nil && m.ctx.Err() != nil
+}
+
+// ToAllCtx works like ToAll but sends the message with the context, see WithContext.
+func (b *broadcaster) ToAllCtx(ctx context.Context, data interface{}, except ...string) error {
+	return b.publish(&Message{Data: data, ToAll: true, Except: except, ctx: ctx})
+}
+
+// ToRoomCtx works like ToRoom but sends the message with the context, see WithContext.
+func (b *broadcaster) ToRoomCtx(ctx context.Context, data interface{}, room string, except ...string) error {
+	return b.publish(&Message{Data: data, Rooms: []string{room}, Except: except, ctx: ctx})
+}
+
+// SubscribeContext works like Subscribe but the callback also receives the context
+// of the message, see WithContext.
+func (b *broadcaster) SubscribeContext(callback func(ctx context.Context, data interface{})) *Subscription {
+	sub := b.newSubscription(nil)
+	sub.handler = contextHandler(callback)
+	b.subscribed(sub)
+
+	return sub
+}
+
+func (n *namespace) ToAllCtx(ctx context.Context, data interface{}, except ...string) error {
+	return n.broadcaster.publish(n.message(&Message{Data: data, ToAll: true, Except: except, ctx: ctx}))
+}
+
+func (n *namespace) ToRoomCtx(ctx context.Context, data interface{}, room string, except ...string) error {
+	return n.broadcaster.publish(n.message(&Message{Data: data, Rooms: []string{room}, Except: except, ctx: ctx}))
+}
+
+func (n *namespace) SubscribeContext(callback func(ctx context.Context, data interface{})) *Subscription {
+	sub := n.broadcaster.newSubscription(nil)
+	sub.handler = contextHandler(callback)
+	return n.subscribed(sub)
+}
+
+func contextHandler(callback func(ctx context.Context, data interface{})) func(msg *Message) {
+	return func(msg *Message) {
+		callback(msg.Context(), msg.Data)
+	}
+}
@@ -0,0 +1,120 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type contextKey struct{}
+
+type contextDispatcher struct {
+	mockMessageDispatcher
+	contexts chan context.Context
+}
+
+func (d *contextDispatcher) DispatchMessageContext(ctx context.Context, msg *Message) error {
+	d.contexts <- ctx
+	return nil
+}
+
+func TestBroadcaster_ToRoomCtx_ShouldPassContext(t *testing.T) {
+	var middlewareValue interface{}
+	dispatcher := &contextDispatcher{contexts: make(chan context.Context, 1)}
+	b, cancel, _ := New(WithDispatcher(dispatcher), WithSynchronousDelivery(), WithMiddleware(func(next SendFunc) SendFunc {
+		return func(msg *Message) error {
+			middlewareValue = msg.Context().Value(contextKey{})
+			return next(msg)
+		}
+	}))
+	defer cancel()
+	var callbackValue interface{}
+	s := b.SubscribeContext(func(ctx context.Context, data interface{}) {
+		callbackValue = ctx.Value(contextKey{})
+	})
+	b.JoinRoom(s, "room")
+	ctx := context.WithValue(context.Background(), contextKey{}, "request-1")
+
+	if err := b.ToRoomCtx(ctx, "data", "room"); err != nil {
+		t.Fatalf("ToRoomCtx() returned %v", err)
+	}
+
+	if middlewareValue != "request-1" || callbackValue != "request-1" {
+		t.Fatalf("Middleware got %v and callback got %v; want request-1", middlewareValue, callbackValue)
+	}
+
+	if dispatched := <-dispatcher.contexts; dispatched.Value(contextKey{}) != "request-1" {
+		t.Fatalf("Dispatcher got context value %v; want request-1", dispatched.Value(contextKey{}))
+	}
+}
+
+func TestBroadcaster_ToAllCtx_ShouldDropCanceledMessages(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	called := false
+	b.Subscribe(func(_ interface{}) { called = true })
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancelCtx()
+
+	b.ToAllCtx(ctx, "data")
+
+	if called {
+		t.Fatalf("Message with a canceled context was delivered")
+	}
+}
+
+func TestMessage_Context(t *testing.T) {
+	msg := &Message{}
+	if msg.Context() != context.Background() {
+		t.Fatalf("Context() of a message without context should be the background context")
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	copied := msg.WithContext(ctx)
+
+	if copied.Context() != ctx || msg.Context() != context.Background() {
+		t.Fatalf("WithContext() should change the context of a copy only")
+	}
+}
+
+func TestNamespace_SubscribeContext(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	n := namespaceOf(t, b, "tenant")
+	var value interface{}
+	n.SubscribeContext(func(ctx context.Context, _ interface{}) {
+		value = ctx.Value(contextKey{})
+	})
+
+	n.ToAllCtx(context.WithValue(context.Background(), contextKey{}, "value"), "data")
+
+	if value != "value" {
+		t.Fatalf("Callback got context value %v; want value", value)
+	}
+}
+
+func TestBroadcaster_ToRoomCtx_RetainedAfterCancel(t *testing.T) {
+	b, cancel, _ := New(WithRetainLast("scores"), WithHistory(10, 0))
+	defer cancel()
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	b.ToRoomCtx(ctx, "state", "scores")
+	cancelCtx()
+	received := make(chan interface{}, 2)
+	s := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	b.JoinRoom(s, "scores")
+	b.Replay(s, "scores", time.Time{})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case got := <-received:
+			if got != "state" {
+				t.Fatalf("late joiner received %v; want state", got)
+			}
+		case <-time.After(time.Second * 3):
+			t.Fatalf("late joiner received %d of 2 messages after the send context was canceled", i)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultDebugHistory = 10
+
+// DebugHandler returns an http.Handler that renders the current rooms, their subscriptions,
+// the pool state and the recent history of every room as JSON, or as HTML when the request
+// has the query parameter format=html. The query parameter history limits the number of
+// messages shown per room, default is 10. History is only shown if it is enabled with
+// WithHistory or WithStore. The handler exposes message data and subscription metadata,
+// so it should not be reachable by untrusted clients.
+func DebugHandler(b Broadcaster) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultDebugHistory
+		if value := r.URL.Query().Get("history"); len(value) > 0 {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				http.Error(w, "history must be a non-negative number", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		state := inspect(b, limit)
+
+		if r.URL.Query().Get("format") == "html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			debugTemplate.Execute(w, state)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(state)
+	})
+}
+
+type debugState struct {
+	Stats BroadcasterStats `json:"stats"`
+	Rooms []debugRoom      `json:"rooms"`
+}
+
+type debugRoom struct {
+	Name          string            `json:"name"`
+	Meta          map[string]string `json:"meta,omitempty"`
+	Subscriptions []string          `json:"subscriptions"`
+	History       []debugMessage    `json:"history,omitempty"`
+}
+
+type debugMessage struct {
+	ID        string    `json:"id"`
+	Origin    string    `json:"origin,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      string    `json:"data"`
+}
+
+// historian is implemented by broadcasters that can list the recent messages of a room.
+type historian interface {
+	recent(room string, limit int) []*Message
+}
+
+func inspect(b Broadcaster, limit int) debugState {
+	state := debugState{
+		Stats: b.Stats(),
+		Rooms: []debugRoom{},
+	}
+
+	h, _ := b.(historian)
+	for _, info := range b.Rooms(nil) {
+		room := debugRoom{
+			Name:          info.Name,
+			Meta:          info.Meta,
+			Subscriptions: b.Subscribers(info.Name),
+		}
+
+		if h != nil && limit > 0 {
+			for _, msg := range h.recent(info.Name, limit) {
+				room.History = append(room.History, debugMessage{
+					ID:        msg.ID,
+					Origin:    msg.Origin,
+					Timestamp: msg.Timestamp,
+					Data:      fmt.Sprint(msg.Data),
+				})
+			}
+		}
+
+		state.Rooms = append(state.Rooms, room)
+	}
+
+	return state
+}
+
+// recent returns up to limit of the most recent messages of a room from the history, oldest first.
+func (b *broadcaster) recent(room string, limit int) []*Message {
+	if b.store == nil {
+		return nil
+	}
+
+	messages := []*Message{}
+	b.store.Range(room, time.Time{}, func(msg *Message) bool {
+		messages = append(messages, msg)
+		return true
+	})
+
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	return messages
+}
+
+func (n *namespace) recent(room string, limit int) []*Message {
+	return n.broadcaster.recent(n.room(room), limit)
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><title>broadcast</title></head>
+<body>
+<h1>broadcast</h1>
+<table>
+<tr><td>Rooms</td><td>{{.Stats.Rooms}}</td></tr>
+<tr><td>Subscriptions</td><td>{{.Stats.Subscriptions}}</td></tr>
+<tr><td>Sent</td><td>{{.Stats.Sent}}</td></tr>
+<tr><td>Delivered</td><td>{{.Stats.Delivered}}</td></tr>
+<tr><td>Dropped</td><td>{{.Stats.Dropped}}</td></tr>
+<tr><td>Pool</td><td>{{.Stats.PoolBusy}} busy, {{.Stats.PoolWorkers}} running, {{.Stats.PoolSize}} max, {{.Stats.PoolQueued}} queued</td></tr>
+</table>
+{{range .Rooms}}
+<h2>{{.Name}}</h2>
+{{range $key, $value := .Meta}}<div>{{$key}}: {{$value}}</div>{{end}}
+<h3>Subscriptions</h3>
+<ul>{{range .Subscriptions}}<li>{{.}}{{with index $.Stats.QueueDepths .}} ({{.}} queued){{end}}</li>{{end}}</ul>
+{{if .History}}
+<h3>History</h3>
+<table>{{range .History}}<tr><td>{{.Timestamp.Format "2006-01-02T15:04:05.000Z07:00"}}</td><td>{{.ID}}</td><td>{{.Data}}</td></tr>{{end}}</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
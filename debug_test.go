@@ -0,0 +1,64 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugHandler_JSON(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery(), WithHistory(5, 0))
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "chat")
+	for _, data := range []string{"a", "b", "c"} {
+		b.ToRoom(data, "chat")
+	}
+	rec := httptest.NewRecorder()
+
+	DebugHandler(b).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/broadcast?history=2", nil))
+
+	var state debugState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("DebugHandler returned invalid JSON: %v", err)
+	}
+
+	if len(state.Rooms) != 2 || state.Rooms[0].Name != "chat" || state.Stats.Sent != 3 {
+		t.Fatalf("DebugHandler returned %+v; want the chat and default rooms and 3 sent messages", state)
+	}
+
+	chat := state.Rooms[0]
+	if len(chat.Subscriptions) != 1 || chat.Subscriptions[0] != s.ID() {
+		t.Fatalf("DebugHandler returned subscriptions %v for chat; want %s", chat.Subscriptions, s.ID())
+	}
+
+	if len(chat.History) != 2 || chat.History[0].Data != "b" || chat.History[1].Data != "c" {
+		t.Fatalf("DebugHandler returned history %+v for chat; want b and c", chat.History)
+	}
+}
+
+func TestDebugHandler_HTML(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	rec := httptest.NewRecorder()
+
+	DebugHandler(b).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/broadcast?format=html", nil))
+
+	if !strings.Contains(rec.Body.String(), "<li>"+s.ID()) {
+		t.Fatalf("DebugHandler HTML does not list subscription %s:\n%s", s.ID(), rec.Body.String())
+	}
+}
+
+func TestDebugHandler_WithInvalidHistory(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	rec := httptest.NewRecorder()
+
+	DebugHandler(b).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/broadcast?history=x", nil))
+
+	if rec.Code != 400 {
+		t.Fatalf("DebugHandler returned status %d; want 400", rec.Code)
+	}
+}
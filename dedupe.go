@@ -0,0 +1,94 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// WithInstanceID sets the ID the broadcaster puts in the Origin of the messages it sends.
+// Messages received through the Dispatcher with the same origin are echoes and are not delivered.
+// By default a unique ID is generated.
+func WithInstanceID(id string) Option {
+	return func(b *broadcaster) error {
+		if len(id) == 0 {
+			return errors.New("instance ID cannot be empty")
+		}
+
+		b.instanceID = id
+		return nil
+	}
+}
+
+// WithDedupeWindow suppresses messages received through the Dispatcher whose ID was already
+// sent or received within the window, e.g. when several dispatchers or overlapping brokers
+// deliver the same message twice. Only a MessageDispatcher keeps message IDs.
+// By default duplicates are not detected.
+func WithDedupeWindow(window time.Duration) Option {
+	return func(b *broadcaster) error {
+		if window <= 0 {
+			return errors.New("dedupe window must be positive")
+		}
+
+		b.dedupe = newDeduper(window)
+		return nil
+	}
+}
+
+// deduper remembers the message IDs seen within a sliding window.
+type deduper struct {
+	mux    *sync.Mutex
+	window time.Duration
+	seen   map[string]struct{}
+	order  []seenID
+}
+
+type seenID struct {
+	id string
+	at time.Time
+}
+
+func newDeduper(window time.Duration) *deduper {
+	return &deduper{
+		mux:    &sync.Mutex{},
+		window: window,
+		seen:   make(map[string]struct{}),
+	}
+}
+
+// add records an ID and reports whether it was already seen within the window.
+func (d *deduper) add(id string, now time.Time) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	expired := 0
+	for expired < len(d.order) && now.Sub(d.order[expired].at) > d.window {
+		delete(d.seen, d.order[expired].id)
+		expired++
+	}
+	d.order = d.order[expired:]
+
+	if _, ok := d.seen[id]; ok {
+		return true
+	}
+
+	d.seen[id] = struct{}{}
+	d.order = append(d.order, seenID{id: id, at: now})
+
+	return false
+}
+
+// isDuplicate reports whether a received message is an echo of a message
+// sent by this broadcaster, a message that was already seen or a repeated
+// idempotency key, see WithIdempotencyWindow.
+func (b *broadcaster) isDuplicate(msg *Message) bool {
+	if len(msg.Origin) > 0 && msg.Origin == b.instanceID {
+		return true
+	}
+
+	if b.dedupe != nil && len(msg.ID) > 0 && b.dedupe.add(msg.ID, b.clock.Now()) {
+		return true
+	}
+
+	return b.isRepeated(msg)
+}
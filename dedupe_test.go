@@ -0,0 +1,79 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithInstanceID_WithEmptyID(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithInstanceID("")(b); err == nil {
+		t.Fatalf("WithInstanceID(\"\"); expected an error")
+	}
+}
+
+func TestWithDedupeWindow_WithNonPositiveWindow(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithDedupeWindow(0)(b); err == nil {
+		t.Fatalf("WithDedupeWindow(0); expected an error")
+	}
+}
+
+func TestDeduper_add(t *testing.T) {
+	d := newDeduper(time.Minute)
+	now := time.Now()
+
+	if d.add("a", now) {
+		t.Fatalf("first ID should not be a duplicate")
+	}
+
+	if !d.add("a", now.Add(time.Second)) {
+		t.Fatalf("ID seen within the window should be a duplicate")
+	}
+
+	if d.add("a", now.Add(time.Minute*2)) {
+		t.Fatalf("ID seen outside of the window should not be a duplicate")
+	}
+}
+
+func TestBroadcaster_ShouldSuppressEchoes(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithInstanceID("instance"), WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+
+	b.ToAll("data")
+	msg := <-dispatcher.dispatched
+	echo := *msg
+	dispatcher.received(&echo)
+
+	if msg.Origin != "instance" {
+		t.Fatalf("message origin is %q; want %q", msg.Origin, "instance")
+	}
+
+	if calls != 1 {
+		t.Fatalf("callback was called %d times; echo should not be delivered", calls)
+	}
+}
+
+func TestBroadcaster_WithDedupeWindowShouldSuppressDuplicates(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithDedupeWindow(time.Minute), WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+
+	dispatcher.received(&Message{ID: "id", Origin: "other", Data: "data", ToAll: true})
+	dispatcher.received(&Message{ID: "id", Origin: "another", Data: "data", ToAll: true})
+
+	if calls != 1 {
+		t.Fatalf("callback was called %d times; duplicate should not be delivered", calls)
+	}
+}
@@ -0,0 +1,427 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// delivery is a message handed to a single subscription.
+type delivery struct {
+	msg        *Message
+	tracker    *tracker
+	attempts   int
+	conflated  *conflated
+	subscriber string
+}
+
+// finish reports that the delivery is completed or abandoned.
+func (d delivery) finish(delivered bool) {
+	var err error
+	if !delivered {
+		err = ErrNotDelivered
+	}
+
+	d.finishWith(err)
+}
+
+// finishWith reports that the delivery is finished with the given error, nil if it succeeded.
+func (d delivery) finishWith(err error) {
+	d = d.current()
+	if d.tracker != nil {
+		d.tracker.done(d.subscriber, err)
+	}
+}
+
+// exclude reports that the subscription of the delivery is not a recipient of the message.
+func (d delivery) exclude() {
+	if d.tracker != nil {
+		d.tracker.done("", ErrNotDelivered)
+	}
+}
+
+// tracker waits for all deliveries of a single message.
+type tracker struct {
+	delivered int64
+	wg        *sync.WaitGroup
+	pending   func(delta int64)
+	receipt   *receiptRecorder
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		wg: &sync.WaitGroup{},
+	}
+}
+
+// track returns a tracker that also counts the deliveries in progress of the broadcaster
+// and records a receipt if the message has one or is audited.
+func (b *broadcaster) track(msg *Message) *tracker {
+	t := newTracker()
+	t.pending = b.addPending
+	if msg.receipt != nil || msg.audited {
+		t.receipt = newReceiptRecorder(func(receipt Receipt, delivered []string) {
+			if msg.receipt != nil {
+				msg.receipt(receipt)
+			}
+
+			if msg.audited {
+				b.audit(msg, nil, receipt, delivered)
+			}
+		})
+	}
+	return t
+}
+
+func (t *tracker) add() {
+	t.wg.Add(1)
+	if t.pending != nil {
+		t.pending(1)
+	}
+}
+
+// done finishes a delivery to the given subscriber with the given error.
+// Steps that are not deliveries to a subscriber have no subscriber.
+func (t *tracker) done(subscriber string, err error) {
+	if err == nil {
+		atomic.AddInt64(&t.delivered, 1)
+	}
+
+	if t.receipt != nil && len(subscriber) > 0 {
+		t.receipt.record(subscriber, err)
+	}
+
+	if t.pending != nil {
+		t.pending(-1)
+	}
+	t.wg.Done()
+}
+
+// wait blocks until all deliveries are finished or the context is done
+// and returns the number of successful deliveries.
+func (t *tracker) wait(ctx context.Context) (int, error) {
+	donec := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(donec)
+	}()
+
+	select {
+	case <-donec:
+		return int(atomic.LoadInt64(&t.delivered)), nil
+	case <-ctx.Done():
+		return int(atomic.LoadInt64(&t.delivered)), ctx.Err()
+	}
+}
+
+// fanOut schedules the delivery of a message to all subscriptions within
+// the target rooms that are not excluded, subject to the rate limit of the room.
+func (b *broadcaster) fanOut(msg *Message, t *tracker) {
+	if !msg.heartbeat {
+		kept := msg.detached()
+		b.retain(kept)
+		b.record(kept)
+		b.touchRooms(msg.Rooms)
+	}
+
+	if len(msg.ReplyTo) > 0 {
+		request := *msg
+		request.Data = &Request{
+			Data:          msg.Data,
+			CorrelationID: msg.CorrelationID,
+			replyTo:       msg.ReplyTo,
+			broadcaster:   b,
+		}
+		msg = &request
+	}
+
+	l := b.roomLimiter(msg)
+	if l == nil {
+		b.schedule(msg, t)
+		return
+	}
+
+	// The rate limited step is tracked, without counting as a delivery,
+	// so a synchronous sender also waits for delayed messages.
+	d := delivery{msg: msg, tracker: t}
+	if t != nil {
+		t.add()
+	}
+
+	l.limit(func() {
+		b.schedule(msg, t)
+		d.finish(false)
+	}, func() {
+		d.finish(false)
+	})
+}
+
+// schedule delivers a message to every recipient on the pool, transformed
+// for the rooms of the recipient, see SetRoomTransformer, and paced by the fan-out rate, see WithFanoutRate.
+func (b *broadcaster) schedule(original *Message, t *tracker) {
+	transforms := b.transform(original)
+	pool := b.poolFor(original)
+	targets := b.targets(original)
+
+	if b.pacer == nil {
+		b.scheduleTargets(original, targets, transforms, pool, t)
+		return
+	}
+
+	b.pacer.pace(targets, t, func(chunk []*Subscription) {
+		b.scheduleTargets(original, chunk, transforms, pool, t)
+	})
+}
+
+// scheduleTargets schedules the deliveries of a message to the given subscriptions.
+func (b *broadcaster) scheduleTargets(original *Message, targets []*Subscription, transforms []transformed, pool *pool, t *tracker) {
+	for _, sub := range targets {
+		s := sub
+		msg := messageFor(s, original, transforms)
+		d := delivery{msg: msg, tracker: t, subscriber: s.id}
+		if t != nil {
+			t.add()
+		}
+
+		if b.ordered && s.queue != nil {
+			b.deliverOrdered(s, d)
+			continue
+		}
+
+		scheduled := pool.doPriority(func() {
+			if b.isExcluded(s, msg) {
+				d.exclude()
+				return
+			}
+			b.deliver(s, d)
+		}, msg.Priority)
+
+		if !scheduled {
+			d.finish(false)
+		}
+	}
+}
+
+// deliverOrdered queues a delivery on the calling go routine, so the subscription
+// receives messages in the order they were sent, and drains the queue on the pool.
+func (b *broadcaster) deliverOrdered(s *Subscription, d delivery) {
+	if b.isExcluded(s, d.msg) {
+		d.exclude()
+		return
+	}
+
+	if b.skip(s, d) {
+		return
+	}
+
+	b.limitSubscription(s, d, func() {
+		if b.enqueue(s, d) {
+			b.pool.doOrRun(func() {
+				b.drain(s)
+			})
+		}
+	})
+}
+
+// subscriptions returns the local subscriptions with the given IDs that are part of any room.
+func (b *broadcaster) subscriptions(ids []string) []*Subscription {
+	found := make(map[string]*Subscription, len(ids))
+	b.rooms.each(func(_ string, r *room) bool {
+		for _, id := range ids {
+			if s := r.lookup(id); s != nil {
+				found[id] = s
+			}
+		}
+
+		return len(found) < len(ids)
+	})
+
+	subs := []*Subscription{}
+	for _, id := range ids {
+		if s := found[id]; s != nil {
+			subs = append(subs, s)
+			delete(found, id)
+		}
+	}
+
+	return subs
+}
+
+// targets returns the subscriptions that receive a message, narrowed down by sampling
+// or keyed routing, see ToRoomSample and ToRoomKeyed.
+func (b *broadcaster) targets(msg *Message) []*Subscription {
+	return b.route(msg, b.sample(msg, b.recipients(msg)))
+}
+
+// recipients returns the target subscriptions of a message if it has any, otherwise
+// the subscriptions within the target rooms of the message,
+// including the subscriptions that joined the tree of a target room and
+// the selected member of the group with the name of a target room.
+// A subscription that is part of several target rooms is returned once.
+func (b *broadcaster) recipients(msg *Message) []*Subscription {
+	if len(msg.Subscribers) > 0 {
+		return b.subscriptions(msg.Subscribers)
+	}
+
+	if isReply(msg) {
+		return b.replyRecipients(msg)
+	}
+
+	names := b.targetRooms(msg)
+
+	b.mux.RLock()
+	rooms := make([]*room, 0, len(names))
+	groups := []*group{}
+	for _, name := range names {
+		if r := b.rooms.get(name); r != nil {
+			rooms = append(rooms, r)
+		}
+
+		if msg.ToAll {
+			continue
+		}
+
+		if g := b.groups[name]; g != nil {
+			groups = append(groups, g)
+		}
+
+		if len(b.trees) == 0 {
+			continue
+		}
+
+		for _, ancestor := range b.ancestors(name) {
+			if tree := b.trees[ancestor]; tree != nil {
+				rooms = append(rooms, tree)
+			}
+		}
+	}
+	b.mux.RUnlock()
+
+	if len(rooms) == 1 && len(groups) == 0 {
+		return rooms[0].snapshot()
+	}
+
+	seen := make(map[string]struct{})
+	subs := []*Subscription{}
+	add := func(s *Subscription) {
+		if _, ok := seen[s.id]; ok {
+			return
+		}
+
+		seen[s.id] = struct{}{}
+		subs = append(subs, s)
+	}
+
+	for _, r := range rooms {
+		for _, s := range r.snapshot() {
+			add(s)
+		}
+	}
+
+	for _, g := range groups {
+		if s := b.pick(g, msg); s != nil {
+			add(s)
+		}
+	}
+
+	return subs
+}
+
+func (b *broadcaster) deliver(s *Subscription, d delivery) {
+	if b.skip(s, d) {
+		return
+	}
+
+	b.limitSubscription(s, d, func() {
+		if s.queue == nil {
+			b.complete(s, d, b.send(s, d.msg))
+			return
+		}
+
+		if b.enqueue(s, d) {
+			b.drain(s)
+		}
+	})
+}
+
+// skip drops a delivery of an expired or canceled message or to a subscription flagged as slow.
+func (b *broadcaster) skip(s *Subscription, d delivery) bool {
+	if d.msg.expiredAt(b.clock.Now()) || d.msg.canceled() || (b.watchdog != nil && b.watchdog.skip(s)) {
+		b.dropped(s, 1)
+		d.finish(false)
+		return true
+	}
+
+	return false
+}
+
+// enqueue pushes a delivery to the subscription queue, unless it replaced a conflated
+// delivery that is already queued, and reports whether the queue needs to be drained.
+func (b *broadcaster) enqueue(s *Subscription, d delivery) bool {
+	d, replaced := b.conflate(s, d)
+	if replaced {
+		return false
+	}
+
+	if dropped := s.queue.push(d, b.pool.cancelc); dropped > 0 {
+		b.dropped(s, dropped)
+
+		if s.queue.policy == OverflowClose {
+			// Unsubscribe needs the room locks that may be held by the sender.
+			go b.Unsubscribe(s)
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *broadcaster) drain(s *Subscription) {
+	s.queue.drain(func(d delivery) {
+		d = d.current()
+		if d.msg.expiredAt(b.clock.Now()) || d.msg.canceled() {
+			b.dropped(s, 1)
+			d.finish(false)
+			return
+		}
+
+		b.complete(s, d, b.send(s, d.msg))
+	})
+}
+
+// complete finishes a delivery or, if it failed, schedules its redelivery.
+func (b *broadcaster) complete(s *Subscription, d delivery, err error) {
+	if err == nil {
+		b.measureDelivered(d.msg)
+		b.delivered(s)
+		d.finish(true)
+		return
+	}
+
+	if s.ackCallback != nil {
+		if b.redeliver(s, d) {
+			return
+		}
+
+		b.deadLetter(s, d.msg, err)
+	}
+
+	d.finishWith(err)
+}
+
+// send runs the subscription callback and returns its error.
+func (b *broadcaster) send(s *Subscription, msg *Message) (err error) {
+	span, _ := b.startSpan(SpanDeliver, msg)
+	defer func() { span.End(err) }()
+
+	atomic.AddInt32(&s.inFlight, 1)
+	start := b.clock.Now()
+	stop := b.watch(s, start)
+	err = b.call(s, msg)
+	latency := b.clock.Now().Sub(start)
+	atomic.AddInt32(&s.inFlight, -1)
+	stop(latency)
+	b.measureCallback(s, latency)
+
+	return err
+}
@@ -0,0 +1,161 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTracker_wait(t *testing.T) {
+	tr := newTracker()
+	tr.add()
+	tr.add()
+	tr.done("a", nil)
+	tr.done("b", ErrNotDelivered)
+
+	delivered, err := tr.wait(context.Background())
+
+	if err != nil || delivered != 1 {
+		t.Fatalf("wait() = %v, %v; want 1, nil", delivered, err)
+	}
+}
+
+func TestTracker_wait_WithExpiredContext(t *testing.T) {
+	tr := newTracker()
+	tr.add()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err := tr.wait(ctx)
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("wait returned %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBroadcaster_ToRoomSync(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	room := "test-room"
+	for i := 0; i < 3; i++ {
+		b.JoinRoom(b.Subscribe(func(_ interface{}) {}), room)
+	}
+	excluded := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(excluded, room, "excluded")
+
+	delivered, err := b.ToRoomSync(context.Background(), struct{}{}, room, "excluded")
+
+	if err != nil || delivered != 3 {
+		t.Fatalf("ToRoomSync() = %v, %v; want 3, nil", delivered, err)
+	}
+}
+
+func TestBroadcaster_ToAllSync_ShouldWaitForCallbacks(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	called := false
+	b.Subscribe(func(_ interface{}) {
+		<-time.After(time.Millisecond * 50)
+		called = true
+	})
+
+	delivered, _ := b.ToAllSync(context.Background(), struct{}{})
+
+	if !called || delivered != 1 {
+		t.Fatalf("ToAllSync should return after all callbacks have run")
+	}
+}
+
+func TestBroadcaster_ToAllSync_WithExpiredContext(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	b.Subscribe(func(_ interface{}) {
+		<-release
+	})
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancelCtx()
+
+	delivered, err := b.ToAllSync(ctx, struct{}{})
+
+	if err != context.DeadlineExceeded || delivered != 0 {
+		t.Fatalf("ToAllSync() = %v, %v; want 0, %v", delivered, err, context.DeadlineExceeded)
+	}
+}
+
+func TestBroadcaster_ToAllSync_ShouldNotCountPanickingCallbacks(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	b.Subscribe(func(_ interface{}) {
+		panic("test panic")
+	})
+	b.Subscribe(func(_ interface{}) {})
+
+	delivered, _ := b.ToAllSync(context.Background(), struct{}{})
+
+	if delivered != 1 {
+		t.Fatalf("ToAllSync delivered to %v subscriptions; want 1", delivered)
+	}
+}
+
+func TestWithSynchronousDelivery(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	called := false
+	b.Subscribe(func(_ interface{}) {
+		called = true
+	})
+
+	b.ToAll(struct{}{})
+
+	if !called {
+		t.Fatalf("ToAll with synchronous delivery should return after all callbacks have run")
+	}
+}
+
+func TestBroadcaster_ToAll_WithOrderedDeliveryShouldKeepOrder(t *testing.T) {
+	b, cancel, _ := New(WithOrderedDelivery())
+	defer cancel()
+	received := make(chan interface{}, 100)
+	b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	for i := 0; i < 100; i++ {
+		b.ToAll(i)
+	}
+
+	for i := 0; i < 100; i++ {
+		select {
+		case data := <-received:
+			if data != i {
+				t.Fatalf("received %v; want %v", data, i)
+			}
+		case <-time.After(time.Second * 3):
+			t.Fatalf("message %v was not received", i)
+		}
+	}
+}
+
+func TestBroadcaster_ToAll_WithOrderedDeliveryShouldNotBlockOtherSubscriptions(t *testing.T) {
+	b, cancel, _ := New(WithOrderedDelivery())
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	received := make(chan interface{}, 1)
+	b.Subscribe(func(_ interface{}) {
+		<-release
+	})
+	b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	b.ToAll(1)
+
+	select {
+	case <-received:
+	case <-time.After(time.Second * 3):
+		t.Fatalf("a blocked subscription should not delay other subscriptions")
+	}
+}
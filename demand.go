@@ -0,0 +1,147 @@
+package broadcast
+
+import "sync"
+
+// demand holds the hooks of a room set with OnDemand.
+type demand struct {
+	mux     *sync.Mutex
+	idle    *sync.Cond
+	start   func()
+	stop    func()
+	active  bool
+	running bool
+	removed bool
+}
+
+func newDemand(start func(), stop func()) *demand {
+	d := &demand{mux: &sync.Mutex{}, start: start, stop: stop}
+	d.idle = sync.NewCond(d.mux)
+	return d
+}
+
+// OnDemand sets functions that are called when a room gets its first subscription and when its
+// last subscription leaves, e.g. to poll an upstream feed only while someone is listening.
+// If the room already has subscriptions, start is called right away. Calls of start and stop
+// alternate and never overlap, a subscription joining an empty room while stop runs leads to
+// start being called once stop returns. The hooks are called on the go routine changing the room,
+// or on the one still running a hook of the room, without holding a lock, so they may join or
+// leave the room. Stop is also called when the broadcaster is canceled or the hooks are replaced.
+// Passing nil for both functions removes the hooks of the room.
+func (b *broadcaster) OnDemand(room string, start func(), stop func()) {
+	var d *demand
+	if start != nil || stop != nil {
+		d = newDemand(start, stop)
+	}
+
+	b.mux.Lock()
+	replaced := b.demands[room]
+	if d == nil {
+		delete(b.demands, room)
+	} else {
+		if b.demands == nil {
+			b.demands = make(map[string]*demand)
+		}
+		b.demands[room] = d
+	}
+	b.mux.Unlock()
+
+	if replaced != nil {
+		replaced.remove()
+	}
+
+	b.signalDemand(room)
+}
+
+func (n *namespace) OnDemand(room string, start func(), stop func()) {
+	n.broadcaster.OnDemand(n.room(room), start, stop)
+}
+
+// signalDemand calls the hooks of the room if it gained or lost its subscriptions.
+func (b *broadcaster) signalDemand(room string) {
+	b.mux.RLock()
+	d := b.demands[room]
+	b.mux.RUnlock()
+
+	if d == nil {
+		return
+	}
+
+	d.update(func() bool {
+		return !b.isClosed() && b.CountSubscribers(room) > 0
+	})
+}
+
+// stopDemands calls stop for all rooms whose producers are running.
+func (b *broadcaster) stopDemands() {
+	b.mux.RLock()
+	demands := make([]*demand, 0, len(b.demands))
+	for _, d := range b.demands {
+		demands = append(demands, d)
+	}
+	b.mux.RUnlock()
+
+	for _, d := range demands {
+		d.update(never)
+		d.wait()
+	}
+}
+
+// remove stops the producer of replaced hooks and keeps it from starting again.
+func (d *demand) remove() {
+	d.mux.Lock()
+	d.removed = true
+	d.mux.Unlock()
+
+	d.update(never)
+	d.wait()
+}
+
+// update calls start or stop until the producer runs exactly while wanted reports true.
+// Only one go routine calls the hooks at a time and it doesn't hold the lock while doing so.
+// Other go routines leave the update to it, it checks wanted again after every call and so
+// sees the changes they signal, including those made by the hooks themselves.
+func (d *demand) update(wanted func() bool) {
+	d.mux.Lock()
+	if d.running {
+		d.mux.Unlock()
+		return
+	}
+
+	d.running = true
+	for {
+		active := !d.removed && wanted()
+		if active == d.active {
+			break
+		}
+
+		d.active = active
+		hook := d.stop
+		if active {
+			hook = d.start
+		}
+
+		if hook != nil {
+			d.mux.Unlock()
+			hook()
+			d.mux.Lock()
+		}
+	}
+
+	d.running = false
+	d.idle.Broadcast()
+	d.mux.Unlock()
+}
+
+// wait blocks until no go routine calls the hooks.
+func (d *demand) wait() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	for d.running {
+		d.idle.Wait()
+	}
+}
+
+func never() bool {
+	return false
+}
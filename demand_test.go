@@ -0,0 +1,218 @@
+package broadcast
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// demandRecorder records the calls of the hooks set with OnDemand.
+type demandRecorder struct {
+	mux   *sync.Mutex
+	calls []string
+}
+
+func newDemandRecorder() *demandRecorder {
+	return &demandRecorder{mux: &sync.Mutex{}}
+}
+
+func (r *demandRecorder) hooks(name string) (func(), func()) {
+	record := func(call string) func() {
+		return func() {
+			r.mux.Lock()
+			defer r.mux.Unlock()
+			r.calls = append(r.calls, name+":"+call)
+		}
+	}
+
+	return record("start"), record("stop")
+}
+
+func (r *demandRecorder) String() string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return strings.Join(r.calls, " ")
+}
+
+func TestBroadcaster_OnDemand(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	recorder := newDemandRecorder()
+	start, stop := recorder.hooks("feed")
+	b.OnDemand("feed", start, stop)
+	s1 := b.Subscribe(func(_ interface{}) {})
+	s2 := b.Subscribe(func(_ interface{}) {})
+
+	b.JoinRoom(s1, "feed")
+	b.JoinRoom(s2, "feed")
+	b.LeaveRoom(s1, "feed")
+	b.Unsubscribe(s2)
+	b.JoinRoom(s1, "feed")
+
+	if calls := recorder.String(); calls != "feed:start feed:stop feed:start" {
+		t.Fatalf("OnDemand hooks were called %q; want %q", calls, "feed:start feed:stop feed:start")
+	}
+}
+
+func TestBroadcaster_OnDemand_RoomWithSubscriptions(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	recorder := newDemandRecorder()
+	start, stop := recorder.hooks("feed")
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "feed")
+
+	b.OnDemand("feed", start, stop)
+
+	if calls := recorder.String(); calls != "feed:start" {
+		t.Fatalf("OnDemand hooks were called %q; want %q", calls, "feed:start")
+	}
+}
+
+func TestBroadcaster_OnDemand_Replaced(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	recorder := newDemandRecorder()
+	start, stop := recorder.hooks("old")
+	b.OnDemand("feed", start, stop)
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "feed")
+
+	start, stop = recorder.hooks("new")
+	b.OnDemand("feed", start, stop)
+	b.OnDemand("feed", nil, nil)
+	b.LeaveRoom(s, "feed")
+	b.JoinRoom(s, "feed")
+
+	if calls, want := recorder.String(), "old:start old:stop new:start new:stop"; calls != want {
+		t.Fatalf("OnDemand hooks were called %q; want %q", calls, want)
+	}
+}
+
+func TestBroadcaster_OnDemand_StopsWhenCanceled(t *testing.T) {
+	b, cancel, _ := New()
+	recorder := newDemandRecorder()
+	start, stop := recorder.hooks("feed")
+	b.OnDemand("feed", start, stop)
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "feed")
+
+	cancel()
+	b.Unsubscribe(s)
+
+	if calls := recorder.String(); calls != "feed:start feed:stop" {
+		t.Fatalf("OnDemand hooks were called %q; want %q", calls, "feed:start feed:stop")
+	}
+}
+
+func TestBroadcaster_OnDemand_ConcurrentChanges(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	mux := &sync.Mutex{}
+	running, overlapped := 0, false
+	enter := func(delta int) func() {
+		return func() {
+			mux.Lock()
+			defer mux.Unlock()
+			running += delta
+			if running < 0 || running > 1 {
+				overlapped = true
+			}
+		}
+	}
+	b.OnDemand("feed", enter(1), enter(-1))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := b.Subscribe(func(_ interface{}) {})
+			for j := 0; j < 100; j++ {
+				b.JoinRoom(s, "feed")
+				b.LeaveRoom(s, "feed")
+			}
+		}()
+	}
+	wg.Wait()
+
+	mux.Lock()
+	defer mux.Unlock()
+	if overlapped || running != 0 {
+		t.Fatalf("OnDemand hooks left %d producers running, overlapped: %v", running, overlapped)
+	}
+}
+
+func TestNamespace_OnDemand(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	recorder := newDemandRecorder()
+	start, stop := recorder.hooks("feed")
+	ns := namespaceOf(t, b, "tenant")
+	ns.OnDemand("feed", start, stop)
+	s := ns.Subscribe(func(_ interface{}) {})
+
+	b.JoinRoom(b.Subscribe(func(_ interface{}) {}), "feed")
+	ns.JoinRoom(s, "feed")
+	ns.LeaveRoom(s, "feed")
+
+	if calls := recorder.String(); calls != "feed:start feed:stop" {
+		t.Fatalf("OnDemand hooks were called %q; want %q", calls, "feed:start feed:stop")
+	}
+}
+
+func TestBroadcaster_OnDemand_HooksChangingTheRoom(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	recorder := newDemandRecorder()
+	start, stop := recorder.hooks("feed")
+	bot := b.Subscribe(func(_ interface{}) {})
+	b.OnDemand("feed", func() {
+		start()
+		b.JoinRoom(bot, "feed")
+	}, func() {
+		stop()
+		b.LeaveRoom(bot, "feed")
+	})
+	s := b.Subscribe(func(_ interface{}) {})
+	done := make(chan struct{})
+
+	go func() {
+		b.JoinRoom(s, "feed")
+		b.LeaveRoom(s, "feed")
+		b.LeaveRoom(bot, "feed")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 3):
+		t.Fatal("hooks that join and leave the room deadlocked")
+	}
+
+	if calls := recorder.String(); calls != "feed:start feed:stop" {
+		t.Fatalf("OnDemand hooks were called %q; want %q", calls, "feed:start feed:stop")
+	}
+}
+
+func TestBroadcaster_OnDemand_StopJoiningTheRoom(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	recorder := newDemandRecorder()
+	start, stop := recorder.hooks("feed")
+	keeper := b.Subscribe(func(_ interface{}) {})
+	b.OnDemand("feed", start, func() {
+		stop()
+		b.JoinRoom(keeper, "feed")
+	})
+	s := b.Subscribe(func(_ interface{}) {})
+
+	b.JoinRoom(s, "feed")
+	b.LeaveRoom(s, "feed")
+
+	if calls := recorder.String(); calls != "feed:start feed:stop feed:start" {
+		t.Fatalf("OnDemand hooks were called %q; want %q", calls, "feed:start feed:stop feed:start")
+	}
+}
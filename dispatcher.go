@@ -11,6 +11,29 @@ type Dispatcher interface {
 	Received(callback func(data interface{}, toAll bool, room string, except ...string))
 }
 
+// MessageDispatcher can be implemented by a Dispatcher to send and receive whole messages.
+// When implemented, DispatchMessage and ReceivedMessage are used instead of Dispatch and Received,
+// so message fields that Dispatch has no parameters for, like ExceptSubscribers, reach other instances.
+// Without it, a message sent to several rooms is dispatched once per room and
+// room patterns and cascades are resolved against the rooms known to the sending instance.
+type MessageDispatcher interface {
+	Dispatcher
+	// DispatchMessage sends a message to an external service.
+	DispatchMessage(msg *Message)
+	// ReceivedMessage is called with the callback the Dispatcher needs to use
+	// when a message is received from an external service.
+	ReceivedMessage(callback func(msg *Message))
+}
+
+// FallibleDispatcher can be implemented by a MessageDispatcher whose sends can fail.
+// When implemented, TryDispatchMessage is used instead of DispatchMessage
+// and its errors are reported to the Metrics.
+type FallibleDispatcher interface {
+	MessageDispatcher
+	// TryDispatchMessage sends a message to an external service and returns an error if it failed.
+	TryDispatchMessage(msg *Message) error
+}
+
 type noopDispatcher struct{}
 
 func (d *noopDispatcher) Dispatch(data interface{}, toAll bool, room string, except ...string) {
@@ -18,3 +41,22 @@ func (d *noopDispatcher) Dispatch(data interface{}, toAll bool, room string, exc
 
 func (d *noopDispatcher) Received(callback func(data interface{}, toAll bool, room string, except ...string)) {
 }
+
+// PresenceEvent describes a subscription joining or leaving a room on a broadcaster instance.
+// An event without a room announces that the instance started, when Joined is set, or stopped.
+type PresenceEvent struct {
+	Instance     string
+	Room         string
+	Subscription string
+	Joined       bool
+}
+
+// PresenceDispatcher can be implemented by a Dispatcher to share room membership
+// between instances, see WithClusterPresence.
+type PresenceDispatcher interface {
+	// DispatchPresence sends a presence event to the other instances.
+	DispatchPresence(event PresenceEvent)
+	// ReceivedPresence is called with the callback the Dispatcher needs to use
+	// when a presence event is received from another instance.
+	ReceivedPresence(callback func(event PresenceEvent))
+}
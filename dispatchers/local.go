@@ -0,0 +1,114 @@
+// Package dispatchers contains Dispatcher implementations for the broadcast package.
+package dispatchers
+
+import (
+	"sync"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+// LocalBridge links broadcaster instances within the same process, so the behavior
+// of several instances can be tested without a broker. Every instance needs its own
+// Dispatcher created with Dispatcher. Messages and presence events dispatched by one
+// instance are received by all other instances on the dispatching go routine.
+type LocalBridge struct {
+	mux       *sync.RWMutex
+	endpoints []*LocalDispatcher
+}
+
+// NewLocalBridge creates a bridge without instances.
+func NewLocalBridge() *LocalBridge {
+	return &LocalBridge{mux: &sync.RWMutex{}}
+}
+
+// Dispatcher creates a Dispatcher that connects a broadcaster to the bridge.
+func (l *LocalBridge) Dispatcher() *LocalDispatcher {
+	d := &LocalDispatcher{bridge: l, mux: &sync.RWMutex{}}
+
+	l.mux.Lock()
+	l.endpoints = append(l.endpoints, d)
+	l.mux.Unlock()
+
+	return d
+}
+
+// others returns the dispatchers of the bridge except the given one.
+func (l *LocalBridge) others(d *LocalDispatcher) []*LocalDispatcher {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+
+	others := make([]*LocalDispatcher, 0, len(l.endpoints))
+	for _, e := range l.endpoints {
+		if e != d {
+			others = append(others, e)
+		}
+	}
+
+	return others
+}
+
+// LocalDispatcher is the Dispatcher of a single instance linked by a LocalBridge.
+// It implements broadcast.MessageDispatcher and broadcast.PresenceDispatcher.
+type LocalDispatcher struct {
+	bridge   *LocalBridge
+	mux      *sync.RWMutex
+	received func(msg *broadcast.Message)
+	presence func(event broadcast.PresenceEvent)
+}
+
+// Dispatch sends a message to a single room of the other instances.
+func (d *LocalDispatcher) Dispatch(data interface{}, toAll bool, room string, except ...string) {
+	msg := &broadcast.Message{Data: data, ToAll: toAll, Except: except}
+	if len(room) > 0 {
+		msg.Rooms = []string{room}
+	}
+
+	d.DispatchMessage(msg)
+}
+
+// Received is not used since LocalDispatcher implements ReceivedMessage.
+func (d *LocalDispatcher) Received(callback func(data interface{}, toAll bool, room string, except ...string)) {
+}
+
+// DispatchMessage sends a copy of the message to every other instance.
+func (d *LocalDispatcher) DispatchMessage(msg *broadcast.Message) {
+	for _, e := range d.bridge.others(d) {
+		e.mux.RLock()
+		received := e.received
+		e.mux.RUnlock()
+
+		if received != nil {
+			copied := *msg
+			received(&copied)
+		}
+	}
+}
+
+// ReceivedMessage sets the callback used to pass messages of other instances to the broadcaster.
+func (d *LocalDispatcher) ReceivedMessage(callback func(msg *broadcast.Message)) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.received = callback
+}
+
+// DispatchPresence sends a presence event to every other instance.
+func (d *LocalDispatcher) DispatchPresence(event broadcast.PresenceEvent) {
+	for _, e := range d.bridge.others(d) {
+		e.mux.RLock()
+		presence := e.presence
+		e.mux.RUnlock()
+
+		if presence != nil {
+			presence(event)
+		}
+	}
+}
+
+// ReceivedPresence sets the callback used to pass presence events of other instances to the broadcaster.
+func (d *LocalDispatcher) ReceivedPresence(callback func(event broadcast.PresenceEvent)) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.presence = callback
+}
@@ -0,0 +1,42 @@
+package dispatchers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+	"github.com/go-broadcast/broadcast/broadcasttest"
+)
+
+func TestLocalBridge_ShouldForwardMessagesToOtherInstances(t *testing.T) {
+	bridge := NewLocalBridge()
+	a := broadcasttest.New(t, broadcast.WithDispatcher(bridge.Dispatcher()))
+	b := broadcasttest.New(t, broadcast.WithDispatcher(bridge.Dispatcher()))
+	local := broadcasttest.Subscribe(a, "test-room")
+	remote := broadcasttest.Subscribe(b, "test-room")
+	other := broadcasttest.Subscribe(b, "other-room")
+
+	a.ToRoom("data", "test-room")
+
+	if data := broadcasttest.ExpectDelivery(t, remote, time.Second); data != "data" {
+		t.Fatalf("remote subscription received %v; want data", data)
+	}
+	if data := broadcasttest.ExpectDelivery(t, local, time.Second); data != "data" {
+		t.Fatalf("local subscription received %v; want data", data)
+	}
+	broadcasttest.ExpectNoDelivery(t, local, time.Millisecond*10)
+	broadcasttest.ExpectNoDelivery(t, other, time.Millisecond*10)
+}
+
+func TestLocalBridge_ShouldForwardPresence(t *testing.T) {
+	bridge := NewLocalBridge()
+	a := broadcasttest.New(t, broadcast.WithDispatcher(bridge.Dispatcher()), broadcast.WithClusterPresence(), broadcast.WithInstanceID("a"))
+	b := broadcasttest.New(t, broadcast.WithDispatcher(bridge.Dispatcher()), broadcast.WithClusterPresence(), broadcast.WithInstanceID("b"))
+	remote := broadcasttest.Subscribe(b, "test-room")
+
+	members := a.ClusterSubscribers("test-room")
+
+	if len(members) != 1 || members[0].ID != remote.Subscription.ID() || members[0].Instance != "b" {
+		t.Fatalf("ClusterSubscribers returned %v; want the subscription of instance b", members)
+	}
+}
@@ -0,0 +1,318 @@
+package dispatchers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+const (
+	defaultStreamReadCount int64 = 100
+	defaultStreamBlock           = time.Second * 5
+)
+
+// StreamEntry is an entry of a Redis stream holding an encoded message.
+type StreamEntry struct {
+	ID      string
+	Payload []byte
+}
+
+// StreamClient runs the Redis stream commands used by RedisStreamDispatcher. It is implemented
+// by a thin adapter around the Redis client of the application, so this package doesn't depend
+// on a Redis client library. The payload is stored in a single field of the entry.
+type StreamClient interface {
+	// CreateGroup creates a consumer group reading the stream from the start ID, creating
+	// the stream if it doesn't exist (XGROUP CREATE stream group start MKSTREAM).
+	// It returns nil if the group already exists.
+	CreateGroup(ctx context.Context, stream, group, start string) error
+	// Add appends an entry to the stream and trims the stream to about maxLen entries
+	// if maxLen is positive (XADD stream MAXLEN ~ maxLen * payload ...).
+	Add(ctx context.Context, stream string, maxLen int64, payload []byte) error
+	// ReadGroup reads up to count entries of the stream for a consumer of the group
+	// (XREADGROUP GROUP group consumer COUNT count BLOCK block STREAMS stream id). The ID ">"
+	// waits up to block for new entries, the ID "0" returns the entries that were read by
+	// the consumer but not acknowledged. It returns no entries if none arrived in time.
+	ReadGroup(ctx context.Context, stream, group, consumer, id string, count int64, block time.Duration) ([]StreamEntry, error)
+	// Ack acknowledges entries read by the group (XACK stream group id...).
+	Ack(ctx context.Context, stream, group string, ids ...string) error
+}
+
+// StreamOption is used to change the settings of a RedisStreamDispatcher.
+type StreamOption func(d *RedisStreamDispatcher) error
+
+// WithStreamMaxLen trims the stream to about maxLen entries on every dispatch, which limits
+// how long an instance can be down without missing messages. By default the stream is not trimmed.
+func WithStreamMaxLen(maxLen int64) StreamOption {
+	return func(d *RedisStreamDispatcher) error {
+		if maxLen <= 0 {
+			return errors.New("stream max length must be positive")
+		}
+
+		d.maxLen = maxLen
+		return nil
+	}
+}
+
+// WithStreamRead sets how many entries are read at once and how long a read waits for new entries.
+// Default is 100 entries and 5 seconds.
+func WithStreamRead(count int64, block time.Duration) StreamOption {
+	return func(d *RedisStreamDispatcher) error {
+		if count <= 0 {
+			return errors.New("stream read count must be positive")
+		}
+
+		if block <= 0 {
+			return errors.New("stream read block must be positive")
+		}
+
+		d.count = count
+		d.block = block
+		return nil
+	}
+}
+
+// WithStreamErrorHandler sets a function that is called when a message can't be added
+// to the stream, read from it or decoded. By default errors are ignored.
+func WithStreamErrorHandler(handler func(err error)) StreamOption {
+	return func(d *RedisStreamDispatcher) error {
+		if handler == nil {
+			return errors.New("stream error handler cannot be nil")
+		}
+
+		d.errorHandler = handler
+		return nil
+	}
+}
+
+// WithStreamClock sets the Clock used to wait before a failed read is retried,
+// e.g. the one passed to broadcast.WithClock. Default is the wall clock.
+func WithStreamClock(clock broadcast.Clock) StreamOption {
+	return func(d *RedisStreamDispatcher) error {
+		if clock == nil {
+			return errors.New("stream clock cannot be nil")
+		}
+
+		d.clock = clock
+		return nil
+	}
+}
+
+// RedisStreamDispatcher dispatches messages through a Redis stream. Every instance reads the
+// stream with its own consumer group, so unlike with Pub/Sub an instance that was down catches up
+// on the messages added while it was gone when it reads again, as long as the stream still holds them.
+// It implements broadcast.FallibleDispatcher and keeps all fields of the messages.
+type RedisStreamDispatcher struct {
+	client       StreamClient
+	stream       string
+	group        string
+	maxLen       int64
+	count        int64
+	block        time.Duration
+	errorHandler func(err error)
+	clock        broadcast.Clock
+	mux          *sync.RWMutex
+	received     func(msg *broadcast.Message)
+}
+
+// NewRedisStreamDispatcher creates a dispatcher for the stream. The group identifies the instance
+// and has to be unique per instance and stable across restarts, e.g. the host name. Using the
+// same value for broadcast.WithInstanceID keeps a restarted instance from delivering its own
+// unacknowledged messages again.
+func NewRedisStreamDispatcher(client StreamClient, stream string, group string, options ...StreamOption) (*RedisStreamDispatcher, error) {
+	if client == nil {
+		return nil, errors.New("stream client cannot be nil")
+	}
+
+	if len(stream) == 0 || len(group) == 0 {
+		return nil, errors.New("stream and group cannot be empty")
+	}
+
+	d := &RedisStreamDispatcher{
+		client: client,
+		stream: stream,
+		group:  group,
+		count:  defaultStreamReadCount,
+		block:  defaultStreamBlock,
+		mux:    &sync.RWMutex{},
+	}
+
+	for _, option := range options {
+		err := option(d)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// Run reads the stream and passes the messages to the broadcaster until the context is done,
+// then it returns the context error. It is started after the broadcaster is created. A new group
+// starts with the messages added from then on. Messages that were read but not acknowledged
+// before the last Run stopped are passed again first. Read errors are retried after waiting
+// for the read block duration.
+func (d *RedisStreamDispatcher) Run(ctx context.Context) error {
+	if err := d.client.CreateGroup(ctx, d.stream, d.group, "$"); err != nil {
+		return err
+	}
+
+	id := "0"
+	for {
+		entries, err := d.client.ReadGroup(ctx, d.stream, d.group, d.group, id, d.count, d.block)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			d.fail(err)
+			if !d.wait(ctx) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		if len(entries) == 0 {
+			id = ">"
+			continue
+		}
+
+		ids := make([]string, len(entries))
+		for i, e := range entries {
+			d.receive(e)
+			ids[i] = e.ID
+		}
+
+		if err := d.client.Ack(ctx, d.stream, d.group, ids...); err != nil {
+			d.fail(err)
+		}
+	}
+}
+
+// wait waits for the read block duration and reports whether the context is still active.
+func (d *RedisStreamDispatcher) wait(ctx context.Context) bool {
+	var c <-chan time.Time
+	if d.clock != nil {
+		timer := d.clock.NewTimer(d.block)
+		defer timer.Stop()
+		c = timer.C()
+	} else {
+		timer := time.NewTimer(d.block)
+		defer timer.Stop()
+		c = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-c:
+		return true
+	}
+}
+
+// Dispatch sends a message to a single room of all instances.
+func (d *RedisStreamDispatcher) Dispatch(data interface{}, toAll bool, room string, except ...string) {
+	msg := &broadcast.Message{Data: data, ToAll: toAll, Except: except}
+	if len(room) > 0 {
+		msg.Rooms = []string{room}
+	}
+
+	d.DispatchMessage(msg)
+}
+
+// Received is not used since RedisStreamDispatcher implements ReceivedMessage.
+func (d *RedisStreamDispatcher) Received(callback func(data interface{}, toAll bool, room string, except ...string)) {
+}
+
+// DispatchMessage adds the message to the stream and reports errors to the error handler.
+func (d *RedisStreamDispatcher) DispatchMessage(msg *broadcast.Message) {
+	if err := d.TryDispatchMessage(msg); err != nil {
+		d.fail(err)
+	}
+}
+
+// TryDispatchMessage adds the message to the stream.
+func (d *RedisStreamDispatcher) TryDispatchMessage(msg *broadcast.Message) error {
+	payload, err := encodeStreamMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	return d.client.Add(context.Background(), d.stream, d.maxLen, payload)
+}
+
+// ReceivedMessage sets the callback used to pass messages read from the stream to the broadcaster.
+func (d *RedisStreamDispatcher) ReceivedMessage(callback func(msg *broadcast.Message)) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.received = callback
+}
+
+func (d *RedisStreamDispatcher) receive(e StreamEntry) {
+	msg, err := decodeStreamMessage(e.Payload)
+	if err != nil {
+		d.fail(err)
+		return
+	}
+
+	d.mux.RLock()
+	received := d.received
+	d.mux.RUnlock()
+
+	if received != nil {
+		received(msg)
+	}
+}
+
+func (d *RedisStreamDispatcher) fail(err error) {
+	if d.errorHandler != nil {
+		d.errorHandler(err)
+	}
+}
+
+// streamMessage is the JSON encoding of a message in a stream entry.
+// Byte slice payloads are kept apart from JSON payloads, so they are received as they were sent.
+type streamMessage struct {
+	*broadcast.Message
+	Data  json.RawMessage `json:",omitempty"`
+	Bytes []byte          `json:",omitempty"`
+}
+
+func encodeStreamMessage(msg *broadcast.Message) ([]byte, error) {
+	encoded := streamMessage{Message: msg}
+
+	if raw, ok := msg.Data.([]byte); ok {
+		encoded.Bytes = raw
+	} else {
+		data, err := json.Marshal(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		encoded.Data = data
+	}
+
+	return json.Marshal(encoded)
+}
+
+func decodeStreamMessage(payload []byte) (*broadcast.Message, error) {
+	decoded := streamMessage{Message: &broadcast.Message{}}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, err
+	}
+
+	msg := decoded.Message
+	if decoded.Bytes != nil {
+		msg.Data = decoded.Bytes
+	} else if len(decoded.Data) > 0 {
+		if err := json.Unmarshal(decoded.Data, &msg.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,231 @@
+package dispatchers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+	"github.com/go-broadcast/broadcast/broadcasttest"
+)
+
+// memoryStream implements StreamClient for a single stream in memory.
+type memoryStream struct {
+	mux     *sync.Mutex
+	entries []StreamEntry
+	groups  map[string]*memoryGroup
+}
+
+type memoryGroup struct {
+	next    int
+	pending []StreamEntry
+}
+
+func newMemoryStream() *memoryStream {
+	return &memoryStream{mux: &sync.Mutex{}, groups: make(map[string]*memoryGroup)}
+}
+
+func (m *memoryStream) CreateGroup(_ context.Context, _, group, _ string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, ok := m.groups[group]; !ok {
+		m.groups[group] = &memoryGroup{next: len(m.entries)}
+	}
+	return nil
+}
+
+func (m *memoryStream) Add(_ context.Context, _ string, _ int64, payload []byte) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.entries = append(m.entries, StreamEntry{ID: fmt.Sprintf("%d-0", len(m.entries)+1), Payload: payload})
+	return nil
+}
+
+func (m *memoryStream) ReadGroup(ctx context.Context, _, group, _, id string, count int64, block time.Duration) ([]StreamEntry, error) {
+	deadline := time.Now().Add(block)
+	for {
+		m.mux.Lock()
+		g := m.groups[group]
+		if id == "0" {
+			pending := append([]StreamEntry{}, g.pending...)
+			m.mux.Unlock()
+			return pending, nil
+		}
+
+		if g.next < len(m.entries) {
+			end := g.next + int(count)
+			if end > len(m.entries) {
+				end = len(m.entries)
+			}
+			entries := append([]StreamEntry{}, m.entries[g.next:end]...)
+			g.pending = append(g.pending, entries...)
+			g.next = end
+			m.mux.Unlock()
+			return entries, nil
+		}
+		m.mux.Unlock()
+
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			return nil, ctx.Err()
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (m *memoryStream) Ack(_ context.Context, _, group string, ids ...string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	g := m.groups[group]
+	for _, id := range ids {
+		for i, e := range g.pending {
+			if e.ID == id {
+				g.pending = append(g.pending[:i], g.pending[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func newStreamBroadcaster(t *testing.T, stream *memoryStream, group string, received chan interface{}) (*RedisStreamDispatcher, broadcast.Broadcaster) {
+	d, err := NewRedisStreamDispatcher(stream, "broadcasts", group, WithStreamRead(10, time.Millisecond*10))
+	if err != nil {
+		t.Fatalf("NewRedisStreamDispatcher() returned %v", err)
+	}
+
+	b, cancel, err := broadcast.New(broadcast.WithDispatcher(d), broadcast.WithInstanceID(group), broadcast.WithSynchronousDelivery())
+	if err != nil {
+		t.Fatalf("New() returned %v", err)
+	}
+	t.Cleanup(cancel)
+
+	if received != nil {
+		b.Subscribe(func(data interface{}) { received <- data })
+	}
+
+	return d, b
+}
+
+func run(d *RedisStreamDispatcher) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func expectData(t *testing.T, received chan interface{}, want interface{}) {
+	select {
+	case data := <-received:
+		if fmt.Sprint(data) != fmt.Sprint(want) {
+			t.Fatalf("received %v; want %v", data, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("did not receive %v", want)
+	}
+}
+
+func TestRedisStreamDispatcher_ShouldDeliverToOtherInstances(t *testing.T) {
+	stream := newMemoryStream()
+	received := make(chan interface{}, 10)
+	_, sender := newStreamBroadcaster(t, stream, "a", nil)
+	d, _ := newStreamBroadcaster(t, stream, "b", received)
+	stop := run(d)
+	defer stop()
+	time.Sleep(time.Millisecond * 20)
+
+	sender.ToAll([]byte("raw"))
+	sender.ToAll(map[string]interface{}{"count": 1.0})
+
+	expectData(t, received, []byte("raw"))
+	expectData(t, received, map[string]interface{}{"count": 1.0})
+}
+
+func TestRedisStreamDispatcher_ShouldCatchUpAfterDowntime(t *testing.T) {
+	stream := newMemoryStream()
+	received := make(chan interface{}, 10)
+	_, sender := newStreamBroadcaster(t, stream, "a", nil)
+	d, _ := newStreamBroadcaster(t, stream, "b", received)
+	stop := run(d)
+	time.Sleep(time.Millisecond * 20)
+	stop()
+
+	sender.ToAll("missed")
+
+	stop = run(d)
+	defer stop()
+	expectData(t, received, "missed")
+}
+
+func TestRedisStreamDispatcher_ShouldRedeliverUnacknowledged(t *testing.T) {
+	stream := newMemoryStream()
+	received := make(chan interface{}, 10)
+	_, sender := newStreamBroadcaster(t, stream, "a", nil)
+	d, _ := newStreamBroadcaster(t, stream, "b", received)
+	stream.CreateGroup(context.Background(), "broadcasts", "b", "$")
+
+	sender.ToAll("pending")
+	stream.ReadGroup(context.Background(), "broadcasts", "b", "b", ">", 10, 0)
+
+	stop := run(d)
+	defer stop()
+	expectData(t, received, "pending")
+}
+
+func TestNewRedisStreamDispatcher_Invalid(t *testing.T) {
+	if _, err := NewRedisStreamDispatcher(nil, "stream", "group"); err == nil {
+		t.Fatalf("NewRedisStreamDispatcher() without client should fail")
+	}
+
+	if _, err := NewRedisStreamDispatcher(newMemoryStream(), "stream", ""); err == nil {
+		t.Fatalf("NewRedisStreamDispatcher() without group should fail")
+	}
+
+	if _, err := NewRedisStreamDispatcher(newMemoryStream(), "stream", "group", WithStreamMaxLen(0)); err == nil {
+		t.Fatalf("NewRedisStreamDispatcher() with zero max length should fail")
+	}
+}
+
+// failingStream is a stream whose reads fail.
+type failingStream struct {
+	*memoryStream
+	reads chan struct{}
+}
+
+func (s *failingStream) ReadGroup(_ context.Context, _, _, _, _ string, _ int64, _ time.Duration) ([]StreamEntry, error) {
+	s.reads <- struct{}{}
+	return nil, errors.New("read failed")
+}
+
+func TestRedisStreamDispatcher_WithStreamClock(t *testing.T) {
+	stream := &failingStream{memoryStream: newMemoryStream(), reads: make(chan struct{}, 1)}
+	clock := broadcasttest.NewClock(time.Now())
+	d, _ := NewRedisStreamDispatcher(stream, "broadcasts", "a", WithStreamRead(10, time.Hour), WithStreamClock(clock))
+	defer run(d)()
+	<-stream.reads
+
+	deadline := time.After(time.Second * 3)
+	for {
+		// The wait starts after the failed read, so advance until it is retried.
+		clock.Advance(time.Hour)
+		select {
+		case <-stream.reads:
+			return
+		case <-deadline:
+			t.Fatal("failed read was not retried after the clock advanced by the read block")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrDraining is returned when a message is sent after Drain was called.
+var ErrDraining = errors.New("broadcaster is draining")
+
+// ErrBroadcasterClosed is returned by operations on a broadcaster after it was
+// canceled or drained. Subscriptions created after that are closed and receive nothing.
+var ErrBroadcasterClosed = errors.New("broadcaster is closed")
+
+// Drain stops accepting messages, from senders and from the Dispatcher, and waits until
+// all messages that were accepted are delivered to local subscriptions, including pending
+// redeliveries and messages delayed by rate limits, and passed to the Dispatcher.
+// It then releases all resources like the CancelFunc returned by New. If the context is
+// done first, resources are released anyway and the context error is returned.
+// Drain must not be called from a subscription callback, it would wait for itself.
+// It returns ErrBroadcasterClosed if the broadcaster was already canceled.
+func (b *broadcaster) Drain(ctx context.Context) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	atomic.StoreInt32(&b.draining, 1)
+	defer b.cancel()
+
+	// Batched messages don't wait for their latency once nothing else is accepted.
+	if b.batcher != nil {
+		b.flushBatch()
+	}
+
+	for atomic.LoadInt64(&b.counters.pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.settled:
+		}
+	}
+
+	return nil
+}
+
+// Drain drains the whole broadcaster, not only the namespace.
+func (n *namespace) Drain(ctx context.Context) error {
+	return n.broadcaster.Drain(ctx)
+}
+
+// accept counts a send in progress unless the broadcaster is draining.
+// Every accepted send must be released.
+func (b *broadcaster) accept() error {
+	// Counting before checking the flag makes sure Drain either sees the
+	// send in progress or the send sees the flag.
+	atomic.AddInt64(&b.counters.pending, 1)
+
+	if b.isClosed() {
+		b.release()
+		return ErrBroadcasterClosed
+	}
+
+	if atomic.LoadInt32(&b.draining) == 1 {
+		b.release()
+		return ErrDraining
+	}
+
+	return nil
+}
+
+func (b *broadcaster) isClosed() bool {
+	return atomic.LoadInt32(&b.closed) == 1
+}
+
+func (b *broadcaster) release() {
+	b.addPending(-1)
+}
+
+// addPending changes the number of sends and deliveries in progress
+// and wakes up Drain once none is left.
+func (b *broadcaster) addPending(delta int64) {
+	if atomic.AddInt64(&b.counters.pending, delta) != 0 {
+		return
+	}
+
+	select {
+	case b.settled <- struct{}{}:
+	default:
+	}
+}
+
+// pendingTracker returns a tracker that counts deliveries which are not part of a send,
+// like retained and replayed messages, as in progress, so Drain waits for them.
+func (b *broadcaster) pendingTracker() *tracker {
+	t := newTracker()
+	t.pending = b.addPending
+	return t
+}
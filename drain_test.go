@@ -0,0 +1,158 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_Drain_ShouldWaitForDeliveries(t *testing.T) {
+	b, _, _ := New()
+	var delivered int32
+	b.Subscribe(func(_ interface{}) {
+		time.Sleep(time.Millisecond * 50)
+		atomic.AddInt32(&delivered, 1)
+	})
+	b.ToAll("data")
+
+	err := b.Drain(context.Background())
+
+	if err != nil {
+		t.Fatalf("Drain() returned %v; want nil", err)
+	}
+
+	if atomic.LoadInt32(&delivered) != 1 {
+		t.Fatalf("Drain() returned before the message was delivered")
+	}
+
+	waitOrTimeout(b.Done())
+}
+
+func TestBroadcaster_Drain_ShouldWaitForRedeliveries(t *testing.T) {
+	b, _, _ := New(WithRedelivery(3, time.Millisecond*20))
+	var attempts int32
+	b.SubscribeAck(func(_ interface{}) error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	b.ToAll("data")
+
+	b.Drain(context.Background())
+
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Fatalf("Drain() returned after %d attempts; want 3", n)
+	}
+}
+
+func TestBroadcaster_Drain_ShouldRejectMessages(t *testing.T) {
+	b, _, _ := New()
+	release := make(chan struct{})
+	b.Subscribe(func(_ interface{}) {
+		<-release
+	})
+	b.ToAll("data")
+	drained := make(chan struct{})
+	go func() {
+		b.Drain(context.Background())
+		close(drained)
+	}()
+
+	err := b.ToRoom("data", "room")
+	for err == nil {
+		time.Sleep(time.Millisecond)
+		err = b.ToRoom("data", "room")
+	}
+	close(release)
+	<-drained
+
+	if err != ErrDraining {
+		t.Fatalf("ToRoom() while draining returned %v; want ErrDraining", err)
+	}
+
+	if err := b.ToRoom("data", "room"); err != ErrBroadcasterClosed {
+		t.Fatalf("ToRoom() after Drain() returned %v; want ErrBroadcasterClosed", err)
+	}
+}
+
+func TestBroadcaster_Drain_WithExpiredContext(t *testing.T) {
+	b, _, _ := New()
+	release := make(chan struct{})
+	defer close(release)
+	b.Subscribe(func(_ interface{}) {
+		<-release
+	})
+	b.ToAll("data")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	err := b.Drain(ctx)
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Drain() returned %v; want context.DeadlineExceeded", err)
+	}
+}
+
+func TestBroadcaster_Drain_WithClock(t *testing.T) {
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	b, _, _ := New(WithClock(clock))
+	release := make(chan struct{})
+	b.Subscribe(func(_ interface{}) {
+		<-release
+	})
+	b.ToAll("data")
+	drained := make(chan error)
+	go func() {
+		drained <- b.Drain(context.Background())
+	}()
+
+	close(release)
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatalf("Drain() returned %v; want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Drain() should return once the message is delivered without waiting for the clock")
+	}
+}
+
+func TestBroadcaster_Drain_ShouldWaitForRetainedMessages(t *testing.T) {
+	b, _, _ := New(WithRetainLast("state"))
+	b.ToRoom("data", "state")
+	var delivered int32
+	s := b.Subscribe(func(_ interface{}) {
+		time.Sleep(time.Millisecond * 50)
+		atomic.AddInt32(&delivered, 1)
+	})
+	b.JoinRoom(s, "state")
+
+	b.Drain(context.Background())
+
+	if atomic.LoadInt32(&delivered) != 1 {
+		t.Fatalf("Drain() returned before the retained message was delivered")
+	}
+}
+
+func TestBroadcaster_Drain_ShouldWaitForReplays(t *testing.T) {
+	b, _, _ := New(WithHistory(10, 0))
+	b.ToRoom("first", "chat")
+	b.ToRoom("second", "chat")
+	var delivered int32
+	s := b.Subscribe(func(_ interface{}) {
+		time.Sleep(time.Millisecond * 50)
+		atomic.AddInt32(&delivered, 1)
+	})
+	b.Replay(s, "chat", time.Time{})
+
+	b.Drain(context.Background())
+
+	if n := atomic.LoadInt32(&delivered); n != 2 {
+		t.Fatalf("Drain() returned after %d replayed messages were delivered; want 2", n)
+	}
+}
@@ -0,0 +1,69 @@
+package broadcast
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// Encryptor encrypts the payloads of messages passed to the Dispatcher
+// and decrypts the payloads of messages received through it.
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// WithEncryption encrypts the payloads of messages before they are passed to the Dispatcher
+// and decrypts them when they are received, so they cross a shared broker encrypted. All
+// instances need the same key. Dispatchers receive the payload as a byte slice. Byte slices
+// are received as they were sent, other payloads are encoded as JSON and received as the
+// values encoding/json decodes into an interface{}. Local subscriptions receive the original payload.
+func WithEncryption(encryptor Encryptor) Option {
+	return func(b *broadcaster) error {
+		if encryptor == nil {
+			return errors.New("encryptor cannot be nil")
+		}
+
+		b.encryptor = encryptor
+		return nil
+	}
+}
+
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM returns an Encryptor using AES-GCM with a random nonce per payload.
+// The key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewAESGCM(key []byte) (Encryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesGCM{aead: aead}, nil
+}
+
+func (e *aesGCM) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (e *aesGCM) Decrypt(ciphertext []byte) ([]byte, error) {
+	size := e.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	return e.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+}
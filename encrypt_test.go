@@ -0,0 +1,99 @@
+package broadcast
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewAESGCM_WithInvalidKey(t *testing.T) {
+	if _, err := NewAESGCM([]byte("short")); err == nil {
+		t.Fatalf("NewAESGCM() with a 5 byte key; expected an error")
+	}
+}
+
+func TestAESGCM_Decrypt(t *testing.T) {
+	e, _ := NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	plaintext := []byte("secret")
+
+	ciphertext, _ := e.Encrypt(plaintext)
+	got, err := e.Decrypt(ciphertext)
+
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt() returned %q, %v; want %q", got, err, plaintext)
+	}
+
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatalf("Encrypt() returned the plaintext")
+	}
+}
+
+func TestWithEncryption_WithNilEncryptor(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithEncryption(nil)(b); err == nil {
+		t.Fatalf("WithEncryption(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_WithEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	e1, _ := NewAESGCM(key)
+	e2, _ := NewAESGCM(key)
+	sender := &mockMessageDispatcher{dispatched: make(chan *Message, 2)}
+	receiver := &mockMessageDispatcher{}
+	b1, cancel1, _ := New(WithEncryption(e1), WithDispatcher(sender), WithSynchronousDelivery())
+	defer cancel1()
+	b2, cancel2, _ := New(WithEncryption(e2), WithDispatcher(receiver), WithSynchronousDelivery())
+	defer cancel2()
+	received := []interface{}{}
+	b2.Subscribe(func(data interface{}) {
+		received = append(received, data)
+	})
+
+	b1.ToAll(map[string]string{"text": "secret"})
+	b1.ToAll([]byte("raw"))
+	for i := 0; i < 2; i++ {
+		msg := <-sender.dispatched
+		if bytes.Contains(msg.Data.([]byte), []byte("secret")) {
+			t.Fatalf("Dispatched payload is not encrypted")
+		}
+		receiver.received(msg)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Receiving broadcaster delivered %d messages; want 2", len(received))
+	}
+
+	if m, ok := received[0].(map[string]interface{}); !ok || m["text"] != "secret" {
+		t.Fatalf("Receiving broadcaster delivered %v; want the decrypted map", received[0])
+	}
+
+	if raw, ok := received[1].([]byte); !ok || string(raw) != "raw" {
+		t.Fatalf("Receiving broadcaster delivered %v; want the raw bytes", received[1])
+	}
+}
+
+func TestBroadcaster_WithEncryption_WithWrongKey(t *testing.T) {
+	e1, _ := NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	e2, _ := NewAESGCM(bytes.Repeat([]byte{2}, 32))
+	sender := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	receiver := &mockMessageDispatcher{}
+	b1, cancel1, _ := New(WithEncryption(e1), WithDispatcher(sender), WithSynchronousDelivery())
+	defer cancel1()
+	var payloadErr error
+	b2, cancel2, _ := New(WithEncryption(e2), WithDispatcher(receiver), WithSynchronousDelivery(), WithPayloadErrorHandler(func(err error) {
+		payloadErr = err
+	}))
+	defer cancel2()
+	called := false
+	b2.Subscribe(func(_ interface{}) {
+		called = true
+	})
+
+	b1.ToAll("data")
+	receiver.received(<-sender.dispatched)
+
+	if payloadErr == nil || called {
+		t.Fatalf("Message with a payload encrypted with another key was delivered")
+	}
+}
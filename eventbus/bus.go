@@ -0,0 +1,226 @@
+// Package eventbus is a typed layer on top of a Broadcaster. Event types are registered
+// under a name, every name is mapped to a room, and events are encoded when they are
+// emitted and decoded into their Go type before they are passed to the handlers:
+//
+//	bus, err := eventbus.New(b)
+//	err = bus.Register("user.created", UserCreated{})
+//	sub, err := bus.On(func(e UserCreated) { ... })
+//	err = bus.Emit(UserCreated{ID: "42"})
+//
+// Handlers are plain functions taking the event type, since the module supports Go
+// versions without type parameters. Their signature is checked when they are added.
+package eventbus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+const defaultRoomPrefix = "event:"
+
+// Codec encodes events to the payloads sent through the broadcaster and decodes them.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Option is used to change the settings of a Bus.
+type Option func(bus *Bus) error
+
+// WithCodec sets the Codec of the events. All instances need the same codec. Default is JSON.
+func WithCodec(codec Codec) Option {
+	return func(bus *Bus) error {
+		if codec == nil {
+			return errors.New("codec cannot be nil")
+		}
+
+		bus.codec = codec
+		return nil
+	}
+}
+
+// WithRoomPrefix sets the prefix of the rooms events are sent to, the event name
+// is appended to it. Default is "event:".
+func WithRoomPrefix(prefix string) Option {
+	return func(bus *Bus) error {
+		bus.prefix = prefix
+		return nil
+	}
+}
+
+// WithErrorHandler sets a function that is called when a received event can't be decoded.
+// Such events are not passed to the handler. By default errors are ignored.
+func WithErrorHandler(handler func(err error)) Option {
+	return func(bus *Bus) error {
+		if handler == nil {
+			return errors.New("error handler cannot be nil")
+		}
+
+		bus.errorHandler = handler
+		return nil
+	}
+}
+
+// Bus sends and receives registered event types through a Broadcaster.
+type Bus struct {
+	broadcaster  broadcast.Broadcaster
+	codec        Codec
+	prefix       string
+	errorHandler func(err error)
+	mux          *sync.RWMutex
+	names        map[reflect.Type]string
+	types        map[string]reflect.Type
+}
+
+// New creates a Bus on top of the given Broadcaster.
+func New(b broadcast.Broadcaster, options ...Option) (*Bus, error) {
+	if b == nil {
+		return nil, errors.New("broadcaster cannot be nil")
+	}
+
+	bus := &Bus{
+		broadcaster: b,
+		codec:       jsonCodec{},
+		prefix:      defaultRoomPrefix,
+		mux:         &sync.RWMutex{},
+		names:       make(map[reflect.Type]string),
+		types:       make(map[string]reflect.Type),
+	}
+
+	for _, option := range options {
+		err := option(bus)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
+
+// Register registers the type of the event under the name. Every instance registers
+// the same names for its event types. A name and a type can only be registered once.
+func (bus *Bus) Register(name string, event interface{}) error {
+	if len(name) == 0 {
+		return errors.New("event name cannot be empty")
+	}
+
+	if event == nil {
+		return errors.New("event cannot be nil")
+	}
+
+	t := reflect.TypeOf(event)
+
+	bus.mux.Lock()
+	defer bus.mux.Unlock()
+
+	if _, ok := bus.types[name]; ok {
+		return fmt.Errorf("event name %q is already registered", name)
+	}
+
+	if registered, ok := bus.names[t]; ok {
+		return fmt.Errorf("event type %v is already registered as %q", t, registered)
+	}
+
+	bus.names[t] = name
+	bus.types[name] = t
+	return nil
+}
+
+// On subscribes a handler to the events of a registered type. The handler is a function
+// with a single parameter of the event type, e.g. func(e UserCreated). The returned
+// subscription is closed with the Unsubscribe method of the Broadcaster.
+func (bus *Bus) On(handler interface{}) (*broadcast.Subscription, error) {
+	h := reflect.ValueOf(handler)
+	if h.Kind() != reflect.Func || h.Type().NumIn() != 1 || h.Type().NumOut() != 0 {
+		return nil, errors.New("handler must be a function with a single event parameter")
+	}
+
+	t := h.Type().In(0)
+	name, err := bus.name(t)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := bus.broadcaster.Subscribe(func(data interface{}) {
+		payload, ok := data.([]byte)
+		if !ok {
+			bus.fail(fmt.Errorf("event %q has a payload of type %T", name, data))
+			return
+		}
+
+		event := reflect.New(t)
+		if err := bus.codec.Unmarshal(payload, event.Interface()); err != nil {
+			bus.fail(err)
+			return
+		}
+
+		h.Call([]reflect.Value{event.Elem()})
+	})
+
+	// The subscription only receives events, not the messages sent to all subscriptions.
+	bus.broadcaster.LeaveRoom(sub, sub.Rooms()...)
+	if err := bus.broadcaster.JoinRoom(sub, bus.room(name)); err != nil {
+		bus.broadcaster.Unsubscribe(sub)
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// Emit sends an event of a registered type to the handlers of all instances.
+// It returns the error of the Broadcaster if the event is rejected.
+func (bus *Bus) Emit(event interface{}) error {
+	if event == nil {
+		return errors.New("event cannot be nil")
+	}
+
+	name, err := bus.name(reflect.TypeOf(event))
+	if err != nil {
+		return err
+	}
+
+	payload, err := bus.codec.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return bus.broadcaster.ToRoom(payload, bus.room(name))
+}
+
+func (bus *Bus) name(t reflect.Type) (string, error) {
+	bus.mux.RLock()
+	defer bus.mux.RUnlock()
+
+	name, ok := bus.names[t]
+	if !ok {
+		return "", fmt.Errorf("event type %v is not registered", t)
+	}
+
+	return name, nil
+}
+
+func (bus *Bus) room(name string) string {
+	return bus.prefix + name
+}
+
+func (bus *Bus) fail(err error) {
+	if bus.errorHandler != nil {
+		bus.errorHandler(err)
+	}
+}
@@ -0,0 +1,114 @@
+package eventbus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+type userCreated struct {
+	ID   string
+	Name string
+}
+
+type userDeleted struct {
+	ID string
+}
+
+func newBus(t *testing.T, options ...Option) (*Bus, broadcast.Broadcaster) {
+	b, cancel, err := broadcast.New(broadcast.WithSynchronousDelivery())
+	if err != nil {
+		t.Fatalf("broadcast.New() returned %v", err)
+	}
+	t.Cleanup(cancel)
+
+	bus, err := New(b, options...)
+	if err != nil {
+		t.Fatalf("New() returned %v", err)
+	}
+
+	if err := bus.Register("user.created", userCreated{}); err != nil {
+		t.Fatalf("Register() returned %v", err)
+	}
+
+	if err := bus.Register("user.deleted", userDeleted{}); err != nil {
+		t.Fatalf("Register() returned %v", err)
+	}
+
+	return bus, b
+}
+
+func TestBus_Emit_ShouldCallHandlersOfType(t *testing.T) {
+	bus, b := newBus(t)
+	created := []userCreated{}
+	deleted := 0
+	if _, err := bus.On(func(e userCreated) { created = append(created, e) }); err != nil {
+		t.Fatalf("On() returned %v", err)
+	}
+	bus.On(func(e userDeleted) { deleted++ })
+
+	if err := bus.Emit(userCreated{ID: "42", Name: "Ada"}); err != nil {
+		t.Fatalf("Emit() returned %v", err)
+	}
+	b.ToAll("not an event")
+
+	if len(created) != 1 || created[0] != (userCreated{ID: "42", Name: "Ada"}) {
+		t.Fatalf("handler received %v; want the emitted event", created)
+	}
+
+	if deleted != 0 {
+		t.Fatalf("handler of another event type was called %d times", deleted)
+	}
+}
+
+func TestBus_Register_Duplicate(t *testing.T) {
+	bus, _ := newBus(t)
+
+	if err := bus.Register("user.created", struct{}{}); err == nil {
+		t.Fatalf("Register() with a registered name should fail")
+	}
+
+	if err := bus.Register("other", userCreated{}); err == nil {
+		t.Fatalf("Register() with a registered type should fail")
+	}
+}
+
+func TestBus_On_InvalidHandler(t *testing.T) {
+	bus, _ := newBus(t)
+
+	for _, handler := range []interface{}{nil, "handler", func() {}, func(e userCreated) error { return nil }, func(e int) {}} {
+		if _, err := bus.On(handler); err == nil {
+			t.Fatalf("On(%T) should fail", handler)
+		}
+	}
+}
+
+func TestBus_Emit_Unregistered(t *testing.T) {
+	bus, _ := newBus(t)
+
+	if err := bus.Emit(42); err == nil {
+		t.Fatalf("Emit() of an unregistered type should fail")
+	}
+}
+
+type failingCodec struct {
+	jsonCodec
+}
+
+func (failingCodec) Unmarshal(_ []byte, _ interface{}) error {
+	return errors.New("invalid")
+}
+
+func TestBus_WithErrorHandler(t *testing.T) {
+	var failure error
+	bus, _ := newBus(t, WithCodec(failingCodec{}), WithErrorHandler(func(err error) { failure = err }))
+	called := false
+	bus.On(func(e userCreated) { called = true })
+
+	bus.Emit(userCreated{ID: "42"})
+
+	if called || failure == nil {
+		t.Fatalf("handler called %v with error %v; want the decode error instead", called, failure)
+	}
+}
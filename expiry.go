@@ -0,0 +1,174 @@
+package broadcast
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RoomExpiry selects which rooms expire, see WithRoomExpiry.
+type RoomExpiry int
+
+const (
+	// ExpireWhenEmpty removes rooms that had no subscriptions for the idle period,
+	// including rooms created with CreateRoom that nobody joined.
+	ExpireWhenEmpty RoomExpiry = iota
+	// ExpireWhenIdle removes rooms that had no messages and no subscriptions joining
+	// or leaving for the idle period. Their subscriptions leave the room.
+	ExpireWhenIdle
+)
+
+// WithRoomExpiry removes rooms after they were idle for the given period, so rooms created
+// per game, session or document are reclaimed even if clients never leave them.
+// Rooms are checked every half period, so a room expires within 1.5 periods of idleness.
+// Default rooms never expire. By default rooms don't expire. An expired room loses its retained
+// message, its history unless it is kept by a Store set with WithStore, its transformer, its codec
+// and its OnDemand hooks, which are stopped.
+func WithRoomExpiry(idle time.Duration, expiry RoomExpiry) Option {
+	return func(b *broadcaster) error {
+		if idle <= 0 {
+			return errors.New("room idle period must be positive")
+		}
+
+		if expiry < ExpireWhenEmpty || expiry > ExpireWhenIdle {
+			return errors.New("unknown room expiry")
+		}
+
+		b.roomIdle = idle
+		b.roomExpiry = expiry
+		return nil
+	}
+}
+
+// WithRoomExpiredHook sets a function that is called with every room removed by WithRoomExpiry.
+func WithRoomExpiredHook(hook func(room string)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("room expired hook cannot be nil")
+		}
+
+		b.roomExpiredHook = hook
+		return nil
+	}
+}
+
+// touch records activity in the room.
+func (r *room) touch(now time.Time) {
+	atomic.StoreInt64(&r.active, now.UnixNano())
+}
+
+// idleSince returns when the room was last active. A room that was never
+// active is marked active now, so it is idle from its first check.
+func (r *room) idleSince(now time.Time) time.Time {
+	if atomic.CompareAndSwapInt64(&r.active, 0, now.UnixNano()) {
+		return now
+	}
+
+	return time.Unix(0, atomic.LoadInt64(&r.active))
+}
+
+// touchRooms records activity in the given rooms if rooms expire.
+func (b *broadcaster) touchRooms(names []string) {
+	if b.roomIdle == 0 {
+		return
+	}
+
+	now := b.clock.Now()
+	for _, name := range names {
+		if r := b.rooms.get(name); r != nil {
+			r.touch(now)
+		}
+	}
+}
+
+// scheduleExpiry starts checking rooms for expiry every half idle period.
+func (b *broadcaster) scheduleExpiry() {
+	if b.roomIdle == 0 {
+		return
+	}
+
+	interval := b.roomIdle / 2
+	var sweep func()
+	sweep = func() {
+		if b.isClosed() {
+			return
+		}
+
+		b.expireRooms()
+		b.clock.AfterFunc(interval, sweep)
+	}
+	b.clock.AfterFunc(interval, sweep)
+}
+
+// expireRooms removes the rooms that are idle for longer than the idle period.
+func (b *broadcaster) expireRooms() {
+	now := b.clock.Now()
+	expired := []string{}
+	b.rooms.each(func(name string, r *room) bool {
+		if b.expired(name, r, now) {
+			expired = append(expired, name)
+		}
+		return true
+	})
+
+	for _, name := range expired {
+		b.expireRoom(name, now)
+	}
+}
+
+// expired reports whether the room was idle for longer than the idle period.
+func (b *broadcaster) expired(name string, r *room, now time.Time) bool {
+	if b.isDefaultRoom(name) || now.Sub(r.idleSince(now)) <= b.roomIdle {
+		return false
+	}
+
+	return b.roomExpiry != ExpireWhenEmpty || r.count() == 0
+}
+
+// expireRoom removes the room and its state unless a subscription joined it since it was checked.
+func (b *broadcaster) expireRoom(name string, now time.Time) {
+	r := b.rooms.removeIf(name, func(r *room) bool {
+		return b.expired(name, r, now)
+	})
+	if r == nil {
+		return
+	}
+
+	if subs := r.snapshot(); len(subs) > 0 {
+		for _, s := range subs {
+			b.announce(s, name, false)
+		}
+		b.roomsEmptied([]string{name})
+	}
+
+	b.forgetRoom(name)
+
+	if b.roomExpiredHook != nil {
+		b.roomExpiredHook(name)
+	}
+}
+
+// forgetRoom removes the retained message, history, transformer, codec and demand hooks of a room.
+func (b *broadcaster) forgetRoom(name string) {
+	if s, ok := b.store.(*ringStore); ok {
+		s.remove(name)
+	}
+
+	b.mux.Lock()
+	delete(b.retained, name)
+	delete(b.transformers, name)
+	delete(b.roomCodecs, name)
+	d := b.demands[name]
+	delete(b.demands, name)
+	b.mux.Unlock()
+
+	if d != nil {
+		d.remove()
+	}
+}
+
+// isDefaultRoom reports whether the room is the default room of the broadcaster or a namespace.
+func (b *broadcaster) isDefaultRoom(name string) bool {
+	return name == b.defaultRoomName || strings.HasSuffix(name, b.roomSeparator+b.defaultRoomName)
+}
@@ -0,0 +1,149 @@
+package broadcast
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock whose time is moved by tests. Its timers never fire.
+type manualClock struct {
+	mux *sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.now
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func (c *manualClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(time.Hour)}
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(time.Hour, func() {})}
+}
+
+func createExpiryTestBroadcaster(expiry RoomExpiry) (*broadcaster, *manualClock, *[]string) {
+	b := createTestBroadcaster()
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	b.clock = clock
+	WithRoomExpiry(time.Minute, expiry)(b)
+	expired := &[]string{}
+	WithRoomExpiredHook(func(room string) {
+		*expired = append(*expired, room)
+	})(b)
+
+	return b, clock, expired
+}
+
+func TestBroadcaster_expireRooms_WhenEmpty(t *testing.T) {
+	b, clock, expired := createExpiryTestBroadcaster(ExpireWhenEmpty)
+	b.CreateRoom("empty-room", nil)
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "joined-room")
+	b.expireRooms()
+
+	clock.advance(time.Minute * 2)
+	b.expireRooms()
+
+	if len(*expired) != 1 || (*expired)[0] != "empty-room" || b.rooms.get("empty-room") != nil {
+		t.Fatalf("expired rooms %v; want only empty-room", *expired)
+	}
+}
+
+func TestBroadcaster_expireRooms_WhenIdle(t *testing.T) {
+	b, clock, expired := createExpiryTestBroadcaster(ExpireWhenIdle)
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "idle-room", "active-room")
+
+	clock.advance(time.Second * 50)
+	b.ToRoom("data", "active-room")
+	clock.advance(time.Second * 50)
+	b.expireRooms()
+
+	if len(*expired) != 1 || (*expired)[0] != "idle-room" {
+		t.Fatalf("expired rooms %v; want only idle-room", *expired)
+	}
+
+	rooms := map[string]bool{}
+	for _, room := range b.RoomsOf(s) {
+		rooms[room] = true
+	}
+	if rooms["idle-room"] || !rooms["active-room"] {
+		t.Fatalf("RoomsOf returned %v; want the subscription removed from idle-room only", rooms)
+	}
+}
+
+func TestBroadcaster_expireRooms_ShouldKeepDefaultRoom(t *testing.T) {
+	b, clock, expired := createExpiryTestBroadcaster(ExpireWhenIdle)
+	b.Subscribe(func(_ interface{}) {})
+	namespaceOf(t, b, "tenant").Subscribe(func(_ interface{}) {})
+
+	clock.advance(time.Minute * 2)
+	b.expireRooms()
+
+	if len(*expired) != 0 {
+		t.Fatalf("expired rooms %v; want default rooms to be kept", *expired)
+	}
+}
+
+func TestBroadcaster_expireRoom_ShouldKeepJoinedRoom(t *testing.T) {
+	b, clock, expired := createExpiryTestBroadcaster(ExpireWhenEmpty)
+	b.CreateRoom("chat", nil)
+	clock.advance(time.Minute * 2)
+	now := clock.Now()
+	s := b.Subscribe(func(_ interface{}) {})
+
+	// The subscription joins after the room was found to be expired.
+	b.JoinRoom(s, "chat")
+	b.expireRoom("chat", now)
+
+	if len(*expired) != 0 || b.CountSubscribers("chat") != 1 {
+		t.Fatalf("expired rooms %v; want the room a subscription joined to be kept", *expired)
+	}
+}
+
+func TestBroadcaster_expireRooms_ShouldForgetRoomState(t *testing.T) {
+	b, clock, _ := createExpiryTestBroadcaster(ExpireWhenEmpty)
+	WithRetainLast("chat")(b)
+	WithHistory(10, 0)(b)
+	WithRoomCodec("chat", JSONCodec{}, nil)(b)
+	b.SetRoomTransformer("chat", func(data interface{}) interface{} { return data })
+	b.OnDemand("chat", func() {}, func() {})
+	b.CreateRoom("chat", nil)
+	b.ToRoom("data", "chat")
+
+	clock.advance(time.Minute * 2)
+	b.expireRooms()
+
+	if b.retained["chat"] != nil || b.store.(*ringStore).rooms["chat"] != nil {
+		t.Fatalf("expired room should lose its retained message and history")
+	}
+
+	if b.transformers["chat"] != nil || b.roomCodecs["chat"] != nil || b.demands["chat"] != nil {
+		t.Fatalf("expired room should lose its transformer, codec and demand hooks")
+	}
+}
+
+func TestWithRoomExpiry_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomExpiry(0, ExpireWhenEmpty)(b); err == nil {
+		t.Fatalf("WithRoomExpiry should fail with a non-positive idle period")
+	}
+
+	if err := WithRoomExpiry(time.Minute, RoomExpiry(5))(b); err == nil {
+		t.Fatalf("WithRoomExpiry should fail with an unknown expiry")
+	}
+}
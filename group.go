@@ -0,0 +1,122 @@
+package broadcast
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync/atomic"
+)
+
+// group is a set of subscriptions where each message is delivered to a single member.
+type group struct {
+	next    uint64
+	members *room
+}
+
+// WithKey sets the key of a message. Messages with the same key sent to a group
+// are delivered to the same member as long as the members of the group don't change.
+// Messages without a key are distributed between the members round-robin.
+func WithKey(key string) SendOption {
+	return func(msg *Message) {
+		msg.Key = key
+	}
+}
+
+// JoinGroup adds a subscription to one or multiple groups. A message sent to a room
+// is delivered to all subscriptions that joined the room and to exactly one member
+// of the group with the same name, which allows rooms to be used as work queues.
+// Groups are local to an instance: with a Dispatcher, every instance that receives the message
+// delivers it to one member of its own group, so a cluster delivers one copy per instance with
+// members, not one copy in total. Use the consumer groups of the broker when a message must be
+// handled exactly once across instances.
+// Subsequent calls with the same group and subscription have no effect.
+// The Authorizer is asked whether the subscription may join the room named like every group,
+// if it rejects any of them, the subscription joins none of the groups and the error is returned.
+func (b *broadcaster) JoinGroup(sub *Subscription, groups ...string) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	if sub.isClosed() {
+		return ErrSubscriptionClosed
+	}
+
+	if err := b.authorizeJoin(sub, groups); err != nil {
+		return err
+	}
+
+	b.joinGroup(sub, groups...)
+	return nil
+}
+
+func (b *broadcaster) joinGroup(sub *Subscription, groups ...string) {
+	for _, name := range groups {
+		b.mux.Lock()
+		g := b.groups[name]
+		if g == nil {
+			g = &group{members: newRoom()}
+			b.groups[name] = g
+		}
+		b.mux.Unlock()
+
+		g.members.addSubscription(sub)
+	}
+}
+
+// LeaveGroup removes a subscription from one or multiple groups.
+func (b *broadcaster) LeaveGroup(sub *Subscription, groups ...string) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	for _, name := range groups {
+		if g := b.groups[name]; g != nil {
+			g.members.removeSubscription(sub)
+		}
+	}
+}
+
+// pick selects the member of the group that receives the message.
+// Excluded members are never selected.
+func (b *broadcaster) pick(g *group, msg *Message) *Subscription {
+	members := []*Subscription{}
+	for _, s := range g.members.snapshot() {
+		if !b.isExcluded(s, msg) {
+			members = append(members, s)
+		}
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	if len(msg.Key) > 0 {
+		return rendezvous(members, msg.Key)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].id < members[j].id
+	})
+	next := atomic.AddUint64(&g.next, 1) - 1
+
+	return members[next%uint64(len(members))]
+}
+
+// rendezvous returns the subscription with the highest hash of the key and its ID,
+// so only the keys of a removed member move to other members.
+func rendezvous(subs []*Subscription, key string) *Subscription {
+	var selected *Subscription
+	var max uint64
+
+	for _, s := range subs {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte(s.id))
+		score := h.Sum64()
+
+		if selected == nil || score > max {
+			selected = s
+			max = score
+		}
+	}
+
+	return selected
+}
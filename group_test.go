@@ -0,0 +1,132 @@
+package broadcast
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBroadcaster_JoinGroup_ShouldDeliverToOneMember(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	for i := 0; i < 3; i++ {
+		b.JoinGroup(counter.subscribe(b), "workers")
+	}
+
+	for i := 0; i < 6; i++ {
+		b.ToRoom(i, "workers")
+	}
+
+	if counter.total() != 6 {
+		t.Fatalf("group members received %v messages; want 6", counter.total())
+	}
+
+	for id, calls := range counter.calls {
+		if calls != 2 {
+			t.Fatalf("member %v received %v messages; want 2 with round-robin", id, calls)
+		}
+	}
+}
+
+func TestBroadcaster_JoinGroup_WithKeyShouldPickSameMember(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	for i := 0; i < 5; i++ {
+		b.JoinGroup(counter.subscribe(b), "workers")
+	}
+
+	for i := 0; i < 10; i++ {
+		b.ToRoomWithOptions(i, "workers", WithKey("entity-1"))
+	}
+
+	if len(counter.calls) != 1 {
+		t.Fatalf("messages with the same key were delivered to %v members; want 1", len(counter.calls))
+	}
+}
+
+func TestBroadcaster_JoinGroup_ShouldSkipExcludedMembers(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	excluded := counter.subscribe(b)
+	b.JoinGroup(excluded, "workers")
+	b.JoinGroup(counter.subscribe(b), "workers")
+
+	for i := 0; i < 4; i++ {
+		b.ToRoomWithOptions(i, "workers", ExceptSubscribers(excluded.ID()))
+	}
+
+	if counter.calls[excluded.ID()] != 0 || counter.total() != 4 {
+		t.Fatalf("excluded group member should not be selected; got %v", counter.calls)
+	}
+}
+
+func TestBroadcaster_LeaveGroup(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	subscription := counter.subscribe(b)
+	b.JoinGroup(subscription, "workers")
+
+	b.LeaveGroup(subscription, "workers")
+	b.ToRoom(struct{}{}, "workers")
+
+	if counter.total() != 0 {
+		t.Fatalf("LeaveGroup didn't remove subscription from the group")
+	}
+}
+
+func TestRendezvous_ShouldKeepKeysOfRemainingMembers(t *testing.T) {
+	subs := []*Subscription{{id: "a"}, {id: "b"}, {id: "c"}, {id: "d"}}
+	keys := []string{"k1", "k2", "k3", "k4", "k5", "k6", "k7", "k8"}
+	before := map[string]*Subscription{}
+	for _, k := range keys {
+		before[k] = rendezvous(subs, k)
+	}
+
+	remaining := subs[:3]
+	for _, k := range keys {
+		if before[k] == subs[3] {
+			continue
+		}
+
+		if got := rendezvous(remaining, k); got != before[k] {
+			t.Fatalf("key %v moved from %v to %v after removing another member", k, before[k].id, got.id)
+		}
+	}
+}
+
+type callCounter struct {
+	mux   sync.Mutex
+	calls map[string]int
+}
+
+func newCallCounter() *callCounter {
+	return &callCounter{calls: make(map[string]int)}
+}
+
+// subscribe creates a subscription that is not part of the default room and counts its calls.
+func (c *callCounter) subscribe(b Broadcaster) *Subscription {
+	var sub *Subscription
+	sub = b.Subscribe(func(_ interface{}) {
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		c.calls[sub.ID()]++
+	})
+	b.LeaveRoom(sub, b.RoomsOf(sub)...)
+
+	return sub
+}
+
+func (c *callCounter) total() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	total := 0
+	for _, calls := range c.calls {
+		total += calls
+	}
+
+	return total
+}
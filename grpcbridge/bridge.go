@@ -0,0 +1,216 @@
+// Package grpcbridge exposes a Broadcaster as the Subscribe/Publish streaming
+// service described in broadcast.proto, so clients written in any language
+// can join rooms and receive broadcast payloads as a server stream.
+//
+// The Go types in this package mirror the messages in broadcast.proto and Server implements
+// the service methods with them, so the package doesn't depend on a gRPC runtime. Code generated
+// from broadcast.proto goes to its own package, broadcastpb, whose types differ from the types
+// of this package, so the generated service is implemented by a thin adapter:
+//
+//	type service struct {
+//		broadcastpb.UnimplementedBroadcastServer
+//		server *grpcbridge.Server
+//	}
+//
+//	func (s *service) Subscribe(req *broadcastpb.SubscribeRequest, stream broadcastpb.Broadcast_SubscribeServer) error {
+//		return s.server.Subscribe(&grpcbridge.SubscribeRequest{Rooms: req.Rooms}, streamAdapter{stream})
+//	}
+//
+//	func (s *service) Publish(ctx context.Context, req *broadcastpb.PublishRequest) (*broadcastpb.PublishResponse, error) {
+//		_, err := s.server.Publish(ctx, &grpcbridge.PublishRequest{
+//			Payload: req.Payload, Room: req.Room, ToAll: req.ToAll, Except: req.Except,
+//		})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return &broadcastpb.PublishResponse{}, nil
+//	}
+//
+//	// streamAdapter converts the messages of this package to the generated message type.
+//	type streamAdapter struct {
+//		broadcastpb.Broadcast_SubscribeServer
+//	}
+//
+//	func (a streamAdapter) Send(msg *grpcbridge.Message) error {
+//		return a.Broadcast_SubscribeServer.Send(&broadcastpb.Message{Payload: msg.Payload})
+//	}
+//
+// The service is registered with broadcastpb.RegisterBroadcastServer(grpcServer, &service{server: server}).
+package grpcbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+const defaultBufferSize = 64
+
+// SubscribeRequest lists the rooms a client wants to receive messages from.
+type SubscribeRequest struct {
+	Rooms []string
+}
+
+// PublishRequest describes a message sent by a client.
+type PublishRequest struct {
+	Payload []byte
+	Room    string
+	ToAll   bool
+	Except  []string
+}
+
+// PublishResponse is returned after a message is handed to the broadcaster.
+type PublishResponse struct{}
+
+// Message is a single broadcast payload streamed to a subscribed client.
+type Message struct {
+	Payload []byte
+}
+
+// SubscribeStream is the server side of a Subscribe call.
+type SubscribeStream interface {
+	Send(*Message) error
+	Context() context.Context
+}
+
+// Encoder converts broadcast data to the payload sent to clients.
+type Encoder func(data interface{}) ([]byte, error)
+
+// Option is used to change server settings.
+type Option func(s *Server) error
+
+// WithEncoder sets the function used to encode broadcast data that is not
+// already a byte slice. Default is JSON.
+func WithEncoder(encoder Encoder) Option {
+	return func(s *Server) error {
+		if encoder == nil {
+			return errors.New("encoder cannot be nil")
+		}
+
+		s.encode = encoder
+		return nil
+	}
+}
+
+// WithBufferSize sets how many messages can be queued for a single client
+// while the stream is busy sending. Messages that don't fit are dropped and
+// counted by Server.Dropped. Default is 64.
+func WithBufferSize(size int) Option {
+	return func(s *Server) error {
+		if size <= 0 {
+			return errors.New("buffer size must be positive")
+		}
+
+		s.bufferSize = size
+		return nil
+	}
+}
+
+// Server implements the Broadcast gRPC service on top of a Broadcaster.
+type Server struct {
+	dropped     uint64
+	broadcaster broadcast.Broadcaster
+	encode      Encoder
+	bufferSize  int
+}
+
+// NewServer creates a new Server backed by the given Broadcaster.
+func NewServer(b broadcast.Broadcaster, options ...Option) (*Server, error) {
+	if b == nil {
+		return nil, errors.New("broadcaster cannot be nil")
+	}
+
+	s := &Server{
+		broadcaster: b,
+		encode:      json.Marshal,
+		bufferSize:  defaultBufferSize,
+	}
+
+	for _, option := range options {
+		err := option(s)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Subscribe creates a subscription that joins the requested rooms and
+// forwards every received message to the stream. Like any other subscription
+// it is also part of the default room and receives messages sent to all. It returns when the
+// stream's context is done, the broadcaster stops or a send fails.
+func (s *Server) Subscribe(req *SubscribeRequest, stream SubscribeStream) error {
+	ctx := stream.Context()
+	messages := make(chan *Message, s.bufferSize)
+
+	subscription := s.broadcaster.Subscribe(func(data interface{}) {
+		payload, err := s.payload(data)
+		if err != nil {
+			return
+		}
+
+		select {
+		case messages <- &Message{Payload: payload}:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	})
+	defer s.broadcaster.Unsubscribe(subscription)
+
+	if err := s.broadcaster.JoinRoom(subscription, req.Rooms...); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.broadcaster.Done():
+			return nil
+		case msg := <-messages:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Dropped returns the number of messages that were dropped because the buffer of a client was full,
+// see WithBufferSize.
+func (s *Server) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Publish sends the request payload to a room or, if ToAll is set, to all subscribers.
+// Subscribers receive the payload as a byte slice.
+func (s *Server) Publish(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
+	if !req.ToAll && len(req.Room) == 0 {
+		return nil, errors.New("room is required when not sending to all subscribers")
+	}
+
+	var err error
+	if req.ToAll {
+		err = s.broadcaster.ToAll(req.Payload, req.Except...)
+	} else {
+		err = s.broadcaster.ToRoom(req.Payload, req.Room, req.Except...)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublishResponse{}, nil
+}
+
+func (s *Server) payload(data interface{}) ([]byte, error) {
+	if b, ok := data.([]byte); ok {
+		return b, nil
+	}
+
+	return s.encode(data)
+}
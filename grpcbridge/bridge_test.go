@@ -0,0 +1,161 @@
+package grpcbridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+)
+
+func TestNewServer_WithNilBroadcaster(t *testing.T) {
+	_, err := NewServer(nil)
+
+	if err == nil {
+		t.Fatal("NewServer(nil) should return an error")
+	}
+}
+
+func TestNewServer_WithInvalidOption(t *testing.T) {
+	b, cancel, _ := broadcast.New()
+	defer cancel()
+
+	_, err := NewServer(b, WithBufferSize(0))
+
+	if err == nil {
+		t.Fatal("NewServer with invalid option should return an error")
+	}
+}
+
+func TestServer_Subscribe_ShouldStreamRoomMessages(t *testing.T) {
+	b, cancel, _ := broadcast.New()
+	defer cancel()
+	server, _ := NewServer(b)
+	stream := newMockStream()
+	errc := make(chan error)
+
+	go func() {
+		errc <- server.Subscribe(&SubscribeRequest{Rooms: []string{"test-room"}}, stream)
+	}()
+	waitForSubscription(t, b, "test-room")
+
+	server.Publish(context.Background(), &PublishRequest{Payload: []byte("hello"), Room: "test-room"})
+
+	select {
+	case msg := <-stream.sent:
+		if string(msg.Payload) != "hello" {
+			t.Fatalf("Subscribe streamed %q; want %q", msg.Payload, "hello")
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("Subscribe did not stream published message")
+	}
+
+	stream.cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("Subscribe returned %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestServer_Subscribe_ShouldEncodeData(t *testing.T) {
+	b, cancel, _ := broadcast.New()
+	defer cancel()
+	server, _ := NewServer(b)
+	stream := newMockStream()
+	defer stream.cancel()
+
+	go server.Subscribe(&SubscribeRequest{Rooms: []string{"test-room"}}, stream)
+	waitForSubscription(t, b, "test-room")
+
+	b.ToRoom(map[string]int{"a": 1}, "test-room")
+
+	select {
+	case msg := <-stream.sent:
+		if string(msg.Payload) != `{"a":1}` {
+			t.Fatalf("Subscribe streamed %s; want JSON encoded data", msg.Payload)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("Subscribe did not stream message")
+	}
+}
+
+func TestServer_Publish_WithoutRoom(t *testing.T) {
+	b, cancel, _ := broadcast.New()
+	defer cancel()
+	server, _ := NewServer(b)
+
+	_, err := server.Publish(context.Background(), &PublishRequest{Payload: []byte("hello")})
+
+	if err == nil {
+		t.Fatal("Publish without room should return an error")
+	}
+}
+
+func TestServer_Subscribe_ShouldCountDroppedMessages(t *testing.T) {
+	b, cancel, _ := broadcast.New()
+	defer cancel()
+	server, _ := NewServer(b, WithBufferSize(1))
+	stream := &blockingStream{mockStream: newMockStream(), release: make(chan struct{})}
+	defer close(stream.release)
+	defer stream.cancel()
+
+	go server.Subscribe(&SubscribeRequest{Rooms: []string{"test-room"}}, stream)
+	waitForSubscription(t, b, "test-room")
+
+	deadline := time.Now().Add(time.Second * 3)
+	for server.Dropped() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Dropped() = 0 while the stream was blocked; want dropped messages")
+		}
+		b.ToRoom([]byte("data"), "test-room")
+		<-time.After(time.Millisecond)
+	}
+}
+
+type mockStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	sent   chan *Message
+}
+
+func newMockStream() *mockStream {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &mockStream{
+		ctx:    ctx,
+		cancel: cancel,
+		sent:   make(chan *Message, 10),
+	}
+}
+
+func (s *mockStream) Send(msg *Message) error {
+	s.sent <- msg
+	return nil
+}
+
+func (s *mockStream) Context() context.Context {
+	return s.ctx
+}
+
+// waitForSubscription waits until the streaming subscription joined the room.
+func waitForSubscription(t *testing.T, b broadcast.Broadcaster, room string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 3)
+	for b.CountSubscribers(room) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscription did not join %s", room)
+		}
+		<-time.After(time.Millisecond)
+	}
+}
+
+// blockingStream is a stream whose sends block until it is released.
+type blockingStream struct {
+	*mockStream
+	release chan struct{}
+}
+
+func (s *blockingStream) Send(msg *Message) error {
+	<-s.release
+	return nil
+}
@@ -0,0 +1,72 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Pinger can be implemented by a Dispatcher to report whether its connection
+// to the external service is alive, see Broadcaster.Health.
+type Pinger interface {
+	// Ping returns an error if the external service can't be reached.
+	Ping(ctx context.Context) error
+}
+
+// Health returns nil if the broadcaster accepts messages, its pool runs tasks and
+// the Dispatcher, if it implements Pinger, reaches its external service.
+// It returns ErrDraining or ErrBroadcasterClosed once the broadcaster stops accepting messages,
+// so it can be used as a readiness probe.
+func (b *broadcaster) Health(ctx context.Context) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	if atomic.LoadInt32(&b.draining) == 1 {
+		return ErrDraining
+	}
+
+	if err := b.pingPool(ctx); err != nil {
+		return err
+	}
+
+	if p, ok := b.dispatcher.(Pinger); ok {
+		if err := p.Ping(ctx); err != nil {
+			return fmt.Errorf("dispatcher: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pingPool waits until the pool runs a high priority task.
+func (b *broadcaster) pingPool(ctx context.Context) error {
+	done := make(chan struct{})
+	scheduled := make(chan bool, 1)
+	go func() {
+		scheduled <- b.pool.doPriority(func() { close(done) }, PriorityHigh)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case ok := <-scheduled:
+		if !ok {
+			return ErrBroadcasterClosed
+		}
+	case <-ctx.Done():
+		return fmt.Errorf("worker pool: %w", ctx.Err())
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("worker pool: %w", ctx.Err())
+	}
+}
+
+// Health reports the health of the whole broadcaster, not only the namespace.
+func (n *namespace) Health(ctx context.Context) error {
+	return n.broadcaster.Health(ctx)
+}
@@ -0,0 +1,63 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type pingDispatcher struct {
+	noopDispatcher
+	err error
+}
+
+func (d *pingDispatcher) Ping(ctx context.Context) error {
+	return d.err
+}
+
+func TestBroadcaster_Health(t *testing.T) {
+	b, cancel, _ := New(WithDispatcher(&pingDispatcher{}))
+	defer cancel()
+
+	if err := b.Health(context.Background()); err != nil {
+		t.Fatalf("Health() = %v; want nil", err)
+	}
+}
+
+func TestBroadcaster_Health_WithFailingPinger(t *testing.T) {
+	pingErr := errors.New("broker unreachable")
+	b, cancel, _ := New(WithDispatcher(&pingDispatcher{err: pingErr}))
+	defer cancel()
+
+	if err := b.Health(context.Background()); !errors.Is(err, pingErr) {
+		t.Fatalf("Health() = %v; want %v", err, pingErr)
+	}
+}
+
+func TestBroadcaster_Health_WithSaturatedPool(t *testing.T) {
+	b, cancel, _ := New(WithPoolSize(1))
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	br := b.(*broadcaster)
+	for i := 0; i < 1+workerQueueSize; i++ {
+		br.pool.doPriority(func() { <-release }, PriorityHigh)
+	}
+
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancelCtx()
+
+	if err := b.Health(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Health() = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBroadcaster_Health_AfterCancel(t *testing.T) {
+	b, cancel, _ := New()
+	cancel()
+
+	if err := b.Health(context.Background()); err != ErrBroadcasterClosed {
+		t.Fatalf("Health() = %v; want %v", err, ErrBroadcasterClosed)
+	}
+}
@@ -0,0 +1,131 @@
+package broadcast
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// WithHeartbeat sends the payload to the subscriptions of every room at the given interval,
+// so connections behind subscription callbacks that died are noticed. Subscriptions within
+// several rooms receive it once. Heartbeats are delivered to local subscriptions only and are
+// not recorded in the history or retained. See IdleSubscribers and WithIdleEviction.
+func WithHeartbeat(interval time.Duration, payload interface{}) Option {
+	return func(b *broadcaster) error {
+		if interval <= 0 {
+			return errors.New("heartbeat interval must be positive")
+		}
+
+		b.heartbeatInterval = interval
+		b.heartbeatPayload = payload
+		return nil
+	}
+}
+
+// WithIdleEviction unsubscribes subscriptions that didn't receive a message for longer
+// than the threshold, checked before every heartbeat. The threshold should span a few heartbeat
+// intervals, so subscriptions still processing the last heartbeat are not evicted. It requires WithHeartbeat.
+func WithIdleEviction(threshold time.Duration) Option {
+	return func(b *broadcaster) error {
+		if threshold <= 0 {
+			return errors.New("idle threshold must be positive")
+		}
+
+		b.idleThreshold = threshold
+		return nil
+	}
+}
+
+// IdleSubscribers returns the IDs of the subscriptions that didn't receive a message
+// for longer than the threshold, sorted. Subscriptions that never received a message
+// are idle since they were created. A delivery counts when the callback returned without error.
+func (b *broadcaster) IdleSubscribers(threshold time.Duration) []string {
+	ids := []string{}
+	for _, s := range b.idle(threshold) {
+		ids = append(ids, s.id)
+	}
+
+	return ids
+}
+
+func (n *namespace) IdleSubscribers(threshold time.Duration) []string {
+	ids := []string{}
+	for _, s := range n.broadcaster.idle(threshold) {
+		if n.owns(s) {
+			ids = append(ids, s.id)
+		}
+	}
+
+	return ids
+}
+
+// idle returns the subscriptions that didn't receive a message for longer than the threshold sorted by ID.
+func (b *broadcaster) idle(threshold time.Duration) []*Subscription {
+	since := b.clock.Now().Add(-threshold).UnixNano()
+	seen := map[string]struct{}{}
+	idle := []*Subscription{}
+	b.rooms.each(func(_ string, r *room) bool {
+		for _, s := range r.snapshot() {
+			if _, ok := seen[s.id]; ok {
+				continue
+			}
+
+			seen[s.id] = struct{}{}
+			if atomic.LoadInt64(&s.lastDelivery) < since {
+				idle = append(idle, s)
+			}
+		}
+		return true
+	})
+
+	sort.Slice(idle, func(i, j int) bool {
+		return idle[i].id < idle[j].id
+	})
+
+	return idle
+}
+
+// scheduleHeartbeat starts sending heartbeats if they are enabled.
+func (b *broadcaster) scheduleHeartbeat() {
+	if b.heartbeatInterval == 0 {
+		return
+	}
+
+	var beat func()
+	beat = func() {
+		if b.isClosed() {
+			return
+		}
+
+		b.heartbeat()
+		b.clock.AfterFunc(b.heartbeatInterval, beat)
+	}
+	b.clock.AfterFunc(b.heartbeatInterval, beat)
+}
+
+// heartbeat evicts idle subscriptions and sends the heartbeat payload to all rooms.
+func (b *broadcaster) heartbeat() {
+	if b.idleThreshold > 0 {
+		for _, s := range b.idle(b.idleThreshold) {
+			b.Unsubscribe(s)
+		}
+	}
+
+	rooms := []string{}
+	b.rooms.each(func(name string, r *room) bool {
+		if r.count() > 0 {
+			rooms = append(rooms, name)
+		}
+		return true
+	})
+
+	if len(rooms) == 0 || b.accept() != nil {
+		return
+	}
+	defer b.release()
+
+	msg := &Message{Data: b.heartbeatPayload, Rooms: rooms, heartbeat: true}
+	b.stamp(msg)
+	b.deliverLocal(msg)
+}
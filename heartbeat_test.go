@@ -0,0 +1,76 @@
+package broadcast
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_heartbeat(t *testing.T) {
+	b := createTestBroadcaster()
+	b.synchronous = true
+	WithHeartbeat(time.Second, "ping")(b)
+	WithHistory(10, 0)(b)
+	received := []interface{}{}
+	s := b.Subscribe(func(data interface{}) {
+		received = append(received, data)
+	})
+	b.JoinRoom(s, "test-room")
+
+	b.heartbeat()
+
+	if !reflect.DeepEqual(received, []interface{}{"ping"}) {
+		t.Fatalf("subscription received %v; want a single ping", received)
+	}
+
+	if n, _ := b.Replay(b.Subscribe(func(_ interface{}) {}), "test-room", time.Time{}); n != 0 {
+		t.Fatalf("heartbeat should not be recorded in the history; replayed %d messages", n)
+	}
+}
+
+func TestBroadcaster_IdleSubscribers(t *testing.T) {
+	b := createTestBroadcaster()
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	b.clock = clock
+	b.synchronous = true
+	idle := b.Subscribe(func(_ interface{}) {})
+	active := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(active, "test-room")
+
+	clock.advance(time.Minute)
+	b.ToRoom("data", "test-room")
+
+	if got := b.IdleSubscribers(time.Second * 30); !reflect.DeepEqual(got, []string{idle.ID()}) {
+		t.Fatalf("IdleSubscribers returned %v; want [%s]", got, idle.ID())
+	}
+}
+
+func TestBroadcaster_heartbeat_WithIdleEviction(t *testing.T) {
+	b := createTestBroadcaster()
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	b.clock = clock
+	b.synchronous = true
+	WithHeartbeat(time.Second, "ping")(b)
+	WithIdleEviction(time.Second * 30)(b)
+	dead := b.SubscribeAck(func(_ interface{}) error {
+		return errors.New("failed")
+	})
+	alive := b.Subscribe(func(_ interface{}) {})
+
+	for i := 0; i < 3; i++ {
+		b.heartbeat()
+		clock.advance(time.Second * 20)
+	}
+
+	if !dead.isClosed() || alive.isClosed() {
+		t.Fatalf("heartbeat should evict the subscription that didn't receive messages only")
+	}
+}
+
+func TestNew_WithIdleEvictionWithoutHeartbeat(t *testing.T) {
+	if _, _, err := New(WithIdleEviction(time.Minute)); err == nil {
+		t.Fatalf("New should fail with idle eviction but no heartbeat")
+	}
+}
@@ -0,0 +1,292 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotInHistory is returned when a message can't be found in the history of a room.
+var ErrNotInHistory = errors.New("message is not in the room history")
+
+// WithHistory keeps up to size of the most recent messages sent to each room in memory,
+// so reconnecting clients can catch up with Replay and ReplaySince.
+// Messages older than ttl are not replayed, a ttl of zero keeps messages until newer ones push them out.
+// The ttl also applies to a Store set with WithStore, in which case only the last of the two options
+// decides where messages are stored. History is disabled by default.
+func WithHistory(size int, ttl time.Duration) Option {
+	return func(b *broadcaster) error {
+		if size <= 0 {
+			return errors.New("history size must be positive")
+		}
+
+		if ttl < 0 {
+			return errors.New("history ttl cannot be negative")
+		}
+
+		b.store = newRingStore(size)
+		b.historyTTL = ttl
+		return nil
+	}
+}
+
+// ringStore is a Store that keeps a bounded number of messages per room.
+type ringStore struct {
+	mux   *sync.RWMutex
+	size  int
+	rooms map[string]*history
+}
+
+func newRingStore(size int) *ringStore {
+	return &ringStore{
+		mux:   &sync.RWMutex{},
+		size:  size,
+		rooms: make(map[string]*history),
+	}
+}
+
+func (s *ringStore) Append(room string, msg *Message) error {
+	s.mux.Lock()
+	h := s.rooms[room]
+	if h == nil {
+		h = newHistory(s.size)
+		s.rooms[room] = h
+	}
+	s.mux.Unlock()
+
+	h.add(msg)
+	return nil
+}
+
+func (s *ringStore) Range(room string, since time.Time, fn func(msg *Message) bool) error {
+	s.mux.RLock()
+	h := s.rooms[room]
+	s.mux.RUnlock()
+
+	if h == nil {
+		return nil
+	}
+
+	for _, msg := range h.messages() {
+		if !msg.Timestamp.After(since) {
+			continue
+		}
+
+		if !fn(msg) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *ringStore) Trim(room string, before time.Time) error {
+	s.mux.RLock()
+	h := s.rooms[room]
+	s.mux.RUnlock()
+
+	if h != nil {
+		h.trim(before)
+	}
+
+	return nil
+}
+
+// remove deletes the history of a room.
+func (s *ringStore) remove(room string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.rooms, room)
+}
+
+// history is a ring buffer of the most recent messages sent to a room.
+type history struct {
+	mux     *sync.Mutex
+	entries []*Message
+	start   int
+	count   int
+}
+
+func newHistory(size int) *history {
+	return &history{
+		mux:     &sync.Mutex{},
+		entries: make([]*Message, size),
+	}
+}
+
+func (h *history) add(msg *Message) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	size := len(h.entries)
+	if h.count < size {
+		h.entries[(h.start+h.count)%size] = msg
+		h.count++
+		return
+	}
+
+	h.entries[h.start] = msg
+	h.start = (h.start + 1) % size
+}
+
+// trim drops the oldest messages that were sent before the given time.
+func (h *history) trim(before time.Time) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	for h.count > 0 && h.entries[h.start].Timestamp.Before(before) {
+		h.entries[h.start] = nil
+		h.start = (h.start + 1) % len(h.entries)
+		h.count--
+	}
+}
+
+// messages returns the messages from oldest to newest.
+func (h *history) messages() []*Message {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	msgs := make([]*Message, 0, h.count)
+	for i := 0; i < h.count; i++ {
+		msgs = append(msgs, h.entries[(h.start+i)%len(h.entries)])
+	}
+
+	return msgs
+}
+
+// record appends the message to the Store under each target room.
+// Requests, replies and messages targeted by attributes are not recorded.
+func (b *broadcaster) record(msg *Message) {
+	if b.store == nil || len(msg.CorrelationID) > 0 || msg.match != nil {
+		return
+	}
+
+	for _, room := range b.targetRooms(msg) {
+		if err := b.store.Append(room, msg); err != nil {
+			b.storeError(err)
+			continue
+		}
+
+		if b.historyTTL == 0 {
+			continue
+		}
+
+		if err := b.store.Trim(room, b.clock.Now().Add(-b.historyTTL)); err != nil {
+			b.storeError(err)
+		}
+	}
+}
+
+func (b *broadcaster) storeError(err error) {
+	if b.storeErrorHandler != nil {
+		b.storeErrorHandler(err)
+	}
+}
+
+// Replay sends the stored messages of a room that were sent after since to a subscription,
+// in the order they were originally sent. Messages whose TTL passed are skipped, see WithMessageTTL.
+// It returns the number of messages that are replayed. Like with JoinRoom, the Authorizer is asked
+// whether the subscription may join the room and its error is returned if it doesn't.
+func (b *broadcaster) Replay(sub *Subscription, room string, since time.Time) (int, error) {
+	if b.isClosed() {
+		return 0, ErrBroadcasterClosed
+	}
+
+	if err := b.authorizeJoin(sub, []string{room}); err != nil {
+		return 0, err
+	}
+
+	msgs, err := b.historyOf(room, since)
+	if err != nil {
+		return 0, err
+	}
+
+	return b.replay(sub, room, msgs), nil
+}
+
+// ReplaySince sends the stored messages of a room that were sent after the message
+// with the given ID to a subscription, in the order they were originally sent.
+// It returns the number of messages that are replayed, or ErrNotInHistory if the message
+// is no longer stored, in which case nothing is replayed. The subscription is authorized like with Replay.
+func (b *broadcaster) ReplaySince(sub *Subscription, room string, id string) (int, error) {
+	if b.isClosed() {
+		return 0, ErrBroadcasterClosed
+	}
+
+	if err := b.authorizeJoin(sub, []string{room}); err != nil {
+		return 0, err
+	}
+
+	msgs, err := b.historyOf(room, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range msgs {
+		if msg.ID == id {
+			return b.replay(sub, room, msgs[i+1:]), nil
+		}
+	}
+
+	return 0, ErrNotInHistory
+}
+
+// historyOf returns the stored messages of a room that were sent after since and haven't expired.
+func (b *broadcaster) historyOf(room string, since time.Time) ([]*Message, error) {
+	if b.store == nil {
+		return nil, nil
+	}
+
+	if b.historyTTL > 0 {
+		if expired := b.clock.Now().Add(-b.historyTTL); since.Before(expired) {
+			since = expired
+		}
+	}
+
+	msgs := []*Message{}
+	err := b.store.Range(room, since, func(msg *Message) bool {
+		msgs = append(msgs, msg)
+		return true
+	})
+
+	return msgs, err
+}
+
+// replay sends the messages that haven't expired to the subscription and returns their number.
+func (b *broadcaster) replay(sub *Subscription, room string, msgs []*Message) int {
+	now := b.clock.Now()
+	deliveries := make([]delivery, 0, len(msgs))
+	t := b.pendingTracker()
+	for _, msg := range msgs {
+		if msg.expiredAt(now) {
+			continue
+		}
+
+		deliveries = append(deliveries, delivery{msg: b.transformFor(room, msg), tracker: t, subscriber: sub.id})
+		t.add()
+	}
+
+	if len(deliveries) == 0 {
+		return 0
+	}
+
+	// A single task keeps the original order of the messages.
+	scheduled := b.pool.do(func() {
+		for _, d := range deliveries {
+			if b.isExcluded(sub, d.msg) {
+				d.exclude()
+				continue
+			}
+			b.deliver(sub, d)
+		}
+	})
+
+	if !scheduled {
+		for _, d := range deliveries {
+			d.finish(false)
+		}
+	}
+
+	return len(deliveries)
+}
@@ -0,0 +1,180 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithHistory_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithHistory(0, 0)(b); err == nil {
+		t.Fatalf("WithHistory(0, 0); expected an error")
+	}
+
+	if err := WithHistory(1, -time.Second)(b); err == nil {
+		t.Fatalf("WithHistory(1, -1s); expected an error")
+	}
+}
+
+func TestHistory_add_ShouldOverwriteOldest(t *testing.T) {
+	h := newHistory(2)
+
+	h.add(&Message{ID: "a"})
+	h.add(&Message{ID: "b"})
+	h.add(&Message{ID: "c"})
+	msgs := h.messages()
+
+	if len(msgs) != 2 || msgs[0].ID != "b" || msgs[1].ID != "c" {
+		t.Fatalf("history should keep the newest messages in order; got %v", msgs)
+	}
+}
+
+func TestBroadcaster_Replay(t *testing.T) {
+	b, cancel, _ := New(WithHistory(10, 0), WithSynchronousDelivery())
+	defer cancel()
+	b.ToRoom(1, "room")
+	<-time.After(time.Millisecond * 10)
+	since := time.Now()
+	b.ToRoom(2, "room")
+	b.ToRoom(3, "room")
+	received := make(chan interface{}, 10)
+	subscription := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	count, err := b.Replay(subscription, "room", since)
+
+	if err != nil || count != 2 {
+		t.Fatalf("Replay() = %v, %v; want 2, nil", count, err)
+	}
+
+	if first, second := <-received, <-received; first != 2 || second != 3 {
+		t.Fatalf("Replay sent %v, %v; want 2, 3", first, second)
+	}
+}
+
+func TestBroadcaster_ReplaySince(t *testing.T) {
+	b, cancel, _ := New(WithHistory(10, 0))
+	defer cancel()
+	ids := make(chan string, 3)
+	recorder := b.SubscribeMessage(func(msg *Message) {
+		ids <- msg.ID
+	})
+	b.JoinRoom(recorder, "room")
+	b.ToRoomSync(context.Background(), 1, "room")
+	b.ToRoomSync(context.Background(), 2, "room")
+	b.ToRoomSync(context.Background(), 3, "room")
+	<-ids
+	lastSeen := <-ids
+	received := make(chan interface{}, 10)
+	subscription := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	count, err := b.ReplaySince(subscription, "room", lastSeen)
+
+	if err != nil || count != 1 {
+		t.Fatalf("ReplaySince() = %v, %v; want 1, nil", count, err)
+	}
+
+	if got := <-received; got != 3 {
+		t.Fatalf("ReplaySince sent %v; want 3", got)
+	}
+}
+
+func TestBroadcaster_ReplaySince_WithUnknownID(t *testing.T) {
+	b, cancel, _ := New(WithHistory(10, 0))
+	defer cancel()
+	b.ToRoom(1, "room")
+	subscription := b.Subscribe(func(_ interface{}) {})
+
+	_, err := b.ReplaySince(subscription, "room", "unknown")
+
+	if err != ErrNotInHistory {
+		t.Fatalf("ReplaySince returned %v; want %v", err, ErrNotInHistory)
+	}
+}
+
+func TestBroadcaster_Replay_ShouldSkipExpiredMessages(t *testing.T) {
+	b, cancel, _ := New(WithHistory(10, time.Millisecond*50))
+	defer cancel()
+	b.ToRoom(1, "room")
+	<-time.After(time.Millisecond * 100)
+	b.ToRoom(2, "room")
+	subscription := b.Subscribe(func(_ interface{}) {})
+
+	count, _ := b.Replay(subscription, "room", time.Time{})
+
+	if count != 1 {
+		t.Fatalf("Replay() = %v; want 1", count)
+	}
+}
+
+func TestBroadcaster_Replay_ShouldSkipMessagesPastTheirTTL(t *testing.T) {
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	b, cancel, _ := New(WithHistory(10, 0), WithClock(clock))
+	defer cancel()
+	b.ToRoomWithOptions("stale", "chat", WithMessageTTL(time.Minute))
+	b.ToRoom("fresh", "chat")
+	clock.advance(time.Minute * 2)
+	received := make(chan interface{}, 2)
+	s := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	n, err := b.Replay(s, "chat", time.Time{})
+
+	if n != 1 || err != nil {
+		t.Fatalf("Replay() = %d, %v; want 1, nil", n, err)
+	}
+
+	// Messages are replayed in order, so the stale message would come first.
+	if got := <-received; got != "fresh" {
+		t.Fatalf("Replay sent %v; want only the message without TTL", got)
+	}
+}
+
+func TestHistory_trim(t *testing.T) {
+	h := newHistory(3)
+	now := time.Now()
+	h.add(&Message{ID: "a", Timestamp: now.Add(-time.Minute)})
+	h.add(&Message{ID: "b", Timestamp: now})
+
+	h.trim(now.Add(-time.Second))
+	msgs := h.messages()
+
+	if len(msgs) != 1 || msgs[0].ID != "b" {
+		t.Fatalf("trim should drop messages sent before the given time; got %v", msgs)
+	}
+}
+
+func TestBroadcaster_Replay_WithoutHistory(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	b.ToRoom(1, "room")
+	subscription := b.Subscribe(func(_ interface{}) {})
+
+	count, err := b.Replay(subscription, "room", time.Time{})
+
+	if err != nil || count != 0 {
+		t.Fatalf("Replay() = %v, %v; want 0, nil", count, err)
+	}
+}
+
+func TestBroadcaster_Replay_WithAuthorizer(t *testing.T) {
+	b, cancel, _ := New(WithHistory(10, 0), WithAuthorizer(&joinAuthorizer{allowed: "public"}))
+	defer cancel()
+	b.ToRoom("secret", "private")
+	subscription := b.Subscribe(func(_ interface{}) {})
+
+	if count, err := b.Replay(subscription, "private", time.Time{}); err == nil || count != 0 {
+		t.Fatalf("Replay() = %v, %v; want 0 and the error of the Authorizer", count, err)
+	}
+
+	if count, err := b.ReplaySince(subscription, "private", "unknown"); err == nil || err == ErrNotInHistory || count != 0 {
+		t.Fatalf("ReplaySince() = %v, %v; want 0 and the error of the Authorizer", count, err)
+	}
+}
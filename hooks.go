@@ -0,0 +1,81 @@
+package broadcast
+
+import "errors"
+
+// WithRoomCreatedHook sets a function that is called when a subscription joins a room
+// that has no subscriptions, e.g. to start producing messages for the room lazily.
+// The hook is called on the go routine joining the room.
+func WithRoomCreatedHook(hook func(room string)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("room created hook cannot be nil")
+		}
+
+		b.roomCreatedHook = hook
+		return nil
+	}
+}
+
+// WithRoomEmptiedHook sets a function that is called when the last subscription leaves a room.
+// The hook is called on the go routine leaving the room or unsubscribing.
+func WithRoomEmptiedHook(hook func(room string)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("room emptied hook cannot be nil")
+		}
+
+		b.roomEmptiedHook = hook
+		return nil
+	}
+}
+
+// WithSubscribeHook sets a function that is called with every new subscription
+// after it joined the default room.
+func WithSubscribeHook(hook func(sub *Subscription)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("subscribe hook cannot be nil")
+		}
+
+		b.subscribeHook = hook
+		return nil
+	}
+}
+
+// WithUnsubscribeHook sets a function that is called once for every
+// subscription after it was removed by Unsubscribe.
+func WithUnsubscribeHook(hook func(sub *Subscription)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("unsubscribe hook cannot be nil")
+		}
+
+		b.unsubscribeHook = hook
+		return nil
+	}
+}
+
+func (b *broadcaster) roomCreated(room string) {
+	b.measureRooms(1)
+	b.signalDemand(room)
+
+	if b.roomCreatedHook != nil {
+		b.roomCreatedHook(room)
+	}
+}
+
+func (b *broadcaster) roomsEmptied(rooms []string) {
+	b.measureRooms(-len(rooms))
+	b.touchRooms(rooms)
+	for _, room := range rooms {
+		b.signalDemand(room)
+	}
+
+	if b.roomEmptiedHook == nil {
+		return
+	}
+
+	for _, room := range rooms {
+		b.roomEmptiedHook(room)
+	}
+}
@@ -0,0 +1,126 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithHooks_WithNilHook(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomCreatedHook(nil)(b); err == nil {
+		t.Fatalf("WithRoomCreatedHook(nil); expected an error")
+	}
+
+	if err := WithRoomEmptiedHook(nil)(b); err == nil {
+		t.Fatalf("WithRoomEmptiedHook(nil); expected an error")
+	}
+
+	if err := WithSubscribeHook(nil)(b); err == nil {
+		t.Fatalf("WithSubscribeHook(nil); expected an error")
+	}
+
+	if err := WithUnsubscribeHook(nil)(b); err == nil {
+		t.Fatalf("WithUnsubscribeHook(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_RoomHooks(t *testing.T) {
+	created, emptied := []string{}, []string{}
+	b, cancel, _ := New(
+		WithRoomCreatedHook(func(room string) { created = append(created, room) }),
+		WithRoomEmptiedHook(func(room string) { emptied = append(emptied, room) }),
+	)
+	defer cancel()
+	s1 := b.Subscribe(func(_ interface{}) {})
+	s2 := b.Subscribe(func(_ interface{}) {})
+
+	b.JoinRoom(s1, "test-room")
+	b.JoinRoom(s2, "test-room")
+	b.LeaveRoom(s1, "test-room")
+	b.LeaveRoom(s2, "test-room")
+	b.JoinRoom(s1, "test-room")
+
+	if len(created) != 3 || created[0] != "default" || created[1] != "test-room" || created[2] != "test-room" {
+		t.Fatalf("room created hook was called with %v; want [default test-room test-room]", created)
+	}
+
+	if len(emptied) != 1 || emptied[0] != "test-room" {
+		t.Fatalf("room emptied hook was called with %v; want [test-room]", emptied)
+	}
+}
+
+func TestBroadcaster_Unsubscribe_ShouldCallRoomEmptiedHook(t *testing.T) {
+	emptied := []string{}
+	b, cancel, _ := New(WithRoomEmptiedHook(func(room string) { emptied = append(emptied, room) }))
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+
+	b.Unsubscribe(s)
+
+	if len(emptied) != 1 || emptied[0] != "default" {
+		t.Fatalf("room emptied hook was called with %v; want [default]", emptied)
+	}
+}
+
+func TestBroadcaster_SubscriptionHooks(t *testing.T) {
+	var subscribed, unsubscribed []*Subscription
+	b, cancel, _ := New(
+		WithSubscribeHook(func(sub *Subscription) { subscribed = append(subscribed, sub) }),
+		WithUnsubscribeHook(func(sub *Subscription) { unsubscribed = append(unsubscribed, sub) }),
+	)
+	defer cancel()
+
+	s := b.Subscribe(func(_ interface{}) {})
+	b.Unsubscribe(s)
+	b.Unsubscribe(s)
+
+	if len(subscribed) != 1 || subscribed[0] != s {
+		t.Fatalf("subscribe hook was called %d times; want once with the subscription", len(subscribed))
+	}
+
+	if len(unsubscribed) != 1 || unsubscribed[0] != s {
+		t.Fatalf("unsubscribe hook was called %d times; want once with the subscription", len(unsubscribed))
+	}
+}
+
+func TestBroadcaster_Request_ShouldNotCallRoomHooks(t *testing.T) {
+	mux := &sync.Mutex{}
+	created, emptied := []string{}, []string{}
+	b, cancel, _ := New(
+		WithRoomCreatedHook(func(room string) {
+			mux.Lock()
+			defer mux.Unlock()
+			created = append(created, room)
+		}),
+		WithRoomEmptiedHook(func(room string) {
+			mux.Lock()
+			defer mux.Unlock()
+			emptied = append(emptied, room)
+		}),
+	)
+	defer cancel()
+	s := b.Subscribe(func(data interface{}) {
+		if req, ok := data.(*Request); ok {
+			req.Reply("pong")
+		}
+	})
+	b.JoinRoom(s, "service")
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancelCtx()
+
+	b.Request(ctx, "ping", "service")
+	b.Gather(ctx, "ping", "service")
+
+	mux.Lock()
+	defer mux.Unlock()
+	if len(created) != 2 || created[0] != "default" || created[1] != "service" || len(emptied) != 0 {
+		t.Fatalf("room hooks were called with created %v and emptied %v; want [default service] and []", created, emptied)
+	}
+
+	if rooms := b.CountRooms(); rooms != 2 {
+		t.Fatalf("CountRooms() = %d after requests; want 2", rooms)
+	}
+}
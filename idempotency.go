@@ -0,0 +1,75 @@
+package broadcast
+
+import (
+	"errors"
+	"time"
+)
+
+// WithIdempotencyWindow suppresses messages whose idempotency key was already sent or received
+// for the same room within the window, e.g. when a producer retries a send or several instances
+// emit the same event. The window is kept per room: a message sent to several rooms is only
+// delivered to the rooms that haven't seen its key. Messages sent to all subscriptions use the
+// default room, messages sent to subscriptions use a window per subscription and messages sent
+// to a room pattern use a window per pattern.
+// Messages without a key are always delivered. By default keys are ignored.
+func WithIdempotencyWindow(window time.Duration) Option {
+	return func(b *broadcaster) error {
+		if window <= 0 {
+			return errors.New("idempotency window must be positive")
+		}
+
+		b.idempotency = newDeduper(window)
+		return nil
+	}
+}
+
+// WithIdempotencyKey sets the idempotency key of a message, see WithIdempotencyWindow.
+func WithIdempotencyKey(key string) SendOption {
+	return func(msg *Message) {
+		msg.IdempotencyKey = key
+	}
+}
+
+// isRepeated records the idempotency key of a message for its targets, narrows the message
+// down to the targets that haven't seen the key yet and reports whether none is left.
+func (b *broadcaster) isRepeated(msg *Message) bool {
+	if b.idempotency == nil || len(msg.IdempotencyKey) == 0 {
+		return false
+	}
+
+	now := b.clock.Now()
+	seen := func(scope string) bool {
+		return b.idempotency.add(msg.Namespace+"\x00"+scope+"\x00"+msg.IdempotencyKey, now)
+	}
+
+	switch {
+	case msg.ToAll:
+		return seen(b.defaultRoomName)
+	case len(msg.Subscribers) > 0:
+		msg.Subscribers = unseen(msg.Subscribers, func(id string) bool {
+			return seen("\x01" + id)
+		})
+		return len(msg.Subscribers) == 0
+	case len(msg.Rooms) == 0 && len(msg.RoomPattern) == 0:
+		return seen("")
+	}
+
+	msg.Rooms = unseen(msg.Rooms, seen)
+	if len(msg.RoomPattern) > 0 && seen("\x00"+msg.RoomPattern) {
+		msg.RoomPattern = ""
+	}
+
+	return len(msg.Rooms) == 0 && len(msg.RoomPattern) == 0
+}
+
+// unseen returns a new slice with the targets that seen reports false for.
+func unseen(targets []string, seen func(target string) bool) []string {
+	result := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if !seen(target) {
+			result = append(result, target)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,104 @@
+package broadcast
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithIdempotencyWindow_WithNonPositiveWindow(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithIdempotencyWindow(0)(b); err == nil {
+		t.Fatalf("WithIdempotencyWindow(0); expected an error")
+	}
+}
+
+func TestBroadcaster_WithIdempotencyWindow(t *testing.T) {
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	b, cancel, _ := New(WithSynchronousDelivery(), WithClock(clock), WithIdempotencyWindow(time.Minute))
+	defer cancel()
+	calls := 0
+	s := b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+	b.JoinRoom(s, "room-a", "room-b")
+
+	b.ToRoomWithOptions("data", "room-a", WithIdempotencyKey("event-1"))
+	b.ToRoomWithOptions("data", "room-a", WithIdempotencyKey("event-1"))
+	b.ToRoomWithOptions("data", "room-a")
+	b.ToRoomWithOptions("data", "room-a")
+
+	if calls != 3 {
+		t.Fatalf("callback was called %d times; want 3 without the repeated key", calls)
+	}
+
+	b.ToRoomWithOptions("data", "room-b", WithIdempotencyKey("event-1"))
+	clock.advance(time.Minute * 2)
+	b.ToRoomWithOptions("data", "room-a", WithIdempotencyKey("event-1"))
+
+	if calls != 5 {
+		t.Fatalf("callback was called %d times; want the key delivered to another room and after the window", calls)
+	}
+}
+
+func TestBroadcaster_WithIdempotencyWindow_AcrossInstances(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithSynchronousDelivery(), WithIdempotencyWindow(time.Minute))
+	defer cancel()
+	calls := 0
+	b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+
+	b.ToAllWithOptions("data", WithIdempotencyKey("event-1"))
+	<-dispatcher.dispatched
+	dispatcher.received(&Message{Data: "data", ToAll: true, Origin: "other", IdempotencyKey: "event-1"})
+	dispatcher.received(&Message{Data: "data", ToAll: true, Origin: "other", IdempotencyKey: "event-2"})
+	dispatcher.received(&Message{Data: "data", ToAll: true, Origin: "other", IdempotencyKey: "event-2"})
+
+	if calls != 2 {
+		t.Fatalf("callback was called %d times; want every key delivered once", calls)
+	}
+}
+
+func TestBroadcaster_WithIdempotencyWindow_ShouldFilterRooms(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery(), WithIdempotencyWindow(time.Minute))
+	defer cancel()
+	received := map[string]int{}
+	a := b.Subscribe(func(data interface{}) {
+		received["a"]++
+	})
+	b.JoinRoom(a, "room-a")
+	c := b.Subscribe(func(data interface{}) {
+		received["b"]++
+	})
+	b.JoinRoom(c, "room-b")
+
+	b.Send("data", ToRoom("room-a"), WithIdempotencyKey("event-1"))
+	b.Send("data", ToRoom("room-a", "room-b"), WithIdempotencyKey("event-1"))
+
+	if received["a"] != 1 || received["b"] != 1 {
+		t.Fatalf("rooms received %v; want the repeated key delivered only to the room that hasn't seen it", received)
+	}
+}
+
+func TestBroadcaster_WithIdempotencyWindow_PerSubscriber(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery(), WithIdempotencyWindow(time.Minute))
+	defer cancel()
+	received := map[string]int{}
+	a, _ := b.SubscribeWithID("a", func(data interface{}) {
+		received["a"]++
+	})
+	c, _ := b.SubscribeWithID("b", func(data interface{}) {
+		received["b"]++
+	})
+
+	b.Send("data", ToSubscriber(a.ID()), WithIdempotencyKey("event-1"))
+	b.Send("data", ToSubscriber(a.ID(), c.ID()), WithIdempotencyKey("event-1"))
+	b.Send("data", ToSubscriber(c.ID()), WithIdempotencyKey("event-1"))
+
+	if received["a"] != 1 || received["b"] != 1 {
+		t.Fatalf("subscriptions received %v; want the key delivered once to each subscription", received)
+	}
+}
@@ -0,0 +1,103 @@
+package broadcast
+
+import (
+	"errors"
+
+	"github.com/rs/xid"
+)
+
+// ErrDuplicateSubscriptionID is returned by SubscribeWithID when
+// a subscription with the ID already exists.
+var ErrDuplicateSubscriptionID = errors.New("subscription ID is already in use")
+
+// WithIDGenerator sets the function that generates the IDs of subscriptions, messages
+// and requests. It must return unique IDs and is called concurrently. Default generates xid IDs.
+func WithIDGenerator(generate func() string) Option {
+	return func(b *broadcaster) error {
+		if generate == nil {
+			return errors.New("ID generator cannot be nil")
+		}
+
+		b.idGenerator = generate
+		return nil
+	}
+}
+
+// SubscribeWithID works like Subscribe but the subscription has the given ID, e.g.
+// a session or user ID, so messages can be sent to it with ToSubscriber. It returns
+// ErrDuplicateSubscriptionID if a subscription with the ID exists, or the reason the
+// subscription was rejected, see WithMaxSubscriptions. The ID can be used again after
+// the subscription unsubscribed.
+func (b *broadcaster) SubscribeWithID(id string, callback func(interface{})) (*Subscription, error) {
+	if err := b.claim(id); err != nil {
+		return nil, err
+	}
+
+	sub := b.newSubscription(callback)
+	sub.id = id
+	if err := b.subscribed(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (n *namespace) SubscribeWithID(id string, callback func(interface{})) (*Subscription, error) {
+	if err := n.broadcaster.claim(id); err != nil {
+		return nil, err
+	}
+
+	sub := n.broadcaster.newSubscription(callback)
+	sub.id = id
+	if err := n.enter(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// claim reserves a custom subscription ID.
+func (b *broadcaster) claim(id string) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	if len(id) == 0 {
+		return errors.New("subscription ID cannot be empty")
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if _, ok := b.claimed[id]; ok {
+		return ErrDuplicateSubscriptionID
+	}
+
+	if len(b.subscriptions([]string{id})) > 0 {
+		return ErrDuplicateSubscriptionID
+	}
+
+	if b.claimed == nil {
+		b.claimed = make(map[string]struct{})
+	}
+
+	b.claimed[id] = struct{}{}
+	return nil
+}
+
+// unclaim releases the ID of a subscription that unsubscribed.
+func (b *broadcaster) unclaim(id string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	delete(b.claimed, id)
+}
+
+// nextID returns a new unique ID.
+func (b *broadcaster) nextID() string {
+	if b.idGenerator != nil {
+		return b.idGenerator()
+	}
+
+	return xid.New().String()
+}
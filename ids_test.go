@@ -0,0 +1,77 @@
+package broadcast
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBroadcaster_SubscribeWithID(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	var got interface{}
+	s, err := b.SubscribeWithID("user-1", func(data interface{}) {
+		got = data
+	})
+
+	if err != nil || s.ID() != "user-1" {
+		t.Fatalf("SubscribeWithID() returned %v, %v; want subscription user-1", s, err)
+	}
+
+	b.ToSubscriber("data", "user-1")
+
+	if got != "data" {
+		t.Fatalf("Subscription with custom ID received %v; want data", got)
+	}
+}
+
+func TestBroadcaster_SubscribeWithID_WithDuplicateID(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	s, _ := b.SubscribeWithID("user-1", func(_ interface{}) {})
+
+	if _, err := b.SubscribeWithID("user-1", func(_ interface{}) {}); err != ErrDuplicateSubscriptionID {
+		t.Fatalf("SubscribeWithID() with a used ID returned %v; want ErrDuplicateSubscriptionID", err)
+	}
+
+	if _, err := namespaceOf(t, b, "tenant").SubscribeWithID("user-1", func(_ interface{}) {}); err != ErrDuplicateSubscriptionID {
+		t.Fatalf("SubscribeWithID() with an ID used in another namespace returned %v; want ErrDuplicateSubscriptionID", err)
+	}
+
+	b.Unsubscribe(s)
+
+	if _, err := b.SubscribeWithID("user-1", func(_ interface{}) {}); err != nil {
+		t.Fatalf("SubscribeWithID() with a released ID returned %v; want nil", err)
+	}
+}
+
+func TestBroadcaster_SubscribeWithID_WithEmptyID(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+
+	if _, err := b.SubscribeWithID("", func(_ interface{}) {}); err == nil {
+		t.Fatalf("SubscribeWithID() with an empty ID; expected an error")
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	var next int32
+	b, cancel, _ := New(WithIDGenerator(func() string {
+		return "id-" + strconv.Itoa(int(atomic.AddInt32(&next, 1)))
+	}))
+	defer cancel()
+
+	s := b.Subscribe(func(_ interface{}) {})
+
+	if s.ID() != "id-1" {
+		t.Fatalf("Subscribe() created subscription %s; want id-1", s.ID())
+	}
+}
+
+func TestWithIDGenerator_WithNilGenerator(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithIDGenerator(nil)(b); err == nil {
+		t.Fatalf("WithIDGenerator(nil); expected an error")
+	}
+}
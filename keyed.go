@@ -0,0 +1,45 @@
+package broadcast
+
+import "errors"
+
+// ToRoomKeyed sends a message to a single subscription within a room selected by the key.
+// Messages with the same key reach the same subscription as long as it is part of the room,
+// and when subscriptions join or leave, only the keys of the leaving subscription or the keys
+// taken over by a joining one move, e.g. to assign entities to workers. Every instance
+// selects one of its own subscriptions.
+func (b *broadcaster) ToRoomKeyed(data interface{}, room string, key string) error {
+	if len(key) == 0 {
+		return errors.New("routing key cannot be empty")
+	}
+
+	return b.publish(&Message{Data: data, Rooms: []string{room}, Key: key, Keyed: true})
+}
+
+func (n *namespace) ToRoomKeyed(data interface{}, room string, key string) error {
+	if len(key) == 0 {
+		return errors.New("routing key cannot be empty")
+	}
+
+	return n.broadcaster.publish(n.message(&Message{Data: data, Rooms: []string{room}, Key: key, Keyed: true}))
+}
+
+// route returns the subscription selected by the key of a keyed message.
+// Subscriptions that are excluded from the message are not selected.
+func (b *broadcaster) route(msg *Message, subs []*Subscription) []*Subscription {
+	if !msg.Keyed {
+		return subs
+	}
+
+	candidates := make([]*Subscription, 0, len(subs))
+	for _, s := range subs {
+		if !b.isExcluded(s, msg) {
+			candidates = append(candidates, s)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return []*Subscription{rendezvous(candidates, msg.Key)}
+}
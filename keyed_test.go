@@ -0,0 +1,52 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcaster_ToRoomKeyed_ShouldPickSameSubscription(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	for i := 0; i < 5; i++ {
+		b.JoinRoom(counter.subscribe(b), "workers")
+	}
+
+	for i := 0; i < 4; i++ {
+		b.ToRoomKeyed(i, "workers", "entity-1")
+	}
+
+	if len(counter.calls) != 1 || counter.total() != 4 {
+		t.Fatalf("messages went to %d subscriptions; want all 4 to one subscription", len(counter.calls))
+	}
+}
+
+func TestBroadcaster_ToRoomKeyed_ShouldKeepKeysOfRemainingSubscriptions(t *testing.T) {
+	b := createTestBroadcaster()
+	subs := []*Subscription{}
+	for i := 0; i < 5; i++ {
+		s := b.Subscribe(func(_ interface{}) {})
+		b.JoinRoom(s, "workers")
+		subs = append(subs, s)
+	}
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	before := map[string]*Subscription{}
+	for _, key := range keys {
+		before[key] = b.targets(&Message{Rooms: []string{"workers"}, Key: key, Keyed: true})[0]
+	}
+
+	b.LeaveRoom(subs[0], "workers")
+
+	for _, key := range keys {
+		after := b.targets(&Message{Rooms: []string{"workers"}, Key: key, Keyed: true})[0]
+		if before[key] != subs[0] && after != before[key] {
+			t.Fatalf("key %s moved from %s to %s although its subscription is still in the room", key, before[key].ID(), after.ID())
+		}
+	}
+}
+
+func TestBroadcaster_ToRoomKeyed_WithEmptyKey(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := b.ToRoomKeyed("data", "workers", ""); err == nil {
+		t.Fatalf("ToRoomKeyed should fail with an empty key")
+	}
+}
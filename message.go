@@ -0,0 +1,156 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Message describes a single broadcast and its recipients.
+type Message struct {
+	// ID uniquely identifies the message.
+	ID string
+	// Origin is the instance ID of the broadcaster that sent the message, see WithInstanceID.
+	Origin string
+	// Timestamp is the time the message was sent.
+	Timestamp time.Time
+	// TTL is how long after Timestamp the message is still delivered, zero means forever.
+	TTL time.Duration
+	// Data is the payload passed to subscription callbacks.
+	Data interface{}
+	// Batch is set when Data is a []interface{} of items sent with ToRoomBatch.
+	Batch bool
+	// ToAll is set when the message is sent to all subscriptions.
+	ToAll bool
+	// Rooms are the target rooms when the message is not sent to all subscriptions.
+	Rooms []string
+	// Namespace is the namespace the message was sent in, see Broadcaster.Namespace.
+	// Subscriptions of other namespaces don't receive the message.
+	Namespace string
+	// Subscribers are the IDs of the target subscriptions, see Broadcaster.ToSubscriber.
+	Subscribers []string
+	// RoomPattern selects additional target rooms by matching their names, see path.Match.
+	RoomPattern string
+	// Cascade extends the target rooms with all of their child rooms.
+	Cascade bool
+	// Sample is the number of subscriptions picked at random that receive the message, see ToRoomSample.
+	Sample int
+	// SamplePercent is the percentage of subscriptions picked at random that receive the message, see ToRoomPercent.
+	SamplePercent float64
+	// Key selects the group member that receives the message, see WithKey.
+	Key string
+	// Keyed delivers the message to the single subscription selected by Key, see ToRoomKeyed.
+	Keyed bool
+	// CorrelationID identifies a request and the replies sent to it.
+	CorrelationID string
+	// ReplyTo is the room replies to a request are sent to.
+	// Subscriptions receive messages with ReplyTo set as a *Request.
+	ReplyTo string
+	// Except lists rooms whose subscriptions don't receive the message.
+	Except []string
+	// ExceptSubscribers lists IDs of subscriptions that don't receive the message.
+	ExceptSubscribers []string
+	// Sender is the ID of the subscription that sent the message, it doesn't receive the message, see ToRoomFrom.
+	// Dispatchers should transfer it to suppress the echo when the message returns to the sending instance.
+	Sender string
+	// Sequences are the sequence numbers of the message in its target rooms on this instance, see LastSeq.
+	Sequences map[string]uint64
+	// Producer identifies the component that sent the message, see AsProducer.
+	Producer string
+	// IdempotencyKey identifies repeated sends of the same message, see WithIdempotencyWindow.
+	// Dispatchers should transfer it to suppress duplicates emitted by several instances.
+	IdempotencyKey string
+	// ContentType is the content type of a payload encoded by the codec of a room, see WithRoomCodec.
+	// Dispatchers should transfer it so the payload can be decoded.
+	ContentType string
+	// Priority orders the delivery of the message when the pool is saturated, see WithPriority.
+	// Dispatchers should transfer it to keep the priority across instances.
+	Priority Priority
+	// Trace holds the trace context of the message, see WithTracer.
+	// Dispatchers should transfer it to keep broadcasts traced across instances.
+	Trace map[string]string
+
+	match     func(meta SubMeta) bool
+	receipt   func(receipt Receipt)
+	heartbeat bool
+	reply     bool
+	local     bool
+	remote    bool
+	audited   bool
+	ctx       context.Context
+}
+
+// SendOption changes how a single message is sent.
+type SendOption func(msg *Message)
+
+// Except excludes subscriptions that are part of the given rooms.
+func Except(rooms ...string) SendOption {
+	return func(msg *Message) {
+		msg.Except = append(msg.Except, rooms...)
+	}
+}
+
+// ExceptSubscribers excludes the subscriptions with the given IDs.
+func ExceptSubscribers(ids ...string) SendOption {
+	return func(msg *Message) {
+		msg.ExceptSubscribers = append(msg.ExceptSubscribers, ids...)
+	}
+}
+
+// LocalOnly delivers the message to the subscriptions of this instance
+// without passing it to the Dispatcher, e.g. for instance maintenance messages.
+func LocalOnly() SendOption {
+	return func(msg *Message) {
+		msg.local = true
+	}
+}
+
+// RemoteOnly passes the message to the Dispatcher without delivering it
+// to the subscriptions of this instance, e.g. to relay messages to other instances.
+func RemoteOnly() SendOption {
+	return func(msg *Message) {
+		msg.remote = true
+	}
+}
+
+// errLocalAndRemote is returned for a message sent with both LocalOnly and RemoteOnly.
+var errLocalAndRemote = errors.New("message cannot be local and remote only")
+
+// WithMessageTTL drops the message instead of delivering it once it is older than ttl,
+// e.g. when it waits in the buffer of a slow subscription, is retained or is replayed from history.
+func WithMessageTTL(ttl time.Duration) SendOption {
+	return func(msg *Message) {
+		msg.TTL = ttl
+	}
+}
+
+// Expired reports whether the TTL of the message has passed by the wall clock.
+// The broadcaster checks TTLs with its Clock, see WithClock.
+func (m *Message) Expired() bool {
+	return m.expiredAt(time.Now())
+}
+
+// expiredAt reports whether the TTL of the message has passed at now,
+// the broadcaster passes the time of its Clock.
+func (m *Message) expiredAt(now time.Time) bool {
+	return m.TTL > 0 && now.Sub(m.Timestamp) > m.TTL
+}
+
+func newMessage(data interface{}, options ...SendOption) *Message {
+	msg := &Message{Data: data}
+
+	for _, option := range options {
+		option(msg)
+	}
+
+	return msg
+}
+
+// roomsOf wraps a single room name, an empty name results in no rooms.
+func roomsOf(room string) []string {
+	if len(room) == 0 {
+		return nil
+	}
+
+	return []string{room}
+}
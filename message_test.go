@@ -0,0 +1,120 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewMessage(t *testing.T) {
+	msg := newMessage("data", Except("room-a"), ExceptSubscribers("sub-a", "sub-b"), Except("room-b"))
+
+	if msg.Data != "data" {
+		t.Fatalf("newMessage should set data; got %v", msg.Data)
+	}
+
+	if len(msg.Except) != 2 || msg.Except[0] != "room-a" || msg.Except[1] != "room-b" {
+		t.Fatalf("Except should add excluded rooms; got %v", msg.Except)
+	}
+
+	if len(msg.ExceptSubscribers) != 2 {
+		t.Fatalf("ExceptSubscribers should add excluded subscriptions; got %v", msg.ExceptSubscribers)
+	}
+}
+
+func TestMessage_Expired(t *testing.T) {
+	msg := newMessage("data", WithMessageTTL(time.Minute))
+	msg.Timestamp = time.Now().Add(-time.Hour)
+
+	if !msg.Expired() {
+		t.Fatalf("message older than its TTL should be expired")
+	}
+
+	msg.Timestamp = time.Now()
+	if msg.Expired() {
+		t.Fatalf("message younger than its TTL should not be expired")
+	}
+}
+
+func TestMessage_Expired_WithoutTTL(t *testing.T) {
+	msg := &Message{Timestamp: time.Now().Add(-time.Hour)}
+
+	if msg.Expired() {
+		t.Fatalf("message without TTL should never expire")
+	}
+}
+
+func TestBroadcaster_WithMessageTTL_ShouldDropQueuedMessages(t *testing.T) {
+	b, cancel, _ := New(WithSubscriberBuffer(10, OverflowBlock), WithOrderedDelivery())
+	defer cancel()
+	received := make(chan interface{}, 10)
+	subscription := b.Subscribe(func(data interface{}) {
+		if data == "slow" {
+			<-time.After(time.Millisecond * 100)
+		}
+		received <- data
+	})
+
+	b.ToAll("slow")
+	b.ToAllWithOptions("stale", WithMessageTTL(time.Millisecond*10))
+	b.ToAll("fresh")
+	<-time.After(time.Millisecond * 300)
+
+	if subscription.Dropped() != 1 || len(received) != 2 {
+		t.Fatalf("expired message should be dropped; received %v, dropped %v", len(received), subscription.Dropped())
+	}
+}
+
+func TestBroadcaster_LocalOnly_ShouldSkipDispatcher(t *testing.T) {
+	dispatcher := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	called := false
+	b.Subscribe(func(_ interface{}) { called = true })
+
+	if err := b.ToAllWithOptions("data", LocalOnly()); err != nil {
+		t.Fatalf("ToAllWithOptions() returned %v", err)
+	}
+
+	if !called {
+		t.Fatalf("LocalOnly message was not delivered locally")
+	}
+
+	if len(dispatcher.dispatched) != 0 {
+		t.Fatalf("LocalOnly message was dispatched")
+	}
+}
+
+func TestBroadcaster_RemoteOnly_ShouldSkipLocalDelivery(t *testing.T) {
+	dispatcher := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	called := false
+	s := b.Subscribe(func(_ interface{}) { called = true })
+	b.JoinRoom(s, "room")
+
+	if err := b.ToRoomWithOptions("data", "room", RemoteOnly()); err != nil {
+		t.Fatalf("ToRoomWithOptions() returned %v", err)
+	}
+
+	if called {
+		t.Fatalf("RemoteOnly message was delivered locally")
+	}
+
+	if msg := <-dispatcher.dispatched; msg.Data != "data" {
+		t.Fatalf("Dispatched message has data %v; want data", msg.Data)
+	}
+}
+
+func TestBroadcaster_LocalOnly_RemoteOnly_ShouldFail(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+
+	if err := b.ToAllWithOptions("data", LocalOnly(), RemoteOnly()); err == nil {
+		t.Fatalf("ToAllWithOptions() with LocalOnly and RemoteOnly should fail")
+	}
+
+	if _, err := b.(*broadcaster).publishSync(context.Background(), newMessage("data", LocalOnly(), RemoteOnly())); err == nil {
+		t.Fatalf("publishSync() with LocalOnly and RemoteOnly should fail")
+	}
+}
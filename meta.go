@@ -0,0 +1,46 @@
+package broadcast
+
+// SubMeta holds the attributes of a subscription, e.g. user ID, locale or device type.
+type SubMeta map[string]string
+
+// SubscribeOption changes how a single subscription is created.
+type SubscribeOption func(s *Subscription)
+
+// WithMeta attaches an attribute to the subscription.
+func WithMeta(key, value string) SubscribeOption {
+	return func(s *Subscription) {
+		if s.meta == nil {
+			s.meta = SubMeta{}
+		}
+
+		s.meta[key] = value
+	}
+}
+
+// SubscribeWithOptions works like Subscribe but the subscription is changed with subscribe options.
+func (b *broadcaster) SubscribeWithOptions(callback func(interface{}), options ...SubscribeOption) *Subscription {
+	sub := b.newSubscription(callback)
+	for _, option := range options {
+		option(sub)
+	}
+	b.subscribed(sub)
+
+	return sub
+}
+
+// ToMatching sends a message to all subscriptions within the default room whose attributes
+// are accepted by match. The predicate can't be sent to other instances, so the message
+// is only delivered to local subscriptions and is not passed to the Dispatcher.
+func (b *broadcaster) ToMatching(data interface{}, match func(meta SubMeta) bool) error {
+	return b.publishLocal(&Message{Data: data, ToAll: true, match: match})
+}
+
+// Meta returns a copy of the attributes of the subscription.
+func (s *Subscription) Meta() SubMeta {
+	meta := make(SubMeta, len(s.meta))
+	for k, v := range s.meta {
+		meta[k] = v
+	}
+
+	return meta
+}
@@ -0,0 +1,41 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcaster_SubscribeWithOptions_ShouldAttachMeta(t *testing.T) {
+	b := createTestBroadcaster()
+
+	s := b.SubscribeWithOptions(func(_ interface{}) {}, WithMeta("locale", "en"), WithMeta("device", "ios"))
+
+	meta := s.Meta()
+	if meta["locale"] != "en" || meta["device"] != "ios" {
+		t.Fatalf("Meta returned %v; want locale and device", meta)
+	}
+
+	meta["locale"] = "de"
+	if s.Meta()["locale"] != "en" {
+		t.Fatalf("Meta should return a copy")
+	}
+}
+
+func TestBroadcaster_ToMatching(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	english, german := 0, 0
+	b.SubscribeWithOptions(func(_ interface{}) { english++ }, WithMeta("locale", "en"))
+	b.SubscribeWithOptions(func(_ interface{}) { german++ }, WithMeta("locale", "de"))
+	b.Subscribe(func(_ interface{}) { german++ })
+
+	b.ToMatching("hello", func(meta SubMeta) bool {
+		return meta["locale"] == "en"
+	})
+
+	if english != 1 || german != 0 {
+		t.Fatalf("ToMatching delivered to %d matching and %d other subscriptions; want 1 and 0", english, german)
+	}
+
+	if len(dispatcher.dispatched) != 0 {
+		t.Fatalf("ToMatching should not dispatch the message")
+	}
+}
@@ -0,0 +1,109 @@
+package broadcast
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives the measurements of a broadcaster. It is implemented by adapters for
+// metrics systems, e.g. the Metrics of the prombroadcast module, which registers the measurements
+// with Prometheus. Methods are called concurrently and should not block.
+type Metrics interface {
+	// MessageSent is called for each target room of a message sent by this instance.
+	// Messages sent to subscription IDs are reported with an empty room.
+	MessageSent(room string)
+	// MessageDelivered is called when a subscription callback completed, with the time since the message was sent.
+	MessageDelivered(latency time.Duration)
+	// MessagesDropped is called when messages were not delivered to a subscription, see Subscription.Dropped.
+	MessagesDropped(count int)
+	// DispatchFailed is called when a FallibleDispatcher returns an error.
+	DispatchFailed()
+	// SubscriptionsChanged is called with +1 for a new subscription and -1 when it unsubscribes.
+	SubscriptionsChanged(delta int)
+	// RoomsChanged is called with +1 when a room gets its first subscription and -1 when it becomes empty.
+	RoomsChanged(delta int)
+	// PoolUtilization is called when a pool go routine starts or finishes a task
+	// with the number of busy go routines and the pool size. The pool is empty
+	// for the shared pool and the room name for pools set by WithRoomPool.
+	PoolUtilization(pool string, busy int, size int)
+}
+
+// WithMetrics sets the Metrics that receive the measurements of the broadcaster.
+func WithMetrics(metrics Metrics) Option {
+	return func(b *broadcaster) error {
+		if metrics == nil {
+			return errors.New("metrics cannot be nil")
+		}
+
+		b.metrics = metrics
+		return nil
+	}
+}
+
+// observePools reports the utilization of the shared pool and all room pools to the metrics.
+func (b *broadcaster) observePools() {
+	if b.metrics == nil {
+		return
+	}
+
+	b.pool.observe = b.poolObserver("")
+	for room, p := range b.roomPools {
+		p.observe = b.poolObserver(room)
+	}
+}
+
+func (b *broadcaster) poolObserver(name string) func(busy int, size int) {
+	return func(busy int, size int) {
+		b.metrics.PoolUtilization(name, busy, size)
+	}
+}
+
+// dropped counts messages that were not delivered to a subscription.
+func (b *broadcaster) dropped(s *Subscription, count int) {
+	atomic.AddUint64(&s.dropped, uint64(count))
+	atomic.AddUint64(&b.counters.dropped, uint64(count))
+
+	if b.metrics != nil {
+		b.metrics.MessagesDropped(count)
+	}
+}
+
+func (b *broadcaster) measureSent(msg *Message) {
+	atomic.AddUint64(&b.counters.sent, 1)
+
+	if b.metrics == nil {
+		return
+	}
+
+	if len(msg.Subscribers) > 0 || isReply(msg) {
+		b.metrics.MessageSent("")
+		return
+	}
+
+	for _, room := range b.targetRooms(msg) {
+		b.metrics.MessageSent(room)
+	}
+}
+
+func (b *broadcaster) measureDelivered(msg *Message) {
+	atomic.AddUint64(&b.counters.delivered, 1)
+
+	if b.metrics != nil {
+		b.metrics.MessageDelivered(b.clock.Now().Sub(msg.Timestamp))
+	}
+}
+
+func (b *broadcaster) measureSubscriptions(delta int) {
+	atomic.AddInt64(&b.counters.subscriptions, int64(delta))
+
+	if b.metrics != nil {
+		b.metrics.SubscriptionsChanged(delta)
+	}
+}
+
+func (b *broadcaster) measureRooms(delta int) {
+	if b.metrics != nil && delta != 0 {
+		b.metrics.RoomsChanged(delta)
+	}
+}
@@ -0,0 +1,128 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithMetrics_WithNilMetrics(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithMetrics(nil)(b); err == nil {
+		t.Fatalf("WithMetrics(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_WithMetrics(t *testing.T) {
+	m := &recordingMetrics{}
+	dispatcher := &failingDispatcher{}
+	b, cancel, _ := New(WithMetrics(m), WithDispatcher(dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "test-room")
+
+	b.ToRoom("data", "test-room")
+	b.LeaveRoom(s, "test-room")
+	b.Unsubscribe(s)
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if len(m.sent) != 1 || m.sent[0] != "test-room" || m.delivered != 1 || m.dispatchFailed != 1 {
+		t.Fatalf("metrics recorded sent %v, %d deliveries and %d dispatch failures; want test-room, 1 and 1", m.sent, m.delivered, m.dispatchFailed)
+	}
+
+	if m.subscriptions != 0 || m.rooms != 0 || m.pools[""] == 0 {
+		t.Fatalf("metrics recorded %d subscriptions, %d rooms and pool size %d; want 0, 0 and the pool size", m.subscriptions, m.rooms, m.pools[""])
+	}
+}
+
+func TestBroadcaster_WithMetrics_RoomPool(t *testing.T) {
+	m := &recordingMetrics{}
+	b, cancel, _ := New(WithMetrics(m), WithRoomPool("slow", 3))
+	defer cancel()
+	done := make(chan struct{})
+	s := b.Subscribe(func(_ interface{}) {
+		close(done)
+	})
+	b.JoinRoom(s, "slow")
+
+	b.ToRoom("data", "slow")
+	waitOrTimeout(done)
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.pools["slow"] != 3 {
+		t.Fatalf("metrics recorded room pool size %d; want 3", m.pools["slow"])
+	}
+}
+
+type recordingMetrics struct {
+	mux            sync.Mutex
+	sent           []string
+	delivered      int
+	dropped        int
+	dispatchFailed int
+	subscriptions  int
+	rooms          int
+	pools          map[string]int
+}
+
+func (m *recordingMetrics) MessageSent(room string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.sent = append(m.sent, room)
+}
+
+func (m *recordingMetrics) MessageDelivered(_ time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.delivered++
+}
+
+func (m *recordingMetrics) MessagesDropped(count int) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.dropped += count
+}
+
+func (m *recordingMetrics) DispatchFailed() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.dispatchFailed++
+}
+
+func (m *recordingMetrics) SubscriptionsChanged(delta int) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.subscriptions += delta
+}
+
+func (m *recordingMetrics) RoomsChanged(delta int) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.rooms += delta
+}
+
+func (m *recordingMetrics) PoolUtilization(pool string, _ int, size int) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.pools == nil {
+		m.pools = make(map[string]int)
+	}
+	m.pools[pool] = size
+}
+
+type failingDispatcher struct {
+	noopDispatcher
+}
+
+func (d *failingDispatcher) DispatchMessage(_ *Message) {}
+
+func (d *failingDispatcher) ReceivedMessage(_ func(msg *Message)) {}
+
+func (d *failingDispatcher) TryDispatchMessage(_ *Message) error {
+	return errors.New("broker unavailable")
+}
@@ -0,0 +1,63 @@
+package broadcast
+
+import "errors"
+
+// SendFunc sends a message.
+type SendFunc func(msg *Message) error
+
+// Middleware wraps the sending of messages. It can inspect or modify a message before
+// passing it to next, or drop it by returning without calling next. The error it
+// returns is returned to the sender, e.g. by ToRoom.
+type Middleware func(next SendFunc) SendFunc
+
+// WithMiddleware adds middleware that is applied to every message sent by this instance
+// before it is authorized, dispatched and delivered. Messages received through the
+// Dispatcher already passed the middleware of the sending instance. Middleware runs in
+// the order it is added, the first one receives the message first. The message has
+// its ID and timestamp set.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(b *broadcaster) error {
+		for _, m := range middleware {
+			if m == nil {
+				return errors.New("middleware cannot be nil")
+			}
+		}
+
+		b.middleware = append(b.middleware, middleware...)
+		return nil
+	}
+}
+
+// WithInboundMiddleware adds middleware that is applied to every message received through
+// the Dispatcher before it is delivered to local subscriptions, e.g. to validate, decode or
+// authenticate messages coming from the broker. Echoes and duplicates are dropped before and
+// payloads are decrypted and decompressed before the middleware runs. A message is dropped
+// when the middleware doesn't call next, an error it returns is reported to the handler set
+// by WithPayloadErrorHandler. Middleware runs in the order it is added.
+func WithInboundMiddleware(middleware ...Middleware) Option {
+	return func(b *broadcaster) error {
+		for _, m := range middleware {
+			if m == nil {
+				return errors.New("inbound middleware cannot be nil")
+			}
+		}
+
+		b.inboundMiddleware = append(b.inboundMiddleware, middleware...)
+		return nil
+	}
+}
+
+// intercept passes a message through the middleware to the given send function.
+func (b *broadcaster) intercept(msg *Message, send SendFunc) error {
+	b.stamp(msg)
+	return chain(b.middleware, send)(msg)
+}
+
+// chain wraps the send function with the middleware, the first one runs first.
+func chain(middleware []Middleware, send SendFunc) SendFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		send = middleware[i](send)
+	}
+
+	return send
+}
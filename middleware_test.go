@@ -0,0 +1,111 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithMiddleware_WithNilMiddleware(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithMiddleware(nil)(b); err == nil {
+		t.Fatalf("WithMiddleware(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_WithMiddleware_ShouldRunInOrder(t *testing.T) {
+	order := []string{}
+	layer := func(name string) Middleware {
+		return func(next SendFunc) SendFunc {
+			return func(msg *Message) error {
+				order = append(order, name)
+				msg.Data = msg.Data.(string) + "+" + name
+				return next(msg)
+			}
+		}
+	}
+	b, cancel, _ := New(WithMiddleware(layer("a"), layer("b")), WithSynchronousDelivery())
+	defer cancel()
+	var got interface{}
+	b.Subscribe(func(data interface{}) {
+		got = data
+	})
+
+	b.ToAll("data")
+
+	if got != "data+a+b" || len(order) != 2 || order[0] != "a" {
+		t.Fatalf("Subscription received %v after middleware %v; want data+a+b after [a b]", got, order)
+	}
+}
+
+func TestBroadcaster_WithMiddleware_ShouldDropMessages(t *testing.T) {
+	rejected := errors.New("rejected")
+	dispatched := false
+	dispatcher := &mockDispatcher{dispatch: func(_ interface{}, _ bool, _ string, _ ...string) {
+		dispatched = true
+	}}
+	b, cancel, _ := New(WithDispatcher(dispatcher), WithSynchronousDelivery(), WithMiddleware(func(next SendFunc) SendFunc {
+		return func(msg *Message) error {
+			if msg.Data == "invalid" {
+				return rejected
+			}
+			return next(msg)
+		}
+	}))
+	defer cancel()
+	called := false
+	b.Subscribe(func(_ interface{}) {
+		called = true
+	})
+
+	err := b.ToRoom("invalid", "default")
+
+	if err != rejected || called || dispatched {
+		t.Fatalf("ToRoom() returned %v, delivered %v and dispatched %v; want the middleware error and no delivery", err, called, dispatched)
+	}
+}
+
+func TestWithInboundMiddleware_WithNilMiddleware(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithInboundMiddleware(nil)(b); err == nil {
+		t.Fatalf("WithInboundMiddleware(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_WithInboundMiddleware_ShouldFilterReceivedMessages(t *testing.T) {
+	forged := errors.New("forged")
+	var reported error
+	dispatcher := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(
+		WithDispatcher(dispatcher),
+		WithSynchronousDelivery(),
+		WithPayloadErrorHandler(func(err error) { reported = err }),
+		WithInboundMiddleware(func(next SendFunc) SendFunc {
+			return func(msg *Message) error {
+				if msg.Trace["signature"] != "valid" {
+					return forged
+				}
+				msg.Data = "verified " + msg.Data.(string)
+				return next(msg)
+			}
+		}),
+	)
+	defer cancel()
+	received := []interface{}{}
+	b.Subscribe(func(data interface{}) {
+		received = append(received, data)
+	})
+
+	dispatcher.received(&Message{ID: "1", Origin: "other", Data: "data", ToAll: true, Trace: map[string]string{"signature": "valid"}})
+	dispatcher.received(&Message{ID: "2", Origin: "other", Data: "data", ToAll: true})
+	b.ToAll("local")
+
+	if len(received) != 2 || received[0] != "verified data" || received[1] != "local" {
+		t.Fatalf("Subscription received %v; want the verified and the local message", received)
+	}
+
+	if reported != forged {
+		t.Fatalf("Payload error handler received %v; want %v", reported, forged)
+	}
+}
@@ -0,0 +1,340 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrForeignSubscription is returned when a namespace is used with
+// a subscription that was created in another namespace.
+var ErrForeignSubscription = errors.New("subscription belongs to another namespace")
+
+// Namespace returns a view of the broadcaster whose rooms are isolated from the rooms of other
+// namespaces. Room names are prefixed with the namespace name and the room separator, also
+// in messages passed to the Dispatcher, and subscriptions created through the view join the
+// default room of the namespace. Messages sent through the view are never delivered to
+// subscriptions of other namespaces, and sending to all reaches only the namespace.
+// The name cannot be empty or contain the room separator, so the rooms of two namespaces
+// never share a prefix.
+func (b *broadcaster) Namespace(name string) (Broadcaster, error) {
+	if err := b.checkNamespace(name); err != nil {
+		return nil, err
+	}
+
+	return b.namespace(name), nil
+}
+
+func (b *broadcaster) checkNamespace(name string) error {
+	if len(name) == 0 {
+		return errors.New("namespace name cannot be empty")
+	}
+
+	if strings.Contains(name, b.roomSeparator) {
+		return errors.New("namespace name cannot contain the room separator")
+	}
+
+	return nil
+}
+
+// namespace returns the view of a namespace whose name was checked before.
+func (b *broadcaster) namespace(name string) *namespace {
+	return &namespace{
+		broadcaster: b,
+		name:        name,
+		prefix:      name + b.roomSeparator,
+	}
+}
+
+type namespace struct {
+	broadcaster *broadcaster
+	name        string
+	prefix      string
+}
+
+func (n *namespace) room(name string) string {
+	return n.prefix + name
+}
+
+func (n *namespace) rooms(names []string) []string {
+	if names == nil {
+		return nil
+	}
+
+	rooms := make([]string, len(names))
+	for i, name := range names {
+		rooms[i] = n.room(name)
+	}
+
+	return rooms
+}
+
+// message moves a message into the namespace.
+func (n *namespace) message(msg *Message) *Message {
+	if msg.ToAll {
+		msg.ToAll = false
+		msg.Rooms = []string{n.room(n.broadcaster.defaultRoomName)}
+	} else {
+		msg.Rooms = n.rooms(msg.Rooms)
+	}
+
+	if len(msg.RoomPattern) > 0 {
+		msg.RoomPattern = n.room(msg.RoomPattern)
+	}
+
+	msg.Except = n.rooms(msg.Except)
+	msg.Namespace = n.name
+	return msg
+}
+
+func (n *namespace) owns(s *Subscription) bool {
+	return s.namespace == n.name
+}
+
+func (n *namespace) subscribed(sub *Subscription) *Subscription {
+	n.enter(sub)
+	return sub
+}
+
+// enter adds a new subscription to the default room of the namespace and calls the subscribe hook.
+// It returns the error of a subscription that was rejected.
+func (n *namespace) enter(sub *Subscription) error {
+	sub.namespace = n.name
+	sub.broadcaster = n
+	if n.broadcaster.isClosed() {
+		sub.closed = 1
+		return ErrBroadcasterClosed
+	}
+
+	if err := n.broadcaster.enter(sub, n.room(n.broadcaster.defaultRoomName)); err != nil {
+		return err
+	}
+
+	if n.broadcaster.subscribeHook != nil {
+		n.broadcaster.subscribeHook(sub)
+	}
+
+	return nil
+}
+
+func (n *namespace) Subscribe(callback func(interface{})) *Subscription {
+	return n.subscribed(n.broadcaster.newSubscription(callback))
+}
+
+func (n *namespace) SubscribeAck(callback func(interface{}) error) *Subscription {
+	sub := n.broadcaster.newSubscription(nil)
+	sub.ackCallback = callback
+	return n.subscribed(sub)
+}
+
+func (n *namespace) SubscribeMessage(callback func(*Message)) *Subscription {
+	sub := n.broadcaster.newSubscription(nil)
+	sub.handler = callback
+	return n.subscribed(sub)
+}
+
+func (n *namespace) SubscribeWithOptions(callback func(interface{}), options ...SubscribeOption) *Subscription {
+	sub := n.broadcaster.newSubscription(callback)
+	for _, option := range options {
+		option(sub)
+	}
+
+	return n.subscribed(sub)
+}
+
+func (n *namespace) Unsubscribe(s *Subscription) {
+	if n.owns(s) {
+		n.broadcaster.Unsubscribe(s)
+	}
+}
+
+func (n *namespace) JoinRoom(s *Subscription, rooms ...string) error {
+	if !n.owns(s) {
+		return ErrForeignSubscription
+	}
+
+	return n.broadcaster.JoinRoom(s, n.rooms(rooms)...)
+}
+
+func (n *namespace) LeaveRoom(s *Subscription, rooms ...string) {
+	if n.owns(s) {
+		n.broadcaster.LeaveRoom(s, n.rooms(rooms)...)
+	}
+}
+
+func (n *namespace) JoinTree(s *Subscription, rooms ...string) error {
+	if !n.owns(s) {
+		return ErrForeignSubscription
+	}
+
+	return n.broadcaster.JoinTree(s, n.rooms(rooms)...)
+}
+
+func (n *namespace) LeaveTree(s *Subscription, rooms ...string) {
+	if n.owns(s) {
+		n.broadcaster.LeaveTree(s, n.rooms(rooms)...)
+	}
+}
+
+func (n *namespace) JoinGroup(s *Subscription, groups ...string) error {
+	if !n.owns(s) {
+		return ErrForeignSubscription
+	}
+
+	return n.broadcaster.JoinGroup(s, n.rooms(groups)...)
+}
+
+func (n *namespace) LeaveGroup(s *Subscription, groups ...string) {
+	if n.owns(s) {
+		n.broadcaster.LeaveGroup(s, n.rooms(groups)...)
+	}
+}
+
+func (n *namespace) ToAll(data interface{}, except ...string) error {
+	return n.broadcaster.publish(n.message(&Message{Data: data, ToAll: true, Except: except}))
+}
+
+func (n *namespace) ToAllWithOptions(data interface{}, options ...SendOption) error {
+	msg := newMessage(data, options...)
+	msg.ToAll = true
+	return n.broadcaster.publish(n.message(msg))
+}
+
+func (n *namespace) ToRoom(data interface{}, room string, except ...string) error {
+	return n.broadcaster.publish(n.message(&Message{Data: data, Rooms: []string{room}, Except: except}))
+}
+
+func (n *namespace) ToSubscriber(data interface{}, id string) error {
+	return n.broadcaster.publish(n.message(&Message{Data: data, Subscribers: []string{id}}))
+}
+
+func (n *namespace) ToRoomWithOptions(data interface{}, room string, options ...SendOption) error {
+	msg := newMessage(data, options...)
+	msg.Rooms = []string{room}
+	return n.broadcaster.publish(n.message(msg))
+}
+
+func (n *namespace) ToRooms(data interface{}, rooms []string, except ...string) error {
+	return n.broadcaster.publish(n.message(&Message{Data: data, Rooms: rooms, Except: except}))
+}
+
+func (n *namespace) ToRoomBatch(items []interface{}, room string, except ...string) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	return n.broadcaster.publish(n.message(&Message{Data: items, Rooms: []string{room}, Except: except, Batch: true}))
+}
+
+func (n *namespace) ToMatching(data interface{}, match func(meta SubMeta) bool) error {
+	return n.broadcaster.publishLocal(n.message(&Message{Data: data, ToAll: true, match: match}))
+}
+
+func (n *namespace) ToRoomPattern(data interface{}, pattern string, except ...string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	return n.broadcaster.publish(n.message(&Message{Data: data, RoomPattern: pattern, Except: except}))
+}
+
+func (n *namespace) Request(ctx context.Context, data interface{}, room string) (interface{}, error) {
+	return n.broadcaster.Request(ctx, data, n.room(room))
+}
+
+func (n *namespace) Gather(ctx context.Context, data interface{}, room string) ([]Reply, error) {
+	return n.broadcaster.Gather(ctx, data, n.room(room))
+}
+
+func (n *namespace) Replay(s *Subscription, room string, since time.Time) (int, error) {
+	if !n.owns(s) {
+		return 0, ErrForeignSubscription
+	}
+
+	return n.broadcaster.Replay(s, n.room(room), since)
+}
+
+func (n *namespace) ReplaySince(s *Subscription, room string, id string) (int, error) {
+	if !n.owns(s) {
+		return 0, ErrForeignSubscription
+	}
+
+	return n.broadcaster.ReplaySince(s, n.room(room), id)
+}
+
+func (n *namespace) ToAllSync(ctx context.Context, data interface{}, except ...string) (int, error) {
+	return n.broadcaster.publishSync(ctx, n.message(&Message{Data: data, ToAll: true, Except: except}))
+}
+
+func (n *namespace) ToRoomSync(ctx context.Context, data interface{}, room string, except ...string) (int, error) {
+	return n.broadcaster.publishSync(ctx, n.message(&Message{Data: data, Rooms: []string{room}, Except: except}))
+}
+
+func (n *namespace) RoomsOf(s *Subscription) []string {
+	rooms := []string{}
+	for _, room := range n.broadcaster.RoomsOf(s) {
+		if strings.HasPrefix(room, n.prefix) {
+			rooms = append(rooms, strings.TrimPrefix(room, n.prefix))
+		}
+	}
+
+	return rooms
+}
+
+func (n *namespace) Subscribers(room string) []string {
+	return n.broadcaster.Subscribers(n.room(room))
+}
+
+func (n *namespace) ForEachSubscriber(room string, fn func(info SubscriberInfo) bool) {
+	n.broadcaster.ForEachSubscriber(n.room(room), fn)
+}
+
+func (n *namespace) CountSubscribers(room string) int {
+	return n.broadcaster.CountSubscribers(n.room(room))
+}
+
+func (n *namespace) CountRooms() int {
+	return len(n.Rooms(func(_ string, info RoomInfo) bool {
+		return info.Subscribers > 0
+	}))
+}
+
+func (n *namespace) ClusterSubscribers(room string) []Member {
+	return n.broadcaster.ClusterSubscribers(n.room(room))
+}
+
+func (n *namespace) CreateRoom(name string, meta map[string]string) {
+	n.broadcaster.CreateRoom(n.room(name), meta)
+}
+
+func (n *namespace) Rooms(filter func(name string, info RoomInfo) bool) []RoomInfo {
+	rooms := []RoomInfo{}
+	for _, info := range n.broadcaster.Rooms(nil) {
+		if !strings.HasPrefix(info.Name, n.prefix) {
+			continue
+		}
+
+		info.Name = strings.TrimPrefix(info.Name, n.prefix)
+		if filter == nil || filter(info.Name, info) {
+			rooms = append(rooms, info)
+		}
+	}
+
+	return rooms
+}
+
+// Namespace returns a namespace nested in this one. It stays part of this namespace:
+// rooms of this namespace that start with the nested name and the separator are its rooms.
+func (n *namespace) Namespace(name string) (Broadcaster, error) {
+	if err := n.broadcaster.checkNamespace(name); err != nil {
+		return nil, err
+	}
+
+	return n.broadcaster.namespace(n.room(name)), nil
+}
+
+func (n *namespace) Done() <-chan struct{} {
+	return n.broadcaster.Done()
+}
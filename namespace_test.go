@@ -0,0 +1,111 @@
+package broadcast
+
+import "testing"
+
+func TestNamespace_ShouldIsolateRooms(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	tenantA, tenantB := namespaceOf(t, b, "a"), namespaceOf(t, b, "b")
+	var receivedA, receivedB, receivedRoot []interface{}
+	sa := tenantA.Subscribe(func(data interface{}) { receivedA = append(receivedA, data) })
+	sb := tenantB.Subscribe(func(data interface{}) { receivedB = append(receivedB, data) })
+	b.Subscribe(func(data interface{}) { receivedRoot = append(receivedRoot, data) })
+	tenantA.JoinRoom(sa, "chat")
+	tenantB.JoinRoom(sb, "chat")
+
+	tenantA.ToRoom("room", "chat")
+	tenantA.ToAll("all")
+
+	if len(receivedA) != 2 || len(receivedB) != 0 || len(receivedRoot) != 0 {
+		t.Fatalf("received %d, %d and %d messages; want only the namespace to receive 2", len(receivedA), len(receivedB), len(receivedRoot))
+	}
+
+	if rooms := tenantA.RoomsOf(sa); len(rooms) != 2 {
+		t.Fatalf("RoomsOf returned %v; want the rooms without prefix", rooms)
+	}
+
+	if count := tenantA.CountSubscribers("chat"); count != 1 {
+		t.Fatalf("CountSubscribers returned %d; want 1", count)
+	}
+}
+
+func TestNamespace_ShouldPrefixDispatchedRooms(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithSynchronousDelivery())
+	defer cancel()
+
+	namespaceOf(t, b, "a").ToRoom("data", "chat", "muted")
+	msg := <-dispatcher.dispatched
+
+	if len(msg.Rooms) != 1 || msg.Rooms[0] != "a/chat" || msg.Except[0] != "a/muted" || msg.Namespace != "a" {
+		t.Fatalf("dispatched message %+v; want rooms prefixed with the namespace", msg)
+	}
+}
+
+func TestNamespace_ToSubscriber_ShouldNotReachOtherNamespaces(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	sb := namespaceOf(t, b, "b").Subscribe(func(_ interface{}) { calls++ })
+
+	namespaceOf(t, b, "a").ToSubscriber("data", sb.ID())
+
+	if calls != 0 {
+		t.Fatalf("subscription of another namespace received the message")
+	}
+}
+
+func TestNamespace_JoinRoom_WithForeignSubscription(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	sb := namespaceOf(t, b, "b").Subscribe(func(_ interface{}) {})
+
+	if err := namespaceOf(t, b, "a").JoinRoom(sb, "chat"); err != ErrForeignSubscription {
+		t.Fatalf("JoinRoom returned %v; want %v", err, ErrForeignSubscription)
+	}
+}
+
+func TestBroadcaster_Namespace_WithInvalidName(t *testing.T) {
+	b := createTestBroadcaster()
+
+	for _, name := range []string{"", "a/b"} {
+		if _, err := b.Namespace(name); err == nil {
+			t.Fatalf("Namespace(%q) should return an error", name)
+		}
+	}
+}
+
+func TestNamespace_Namespace(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	parent := namespaceOf(t, b, "a")
+	nested, err := parent.Namespace("b")
+	if err != nil {
+		t.Fatalf("Namespace() returned %v; want nil", err)
+	}
+	calls := 0
+	s := nested.Subscribe(func(_ interface{}) { calls++ })
+	nested.JoinRoom(s, "x")
+
+	if _, err := parent.Namespace("b/c"); err == nil {
+		t.Fatalf("Namespace() with the room separator should return an error")
+	}
+
+	nested.ToRoom("data", "x")
+
+	if calls != 1 {
+		t.Fatalf("nested namespace delivered %d messages; want 1", calls)
+	}
+}
+
+// namespaceOf returns the namespace with the given name and fails the test if it is invalid.
+func namespaceOf(t *testing.T, b Broadcaster, name string) Broadcaster {
+	t.Helper()
+
+	n, err := b.Namespace(name)
+	if err != nil {
+		t.Fatalf("Namespace(%q) returned %v", name, err)
+	}
+
+	return n
+}
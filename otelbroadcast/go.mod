@@ -0,0 +1,12 @@
+module github.com/go-broadcast/broadcast/otelbroadcast
+
+go 1.16
+
+require (
+	github.com/go-broadcast/broadcast v0.0.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
+
+replace github.com/go-broadcast/broadcast => ../
@@ -0,0 +1,90 @@
+// Package otelbroadcast traces a broadcaster with OpenTelemetry. It is a separate module,
+// so the broadcast package doesn't depend on OpenTelemetry:
+//
+//	tracer := otelbroadcast.NewTracer(otel.GetTracerProvider(), otel.GetTextMapPropagator())
+//	b, cancel, err := broadcast.New(broadcast.WithTracer(tracer))
+//
+// The trace context travels in Message.Trace, so a message sent with
+// broadcast.WithTraceContext continues the trace of the request that sent it:
+//
+//	carrier := propagation.MapCarrier{}
+//	otel.GetTextMapPropagator().Inject(r.Context(), carrier)
+//	b.ToRoomWithOptions(data, room, broadcast.WithTraceContext(carrier))
+package otelbroadcast
+
+import (
+	"context"
+
+	"github.com/go-broadcast/broadcast"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/go-broadcast/broadcast/otelbroadcast"
+
+// Tracer implements broadcast.Tracer with an OpenTelemetry tracer. It extracts the parent
+// span from Message.Trace and injects the context of the new span into the returned carrier.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer creates a Tracer that starts spans with the provider and
+// carries their context with the propagator, e.g. a TraceContext propagator.
+func NewTracer(provider trace.TracerProvider, propagator propagation.TextMapPropagator) *Tracer {
+	return &Tracer{
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagator,
+	}
+}
+
+// Start starts a span for the message. Sending and dispatching are producer spans,
+// receiving and delivering are consumer spans.
+func (t *Tracer) Start(name string, msg *broadcast.Message) (broadcast.Span, map[string]string) {
+	ctx := t.propagator.Extract(context.Background(), propagation.MapCarrier(msg.Trace))
+
+	attributes := []attribute.KeyValue{
+		attribute.String("messaging.system", "broadcast"),
+		attribute.String("messaging.message_id", msg.ID),
+		attribute.Bool("broadcast.to_all", msg.ToAll),
+	}
+	if len(msg.Rooms) > 0 {
+		attributes = append(attributes, attribute.StringSlice("broadcast.rooms", msg.Rooms))
+	}
+	if len(msg.Origin) > 0 {
+		attributes = append(attributes, attribute.String("broadcast.origin", msg.Origin))
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(spanKind(name)), trace.WithAttributes(attributes...))
+
+	carrier := propagation.MapCarrier{}
+	t.propagator.Inject(ctx, carrier)
+	return otelSpan{span}, carrier
+}
+
+func spanKind(name string) trace.SpanKind {
+	switch name {
+	case broadcast.SpanSend, broadcast.SpanDispatch:
+		return trace.SpanKindProducer
+	case broadcast.SpanReceive, broadcast.SpanDeliver:
+		return trace.SpanKindConsumer
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+// otelSpan ends an OpenTelemetry span, recording the error of the traced work.
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
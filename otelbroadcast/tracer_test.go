@@ -0,0 +1,71 @@
+package otelbroadcast
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-broadcast/broadcast"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer() (*Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return NewTracer(provider, propagation.TraceContext{}), recorder
+}
+
+func TestTracer_WithBroadcaster(t *testing.T) {
+	tracer, recorder := newTestTracer()
+	b, cancel, _ := broadcast.New(broadcast.WithTracer(tracer), broadcast.WithSynchronousDelivery())
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "chat")
+
+	b.ToRoom("hello", "chat")
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	send, deliver := spans[broadcast.SpanSend], spans[broadcast.SpanDeliver]
+	if send == nil || deliver == nil {
+		t.Fatalf("recorded spans %v; want send and deliver spans", spans)
+	}
+
+	if send.SpanKind() != trace.SpanKindProducer || deliver.SpanKind() != trace.SpanKindConsumer {
+		t.Fatalf("recorded %v send and %v deliver spans; want producer and consumer", send.SpanKind(), deliver.SpanKind())
+	}
+
+	if deliver.Parent().SpanID() != send.SpanContext().SpanID() {
+		t.Fatalf("deliver span is not a child of the send span")
+	}
+
+	for _, attr := range send.Attributes() {
+		if attr.Key == "messaging.message_id" && len(attr.Value.AsString()) > 0 {
+			return
+		}
+	}
+	t.Fatalf("send span has no message ID in %v", send.Attributes())
+}
+
+func TestTracer_Start_ShouldContinueTrace(t *testing.T) {
+	tracer, recorder := newTestTracer()
+	parent, carrier := tracer.Start(broadcast.SpanSend, &broadcast.Message{ID: "1"})
+
+	child, _ := tracer.Start(broadcast.SpanReceive, &broadcast.Message{ID: "1", Trace: carrier})
+	child.End(errors.New("failed"))
+	parent.End(nil)
+
+	spans := recorder.Ended()
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Fatalf("receive span is not a child of the span in the carrier")
+	}
+
+	if len(spans[0].Events()) != 1 || spans[0].Status().Description != "failed" {
+		t.Fatalf("receive span did not record the error")
+	}
+}
@@ -0,0 +1,93 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// fanoutChunks is the number of chunks per second a paced fan-out is split into.
+const fanoutChunks = 10
+
+// WithFanoutRate limits how many deliveries per second are scheduled, e.g. to keep push gateways
+// or databases called by subscription callbacks from being overwhelmed by a message to a room
+// with 100k subscriptions. Deliveries are scheduled in chunks of a tenth of the rate, the chunks
+// that exceed the rate wait for their turn. The rate is shared by all messages and the deliveries
+// of a message are never dropped, only delayed. By default deliveries are scheduled at once.
+func WithFanoutRate(rate float64) Option {
+	return func(b *broadcaster) error {
+		if rate <= 0 {
+			return errors.New("fan-out rate must be positive")
+		}
+
+		b.pacer = newPacer(rate, b.clock)
+		return nil
+	}
+}
+
+// pacer spreads deliveries over time by reserving a time slot for every chunk.
+type pacer struct {
+	mux   *sync.Mutex
+	rate  float64
+	chunk int
+	next  time.Time
+	clock Clock
+}
+
+func newPacer(rate float64, clock Clock) *pacer {
+	chunk := int(rate / fanoutChunks)
+	if chunk < 1 {
+		chunk = 1
+	}
+
+	return &pacer{
+		mux:   &sync.Mutex{},
+		rate:  rate,
+		chunk: chunk,
+		clock: clock,
+	}
+}
+
+// pace calls schedule with the subscriptions in chunks, each at the time reserved for it.
+// Delayed chunks are tracked, without counting as a delivery, so a synchronous sender
+// also waits for them.
+func (p *pacer) pace(subs []*Subscription, t *tracker, schedule func(chunk []*Subscription)) {
+	for start := 0; start < len(subs); start += p.chunk {
+		end := start + p.chunk
+		if end > len(subs) {
+			end = len(subs)
+		}
+		chunk := subs[start:end]
+
+		delay := p.reserve(len(chunk))
+		if delay <= 0 {
+			schedule(chunk)
+			continue
+		}
+
+		d := delivery{tracker: t}
+		if t != nil {
+			t.add()
+		}
+
+		p.clock.AfterFunc(delay, func() {
+			schedule(chunk)
+			d.finish(false)
+		})
+	}
+}
+
+// reserve reserves the time for n deliveries and returns how long they have to wait for it.
+func (p *pacer) reserve(n int) time.Duration {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	now := p.clock.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+
+	delay := p.next.Sub(now)
+	p.next = p.next.Add(time.Duration(float64(n) / p.rate * float64(time.Second)))
+	return delay
+}
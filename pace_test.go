@@ -0,0 +1,61 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPacer_reserve(t *testing.T) {
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	p := newPacer(100, clock)
+
+	if p.chunk != 10 {
+		t.Fatalf("newPacer(100) has chunks of %d; want 10", p.chunk)
+	}
+
+	if delay := p.reserve(10); delay != 0 {
+		t.Fatalf("reserve() of an idle pacer = %v; want 0", delay)
+	}
+
+	if delay := p.reserve(10); delay != time.Millisecond*100 {
+		t.Fatalf("reserve() of the second chunk = %v; want 100ms", delay)
+	}
+
+	clock.advance(time.Second)
+	if delay := p.reserve(10); delay != 0 {
+		t.Fatalf("reserve() after the reserved time passed = %v; want 0", delay)
+	}
+}
+
+func TestBroadcaster_WithFanoutRate_ShouldSpreadDeliveries(t *testing.T) {
+	b, cancel, _ := New(WithFanoutRate(100))
+	defer cancel()
+	var delivered int32
+	for i := 0; i < 30; i++ {
+		b.Subscribe(func(_ interface{}) {
+			atomic.AddInt32(&delivered, 1)
+		})
+	}
+	start := time.Now()
+
+	n, err := b.ToAllSync(context.Background(), "data")
+
+	if err != nil || n != 30 || atomic.LoadInt32(&delivered) != 30 {
+		t.Fatalf("ToAllSync() = %d, %v with %d deliveries; want 30 deliveries", n, err, delivered)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond*180 {
+		t.Fatalf("30 deliveries at 100 per second took %v; want about 200ms", elapsed)
+	}
+}
+
+func TestWithFanoutRate_WithNonPositiveRate(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithFanoutRate(0)(b); err == nil {
+		t.Fatalf("WithFanoutRate(0); expected an error")
+	}
+}
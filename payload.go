@@ -0,0 +1,114 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Payloads passed through the Dispatcher are encoded with a leading kind byte,
+// so byte slices are received as they were sent.
+const (
+	payloadBytes byte = iota
+	payloadJSON
+)
+
+// WithPayloadErrorHandler sets a function that is called when the payload of a message
+// can't be prepared for the Dispatcher or a received message can't be restored, e.g. because
+// decryption failed or inbound middleware returned an error, see WithInboundMiddleware.
+// Such messages are not dispatched or delivered. By default errors are ignored.
+func WithPayloadErrorHandler(handler func(err error)) Option {
+	return func(b *broadcaster) error {
+		if handler == nil {
+			return errors.New("payload error handler cannot be nil")
+		}
+
+		b.payloadErrorHandler = handler
+		return nil
+	}
+}
+
+// seal converts the payload of a message into the byte slice passed to the Dispatcher.
+// Payloads are left as they are when no option requires the conversion.
+func (b *broadcaster) seal(data interface{}) (interface{}, error) {
+	if b.encryptor == nil && b.compressor == nil {
+		return data, nil
+	}
+
+	payload, err := encodePayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err = b.compress(payload)
+	if err != nil || b.encryptor == nil {
+		return payload, err
+	}
+
+	return b.encryptor.Encrypt(payload)
+}
+
+// unseal restores a payload received through the Dispatcher.
+func (b *broadcaster) unseal(data interface{}) (interface{}, error) {
+	if b.encryptor == nil && b.compressor == nil {
+		return data, nil
+	}
+
+	payload, ok := data.([]byte)
+	if !ok {
+		return nil, errors.New("received payload is not a byte slice")
+	}
+
+	var err error
+	if b.encryptor != nil {
+		if payload, err = b.encryptor.Decrypt(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if payload, err = b.decompress(payload); err != nil {
+		return nil, err
+	}
+
+	return decodePayload(payload)
+}
+
+func (b *broadcaster) payloadError(err error) {
+	if b.payloadErrorHandler != nil {
+		b.payloadErrorHandler(err)
+	}
+}
+
+// encodePayload encodes byte slices as they are and everything else as JSON.
+func encodePayload(data interface{}) ([]byte, error) {
+	if raw, ok := data.([]byte); ok {
+		return append([]byte{payloadBytes}, raw...), nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{payloadJSON}, encoded...), nil
+}
+
+// decodePayload decodes a payload encoded by encodePayload. JSON payloads
+// are decoded into the generic types of encoding/json.
+func decodePayload(payload []byte) (interface{}, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("received payload is empty")
+	}
+
+	switch payload[0] {
+	case payloadBytes:
+		return payload[1:], nil
+	case payloadJSON:
+		var data interface{}
+		if err := json.Unmarshal(payload[1:], &data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	default:
+		return nil, errors.New("received payload has an unknown encoding")
+	}
+}
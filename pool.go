@@ -1,17 +1,61 @@
 package broadcast
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const defaultPoolSize int32 = 100
 const defaultPoolTimeout time.Duration = time.Minute * 5
 
+// workerQueueSize is the number of tasks that can wait in the queue of a busy worker.
+const workerQueueSize = 16
+
+// pool runs tasks on a bounded number of go routines. A task is handed to an idle worker,
+// or to a new worker while the pool is below its size, or queued at a busy worker.
+// Idle workers steal queued tasks from busy ones and take tasks with a higher priority first.
+// When all queues are full, do blocks until a worker takes the task, unless
+// maxPending callers are already blocked, see WithPoolQueueLimit.
 type pool struct {
-	cancelc chan struct{}
-	tickets chan struct{}
-	tasks   chan func()
-	timeout time.Duration
+	cancelc    chan struct{}
+	tickets    chan struct{}
+	tasks      [priorityLevels]chan func()
+	wake       chan struct{}
+	timeout    time.Duration
+	min        int32
+	workers    int32
+	busy       int32
+	pending    int32
+	maxPending int32
+	spillover  SpilloverPolicy
+	next       uint32
+	mux        *sync.RWMutex
+	queues     []*workerQueue
+	clock      Clock
+	observe    func(busy int, size int)
+	drop       func()
+}
+
+// workerQueue holds the tasks waiting for a busy worker by priority level.
+type workerQueue [priorityLevels]chan func()
+
+func newPool(size int, timeout time.Duration) *pool {
+	p := &pool{
+		cancelc:    make(chan struct{}),
+		tickets:    make(chan struct{}, size),
+		wake:       make(chan struct{}, 1),
+		timeout:    timeout,
+		maxPending: unlimitedPending,
+		mux:        &sync.RWMutex{},
+		clock:      realClock{},
+	}
+
+	for i := range p.tasks {
+		p.tasks[i] = make(chan func())
+	}
+
+	return p
 }
 
 func (p *pool) cancel() {
@@ -24,31 +68,241 @@ func (p *pool) cancel() {
 	}
 }
 
-func (p *pool) worker(task func()) {
-	task()
-	timeout := time.After(p.timeout)
+// do runs the task with normal priority, see doPriority.
+func (p *pool) do(task func()) bool {
+	return p.doPriority(task, PriorityNormal)
+}
+
+// doPriority runs the task on a pool go routine and reports whether the task
+// was scheduled before the pool was canceled and not dropped by the spillover policy.
+// When the pool is saturated, queued tasks with a higher priority run first.
+func (p *pool) doPriority(task func(), priority Priority) bool {
+	return p.submit(task, priority, p.spillover)
+}
+
+// doOrRun works like do but runs the task on the calling go routine instead of dropping it.
+func (p *pool) doOrRun(task func()) bool {
+	return p.submit(task, PriorityNormal, SpilloverInline)
+}
+
+func (p *pool) submit(task func(), priority Priority, spillover SpilloverPolicy) bool {
+	level := priority.level()
+
+	select {
+	case <-p.cancelc:
+		return false
+	case p.tasks[level] <- task:
+		return true
+	default:
+	}
+
+	select {
+	case p.tickets <- struct{}{}:
+		p.spawn(task)
+		return true
+	default:
+	}
+
+	if p.enqueue(task, level) {
+		return true
+	}
+
+	if p.maxPending != unlimitedPending {
+		if atomic.AddInt32(&p.pending, 1) > p.maxPending {
+			atomic.AddInt32(&p.pending, -1)
+			return p.spill(task, spillover)
+		}
+		defer atomic.AddInt32(&p.pending, -1)
+	}
+
+	select {
+	case <-p.cancelc:
+		return false
+	case p.tasks[level] <- task:
+	case p.tickets <- struct{}{}:
+		p.spawn(task)
+	}
+
+	return true
+}
+
+func (p *pool) spawn(task func()) {
+	atomic.AddInt32(&p.workers, 1)
+	queue := &workerQueue{}
+	for i := range queue {
+		queue[i] = make(chan func(), workerQueueSize)
+	}
+
+	p.mux.Lock()
+	p.queues = append(p.queues, queue)
+	p.mux.Unlock()
+
+	go func() {
+		p.worker(task, queue)
+		<-p.tickets
+	}()
+}
+
+// enqueue queues the task at the next busy worker with room in its queue
+// and wakes an idle worker to steal it.
+func (p *pool) enqueue(task func(), level int) bool {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	n := len(p.queues)
+	start := int(atomic.AddUint32(&p.next, 1))
+	for i := 0; i < n; i++ {
+		select {
+		case p.queues[(start+i)%n][level] <- task:
+			select {
+			case p.wake <- struct{}{}:
+			default:
+			}
+			return true
+		default:
+		}
+	}
+
+	return false
+}
+
+func (p *pool) worker(task func(), queue *workerQueue) {
+	p.run(task)
+
+	idle := p.clock.NewTimer(p.timeout)
+	defer idle.Stop()
 
 	for {
+		if t := p.take(queue); t != nil {
+			p.run(t)
+			continue
+		}
+
+		if !idle.Stop() {
+			select {
+			case <-idle.C():
+			default:
+			}
+		}
+		idle.Reset(p.timeout)
+
 		select {
-		case t := <-p.tasks:
-			t()
-		case <-timeout:
-			return
+		case t := <-queue[levelHigh]:
+			p.run(t)
+		case t := <-queue[levelNormal]:
+			p.run(t)
+		case t := <-queue[levelLow]:
+			p.run(t)
+		case t := <-p.tasks[levelHigh]:
+			p.run(t)
+		case t := <-p.tasks[levelNormal]:
+			p.run(t)
+		case t := <-p.tasks[levelLow]:
+			p.run(t)
+		case <-p.wake:
+		case <-idle.C():
+			if p.retire() {
+				p.exit(queue)
+				return
+			}
 		case <-p.cancelc:
+			atomic.AddInt32(&p.workers, -1)
+			p.exit(queue)
 			return
 		}
 	}
 }
 
-func (p *pool) do(task func()) {
-	select {
-	case <-p.cancelc:
-		return
-	case p.tasks <- task:
-	case p.tickets <- struct{}{}:
-		go func() {
-			p.worker(task)
-			<-p.tickets
-		}()
+// take returns the task with the highest priority from the queue of the worker,
+// the queues of other workers or a blocked sender.
+func (p *pool) take(queue *workerQueue) func() {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	for level := levelHigh; level >= levelLow; level-- {
+		select {
+		case t := <-queue[level]:
+			return t
+		default:
+		}
+
+		for _, q := range p.queues {
+			select {
+			case t := <-q[level]:
+				return t
+			default:
+			}
+		}
+
+		select {
+		case t := <-p.tasks[level]:
+			return t
+		default:
+		}
+	}
+
+	return nil
+}
+
+// retire reports whether an idle worker may exit without going below the minimum size.
+func (p *pool) retire() bool {
+	for {
+		workers := atomic.LoadInt32(&p.workers)
+		if workers <= p.min {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt32(&p.workers, workers, workers-1) {
+			return true
+		}
+	}
+}
+
+// exit removes the queue of a worker and runs the tasks that are still in it.
+func (p *pool) exit(queue *workerQueue) {
+	p.mux.Lock()
+	for i, q := range p.queues {
+		if q == queue {
+			p.queues = append(p.queues[:i:i], p.queues[i+1:]...)
+			break
+		}
+	}
+	p.mux.Unlock()
+
+	for level := levelHigh; level >= levelLow; level-- {
+		for len(queue[level]) > 0 {
+			p.run(<-queue[level])
+		}
 	}
 }
+
+// run runs a task, counting the busy go routines and reporting them to the observer.
+func (p *pool) run(task func()) {
+	p.busyChanged(atomic.AddInt32(&p.busy, 1))
+	defer func() {
+		p.busyChanged(atomic.AddInt32(&p.busy, -1))
+	}()
+
+	task()
+}
+
+func (p *pool) busyChanged(busy int32) {
+	if p.observe != nil {
+		p.observe(int(busy), cap(p.tickets))
+	}
+}
+
+// queued returns the number of tasks waiting in worker queues.
+func (p *pool) queued() int {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	n := 0
+	for _, q := range p.queues {
+		for _, c := range q {
+			n += len(c)
+		}
+	}
+
+	return n
+}
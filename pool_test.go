@@ -119,11 +119,79 @@ func TestPool_cancel_ShouldCancelWorkersAndPendingTasks(t *testing.T) {
 	}
 }
 
-func createTestPool() *pool {
-	return &pool{
-		cancelc: make(chan struct{}),
-		tickets: make(chan struct{}, 1),
-		tasks:   make(chan func()),
-		timeout: time.Minute * 5,
+func TestPool_do_WorkerShouldNotExitAtMinSize(t *testing.T) {
+	p := createTestPool()
+	p.timeout = time.Millisecond
+	p.min = 1
+
+	p.do(func() {})
+	<-time.After(time.Millisecond * 200)
+
+	workers := len(p.tickets)
+	if workers != 1 {
+		t.Fatalf("worker should not exit when the pool is at its min size")
 	}
 }
+
+func TestPool_do_ShouldQueueTaskAtBusyWorker(t *testing.T) {
+	p := createTestPool()
+	release := make(chan struct{})
+	p.do(func() {
+		<-release
+	})
+
+	done := make(chan struct{})
+	scheduled := make(chan struct{})
+	go func() {
+		p.do(func() {
+			close(done)
+		})
+		close(scheduled)
+	}()
+
+	select {
+	case <-scheduled:
+	case <-time.After(time.Second * 3):
+		t.Fatalf("do should not block while the worker queue has room")
+	}
+
+	if queued := p.queued(); queued != 1 {
+		t.Fatalf("queued() = %d; want 1", queued)
+	}
+
+	close(release)
+	waitOrTimeout(done)
+}
+
+func TestPool_do_IdleWorkerShouldStealQueuedTask(t *testing.T) {
+	p := createTestPool()
+	p.tickets = make(chan struct{}, 2)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+
+	// Fill the queue of the first worker while it is blocked.
+	p.do(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	queue := p.queues[0]
+	done := make(chan struct{})
+	queue[levelNormal] <- func() {
+		close(done)
+	}
+
+	// A second worker runs its task and then takes the queued one.
+	p.do(func() {})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 3):
+		t.Fatalf("idle worker should steal the task queued at a busy worker")
+	}
+}
+
+func createTestPool() *pool {
+	return newPool(1, time.Minute*5)
+}
@@ -0,0 +1,217 @@
+package broadcast
+
+import (
+	"sort"
+	"sync"
+)
+
+// Subscribers returns the sorted IDs of the subscriptions within a room.
+func (b *broadcaster) Subscribers(room string) []string {
+	r := b.rooms.get(room)
+
+	ids := []string{}
+	if r == nil {
+		return ids
+	}
+
+	for _, s := range r.snapshot() {
+		ids = append(ids, s.id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// SubscriberInfo describes a subscription within a room.
+type SubscriberInfo struct {
+	ID    string
+	Meta  SubMeta
+	Stats SubscriptionStats
+}
+
+// ForEachSubscriber calls fn with every subscription within a room in the order they
+// joined it until fn returns false. The subscriptions are taken from a snapshot of the
+// room, so fn runs without holding a lock and subscriptions that join or leave the room
+// meanwhile don't change the iteration.
+func (b *broadcaster) ForEachSubscriber(room string, fn func(info SubscriberInfo) bool) {
+	r := b.rooms.get(room)
+	if r == nil {
+		return
+	}
+
+	for _, s := range r.snapshot() {
+		if !fn(SubscriberInfo{ID: s.id, Meta: s.Meta(), Stats: s.stats()}) {
+			return
+		}
+	}
+}
+
+// CountSubscribers returns the number of subscriptions within a room.
+func (b *broadcaster) CountSubscribers(room string) int {
+	r := b.rooms.get(room)
+	if r == nil {
+		return 0
+	}
+
+	return r.count()
+}
+
+// CountRooms returns the number of rooms that have at least one subscription,
+// including the default room.
+func (b *broadcaster) CountRooms() int {
+	count := 0
+	b.rooms.each(func(_ string, r *room) bool {
+		if r.count() > 0 {
+			count++
+		}
+		return true
+	})
+
+	return count
+}
+
+// Member is a subscription within a room and the instance it belongs to.
+type Member struct {
+	ID       string
+	Instance string
+}
+
+// WithClusterPresence shares room membership with other instances through the Dispatcher, which
+// must implement PresenceDispatcher, so ClusterSubscribers also returns the subscriptions of other
+// instances. Presence events are dispatched on the go routine joining or leaving a room.
+// The view of other instances is eventually consistent: an instance announces its subscriptions
+// to every instance that starts and its subscriptions are forgotten when it is canceled.
+func WithClusterPresence() Option {
+	return func(b *broadcaster) error {
+		b.cluster = newClusterView()
+		return nil
+	}
+}
+
+// ClusterSubscribers returns the subscriptions within a room on this and, with WithClusterPresence,
+// all other instances, sorted by instance and ID.
+func (b *broadcaster) ClusterSubscribers(room string) []Member {
+	members := []Member{}
+	for _, id := range b.Subscribers(room) {
+		members = append(members, Member{ID: id, Instance: b.instanceID})
+	}
+
+	if b.cluster != nil {
+		members = append(members, b.cluster.members(room)...)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Instance != members[j].Instance {
+			return members[i].Instance < members[j].Instance
+		}
+
+		return members[i].ID < members[j].ID
+	})
+
+	return members
+}
+
+// clusterView holds the room membership of other instances.
+type clusterView struct {
+	mux   *sync.RWMutex
+	rooms map[string]map[string]string
+}
+
+func newClusterView() *clusterView {
+	return &clusterView{
+		mux:   &sync.RWMutex{},
+		rooms: make(map[string]map[string]string),
+	}
+}
+
+func (v *clusterView) apply(event PresenceEvent) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if len(event.Room) == 0 {
+		if event.Joined {
+			return
+		}
+
+		for name, subs := range v.rooms {
+			for id, instance := range subs {
+				if instance == event.Instance {
+					delete(subs, id)
+				}
+			}
+
+			if len(subs) == 0 {
+				delete(v.rooms, name)
+			}
+		}
+
+		return
+	}
+
+	subs := v.rooms[event.Room]
+	if event.Joined {
+		if subs == nil {
+			subs = make(map[string]string)
+			v.rooms[event.Room] = subs
+		}
+
+		subs[event.Subscription] = event.Instance
+		return
+	}
+
+	delete(subs, event.Subscription)
+	if len(subs) == 0 {
+		delete(v.rooms, event.Room)
+	}
+}
+
+func (v *clusterView) members(room string) []Member {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+
+	members := []Member{}
+	for id, instance := range v.rooms[room] {
+		members = append(members, Member{ID: id, Instance: instance})
+	}
+
+	return members
+}
+
+// announce tells other instances that a subscription joined or left a room.
+func (b *broadcaster) announce(s *Subscription, room string, joined bool) {
+	if b.cluster == nil {
+		return
+	}
+
+	b.dispatchPresence(PresenceEvent{
+		Instance:     b.instanceID,
+		Room:         room,
+		Subscription: s.id,
+		Joined:       joined,
+	})
+}
+
+func (b *broadcaster) dispatchPresence(event PresenceEvent) {
+	b.dispatcher.(PresenceDispatcher).DispatchPresence(event)
+}
+
+// receivePresence updates the view of other instances and announces
+// the local subscriptions to an instance that started.
+func (b *broadcaster) receivePresence(event PresenceEvent) {
+	if event.Instance == b.instanceID {
+		return
+	}
+
+	b.cluster.apply(event)
+
+	if len(event.Room) > 0 || !event.Joined {
+		return
+	}
+
+	b.rooms.each(func(name string, r *room) bool {
+		for _, s := range r.snapshot() {
+			b.announce(s, name, true)
+		}
+		return true
+	})
+}
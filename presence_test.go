@@ -0,0 +1,159 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcaster_Subscribers(t *testing.T) {
+	b := createTestBroadcaster()
+	s1 := b.Subscribe(func(_ interface{}) {})
+	s2 := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s1, "test-room")
+	b.JoinRoom(s2, "test-room")
+
+	ids := b.Subscribers("test-room")
+
+	if len(ids) != 2 || (ids[0] != s1.ID() && ids[0] != s2.ID()) || ids[0] > ids[1] {
+		t.Fatalf("Subscribers returned %v; want the sorted IDs of both subscriptions", ids)
+	}
+}
+
+func TestBroadcaster_Subscribers_WithMissingRoom(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if ids := b.Subscribers("missing"); len(ids) != 0 {
+		t.Fatalf("Subscribers of a missing room returned %v; want none", ids)
+	}
+}
+
+func TestBroadcaster_ForEachSubscriber(t *testing.T) {
+	b := createTestBroadcaster()
+	s1 := b.SubscribeWithOptions(func(_ interface{}) {}, WithMeta("user", "a"))
+	s2 := b.Subscribe(func(_ interface{}) {})
+	s3 := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s1, "test-room")
+	b.JoinRoom(s2, "test-room")
+
+	infos := []SubscriberInfo{}
+	b.ForEachSubscriber("test-room", func(info SubscriberInfo) bool {
+		b.JoinRoom(s3, "test-room")
+		infos = append(infos, info)
+		return true
+	})
+
+	if len(infos) != 2 || infos[0].ID != s1.ID() || infos[0].Meta["user"] != "a" || infos[1].ID != s2.ID() || infos[1].Stats.ID != s2.ID() {
+		t.Fatalf("ForEachSubscriber visited %v; want the two subscriptions of the snapshot in join order", infos)
+	}
+}
+
+func TestBroadcaster_ForEachSubscriber_Stop(t *testing.T) {
+	b := createTestBroadcaster()
+	b.JoinRoom(b.Subscribe(func(_ interface{}) {}), "test-room")
+	b.JoinRoom(b.Subscribe(func(_ interface{}) {}), "test-room")
+
+	visited := 0
+	b.ForEachSubscriber("test-room", func(_ SubscriberInfo) bool {
+		visited++
+		return false
+	})
+	b.ForEachSubscriber("missing", func(_ SubscriberInfo) bool {
+		visited++
+		return true
+	})
+
+	if visited != 1 {
+		t.Fatalf("ForEachSubscriber visited %d subscriptions; want 1", visited)
+	}
+}
+
+func TestBroadcaster_CountSubscribers(t *testing.T) {
+	b := createTestBroadcaster()
+	s1 := b.Subscribe(func(_ interface{}) {})
+	s2 := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s1, "test-room")
+	b.JoinRoom(s2, "test-room")
+	b.LeaveRoom(s2, "test-room")
+
+	if count := b.CountSubscribers("test-room"); count != 1 {
+		t.Fatalf("CountSubscribers returned %d; want 1", count)
+	}
+
+	if count := b.CountSubscribers("missing"); count != 0 {
+		t.Fatalf("CountSubscribers of a missing room returned %d; want 0", count)
+	}
+}
+
+func TestBroadcaster_CountRooms(t *testing.T) {
+	b := createTestBroadcaster()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "room-a", "room-b")
+	b.LeaveRoom(s, "room-b")
+
+	if count := b.CountRooms(); count != 2 {
+		t.Fatalf("CountRooms returned %d; want 2", count)
+	}
+}
+
+func TestNew_WithClusterPresenceRequiresPresenceDispatcher(t *testing.T) {
+	_, _, err := New(WithClusterPresence())
+
+	if err == nil {
+		t.Fatalf("New with cluster presence and a dispatcher without presence support; expected an error")
+	}
+}
+
+func TestBroadcaster_ClusterSubscribers(t *testing.T) {
+	hub := &presenceHub{}
+	b1, cancel1, _ := New(WithDispatcher(hub.dispatcher()), WithInstanceID("b1"), WithClusterPresence())
+	defer cancel1()
+	s1 := b1.Subscribe(func(_ interface{}) {})
+	b1.JoinRoom(s1, "test-room")
+	b2, cancel2, _ := New(WithDispatcher(hub.dispatcher()), WithInstanceID("b2"), WithClusterPresence())
+	s2 := b2.Subscribe(func(_ interface{}) {})
+	b2.JoinRoom(s2, "test-room")
+
+	members := b1.ClusterSubscribers("test-room")
+
+	if len(members) != 2 || members[0] != (Member{ID: s1.ID(), Instance: "b1"}) || members[1] != (Member{ID: s2.ID(), Instance: "b2"}) {
+		t.Fatalf("ClusterSubscribers returned %v; want members of both instances", members)
+	}
+
+	members = b2.ClusterSubscribers("test-room")
+
+	if len(members) != 2 {
+		t.Fatalf("ClusterSubscribers of a started instance returned %v; want members of both instances", members)
+	}
+
+	b2.LeaveRoom(s2, "test-room")
+	cancel2()
+
+	if members := b1.ClusterSubscribers("test-room"); len(members) != 1 {
+		t.Fatalf("ClusterSubscribers returned %v after the other instance left; want 1 member", members)
+	}
+
+	if members := b1.ClusterSubscribers("default"); len(members) != 1 {
+		t.Fatalf("ClusterSubscribers returned %v after the other instance stopped; want 1 member", members)
+	}
+}
+
+// presenceHub connects the presence events of several broadcasters.
+type presenceHub struct {
+	callbacks []func(event PresenceEvent)
+}
+
+func (h *presenceHub) dispatcher() *presenceDispatcher {
+	return &presenceDispatcher{hub: h}
+}
+
+type presenceDispatcher struct {
+	noopDispatcher
+	hub *presenceHub
+}
+
+func (d *presenceDispatcher) DispatchPresence(event PresenceEvent) {
+	for _, callback := range d.hub.callbacks {
+		callback(event)
+	}
+}
+
+func (d *presenceDispatcher) ReceivedPresence(callback func(event PresenceEvent)) {
+	d.hub.callbacks = append(d.hub.callbacks, callback)
+}
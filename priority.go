@@ -0,0 +1,43 @@
+package broadcast
+
+// Priority orders the delivery of messages when the pool is saturated.
+type Priority int
+
+const (
+	// PriorityLow is used for bulk traffic that can wait for other messages.
+	PriorityLow Priority = -1
+	// PriorityNormal is the priority of messages sent without WithPriority.
+	PriorityNormal Priority = 0
+	// PriorityHigh is used for critical messages, e.g. kick notifications or system alerts.
+	PriorityHigh Priority = 1
+)
+
+// Pool queue levels by priority.
+const (
+	levelLow = iota
+	levelNormal
+	levelHigh
+	priorityLevels
+)
+
+// WithPriority sets the priority of a message. While all pool go routines are busy,
+// deliveries of messages with a higher priority start before the others.
+// It doesn't change the order of messages delivered to an ordered subscription, see WithOrderedDelivery.
+func WithPriority(priority Priority) SendOption {
+	return func(msg *Message) {
+		msg.Priority = priority
+	}
+}
+
+// level returns the pool queue level of the priority.
+// Priorities above high or below low are treated as high or low.
+func (p Priority) level() int {
+	switch {
+	case p >= PriorityHigh:
+		return levelHigh
+	case p <= PriorityLow:
+		return levelLow
+	default:
+		return levelNormal
+	}
+}
@@ -0,0 +1,73 @@
+package broadcast
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWithPriority(t *testing.T) {
+	msg := newMessage("data", WithPriority(PriorityHigh))
+
+	if msg.Priority != PriorityHigh {
+		t.Fatalf("WithPriority(PriorityHigh); got priority %v", msg.Priority)
+	}
+}
+
+func TestPriority_level(t *testing.T) {
+	tests := map[Priority]int{
+		Priority(-5):   levelLow,
+		PriorityLow:    levelLow,
+		PriorityNormal: levelNormal,
+		PriorityHigh:   levelHigh,
+		Priority(5):    levelHigh,
+	}
+
+	for priority, want := range tests {
+		if got := priority.level(); got != want {
+			t.Fatalf("Priority(%d).level() = %d; want %d", priority, got, want)
+		}
+	}
+}
+
+func TestPool_doPriority_ShouldRunHighPriorityTasksFirst(t *testing.T) {
+	p := createTestPool()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.do(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mux sync.Mutex
+	order := []Priority{}
+	var wg sync.WaitGroup
+	for _, priority := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		priority := priority
+		wg.Add(1)
+		p.doPriority(func() {
+			mux.Lock()
+			order = append(order, priority)
+			mux.Unlock()
+			wg.Done()
+		}, priority)
+	}
+	close(release)
+	wg.Wait()
+
+	if order[0] != PriorityHigh || order[1] != PriorityNormal || order[2] != PriorityLow {
+		t.Fatalf("tasks ran in order %v; want high, normal, low", order)
+	}
+}
+
+func TestBroadcaster_ToAllWithOptions_WithPriority(t *testing.T) {
+	b := createTestBroadcaster()
+	received := make(chan struct{})
+	b.Subscribe(func(_ interface{}) {
+		close(received)
+	})
+
+	b.ToAllWithOptions("data", WithPriority(PriorityHigh))
+
+	waitOrTimeout(received)
+}
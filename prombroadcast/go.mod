@@ -0,0 +1,10 @@
+module github.com/go-broadcast/broadcast/prombroadcast
+
+go 1.16
+
+require (
+	github.com/go-broadcast/broadcast v0.0.0
+	github.com/prometheus/client_golang v1.12.2
+)
+
+replace github.com/go-broadcast/broadcast => ../
@@ -0,0 +1,172 @@
+// Package prombroadcast exposes the measurements of a broadcaster as Prometheus metrics.
+// It is a separate module, so the broadcast package doesn't depend on the Prometheus client:
+//
+//	metrics, err := prombroadcast.NewMetrics(prometheus.DefaultRegisterer)
+//	b, cancel, err := broadcast.New(broadcast.WithMetrics(metrics))
+//	http.Handle("/metrics", promhttp.Handler())
+package prombroadcast
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the delivery latency histogram.
+var DefaultBuckets = []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5}
+
+// Metrics implements broadcast.Metrics with Prometheus counters, gauges and a latency histogram.
+type Metrics struct {
+	sent          *prometheus.CounterVec
+	delivered     prometheus.Counter
+	latency       prometheus.Histogram
+	dropped       prometheus.Counter
+	dispatchFails prometheus.Counter
+	subscriptions prometheus.Gauge
+	rooms         prometheus.Gauge
+	poolBusy      *prometheus.GaugeVec
+	poolSize      *prometheus.GaugeVec
+	mux           *sync.RWMutex
+	roomLimit     int
+	labeled       map[string]prometheus.Counter
+}
+
+// NewMetrics creates Metrics and registers them with the registerer. The delivery latency
+// histogram uses the given bucket upper bounds in seconds or DefaultBuckets.
+func NewMetrics(registerer prometheus.Registerer, buckets ...float64) (*Metrics, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	m := &Metrics{
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "broadcast_messages_sent_total",
+			Help: "Messages sent per target room, see LabelRooms.",
+		}, []string{"room"}),
+		delivered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "broadcast_deliveries_total",
+			Help: "Completed subscription callbacks.",
+		}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "broadcast_delivery_latency_seconds",
+			Help:    "Time from sending a message until a subscription callback completed.",
+			Buckets: buckets,
+		}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "broadcast_messages_dropped_total",
+			Help: "Messages not delivered to a subscription.",
+		}),
+		dispatchFails: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "broadcast_dispatch_failures_total",
+			Help: "Messages the dispatcher failed to send.",
+		}),
+		subscriptions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "broadcast_subscriptions",
+			Help: "Active subscriptions.",
+		}),
+		rooms: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "broadcast_rooms",
+			Help: "Rooms with at least one subscription.",
+		}),
+		poolBusy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "broadcast_pool_busy_workers",
+			Help: "Pool go routines running a task, per room pool or with an empty room for the shared pool.",
+		}, []string{"room"}),
+		poolSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "broadcast_pool_size",
+			Help: "Maximum number of pool go routines, per room pool or with an empty room for the shared pool.",
+		}, []string{"room"}),
+		mux:     &sync.RWMutex{},
+		labeled: make(map[string]prometheus.Counter),
+	}
+
+	collectors := []prometheus.Collector{
+		m.sent, m.delivered, m.latency, m.dropped, m.dispatchFails,
+		m.subscriptions, m.rooms, m.poolBusy, m.poolSize,
+	}
+	for _, c := range collectors {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// LabelRooms labels sent messages with their room for at most limit distinct rooms.
+// Messages sent to further rooms are counted with an empty room. Room labels are off
+// by default because every room adds a series, which is unbounded for dynamic room names.
+func (m *Metrics) LabelRooms(limit int) *Metrics {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.roomLimit = limit
+	return m
+}
+
+// MessageSent counts a message sent to a room.
+func (m *Metrics) MessageSent(room string) {
+	m.sentCounter(room).Inc()
+}
+
+// sentCounter returns the counter of the room, or the counter with an empty room
+// when the room is not labeled.
+func (m *Metrics) sentCounter(room string) prometheus.Counter {
+	m.mux.RLock()
+	counter, limit := m.labeled[room], m.roomLimit
+	m.mux.RUnlock()
+
+	if counter != nil {
+		return counter
+	}
+	if len(room) == 0 || limit == 0 {
+		return m.sent.WithLabelValues("")
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if counter = m.labeled[room]; counter != nil {
+		return counter
+	}
+	if len(m.labeled) >= m.roomLimit {
+		return m.sent.WithLabelValues("")
+	}
+
+	counter = m.sent.WithLabelValues(room)
+	m.labeled[room] = counter
+	return counter
+}
+
+// MessageDelivered counts a delivery and observes its latency.
+func (m *Metrics) MessageDelivered(latency time.Duration) {
+	m.delivered.Inc()
+	m.latency.Observe(latency.Seconds())
+}
+
+// MessagesDropped counts dropped messages.
+func (m *Metrics) MessagesDropped(count int) {
+	m.dropped.Add(float64(count))
+}
+
+// DispatchFailed counts a failed dispatch.
+func (m *Metrics) DispatchFailed() {
+	m.dispatchFails.Inc()
+}
+
+// SubscriptionsChanged updates the number of active subscriptions.
+func (m *Metrics) SubscriptionsChanged(delta int) {
+	m.subscriptions.Add(float64(delta))
+}
+
+// RoomsChanged updates the number of rooms with subscriptions.
+func (m *Metrics) RoomsChanged(delta int) {
+	m.rooms.Add(float64(delta))
+}
+
+// PoolUtilization updates the number of busy go routines and the size of a pool.
+func (m *Metrics) PoolUtilization(pool string, busy int, size int) {
+	m.poolBusy.WithLabelValues(pool).Set(float64(busy))
+	m.poolSize.WithLabelValues(pool).Set(float64(size))
+}
@@ -0,0 +1,156 @@
+package prombroadcast
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-broadcast/broadcast"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestMetrics(t *testing.T, buckets ...float64) (*Metrics, *prometheus.Registry) {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	m, err := NewMetrics(registry, buckets...)
+	if err != nil {
+		t.Fatalf("NewMetrics returned %v", err)
+	}
+
+	return m, registry
+}
+
+func TestMetrics(t *testing.T) {
+	m, registry := newTestMetrics(t, 0.1, 1)
+	m.LabelRooms(10)
+	m.MessageSent("chat")
+	m.MessageSent("chat")
+	m.MessageDelivered(time.Millisecond * 500)
+	m.MessagesDropped(3)
+	m.SubscriptionsChanged(2)
+	m.SubscriptionsChanged(-1)
+
+	if got := testutil.ToFloat64(m.sent.WithLabelValues("chat")); got != 2 {
+		t.Fatalf("sent messages of chat = %v; want 2", got)
+	}
+
+	if testutil.ToFloat64(m.delivered) != 1 || testutil.ToFloat64(m.dropped) != 3 || testutil.ToFloat64(m.subscriptions) != 1 {
+		t.Fatalf("metrics did not count the delivery, the dropped messages and the subscription")
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather returned %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() != "broadcast_delivery_latency_seconds" {
+			continue
+		}
+
+		h := f.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 1 || h.GetBucket()[0].GetCumulativeCount() != 0 || h.GetBucket()[1].GetCumulativeCount() != 1 {
+			t.Fatalf("latency histogram = %v; want the delivery in the 1s bucket", h)
+		}
+		return
+	}
+	t.Fatalf("registry has no latency histogram")
+}
+
+func TestMetrics_WithBroadcaster(t *testing.T) {
+	m, _ := newTestMetrics(t)
+	m.LabelRooms(10)
+	b, cancel, _ := broadcast.New(broadcast.WithMetrics(m), broadcast.WithSynchronousDelivery())
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "chat")
+
+	b.ToRoom("hello", "chat")
+
+	if testutil.ToFloat64(m.sent.WithLabelValues("chat")) != 1 || testutil.ToFloat64(m.delivered) != 1 {
+		t.Fatalf("metrics did not count the message sent to chat and its delivery")
+	}
+
+	if testutil.ToFloat64(m.subscriptions) != 1 || testutil.ToFloat64(m.rooms) != 2 {
+		t.Fatalf("metrics did not count the subscription and its rooms")
+	}
+}
+
+func TestMetrics_LabelRooms_ShouldLimitLabels(t *testing.T) {
+	m, _ := newTestMetrics(t)
+	m.LabelRooms(1)
+	m.MessageSent("a")
+	m.MessageSent("b")
+	m.MessageSent("c")
+	m.MessageSent("a")
+
+	if testutil.ToFloat64(m.sent.WithLabelValues("a")) != 2 || testutil.ToFloat64(m.sent.WithLabelValues("")) != 2 {
+		t.Fatalf("metrics should label the first room and count the others with an empty room")
+	}
+
+	if n := testutil.CollectAndCount(m.sent); n != 2 {
+		t.Fatalf("metrics have %d sent series; want 2", n)
+	}
+}
+
+func TestMetrics_MessageSent_WithoutRoomLabels(t *testing.T) {
+	m, _ := newTestMetrics(t)
+	m.MessageSent("a")
+	m.MessageSent("b")
+
+	if testutil.ToFloat64(m.sent.WithLabelValues("")) != 2 || testutil.CollectAndCount(m.sent) != 1 {
+		t.Fatalf("metrics should count rooms without labels")
+	}
+}
+
+func TestMetrics_PoolUtilization_WithRoomPools(t *testing.T) {
+	m, _ := newTestMetrics(t)
+	b, cancel, _ := broadcast.New(broadcast.WithMetrics(m), broadcast.WithRoomPool("slow", 3))
+	defer cancel()
+	done := make(chan struct{})
+	s := b.Subscribe(func(_ interface{}) {
+		close(done)
+	})
+	b.JoinRoom(s, "slow")
+
+	b.ToRoom("data", "slow")
+	<-done
+
+	if testutil.ToFloat64(m.poolSize.WithLabelValues("slow")) != 3 {
+		t.Fatalf("metrics did not report the size of the room pool")
+	}
+}
+
+func TestMetrics_Concurrently(t *testing.T) {
+	m, registry := newTestMetrics(t)
+	m.LabelRooms(2)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.MessageSent(string(rune('a' + j%4)))
+				m.MessageDelivered(time.Millisecond)
+				m.PoolUtilization("", i, 8)
+				registry.Gather()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if testutil.ToFloat64(m.delivered) != 800 || testutil.CollectAndCount(m.sent) != 3 {
+		t.Fatalf("metrics lost deliveries or labeled more rooms than the limit")
+	}
+}
+
+func TestNewMetrics_WithRegisteredMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	NewMetrics(registry)
+
+	if _, err := NewMetrics(registry); err == nil {
+		t.Fatalf("NewMetrics should fail when the metrics are already registered")
+	}
+}
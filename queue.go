@@ -0,0 +1,134 @@
+package broadcast
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy defines what happens when a message is sent to
+// a subscription whose buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room in the buffer.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered message to make room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest discards the new message.
+	OverflowDropNewest
+	// OverflowClose discards the new message and unsubscribes the subscription.
+	OverflowClose
+)
+
+const defaultQueueSize = 64
+
+type queue struct {
+	items     chan delivery
+	policy    OverflowPolicy
+	closed    chan struct{}
+	closeOnce *sync.Once
+	draining  int32
+}
+
+func newQueue(size int, policy OverflowPolicy) *queue {
+	return &queue{
+		items:     make(chan delivery, size),
+		policy:    policy,
+		closed:    make(chan struct{}),
+		closeOnce: &sync.Once{},
+	}
+}
+
+// push adds a delivery to the queue according to the overflow policy
+// and returns the number of deliveries that were dropped.
+func (q *queue) push(d delivery, cancelc <-chan struct{}) int {
+	select {
+	case <-q.closed:
+		d.finish(false)
+		return 1
+	default:
+	}
+
+	switch q.policy {
+	case OverflowBlock:
+		select {
+		case q.items <- d:
+			return 0
+		case <-q.closed:
+		case <-cancelc:
+		}
+	case OverflowDropOldest:
+		dropped := 0
+		for {
+			select {
+			case q.items <- d:
+				return dropped
+			default:
+			}
+
+			select {
+			case old := <-q.items:
+				old.finish(false)
+				dropped++
+			default:
+			}
+		}
+	default:
+		select {
+		case q.items <- d:
+			return 0
+		default:
+		}
+	}
+
+	d.finish(false)
+	return 1
+}
+
+// drain sends queued deliveries until the queue is empty.
+// Only one go routine drains a queue at a time, other callers return immediately.
+func (q *queue) drain(send func(delivery)) {
+	if !atomic.CompareAndSwapInt32(&q.draining, 0, 1) {
+		return
+	}
+
+	for {
+		select {
+		case <-q.closed:
+			atomic.StoreInt32(&q.draining, 0)
+			return
+		default:
+		}
+
+		select {
+		case d := <-q.items:
+			send(d)
+			continue
+		default:
+		}
+
+		atomic.StoreInt32(&q.draining, 0)
+
+		// A message could have been pushed after the queue was found empty
+		// but before the draining flag was cleared.
+		if len(q.items) == 0 || !atomic.CompareAndSwapInt32(&q.draining, 0, 1) {
+			return
+		}
+	}
+}
+
+// close stops the queue and abandons deliveries that were not sent yet.
+func (q *queue) close() {
+	q.closeOnce.Do(func() {
+		close(q.closed)
+	})
+
+	for {
+		select {
+		case d := <-q.items:
+			d.finish(false)
+		default:
+			return
+		}
+	}
+}
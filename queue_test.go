@@ -0,0 +1,147 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueue_push(t *testing.T) {
+	q := newQueue(1, OverflowDropNewest)
+
+	dropped := q.push(delivery{msg: &Message{Data: "a"}}, nil)
+
+	if dropped != 0 || len(q.items) != 1 {
+		t.Fatalf("push should add message to a queue with free space")
+	}
+}
+
+func TestQueue_push_DropNewest(t *testing.T) {
+	q := newQueue(1, OverflowDropNewest)
+	q.push(delivery{msg: &Message{Data: "a"}}, nil)
+
+	dropped := q.push(delivery{msg: &Message{Data: "b"}}, nil)
+
+	if dropped != 1 {
+		t.Fatalf("push dropped %v messages; want 1", dropped)
+	}
+
+	if got := <-q.items; got.msg.Data != "a" {
+		t.Fatalf("push should keep the oldest message; got %v", got)
+	}
+}
+
+func TestQueue_push_DropOldest(t *testing.T) {
+	q := newQueue(1, OverflowDropOldest)
+	q.push(delivery{msg: &Message{Data: "a"}}, nil)
+
+	dropped := q.push(delivery{msg: &Message{Data: "b"}}, nil)
+
+	if dropped != 1 {
+		t.Fatalf("push dropped %v messages; want 1", dropped)
+	}
+
+	if got := <-q.items; got.msg.Data != "b" {
+		t.Fatalf("push should keep the newest message; got %v", got)
+	}
+}
+
+func TestQueue_push_Block(t *testing.T) {
+	q := newQueue(1, OverflowBlock)
+	q.push(delivery{msg: &Message{Data: "a"}}, nil)
+	pushed := make(chan struct{})
+
+	go func() {
+		q.push(delivery{msg: &Message{Data: "b"}}, nil)
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatalf("push should block while the queue is full")
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	<-q.items
+	waitOrTimeout(pushed)
+
+	if got := <-q.items; got.msg.Data != "b" {
+		t.Fatalf("blocked push should add the message once there is room; got %v", got)
+	}
+}
+
+func TestQueue_push_BlockUntilClosed(t *testing.T) {
+	q := newQueue(1, OverflowBlock)
+	q.push(delivery{msg: &Message{Data: "a"}}, nil)
+	droppedc := make(chan int)
+
+	go func() {
+		droppedc <- q.push(delivery{msg: &Message{Data: "b"}}, nil)
+	}()
+	q.close()
+
+	select {
+	case dropped := <-droppedc:
+		if dropped != 1 {
+			t.Fatalf("push on closed queue dropped %v messages; want 1", dropped)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("close should release blocked push")
+	}
+}
+
+func TestQueue_drain(t *testing.T) {
+	q := newQueue(3, OverflowBlock)
+	q.push(delivery{msg: &Message{Data: "a"}}, nil)
+	q.push(delivery{msg: &Message{Data: "b"}}, nil)
+	q.push(delivery{msg: &Message{Data: "c"}}, nil)
+	got := []interface{}{}
+
+	q.drain(func(d delivery) {
+		got = append(got, d.msg.Data)
+	})
+
+	if len(got) != 3 || got[0] != "a" || got[2] != "c" {
+		t.Fatalf("drain sent %v; want [a b c]", got)
+	}
+}
+
+func TestQueue_drain_ShouldNotRunConcurrently(t *testing.T) {
+	q := newQueue(2, OverflowBlock)
+	q.push(delivery{msg: &Message{Data: "a"}}, nil)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+
+	go q.drain(func(_ delivery) {
+		started <- struct{}{}
+		<-release
+	})
+	<-started
+
+	q.push(delivery{msg: &Message{Data: "b"}}, nil)
+	called := false
+	q.drain(func(_ delivery) {
+		called = true
+	})
+	close(release)
+
+	if called {
+		t.Fatalf("drain should return while another go routine is draining")
+	}
+}
+
+func TestQueue_close_ShouldFinishPendingDeliveries(t *testing.T) {
+	q := newQueue(2, OverflowBlock)
+	tr := newTracker()
+	tr.add()
+	tr.add()
+	q.push(delivery{msg: &Message{Data: "a"}, tracker: tr}, nil)
+	q.push(delivery{msg: &Message{Data: "b"}, tracker: tr}, nil)
+
+	q.close()
+	delivered, err := tr.wait(context.Background())
+
+	if err != nil || delivered != 0 {
+		t.Fatalf("close should abandon pending deliveries; got %v delivered, error %v", delivered, err)
+	}
+}
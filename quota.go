@@ -0,0 +1,230 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a message exceeds the quota of its producer, see WithProducerQuota.
+var ErrQuotaExceeded = errors.New("producer quota exceeded")
+
+// ProducerQuota limits how much a producer sends per second. Bursts of up to one
+// second worth of messages and bytes are allowed. Zero fields are unlimited.
+type ProducerQuota struct {
+	// Messages is the number of messages per second.
+	Messages float64
+	// Bytes is the payload size per second. Byte slices and strings count their length,
+	// other payloads the length of their JSON encoding. A message larger than Bytes is always rejected.
+	Bytes float64
+}
+
+// AsProducer sets the producer of a message, e.g. the upstream component that sends it,
+// see WithProducerQuota.
+func AsProducer(id string) SendOption {
+	return func(msg *Message) {
+		msg.Producer = id
+	}
+}
+
+// WithProducerQuota limits the messages sent by a producer, see AsProducer. Messages over
+// the quota are rejected with ErrQuotaExceeded and are neither delivered nor dispatched.
+func WithProducerQuota(producer string, quota ProducerQuota) Option {
+	return func(b *broadcaster) error {
+		if len(producer) == 0 {
+			return errors.New("producer cannot be empty")
+		}
+
+		if err := validateQuota(quota); err != nil {
+			return err
+		}
+
+		if b.quotas == nil {
+			b.quotas = newQuotas()
+		}
+
+		b.quotas.limiters[producer] = newProducerLimiter(quota)
+		return nil
+	}
+}
+
+// WithDefaultProducerQuota limits the messages of every producer that has no quota
+// of its own, see WithProducerQuota. Every producer is limited separately.
+// Messages without a producer are not limited.
+func WithDefaultProducerQuota(quota ProducerQuota) Option {
+	return func(b *broadcaster) error {
+		if err := validateQuota(quota); err != nil {
+			return err
+		}
+
+		if b.quotas == nil {
+			b.quotas = newQuotas()
+		}
+
+		b.quotas.fallback = &quota
+		return nil
+	}
+}
+
+// WithProducerRejectionHook sets a function that is called with the producer and the
+// messages rejected because they exceeded its quota, see WithProducerQuota.
+func WithProducerRejectionHook(hook func(producer string, msg *Message)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("producer rejection hook cannot be nil")
+		}
+
+		b.quotaRejectionHook = hook
+		return nil
+	}
+}
+
+func validateQuota(quota ProducerQuota) error {
+	if quota.Messages < 0 || quota.Bytes < 0 {
+		return errors.New("quota cannot be negative")
+	}
+
+	if quota.Messages == 0 && quota.Bytes == 0 {
+		return errors.New("quota must limit messages or bytes")
+	}
+
+	return nil
+}
+
+// quotas holds the limiters of the producers.
+type quotas struct {
+	mux      *sync.Mutex
+	limiters map[string]*producerLimiter
+	fallback *ProducerQuota
+}
+
+func newQuotas() *quotas {
+	return &quotas{
+		mux:      &sync.Mutex{},
+		limiters: make(map[string]*producerLimiter),
+	}
+}
+
+// limiter returns the limiter of a producer or nil if the producer is not limited.
+func (q *quotas) limiter(producer string) *producerLimiter {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	l := q.limiters[producer]
+	if l == nil && q.fallback != nil {
+		l = newProducerLimiter(*q.fallback)
+		q.limiters[producer] = l
+	}
+
+	return l
+}
+
+// producerLimiter is a token bucket for the messages and one for the bytes of a producer.
+type producerLimiter struct {
+	mux      *sync.Mutex
+	quota    ProducerQuota
+	messages float64
+	bytes    float64
+	last     time.Time
+}
+
+func newProducerLimiter(quota ProducerQuota) *producerLimiter {
+	return &producerLimiter{
+		mux:      &sync.Mutex{},
+		quota:    quota,
+		messages: quota.Messages,
+		bytes:    quota.Bytes,
+	}
+}
+
+// take takes a message of the given size from the buckets and reports whether it fits.
+func (l *producerLimiter) take(size int, now time.Time) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last).Seconds()
+		l.messages = refilled(l.messages, elapsed, l.quota.Messages)
+		l.bytes = refilled(l.bytes, elapsed, l.quota.Bytes)
+	}
+	l.last = now
+
+	if l.quota.Messages > 0 && l.messages < 1 {
+		return false
+	}
+
+	if l.quota.Bytes > 0 && l.bytes < float64(size) {
+		return false
+	}
+
+	l.messages--
+	l.bytes -= float64(size)
+	return true
+}
+
+func refilled(tokens float64, elapsed float64, rate float64) float64 {
+	tokens += elapsed * rate
+	if tokens > rate {
+		return rate
+	}
+
+	return tokens
+}
+
+// permit checks that a message may be sent, see WithAuthorizer, WithProducerQuota and WithPoolQueueLimit.
+func (b *broadcaster) permit(msg *Message) error {
+	if err := b.authorize(msg); err != nil {
+		return err
+	}
+
+	if err := b.enforceQuota(msg); err != nil {
+		return err
+	}
+
+	return b.rejectSaturated()
+}
+
+// enforceQuota rejects a message that exceeds the quota of its producer.
+func (b *broadcaster) enforceQuota(msg *Message) error {
+	if b.quotas == nil || len(msg.Producer) == 0 {
+		return nil
+	}
+
+	l := b.quotas.limiter(msg.Producer)
+	if l == nil {
+		return nil
+	}
+
+	size := 0
+	if l.quota.Bytes > 0 {
+		size = payloadSize(msg.Data)
+	}
+
+	if l.take(size, b.clock.Now()) {
+		return nil
+	}
+
+	if b.quotaRejectionHook != nil {
+		b.quotaRejectionHook(msg.Producer, msg)
+	}
+
+	return ErrQuotaExceeded
+}
+
+// payloadSize returns the length of byte slices and strings and the length of the JSON encoding of other payloads.
+func payloadSize(data interface{}) int {
+	switch d := data.(type) {
+	case []byte:
+		return len(d)
+	case string:
+		return len(d)
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return 0
+	}
+
+	return len(encoded)
+}
@@ -0,0 +1,82 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithProducerQuota_WithInvalidQuota(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithProducerQuota("", ProducerQuota{Messages: 1})(b); err == nil {
+		t.Fatalf("WithProducerQuota with an empty producer; expected an error")
+	}
+
+	if err := WithProducerQuota("producer", ProducerQuota{})(b); err == nil {
+		t.Fatalf("WithProducerQuota without limits; expected an error")
+	}
+
+	if err := WithDefaultProducerQuota(ProducerQuota{Messages: -1})(b); err == nil {
+		t.Fatalf("WithDefaultProducerQuota with a negative quota; expected an error")
+	}
+}
+
+func TestBroadcaster_WithProducerQuota(t *testing.T) {
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	rejected := []string{}
+	b, cancel, _ := New(
+		WithSynchronousDelivery(),
+		WithClock(clock),
+		WithProducerQuota("ingest", ProducerQuota{Messages: 2}),
+		WithProducerRejectionHook(func(producer string, msg *Message) {
+			rejected = append(rejected, producer)
+		}),
+	)
+	defer cancel()
+	calls := 0
+	b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+
+	b.ToAllWithOptions("data", AsProducer("ingest"))
+	b.ToAllWithOptions("data", AsProducer("ingest"))
+	err := b.ToAllWithOptions("data", AsProducer("ingest"))
+	b.ToAllWithOptions("data", AsProducer("other"))
+	b.ToAll("data")
+
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("ToAllWithOptions over the quota returned %v; want ErrQuotaExceeded", err)
+	}
+
+	if calls != 4 || len(rejected) != 1 || rejected[0] != "ingest" {
+		t.Fatalf("callback was called %d times and %v were rejected; want 4 calls and one rejection of ingest", calls, rejected)
+	}
+
+	clock.advance(time.Millisecond * 500)
+	if err := b.ToAllWithOptions("data", AsProducer("ingest")); err != nil {
+		t.Fatalf("ToAllWithOptions after the quota refilled returned %v", err)
+	}
+}
+
+func TestBroadcaster_WithDefaultProducerQuota_Bytes(t *testing.T) {
+	b, cancel, _ := New(WithDefaultProducerQuota(ProducerQuota{Bytes: 10}))
+	defer cancel()
+
+	if err := b.ToAllWithOptions("12345678", AsProducer("a")); err != nil {
+		t.Fatalf("ToAllWithOptions within the quota returned %v", err)
+	}
+
+	if err := b.ToAllWithOptions("1234", AsProducer("a")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("ToAllWithOptions over the byte quota returned %v; want ErrQuotaExceeded", err)
+	}
+
+	if err := b.ToAllWithOptions([]byte("1234"), AsProducer("b")); err != nil {
+		t.Fatalf("ToAllWithOptions of another producer returned %v", err)
+	}
+
+	if err := b.ToAllWithOptions(map[string]string{"key": "a long value"}, AsProducer("c")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("ToAllWithOptions of a payload larger than the quota returned %v; want ErrQuotaExceeded", err)
+	}
+}
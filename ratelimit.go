@@ -0,0 +1,228 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy defines what happens to a message that exceeds a rate limit.
+type RateLimitPolicy int
+
+const (
+	// RateLimitDrop discards messages over the limit.
+	RateLimitDrop RateLimitPolicy = iota
+	// RateLimitQueue delays messages over the limit until the rate allows them.
+	RateLimitQueue
+	// RateLimitCoalesce keeps only the latest message over the limit and
+	// delivers it as soon as the rate allows, discarding the ones it replaced.
+	RateLimitCoalesce
+)
+
+// WithRoomRateLimit limits how many messages per second are delivered to the subscriptions
+// of a room, allowing bursts of up to burst messages. Messages sent to all subscribers are
+// limited by the default room. A message sent to several rooms is limited by the first of
+// them that has a limit. The policy decides what happens to messages over the limit.
+func WithRoomRateLimit(room string, rate float64, burst int, policy RateLimitPolicy) Option {
+	return func(b *broadcaster) error {
+		if len(room) == 0 {
+			return errors.New("rate limited room name cannot be empty")
+		}
+
+		if err := validateRateLimit(rate, burst, policy); err != nil {
+			return err
+		}
+
+		if b.roomLimiters == nil {
+			b.roomLimiters = make(map[string]*rateLimiter)
+		}
+
+		b.roomLimiters[room] = newRateLimiter(rate, burst, policy, b.pool.do, b.clock)
+		return nil
+	}
+}
+
+// WithSubscriberRateLimit limits how many messages per second every subscription receives,
+// allowing bursts of up to burst messages. The policy decides what happens to messages over
+// the limit, messages that are discarded are counted by Subscription.Dropped.
+func WithSubscriberRateLimit(rate float64, burst int, policy RateLimitPolicy) Option {
+	return func(b *broadcaster) error {
+		if err := validateRateLimit(rate, burst, policy); err != nil {
+			return err
+		}
+
+		b.subscriberRateLimit = &rateLimit{rate: rate, burst: burst, policy: policy}
+		return nil
+	}
+}
+
+func validateRateLimit(rate float64, burst int, policy RateLimitPolicy) error {
+	if rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+
+	if burst <= 0 {
+		return errors.New("burst must be positive")
+	}
+
+	if policy < RateLimitDrop || policy > RateLimitCoalesce {
+		return errors.New("unknown rate limit policy")
+	}
+
+	return nil
+}
+
+type rateLimit struct {
+	rate   float64
+	burst  int
+	policy RateLimitPolicy
+}
+
+// rateLimiter is a token bucket that runs, delays or drops tasks.
+type rateLimiter struct {
+	mux       *sync.Mutex
+	rate      float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+	policy    RateLimitPolicy
+	do        func(task func()) bool
+	pending   func()
+	discard   func()
+	scheduled bool
+	clock     Clock
+}
+
+func newRateLimiter(rate float64, burst int, policy RateLimitPolicy, do func(task func()) bool, clock Clock) *rateLimiter {
+	return &rateLimiter{
+		mux:    &sync.Mutex{},
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+		policy: policy,
+		do:     do,
+		clock:  clock,
+	}
+}
+
+// setClock replaces the clock of a limiter that was created before the clock was set.
+func (l *rateLimiter) setClock(clock Clock) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.clock = clock
+	l.last = clock.Now()
+}
+
+// limit runs the task if a token is available and otherwise handles it
+// according to the policy. Delayed tasks run with do, drop is called with
+// tasks that are discarded.
+func (l *rateLimiter) limit(run func(), drop func()) {
+	l.mux.Lock()
+	l.refill(l.clock.Now())
+
+	if l.tokens >= 1 && !l.scheduled {
+		l.tokens--
+		l.mux.Unlock()
+		run()
+		return
+	}
+
+	switch l.policy {
+	case RateLimitQueue:
+		l.tokens--
+		wait := l.wait(0)
+		l.mux.Unlock()
+		l.later(wait, run, drop)
+	case RateLimitCoalesce:
+		replaced := l.discard
+		l.pending, l.discard = run, drop
+
+		if !l.scheduled {
+			l.scheduled = true
+			l.clock.AfterFunc(l.wait(1), l.flush)
+		}
+		l.mux.Unlock()
+
+		if replaced != nil {
+			replaced()
+		}
+	default:
+		l.mux.Unlock()
+		drop()
+	}
+}
+
+// flush runs the latest coalesced task.
+func (l *rateLimiter) flush() {
+	l.mux.Lock()
+	l.refill(l.clock.Now())
+	l.tokens--
+	run, drop := l.pending, l.discard
+	l.pending, l.discard = nil, nil
+	l.scheduled = false
+	l.mux.Unlock()
+
+	if !l.do(run) {
+		drop()
+	}
+}
+
+func (l *rateLimiter) later(wait time.Duration, run func(), drop func()) {
+	l.clock.AfterFunc(wait, func() {
+		if !l.do(run) {
+			drop()
+		}
+	})
+}
+
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+
+	l.last = now
+}
+
+// wait returns how long it takes until the bucket holds the given number of tokens.
+func (l *rateLimiter) wait(tokens float64) time.Duration {
+	if l.tokens >= tokens {
+		return 0
+	}
+
+	return time.Duration((tokens - l.tokens) / l.rate * float64(time.Second))
+}
+
+// roomLimiter returns the rate limiter of the first target room of a message that has one.
+func (b *broadcaster) roomLimiter(msg *Message) *rateLimiter {
+	if len(b.roomLimiters) == 0 {
+		return nil
+	}
+
+	if msg.ToAll {
+		return b.roomLimiters[b.defaultRoomName]
+	}
+
+	for _, room := range msg.Rooms {
+		if l := b.roomLimiters[room]; l != nil {
+			return l
+		}
+	}
+
+	return nil
+}
+
+// limitSubscription runs the task if the subscription is within its rate limit.
+func (b *broadcaster) limitSubscription(s *Subscription, d delivery, run func()) {
+	if s.limiter == nil {
+		run()
+		return
+	}
+
+	s.limiter.limit(run, func() {
+		b.dropped(s, 1)
+		d.finish(false)
+	})
+}
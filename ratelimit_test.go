@@ -0,0 +1,147 @@
+package broadcast
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRoomRateLimit_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomRateLimit("", 1, 1, RateLimitDrop)(b); err == nil {
+		t.Fatalf("WithRoomRateLimit with empty room; expected an error")
+	}
+
+	if err := WithRoomRateLimit("room", 0, 1, RateLimitDrop)(b); err == nil {
+		t.Fatalf("WithRoomRateLimit with zero rate; expected an error")
+	}
+
+	if err := WithRoomRateLimit("room", 1, 0, RateLimitDrop)(b); err == nil {
+		t.Fatalf("WithRoomRateLimit with zero burst; expected an error")
+	}
+
+	if err := WithRoomRateLimit("room", 1, 1, RateLimitPolicy(10))(b); err == nil {
+		t.Fatalf("WithRoomRateLimit with unknown policy; expected an error")
+	}
+}
+
+func TestWithSubscriberRateLimit_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithSubscriberRateLimit(-1, 1, RateLimitDrop)(b); err == nil {
+		t.Fatalf("WithSubscriberRateLimit with negative rate; expected an error")
+	}
+}
+
+func TestRateLimiter_limit_Drop(t *testing.T) {
+	l := newRateLimiter(1, 2, RateLimitDrop, runNow, realClock{})
+	var ran, dropped int
+
+	for i := 0; i < 5; i++ {
+		l.limit(func() { ran++ }, func() { dropped++ })
+	}
+
+	if ran != 2 || dropped != 3 {
+		t.Fatalf("limit ran %d and dropped %d tasks; want 2 and 3", ran, dropped)
+	}
+}
+
+func TestRateLimiter_limit_Queue(t *testing.T) {
+	l := newRateLimiter(100, 1, RateLimitQueue, runNow, realClock{})
+	var ran int32
+
+	for i := 0; i < 3; i++ {
+		l.limit(func() { atomic.AddInt32(&ran, 1) }, func() {})
+	}
+
+	if got := atomic.LoadInt32(&ran); got != 1 {
+		t.Fatalf("limit ran %d tasks immediately; want 1", got)
+	}
+
+	<-time.After(time.Millisecond * 100)
+	if got := atomic.LoadInt32(&ran); got != 3 {
+		t.Fatalf("limit ran %d tasks after a delay; want 3", got)
+	}
+}
+
+func TestRateLimiter_limit_Coalesce(t *testing.T) {
+	l := newRateLimiter(100, 1, RateLimitCoalesce, runNow, realClock{})
+	ran := make(chan int, 5)
+	var dropped int32
+
+	for i := 0; i < 4; i++ {
+		n := i
+		l.limit(func() { ran <- n }, func() { atomic.AddInt32(&dropped, 1) })
+	}
+
+	for _, want := range []int{0, 3} {
+		select {
+		case got := <-ran:
+			if got != want {
+				t.Fatalf("limit ran task %d; want %d", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("limit did not run task %d", want)
+		}
+	}
+
+	if got := atomic.LoadInt32(&dropped); got != 2 {
+		t.Fatalf("limit dropped %d tasks; want 2", got)
+	}
+}
+
+func TestBroadcaster_ToRoom_WithRoomRateLimitShouldDrop(t *testing.T) {
+	b, cancel, _ := New(WithRoomRateLimit("limited", 1, 1, RateLimitDrop))
+	defer cancel()
+	var limited, other int32
+	s1 := b.Subscribe(func(_ interface{}) { atomic.AddInt32(&limited, 1) })
+	s2 := b.Subscribe(func(_ interface{}) { atomic.AddInt32(&other, 1) })
+	b.JoinRoom(s1, "limited")
+	b.JoinRoom(s2, "other")
+
+	for i := 0; i < 3; i++ {
+		b.ToRoom(i, "limited")
+		b.ToRoom(i, "other")
+	}
+	<-time.After(time.Millisecond * 100)
+
+	gotLimited, gotOther := atomic.LoadInt32(&limited), atomic.LoadInt32(&other)
+	if gotLimited != 1 || gotOther != 3 {
+		t.Fatalf("received %d limited and %d other messages; want 1 and 3", gotLimited, gotOther)
+	}
+}
+
+func TestBroadcaster_ToRoomSync_WithRoomRateLimitShouldWaitForQueuedMessage(t *testing.T) {
+	b, cancel, _ := New(WithRoomRateLimit("limited", 20, 1, RateLimitQueue))
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "limited")
+	b.ToRoom(0, "limited")
+
+	delivered, err := b.ToRoomSync(context.Background(), 1, "limited")
+
+	if err != nil || delivered != 1 {
+		t.Fatalf("ToRoomSync returned (%d, %v); want (1, nil)", delivered, err)
+	}
+}
+
+func TestBroadcaster_ToAll_WithSubscriberRateLimitShouldCountDropped(t *testing.T) {
+	b, cancel, _ := New(WithSubscriberRateLimit(1, 2, RateLimitDrop), WithSynchronousDelivery())
+	defer cancel()
+	subscription := b.Subscribe(func(_ interface{}) {})
+
+	for i := 0; i < 5; i++ {
+		b.ToAll(i)
+	}
+
+	if subscription.Dropped() != 3 {
+		t.Fatalf("subscription dropped %d messages; want 3", subscription.Dropped())
+	}
+}
+
+func runNow(task func()) bool {
+	task()
+	return true
+}
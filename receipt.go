@@ -0,0 +1,78 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotDelivered is reported in a Receipt for a subscription that didn't receive the message
+// without an error of its callback, e.g. because the message was dropped by a rate limit,
+// a full buffer or its TTL.
+var ErrNotDelivered = errors.New("message was not delivered")
+
+// Receipt reports the outcome of the local deliveries of a message, see WithReceipt.
+type Receipt struct {
+	// Recipients is the number of local subscriptions the message was handed to.
+	Recipients int
+	// Delivered is the number of subscriptions that received the message.
+	Delivered int
+	// Failed holds the error of every subscription that didn't receive the message by subscription ID.
+	Failed map[string]error
+}
+
+// WithReceipt calls the callback with the outcome of the local deliveries once all of them are finished,
+// including redeliveries. A receipt with no recipients means that no local subscription was listening.
+// The callback runs on another go routine and is not called on other instances.
+func WithReceipt(callback func(receipt Receipt)) SendOption {
+	return func(msg *Message) {
+		msg.receipt = callback
+	}
+}
+
+// receiptRecorder collects the results of the deliveries of a message with a receipt
+// or an audit record and the IDs of the subscriptions that received it.
+type receiptRecorder struct {
+	mux       *sync.Mutex
+	receipt   Receipt
+	delivered []string
+	callback  func(receipt Receipt, delivered []string)
+}
+
+func newReceiptRecorder(callback func(receipt Receipt, delivered []string)) *receiptRecorder {
+	return &receiptRecorder{
+		mux:      &sync.Mutex{},
+		receipt:  Receipt{Failed: make(map[string]error)},
+		callback: callback,
+	}
+}
+
+func (r *receiptRecorder) record(subscriber string, err error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.receipt.Recipients++
+	if err == nil {
+		r.receipt.Delivered++
+		r.delivered = append(r.delivered, subscriber)
+		return
+	}
+
+	r.receipt.Failed[subscriber] = err
+}
+
+// report calls the receipt callback once all deliveries tracked by t are finished.
+func (t *tracker) report() {
+	if t.receipt == nil {
+		return
+	}
+
+	go func() {
+		t.wg.Wait()
+
+		t.receipt.mux.Lock()
+		receipt, delivered := t.receipt.receipt, t.receipt.delivered
+		t.receipt.mux.Unlock()
+
+		t.receipt.callback(receipt, delivered)
+	}()
+}
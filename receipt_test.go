@@ -0,0 +1,75 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_ToRoomWithOptions_WithReceipt(t *testing.T) {
+	b, cancel, _ := New(WithRedelivery(1, 0))
+	defer cancel()
+	callbackErr := errors.New("callback failed")
+	ok := b.Subscribe(func(_ interface{}) {})
+	failing := b.SubscribeAck(func(_ interface{}) error {
+		return callbackErr
+	})
+	other := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(ok, "test-room")
+	b.JoinRoom(failing, "test-room")
+	b.JoinRoom(other, "other-room")
+	receipts := make(chan Receipt, 1)
+
+	b.ToRoomWithOptions("data", "test-room", WithReceipt(func(r Receipt) {
+		receipts <- r
+	}))
+
+	select {
+	case r := <-receipts:
+		if r.Recipients != 2 || r.Delivered != 1 || len(r.Failed) != 1 || r.Failed[failing.ID()] != callbackErr {
+			t.Fatalf("got receipt %+v; want 2 recipients, 1 delivered and the callback error of %s", r, failing.ID())
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("receipt callback was not called")
+	}
+}
+
+func TestBroadcaster_ToRoomWithOptions_WithReceiptAndNoRecipients(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	receipts := make(chan Receipt, 1)
+
+	b.ToRoomWithOptions("data", "empty-room", WithReceipt(func(r Receipt) {
+		receipts <- r
+	}))
+
+	select {
+	case r := <-receipts:
+		if r.Recipients != 0 {
+			t.Fatalf("got receipt %+v; want no recipients", r)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("receipt callback was not called")
+	}
+}
+
+func TestBroadcaster_ToAllWithOptions_WithReceiptShouldSkipOtherNamespaces(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	namespaceOf(t, b, "tenant").Subscribe(func(_ interface{}) {})
+	b.Subscribe(func(_ interface{}) {})
+	receipts := make(chan Receipt, 1)
+
+	b.ToAllWithOptions("data", WithReceipt(func(r Receipt) {
+		receipts <- r
+	}))
+
+	select {
+	case r := <-receipts:
+		if r.Recipients != 1 || r.Delivered != 1 {
+			t.Fatalf("got receipt %+v; want a single delivered recipient", r)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("receipt callback was not called")
+	}
+}
@@ -0,0 +1,61 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrorHandler is called when a subscription callback panics with
+// the subscription, the message it received and the recovered value.
+type ErrorHandler func(sub *Subscription, data interface{}, recovered interface{})
+
+// WithErrorHandler sets a function that is called when a subscription callback panics.
+// Panics are always recovered, by default they are ignored.
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(b *broadcaster) error {
+		if handler == nil {
+			return errors.New("error handler cannot be nil")
+		}
+
+		b.errorHandler = handler
+		return nil
+	}
+}
+
+// WithPanicLimit unsubscribes a subscription once its callback has panicked the given number of times.
+// By default panicking subscriptions are never removed.
+func WithPanicLimit(limit int) Option {
+	return func(b *broadcaster) error {
+		if limit <= 0 {
+			return errors.New("panic limit must be positive")
+		}
+
+		b.panicLimit = int32(limit)
+		return nil
+	}
+}
+
+// call runs the subscription callback and recovers from a panic
+// so it doesn't take down the pool go routine running it.
+// It returns the error of the callback or an error describing the panic.
+func (b *broadcaster) call(s *Subscription, msg *Message) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		err = fmt.Errorf("subscription callback panicked: %v", r)
+
+		if b.errorHandler != nil {
+			b.errorHandler(s, msg.Data, r)
+		}
+
+		if b.panicLimit > 0 && atomic.AddInt32(&s.panics, 1) == b.panicLimit {
+			go b.Unsubscribe(s)
+		}
+	}()
+
+	return s.send(b.clone(msg))
+}
@@ -0,0 +1,69 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithErrorHandler_WithNilHandler(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithErrorHandler(nil)(b); err == nil {
+		t.Fatalf("WithErrorHandler(nil); expected an error")
+	}
+}
+
+func TestWithPanicLimit_WithNonPositiveLimit(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithPanicLimit(0)(b); err == nil {
+		t.Fatalf("WithPanicLimit(0); expected an error")
+	}
+}
+
+func TestBroadcaster_call_ShouldRecover(t *testing.T) {
+	b := createTestBroadcaster()
+	subscription := b.Subscribe(func(_ interface{}) {
+		panic("test panic")
+	})
+
+	b.call(subscription, &Message{Data: struct{}{}})
+}
+
+func TestBroadcaster_call_ShouldCallErrorHandler(t *testing.T) {
+	b := createTestBroadcaster()
+	var gotSub *Subscription
+	var gotData, gotRecovered interface{}
+	WithErrorHandler(func(sub *Subscription, data interface{}, recovered interface{}) {
+		gotSub, gotData, gotRecovered = sub, data, recovered
+	})(b)
+	subscription := b.Subscribe(func(_ interface{}) {
+		panic("test panic")
+	})
+
+	b.call(subscription, &Message{Data: "data"})
+
+	if gotSub != subscription || gotData != "data" || gotRecovered != "test panic" {
+		t.Fatalf("error handler called with (%v, %v, %v); want subscription, data and recovered value", gotSub, gotData, gotRecovered)
+	}
+}
+
+func TestBroadcaster_ToAll_WithPanicLimitShouldUnsubscribe(t *testing.T) {
+	b, cancel, _ := New(WithPanicLimit(2))
+	defer cancel()
+	subscription := b.Subscribe(func(_ interface{}) {
+		panic("test panic")
+	})
+
+	b.ToAll(1)
+	<-time.After(time.Millisecond * 100)
+	if rooms := b.RoomsOf(subscription); len(rooms) == 0 {
+		t.Fatalf("subscription should not be removed before reaching the panic limit")
+	}
+
+	b.ToAll(2)
+	<-time.After(time.Millisecond * 100)
+	if rooms := b.RoomsOf(subscription); len(rooms) != 0 {
+		t.Fatalf("subscription should be removed after reaching the panic limit; still in %v", rooms)
+	}
+}
@@ -0,0 +1,163 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const replyRoomPrefix = "reply:"
+
+// Request is passed to subscription callbacks instead of the message data
+// when the message was sent with Broadcaster.Request.
+type Request struct {
+	// Data is the data passed to Broadcaster.Request.
+	Data interface{}
+	// CorrelationID uniquely identifies the request.
+	CorrelationID string
+
+	replyTo     string
+	broadcaster *broadcaster
+}
+
+// Reply sends data back to the sender of the request. Only the first reply
+// is returned by Broadcaster.Request, subsequent replies are ignored.
+func (r *Request) Reply(data interface{}) {
+	r.broadcaster.publish(&Message{
+		Data:          data,
+		Rooms:         []string{r.replyTo},
+		CorrelationID: r.CorrelationID,
+		reply:         true,
+	})
+}
+
+// Request sends a message to all subscriptions within a room and waits for the first reply.
+// Subscriptions receive a *Request and reply using its Reply method.
+// Requests reach other instances only if the Dispatcher implements MessageDispatcher.
+// If no reply arrives before the context is done, the context error is returned.
+func (b *broadcaster) Request(ctx context.Context, data interface{}, room string) (interface{}, error) {
+	correlationID := b.nextID()
+	replyRoom := replyRoomPrefix + correlationID
+	replies := make(chan interface{}, 1)
+
+	sub := b.newSubscription(func(reply interface{}) {
+		select {
+		case replies <- reply:
+		default:
+		}
+	})
+	defer b.awaitReplies(correlationID, sub)()
+
+	err := b.publish(&Message{
+		Data:          data,
+		Rooms:         []string{room},
+		CorrelationID: correlationID,
+		ReplyTo:       replyRoom,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Reply is a reply collected by Broadcaster.Gather.
+type Reply struct {
+	// Data is the data passed to Request.Reply.
+	Data interface{}
+	// Origin is the instance ID of the broadcaster that sent the reply, see WithInstanceID.
+	Origin string
+	// Timestamp is the time the reply was sent.
+	Timestamp time.Time
+}
+
+// Gather sends a message to all subscriptions within a room like Request and collects
+// all replies until the deadline of the context, e.g. to ask which instances hold a session.
+// The replies are returned in the order they arrived. If the context is canceled before
+// its deadline, the replies collected so far are returned with the context error.
+func (b *broadcaster) Gather(ctx context.Context, data interface{}, room string) ([]Reply, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("gather requires a context with a deadline")
+	}
+
+	correlationID := b.nextID()
+	replyRoom := replyRoomPrefix + correlationID
+	mux := &sync.Mutex{}
+	replies := []Reply{}
+
+	sub := b.newSubscription(nil)
+	sub.handler = func(msg *Message) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		if replies != nil {
+			replies = append(replies, Reply{Data: msg.Data, Origin: msg.Origin, Timestamp: msg.Timestamp})
+		}
+	}
+	defer b.awaitReplies(correlationID, sub)()
+
+	err := b.publish(&Message{
+		Data:          data,
+		Rooms:         []string{room},
+		CorrelationID: correlationID,
+		ReplyTo:       replyRoom,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	<-ctx.Done()
+
+	mux.Lock()
+	defer mux.Unlock()
+	gathered := replies
+	replies = nil
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return gathered, nil
+	}
+
+	return gathered, ctx.Err()
+}
+
+// awaitReplies registers the subscription receiving the replies to a request and returns
+// the function removing it. Reply rooms are not rooms of the broadcaster, so patterns, trees
+// and room listings don't reach them and only replies carrying the correlation ID are delivered.
+func (b *broadcaster) awaitReplies(correlationID string, sub *Subscription) func() {
+	b.mux.Lock()
+	if b.replies == nil {
+		b.replies = make(map[string]*Subscription)
+	}
+	b.replies[correlationID] = sub
+	b.mux.Unlock()
+
+	return func() {
+		b.mux.Lock()
+		delete(b.replies, correlationID)
+		b.mux.Unlock()
+	}
+}
+
+// isReply reports whether the message is a reply to a request, see Request.Reply.
+func isReply(msg *Message) bool {
+	return len(msg.CorrelationID) > 0 && len(msg.ReplyTo) == 0 &&
+		len(msg.Rooms) == 1 && msg.Rooms[0] == replyRoomPrefix+msg.CorrelationID
+}
+
+// replyRecipients returns the subscription waiting for the reply, if the request is pending on this instance.
+func (b *broadcaster) replyRecipients(msg *Message) []*Subscription {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	if sub := b.replies[msg.CorrelationID]; sub != nil {
+		return []*Subscription{sub}
+	}
+
+	return nil
+}
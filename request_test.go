@@ -0,0 +1,182 @@
+package broadcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_Request(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	subscription := b.Subscribe(func(data interface{}) {
+		req, ok := data.(*Request)
+		if !ok {
+			return
+		}
+
+		req.Reply(req.Data.(int) * 2)
+	})
+	b.JoinRoom(subscription, "doubler")
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancelCtx()
+
+	reply, err := b.Request(ctx, 21, "doubler")
+
+	if err != nil {
+		t.Fatalf("Request returned error - %v, want nil error", err)
+	}
+
+	if reply != 42 {
+		t.Fatalf("Request() = %v; want 42", reply)
+	}
+}
+
+func TestBroadcaster_Request_WithoutReply(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancelCtx()
+
+	_, err := b.Request(ctx, struct{}{}, "empty-room")
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Request returned %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBroadcaster_Request_ShouldRemoveReplyRoom(t *testing.T) {
+	b := createTestBroadcaster()
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancelCtx()
+
+	b.Request(ctx, struct{}{}, "empty-room")
+
+	if b.rooms.len() != 0 {
+		t.Fatalf("Request should remove the reply room; rooms left %v", b.rooms.len())
+	}
+}
+
+func TestBroadcaster_Request_WildcardSendIsNoReply(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	pending := make(chan struct{})
+	s := b.Subscribe(func(data interface{}) {
+		if _, ok := data.(*Request); ok {
+			close(pending)
+		}
+	})
+	b.JoinRoom(s, "service")
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancelCtx()
+	go func() {
+		<-pending
+		b.ToRoomPattern("spoofed", "*")
+		b.ToRoomWithOptions("spoofed", "reply:*", Cascade())
+	}()
+
+	reply, err := b.Request(ctx, "ping", "service")
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Request returned %v, %v; want %v", reply, err, context.DeadlineExceeded)
+	}
+}
+
+func TestBroadcaster_Request_ReplyRoomIsHidden(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	rooms := make(chan []RoomInfo, 1)
+	s := b.Subscribe(func(data interface{}) {
+		if req, ok := data.(*Request); ok {
+			rooms <- b.Rooms(nil)
+			req.Reply("pong")
+		}
+	})
+	b.JoinRoom(s, "service")
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second)
+	defer cancelCtx()
+
+	b.Request(ctx, "ping", "service")
+
+	for _, info := range <-rooms {
+		if info.Name != "default" && info.Name != "service" {
+			t.Fatalf("Rooms() listed %q while a request was pending", info.Name)
+		}
+	}
+}
+
+func TestBroadcaster_Request_ShouldDispatchEnvelope(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher))
+	defer cancel()
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancelCtx()
+
+	b.Request(ctx, "data", "room")
+	msg := <-dispatcher.dispatched
+
+	if len(msg.CorrelationID) == 0 || len(msg.ReplyTo) == 0 {
+		t.Fatalf("Request should dispatch correlation ID and reply room; got %+v", msg)
+	}
+}
+
+func TestBroadcaster_ReceivedRequestShouldBeRepliable(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	b.Subscribe(func(data interface{}) {
+		if req, ok := data.(*Request); ok {
+			req.Reply("pong")
+		}
+	})
+
+	dispatcher.received(&Message{Data: "ping", ToAll: true, CorrelationID: "id", ReplyTo: "reply:id"})
+	reply := <-dispatcher.dispatched
+
+	if reply.Data != "pong" || reply.Rooms[0] != "reply:id" || reply.CorrelationID != "id" {
+		t.Fatalf("reply should be dispatched to the reply room; got %+v", reply)
+	}
+}
+
+func TestBroadcaster_Gather(t *testing.T) {
+	b, cancel, _ := New(WithInstanceID("instance"))
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		node := i
+		s := b.Subscribe(func(data interface{}) {
+			if req, ok := data.(*Request); ok && node > 0 {
+				req.Reply(node)
+			}
+		})
+		b.JoinRoom(s, "nodes")
+	}
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancelCtx()
+
+	replies, err := b.Gather(ctx, "session-1", "nodes")
+
+	if err != nil {
+		t.Fatalf("Gather returned error - %v, want nil error", err)
+	}
+
+	if len(replies) != 2 || replies[0].Data == replies[1].Data || replies[0].Origin != "instance" {
+		t.Fatalf("Gather() = %v; want the replies of nodes 1 and 2", replies)
+	}
+}
+
+func TestBroadcaster_Gather_WithoutDeadline(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	ctx, cancelCtx := context.WithCancel(context.Background())
+
+	if _, err := b.Gather(ctx, struct{}{}, "nodes"); err == nil {
+		t.Fatalf("Gather without a deadline should fail")
+	}
+	cancelCtx()
+
+	ctx, cancelCtx = context.WithTimeout(context.Background(), time.Second*3)
+	cancelCtx()
+	if _, err := b.Gather(ctx, struct{}{}, "nodes"); err != context.Canceled {
+		t.Fatalf("Gather with a canceled context returned %v; want %v", err, context.Canceled)
+	}
+}
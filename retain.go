@@ -0,0 +1,112 @@
+package broadcast
+
+import (
+	"errors"
+	"path"
+)
+
+// WithRetainLast makes rooms whose name matches any of the patterns keep the last message
+// sent to them. The retained message is sent to every subscription that joins the room,
+// which is useful for rooms that represent current state like presence or scores.
+// A retained message with a TTL is removed once it expires, see WithMessageTTL.
+// The pattern syntax is the one used by path.Match, "*" retains the last message of every room.
+func WithRetainLast(patterns ...string) Option {
+	return func(b *broadcaster) error {
+		if len(patterns) == 0 {
+			return errors.New("at least one retain pattern is required")
+		}
+
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return err
+			}
+		}
+
+		b.retainPatterns = append(b.retainPatterns, patterns...)
+		return nil
+	}
+}
+
+// retain stores the message for each target room that retains its last message.
+// Requests and messages targeted by attributes are never retained.
+func (b *broadcaster) retain(msg *Message) {
+	if len(b.retainPatterns) == 0 || len(msg.ReplyTo) > 0 || isReply(msg) || msg.match != nil {
+		return
+	}
+
+	if msg.expiredAt(b.clock.Now()) {
+		return
+	}
+
+	for _, room := range b.targetRooms(msg) {
+		if !b.retains(room) {
+			continue
+		}
+
+		b.mux.Lock()
+		b.retained[room] = msg
+		b.mux.Unlock()
+
+		if msg.TTL > 0 {
+			r := room
+			b.clock.AfterFunc(msg.Timestamp.Add(msg.TTL).Sub(b.clock.Now()), func() {
+				b.forget(r, msg)
+			})
+		}
+	}
+}
+
+// forget removes the retained message of a room unless a newer message replaced it.
+func (b *broadcaster) forget(room string, msg *Message) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.retained[room] == msg {
+		delete(b.retained, room)
+	}
+}
+
+func (b *broadcaster) retains(room string) bool {
+	for _, pattern := range b.retainPatterns {
+		if ok, _ := path.Match(pattern, room); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sendRetained sends the retained message of a room to a subscription that just joined it.
+func (b *broadcaster) sendRetained(sub *Subscription, room string) {
+	if len(b.retainPatterns) == 0 {
+		return
+	}
+
+	b.mux.RLock()
+	msg := b.retained[room]
+	b.mux.RUnlock()
+
+	if msg == nil {
+		return
+	}
+
+	if msg.expiredAt(b.clock.Now()) {
+		b.forget(room, msg)
+		return
+	}
+	msg = b.transformFor(room, msg)
+	d := delivery{msg: msg, tracker: b.pendingTracker(), subscriber: sub.id}
+	d.tracker.add()
+
+	scheduled := b.pool.do(func() {
+		if b.isExcluded(sub, msg) {
+			d.exclude()
+			return
+		}
+		b.deliver(sub, d)
+	})
+
+	if !scheduled {
+		d.finish(false)
+	}
+}
@@ -0,0 +1,137 @@
+package broadcast
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithRetainLast_WithInvalidPattern(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRetainLast()(b); err == nil {
+		t.Fatalf("WithRetainLast(); expected an error")
+	}
+
+	if err := WithRetainLast("presence:[")(b); err == nil {
+		t.Fatalf("WithRetainLast with invalid pattern; expected an error")
+	}
+}
+
+func TestBroadcaster_JoinRoom_ShouldSendRetainedMessage(t *testing.T) {
+	b, cancel, _ := New(WithRetainLast("presence:*"))
+	defer cancel()
+	b.ToRoom("first", "presence:lobby")
+	b.ToRoom("last", "presence:lobby")
+	received := make(chan interface{}, 2)
+	subscription := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	b.JoinRoom(subscription, "presence:lobby")
+
+	select {
+	case got := <-received:
+		if got != "last" {
+			t.Fatalf("JoinRoom sent retained message %v; want last", got)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("JoinRoom did not send the retained message")
+	}
+}
+
+func TestBroadcaster_JoinRoom_ShouldNotRetainUnmatchedRooms(t *testing.T) {
+	b, cancel, _ := New(WithRetainLast("presence:*"))
+	defer cancel()
+	b.ToRoom("data", "chat")
+	called := false
+	done := make(chan struct{})
+	subscription := b.Subscribe(func(_ interface{}) {
+		called = true
+		close(done)
+	})
+
+	b.JoinRoom(subscription, "chat")
+	waitOrTimeout(done)
+
+	if called {
+		t.Fatalf("JoinRoom sent a message from a room that doesn't retain messages")
+	}
+}
+
+func TestBroadcaster_JoinRoom_ShouldSendRetainedMessageOnce(t *testing.T) {
+	b, cancel, _ := New(WithRetainLast("*"), WithSynchronousDelivery())
+	defer cancel()
+	b.ToRoom("data", "scores")
+	received := make(chan interface{}, 2)
+	subscription := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	b.JoinRoom(subscription, "scores")
+	b.JoinRoom(subscription, "scores")
+	<-time.After(time.Millisecond * 200)
+
+	if len(received) != 1 {
+		t.Fatalf("retained message was sent %v times; want 1", len(received))
+	}
+}
+
+func TestBroadcaster_RetainLast_ExpiredMessage(t *testing.T) {
+	clock := &manualClock{mux: &sync.Mutex{}, now: time.Now()}
+	b, cancel, _ := New(WithRetainLast("state"), WithClock(clock), WithSynchronousDelivery())
+	defer cancel()
+	b.ToRoomWithOptions("stale", "state", WithMessageTTL(time.Minute))
+	clock.advance(time.Minute * 2)
+	var received []interface{}
+	s := b.Subscribe(func(data interface{}) {
+		received = append(received, data)
+	})
+
+	b.JoinRoom(s, "state")
+
+	if len(received) != 0 {
+		t.Fatalf("JoinRoom sent expired retained message %v", received)
+	}
+
+	bb := b.(*broadcaster)
+	bb.mux.RLock()
+	defer bb.mux.RUnlock()
+	if bb.retained["state"] != nil {
+		t.Fatalf("expired retained message was not removed")
+	}
+}
+
+func TestBroadcaster_RetainLast_ShouldRemoveExpiredMessages(t *testing.T) {
+	b, cancel, _ := New(WithRetainLast("state"))
+	defer cancel()
+	bb := b.(*broadcaster)
+
+	b.ToRoomWithOptions("stale", "state", WithMessageTTL(time.Millisecond*10))
+
+	deadline := time.Now().Add(time.Second * 3)
+	for {
+		bb.mux.RLock()
+		msg := bb.retained["state"]
+		bb.mux.RUnlock()
+		if msg == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired retained message was not removed")
+		}
+		<-time.After(time.Millisecond)
+	}
+}
+
+func TestBroadcaster_forget_ShouldKeepNewerMessage(t *testing.T) {
+	b := createTestBroadcaster()
+	old, newer := &Message{}, &Message{}
+	b.retained["state"] = newer
+
+	b.forget("state", old)
+
+	if b.retained["state"] != newer {
+		t.Fatalf("forget removed a newer retained message")
+	}
+}
@@ -1,25 +1,288 @@
 package broadcast
 
-import "sync"
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
 
+// RoomInfo describes a room and its metadata.
+type RoomInfo struct {
+	Name        string
+	Meta        map[string]string
+	Subscribers int
+}
+
+// Room holds the subscriptions of a room. A custom implementation can be set with
+// WithRoomFactory, e.g. to keep the membership in an external store. The broadcaster
+// serializes Add and Remove per room, while Lookup, Subscriptions and Len are called
+// concurrently with them and with each other whenever a message is sent.
+type Room interface {
+	// Add adds the subscription and reports whether it wasn't already part of the room.
+	Add(sub *Subscription) bool
+	// Remove removes the subscription and reports whether it was part of the room.
+	Remove(sub *Subscription) bool
+	// Lookup returns the subscription with the given ID or nil.
+	Lookup(id string) *Subscription
+	// Subscriptions returns the subscriptions of the room. The slice is not modified by the broadcaster.
+	Subscriptions() []*Subscription
+	// Len returns the number of subscriptions.
+	Len() int
+}
+
+// WithRoomFactory sets the function that creates the Room of every room by its name when the
+// room is first used. If the factory returns nil, the room uses the default implementation.
+// Consumer groups and room trees always use the default implementation.
+func WithRoomFactory(factory func(name string) Room) Option {
+	return func(b *broadcaster) error {
+		if factory == nil {
+			return errors.New("room factory cannot be nil")
+		}
+
+		b.roomFactory = factory
+		return nil
+	}
+}
+
+// room holds the subscriptions of a room with its metadata and activity.
 type room struct {
-	mux           *sync.RWMutex
-	subscriptions map[string]*Subscription
+	// active is the time of the last activity in UnixNano, see WithRoomExpiry,
+	// and seq is the sequence number of the last message, see LastSeq.
+	// They come first for 64-bit alignment.
+	active int64
+	seq    uint64
+	// mux serializes membership changes and guards meta.
+	mux     *sync.RWMutex
+	members Room
+	meta    map[string]string
+}
+
+func newRoom() *room {
+	return newRoomWith(newMemberSet())
+}
+
+func newRoomWith(members Room) *room {
+	return &room{mux: &sync.RWMutex{}, members: members}
+}
+
+// addSubscription adds a subscription to the room and reports whether it wasn't
+// already part of it and whether it is the first subscription of the room.
+func (r *room) addSubscription(sub *Subscription) (added bool, first bool) {
+	added, first, _ = r.addSubscriptionWithin(sub, 0)
+	return added, first
 }
 
-func (r *room) addSubscription(sub *Subscription) {
+// addSubscriptionWithin works like addSubscription but reports that the room is full
+// instead of adding a subscription if it holds capacity subscriptions. Zero capacity is unlimited.
+func (r *room) addSubscriptionWithin(sub *Subscription, capacity int) (added bool, first bool, full bool) {
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
-	if existing := r.subscriptions[sub.id]; existing != nil {
-		return
+	if r.members.Lookup(sub.id) != nil {
+		return false, false, false
 	}
 
-	r.subscriptions[sub.id] = sub
+	if capacity > 0 && r.members.Len() >= capacity {
+		return false, false, true
+	}
+
+	if !r.members.Add(sub) {
+		return false, false, false
+	}
+
+	return true, r.members.Len() == 1, false
 }
 
-func (r *room) removeSubscription(sub *Subscription) {
+// removeSubscription removes a subscription from the room and reports
+// whether it was part of it and whether the room is empty now.
+func (r *room) removeSubscription(sub *Subscription) (removed bool, emptied bool) {
 	r.mux.Lock()
 	defer r.mux.Unlock()
-	delete(r.subscriptions, sub.id)
+
+	if !r.members.Remove(sub) {
+		return false, false
+	}
+
+	return true, r.members.Len() == 0
+}
+
+// lookup returns the subscription of the room with the given ID or nil.
+func (r *room) lookup(id string) *Subscription {
+	return r.members.Lookup(id)
+}
+
+// snapshot returns the current subscriptions of the room.
+// The slice is shared and must not be modified.
+func (r *room) snapshot() []*Subscription {
+	return r.members.Subscriptions()
+}
+
+// count returns the number of subscriptions within the room.
+func (r *room) count() int {
+	return r.members.Len()
+}
+
+// memberSet is the default Room. Add and Remove change a map and a list under a lock,
+// while senders share an immutable snapshot of the list. A change only drops the snapshot,
+// which is copied again on the next read, so filling a room takes O(n) instead of copying
+// all members on every join. Remove is O(n) because it keeps the order of the members.
+type memberSet struct {
+	mux      *sync.RWMutex
+	byID     map[string]*Subscription
+	list     []*Subscription
+	snapshot atomic.Value
+}
+
+// noSnapshot marks a snapshot that has to be rebuilt.
+var noSnapshot []*Subscription
+
+func newMemberSet() *memberSet {
+	m := &memberSet{mux: &sync.RWMutex{}, byID: make(map[string]*Subscription)}
+	m.snapshot.Store(noSnapshot)
+	return m
+}
+
+func (m *memberSet) Add(sub *Subscription) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.byID[sub.id] != nil {
+		return false
+	}
+
+	m.add(sub)
+	return true
+}
+
+// add adds a subscription. It must be called with the lock held.
+func (m *memberSet) add(sub *Subscription) {
+	m.byID[sub.id] = sub
+	m.list = append(m.list, sub)
+	m.snapshot.Store(noSnapshot)
+}
+
+func (m *memberSet) Remove(sub *Subscription) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.byID[sub.id] == nil {
+		return false
+	}
+
+	m.remove(map[string]bool{sub.id: true})
+	return true
+}
+
+// remove removes the subscriptions with the given IDs and returns them.
+// It must be called with the lock held.
+func (m *memberSet) remove(ids map[string]bool) (removed []*Subscription) {
+	// Snapshots are copies, so the list is compacted in place.
+	list := m.list[:0]
+	for _, s := range m.list {
+		if ids[s.id] {
+			delete(m.byID, s.id)
+			removed = append(removed, s)
+			continue
+		}
+
+		list = append(list, s)
+	}
+
+	for i := len(list); i < len(m.list); i++ {
+		m.list[i] = nil
+	}
+	m.list = list
+	m.snapshot.Store(noSnapshot)
+	return removed
+}
+
+func (m *memberSet) Lookup(id string) *Subscription {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	return m.byID[id]
+}
+
+func (m *memberSet) Subscriptions() []*Subscription {
+	if list := m.snapshot.Load().([]*Subscription); list != nil {
+		return list
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if list := m.snapshot.Load().([]*Subscription); list != nil {
+		return list
+	}
+
+	list := make([]*Subscription, len(m.list))
+	copy(list, m.list)
+	m.snapshot.Store(list)
+	return list
+}
+
+func (m *memberSet) Len() int {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	return len(m.list)
+}
+
+// info returns a description of the room with a copy of its metadata.
+func (r *room) info(name string) RoomInfo {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	meta := make(map[string]string, len(r.meta))
+	for k, v := range r.meta {
+		meta[k] = v
+	}
+
+	return RoomInfo{Name: name, Meta: meta, Subscribers: r.count()}
+}
+
+// room returns the room with the given name and creates it if it doesn't exist.
+func (b *broadcaster) room(name string) *room {
+	return b.rooms.getOrCreate(name)
+}
+
+// CreateRoom creates a room without subscriptions and attaches metadata to it.
+// If the room already exists, the metadata is merged into the metadata it has.
+func (b *broadcaster) CreateRoom(name string, meta map[string]string) {
+	r := b.room(name)
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.meta == nil {
+		r.meta = make(map[string]string, len(meta))
+	}
+
+	for k, v := range meta {
+		r.meta[k] = v
+	}
+}
+
+// Rooms returns the rooms accepted by the filter sorted by name.
+// A nil filter accepts all rooms.
+func (b *broadcaster) Rooms(filter func(name string, info RoomInfo) bool) []RoomInfo {
+	infos := []RoomInfo{}
+	b.rooms.each(func(name string, r *room) bool {
+		infos = append(infos, r.info(name))
+		return true
+	})
+
+	rooms := []RoomInfo{}
+	for _, info := range infos {
+		if filter == nil || filter(info.Name, info) {
+			rooms = append(rooms, info)
+		}
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].Name < rooms[j].Name
+	})
+
+	return rooms
 }
@@ -1,6 +1,7 @@
 package broadcast
 
 import (
+	"strconv"
 	"sync"
 	"testing"
 
@@ -12,7 +13,7 @@ func TestRoom_addSubscription(t *testing.T) {
 
 	room.addSubscription(subscription)
 
-	existingSubscription := room.subscriptions[subscription.id]
+	existingSubscription := room.lookup(subscription.id)
 
 	if existingSubscription != subscription {
 		t.Fatalf("addSubscription didn't add subscription")
@@ -29,7 +30,7 @@ func TestRoom_addSubscription_WithExistingSubscription(t *testing.T) {
 
 	room.addSubscription(&otherSubscription)
 
-	existingSubscription := room.subscriptions[subscription.id]
+	existingSubscription := room.lookup(subscription.id)
 
 	if existingSubscription == &otherSubscription {
 		t.Fatalf("addSubscription should not override existing subscription with the same ID")
@@ -42,7 +43,7 @@ func TestRoom_removeSubscription(t *testing.T) {
 
 	room.removeSubscription(subscription)
 
-	existingSubscription := room.subscriptions[subscription.id]
+	existingSubscription := room.lookup(subscription.id)
 
 	if existingSubscription != nil {
 		t.Fatalf("removeSubscription should remove subscription")
@@ -56,15 +57,214 @@ func TestRoom_removeSubscription_WithNonExistingSubscription(t *testing.T) {
 }
 
 func createRoomTestData() (*room, *Subscription) {
-	var mux sync.RWMutex
-	room := room{
-		mux:           &mux,
-		subscriptions: make(map[string]*Subscription),
-	}
+	room := newRoom()
 	subscription := Subscription{
 		id:       xid.New().String(),
 		callback: func(_ interface{}) {},
 	}
 
-	return &room, &subscription
+	return room, &subscription
+}
+
+func TestRoom_snapshot(t *testing.T) {
+	room, subscription := createRoomTestData()
+	room.addSubscription(subscription)
+
+	subs := room.snapshot()
+	room.removeSubscription(subscription)
+
+	if len(subs) != 1 || subs[0] != subscription {
+		t.Fatalf("snapshot should return the subscriptions of the room")
+	}
+}
+
+func TestBroadcaster_CreateRoom_ShouldMergeMeta(t *testing.T) {
+	b := createTestBroadcaster()
+
+	b.CreateRoom("test-room", map[string]string{"tenant": "a", "kind": "chat"})
+	b.CreateRoom("test-room", map[string]string{"kind": "game"})
+
+	rooms := b.Rooms(nil)
+	if len(rooms) != 1 || rooms[0].Name != "test-room" || rooms[0].Meta["tenant"] != "a" || rooms[0].Meta["kind"] != "game" {
+		t.Fatalf("Rooms returned %v; want test-room with merged metadata", rooms)
+	}
+}
+
+func TestBroadcaster_Rooms_WithFilter(t *testing.T) {
+	b := createTestBroadcaster()
+	b.CreateRoom("room-a", map[string]string{"tenant": "a"})
+	b.CreateRoom("room-b", map[string]string{"tenant": "b"})
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "room-a")
+
+	rooms := b.Rooms(func(name string, info RoomInfo) bool {
+		return info.Meta["tenant"] == "a"
+	})
+
+	if len(rooms) != 1 || rooms[0].Name != "room-a" || rooms[0].Subscribers != 1 {
+		t.Fatalf("Rooms returned %v; want room-a with one subscriber", rooms)
+	}
+}
+
+// BenchmarkRoom_snapshot_WithChurn reads the subscriptions of a room
+// with 1000 subscriptions while other subscriptions join and leave it.
+func BenchmarkRoom_snapshot_WithChurn(b *testing.B) {
+	r := newRoom()
+	for i := 0; i < 1000; i++ {
+		r.addSubscription(&Subscription{id: xid.New().String()})
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		s := &Subscription{id: xid.New().String()}
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			r.addSubscription(s)
+			r.removeSubscription(s)
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			for _, s := range r.snapshot() {
+				_ = s.id
+			}
+		}
+	})
+}
+
+func TestMemberSet_Subscriptions_ShouldKeepSnapshots(t *testing.T) {
+	m := newMemberSet()
+	a, b, c := &Subscription{id: "a"}, &Subscription{id: "b"}, &Subscription{id: "c"}
+	m.Add(a)
+	m.Add(b)
+	before := m.Subscriptions()
+
+	m.Remove(a)
+	m.Add(c)
+	after := m.Subscriptions()
+
+	if len(before) != 2 || before[0] != a || before[1] != b {
+		t.Fatalf("Subscriptions() snapshot changed to %v after a change; want [a b]", before)
+	}
+
+	if len(after) != 2 || after[0] != b || after[1] != c || m.Lookup("a") != nil || m.Len() != 2 {
+		t.Fatalf("Subscriptions() = %v; want [b c]", after)
+	}
+}
+
+// BenchmarkRoom_addSubscription joins a single subscription to rooms of realistic sizes.
+func BenchmarkRoom_addSubscription(b *testing.B) {
+	for _, size := range []int{100, 10000, 100000} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			r := newRoom()
+			for i := 0; i < size; i++ {
+				r.addSubscription(&Subscription{id: strconv.Itoa(i)})
+			}
+			subs := make([]*Subscription, b.N)
+			for i := range subs {
+				subs[i] = &Subscription{id: "joined-" + strconv.Itoa(i)}
+			}
+
+			b.ResetTimer()
+			for _, s := range subs {
+				r.addSubscription(s)
+			}
+		})
+	}
+}
+
+// listRoom is a Room that records the subscriptions which joined it.
+type listRoom struct {
+	mux    *sync.RWMutex
+	subs   []*Subscription
+	joined []string
+}
+
+func (r *listRoom) Add(sub *Subscription) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.subs = append(r.subs, sub)
+	r.joined = append(r.joined, sub.ID())
+	return true
+}
+
+func (r *listRoom) Remove(sub *Subscription) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	for i, s := range r.subs {
+		if s == sub {
+			r.subs = append(r.subs[:i:i], r.subs[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *listRoom) Lookup(id string) *Subscription {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	for _, s := range r.subs {
+		if s.ID() == id {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (r *listRoom) Subscriptions() []*Subscription {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	return r.subs
+}
+
+func (r *listRoom) Len() int {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	return len(r.subs)
+}
+
+func TestBroadcaster_WithRoomFactory(t *testing.T) {
+	custom := &listRoom{mux: &sync.RWMutex{}}
+	b, cancel, _ := New(WithSynchronousDelivery(), WithRoomFactory(func(name string) Room {
+		if name == "custom" {
+			return custom
+		}
+		return nil
+	}))
+	defer cancel()
+	received := 0
+	s1 := b.Subscribe(func(_ interface{}) { received++ })
+	s2 := b.Subscribe(func(_ interface{}) {})
+
+	b.JoinRoom(s1, "custom")
+	b.JoinRoomAll("custom", s1, s2)
+	b.ToRoom("data", "custom")
+
+	if received != 1 || len(custom.joined) != 2 || custom.joined[0] != s1.ID() || custom.joined[1] != s2.ID() {
+		t.Fatalf("Custom room has joined %v and delivered %d messages; want both subscriptions once and one message", custom.joined, received)
+	}
+
+	b.ClearRoom("custom")
+	if custom.Len() != 0 {
+		t.Fatalf("ClearRoom() left %d subscriptions in the custom room", custom.Len())
+	}
+}
+
+func TestBroadcaster_WithRoomFactory_Nil(t *testing.T) {
+	if _, _, err := New(WithRoomFactory(nil)); err == nil {
+		t.Fatalf("New() with a nil room factory should fail")
+	}
 }
@@ -0,0 +1,174 @@
+package broadcast
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+const defaultRoomShards = 32
+
+// WithRoomShards sets the number of shards the rooms are spread across by the hash of
+// their name. Every shard has its own lock, so more shards reduce contention when many
+// rooms are joined, left and sent to concurrently. Default is 32.
+func WithRoomShards(shards int) Option {
+	return func(b *broadcaster) error {
+		if shards <= 0 {
+			return errors.New("room shards must be positive")
+		}
+
+		b.rooms = newRoomMap(shards)
+		return nil
+	}
+}
+
+// roomMap holds rooms by name in shards with separate locks.
+type roomMap struct {
+	shards []*roomShard
+	// factory creates the Room of new rooms, see WithRoomFactory.
+	factory func(name string) Room
+}
+
+type roomShard struct {
+	mux   *sync.RWMutex
+	rooms map[string]*room
+}
+
+// roomEntry is a room with its name.
+type roomEntry struct {
+	name string
+	room *room
+}
+
+func newRoomMap(shards int) *roomMap {
+	m := &roomMap{shards: make([]*roomShard, shards)}
+	for i := range m.shards {
+		m.shards[i] = &roomShard{
+			mux:   &sync.RWMutex{},
+			rooms: make(map[string]*room),
+		}
+	}
+
+	return m
+}
+
+func (m *roomMap) shard(name string) *roomShard {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return m.shards[h.Sum32()%uint32(len(m.shards))]
+}
+
+// get returns the room with the given name or nil.
+func (m *roomMap) get(name string) *room {
+	s := m.shard(name)
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.rooms[name]
+}
+
+// getOrCreate returns the room with the given name and creates it if it doesn't exist.
+func (m *roomMap) getOrCreate(name string) *room {
+	s := m.shard(name)
+	s.mux.RLock()
+	r := s.rooms[name]
+	s.mux.RUnlock()
+
+	if r != nil {
+		return r
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if r = s.rooms[name]; r == nil {
+		r = m.newRoom(name)
+		s.rooms[name] = r
+	}
+
+	return r
+}
+
+func (m *roomMap) newRoom(name string) *room {
+	if m.factory != nil {
+		if members := m.factory(name); members != nil {
+			return newRoomWith(members)
+		}
+	}
+
+	return newRoom()
+}
+
+// join calls fn with the room with the given name and creates the room if it doesn't exist.
+// The room can't be removed while fn runs, so subscriptions fn adds are never lost, see removeIf.
+func (m *roomMap) join(name string, fn func(r *room)) {
+	s := m.shard(name)
+	s.mux.RLock()
+	if r := s.rooms[name]; r != nil {
+		defer s.mux.RUnlock()
+		fn(r)
+		return
+	}
+	s.mux.RUnlock()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	r := s.rooms[name]
+	if r == nil {
+		r = m.newRoom(name)
+		s.rooms[name] = r
+	}
+	fn(r)
+}
+
+// removeIf deletes the room with the given name if remove reports true for it and returns it.
+// No subscription joins the room while remove runs.
+func (m *roomMap) removeIf(name string, remove func(r *room) bool) *room {
+	s := m.shard(name)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	r := s.rooms[name]
+	if r == nil || !remove(r) {
+		return nil
+	}
+
+	delete(s.rooms, name)
+	return r
+}
+
+// each calls fn with every room until it returns false. The rooms of a shard
+// are copied before fn is called, so fn runs without holding a shard lock.
+func (m *roomMap) each(fn func(name string, r *room) bool) {
+	for _, s := range m.shards {
+		s.mux.RLock()
+		entries := make([]roomEntry, 0, len(s.rooms))
+		for name, r := range s.rooms {
+			entries = append(entries, roomEntry{name: name, room: r})
+		}
+		s.mux.RUnlock()
+
+		for _, e := range entries {
+			if !fn(e.name, e.room) {
+				return
+			}
+		}
+	}
+}
+
+// len returns the number of rooms.
+func (m *roomMap) len() int {
+	n := 0
+	for _, s := range m.shards {
+		s.mux.RLock()
+		n += len(s.rooms)
+		s.mux.RUnlock()
+	}
+
+	return n
+}
@@ -0,0 +1,71 @@
+package broadcast
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithRoomShards_WithInvalidCount(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomShards(0)(b); err == nil {
+		t.Fatalf("WithRoomShards(0); expected an error")
+	}
+}
+
+func TestRoomMap(t *testing.T) {
+	m := newRoomMap(4)
+	for i := 0; i < 10; i++ {
+		m.getOrCreate("room-" + strconv.Itoa(i))
+	}
+	r := m.getOrCreate("room-3")
+
+	if m.get("room-3") != r || m.len() != 10 {
+		t.Fatalf("roomMap holds %d rooms; want 10 with room-3 returned by get", m.len())
+	}
+
+	if m.removeIf("room-3", func(*room) bool { return false }) != nil || m.get("room-3") != r {
+		t.Fatalf("removeIf deleted room-3 although remove reported false")
+	}
+
+	if m.removeIf("room-3", func(*room) bool { return true }) != r || m.get("room-3") != nil {
+		t.Fatalf("removeIf did not delete room-3")
+	}
+
+	visited := 0
+	m.each(func(_ string, _ *room) bool {
+		visited++
+		return visited < 5
+	})
+
+	if visited != 5 {
+		t.Fatalf("each visited %d rooms after fn returned false; want 5", visited)
+	}
+}
+
+func BenchmarkBroadcaster_JoinRoomToRoom_OneShard(b *testing.B) {
+	benchmarkJoinRoomToRoom(b, 1)
+}
+
+func BenchmarkBroadcaster_JoinRoomToRoom_DefaultShards(b *testing.B) {
+	benchmarkJoinRoomToRoom(b, defaultRoomShards)
+}
+
+// benchmarkJoinRoomToRoom joins and leaves many rooms while sending to them concurrently.
+func benchmarkJoinRoomToRoom(b *testing.B, shards int) {
+	br, cancel, _ := New(WithRoomShards(shards), WithSynchronousDelivery())
+	defer cancel()
+	var next int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		s := br.Subscribe(func(_ interface{}) {})
+		for pb.Next() {
+			room := "room-" + strconv.FormatInt(atomic.AddInt64(&next, 1)%1000, 10)
+			br.JoinRoom(s, room)
+			br.ToRoom("data", room)
+			br.LeaveRoom(s, room)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package broadcast
+
+import "errors"
+
+// WithRoomPool delivers messages sent to a room on a separate pool of at most size go routines,
+// so slow subscriptions of the room don't block the delivery of messages sent to other rooms.
+// Messages sent to all subscribers use the pool of the default room. A message sent to several
+// rooms uses the pool of the first of them that has one. Room pools use the timeout set by WithPoolTimeout.
+func WithRoomPool(room string, size int) Option {
+	return func(b *broadcaster) error {
+		if len(room) == 0 {
+			return errors.New("pooled room name cannot be empty")
+		}
+
+		if size <= 0 {
+			return errors.New("room pool size must be positive")
+		}
+
+		if b.roomPools == nil {
+			b.roomPools = make(map[string]*pool)
+		}
+
+		b.roomPools[room] = newPool(size, defaultPoolTimeout)
+		return nil
+	}
+}
+
+// poolFor returns the pool the message is delivered on.
+func (b *broadcaster) poolFor(msg *Message) *pool {
+	if len(b.roomPools) == 0 {
+		return b.pool
+	}
+
+	if msg.ToAll {
+		if p := b.roomPools[b.defaultRoomName]; p != nil {
+			return p
+		}
+		return b.pool
+	}
+
+	for _, room := range msg.Rooms {
+		if p := b.roomPools[room]; p != nil {
+			return p
+		}
+	}
+
+	return b.pool
+}
+
+// cancelPools cancels the shared pool and all room pools.
+func (b *broadcaster) cancelPools() {
+	b.pool.cancel()
+
+	for _, p := range b.roomPools {
+		p.cancel()
+	}
+}
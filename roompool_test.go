@@ -0,0 +1,62 @@
+package broadcast
+
+import "testing"
+
+func TestWithRoomPool_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomPool("", 1)(b); err == nil {
+		t.Fatalf("WithRoomPool should fail with an empty room name")
+	}
+
+	if err := WithRoomPool("test-room", 0)(b); err == nil {
+		t.Fatalf("WithRoomPool should fail with a non-positive size")
+	}
+}
+
+func TestBroadcaster_poolFor(t *testing.T) {
+	b := createTestBroadcaster()
+	WithRoomPool("pooled-room", 1)(b)
+	WithRoomPool(b.defaultRoomName, 1)(b)
+
+	if p := b.poolFor(&Message{Rooms: []string{"other-room", "pooled-room"}}); p != b.roomPools["pooled-room"] {
+		t.Fatalf("poolFor should return the pool of the first target room that has one")
+	}
+
+	if p := b.poolFor(&Message{ToAll: true}); p != b.roomPools[b.defaultRoomName] {
+		t.Fatalf("poolFor should return the pool of the default room for messages sent to all")
+	}
+
+	if p := b.poolFor(&Message{Rooms: []string{"other-room"}}); p != b.pool {
+		t.Fatalf("poolFor should return the shared pool for rooms without a pool")
+	}
+}
+
+func TestBroadcaster_ToRoom_WithRoomPoolShouldIsolateSlowRoom(t *testing.T) {
+	b, cancel, _ := New(WithPoolSize(1), WithRoomPool("slow-room", 1))
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+
+	for i := 0; i < 3; i++ {
+		slow := b.Subscribe(func(_ interface{}) {
+			<-release
+		})
+		b.JoinRoom(slow, "slow-room")
+	}
+	received := make(chan struct{})
+	fast := b.Subscribe(func(_ interface{}) {
+		close(received)
+	})
+	b.JoinRoom(fast, "fast-room")
+
+	b.ToRoom("data", "slow-room")
+	b.ToRoom("data", "fast-room")
+
+	waitOrTimeout(received)
+	select {
+	case <-received:
+	default:
+		t.Fatalf("slow room should not block delivery to other rooms")
+	}
+}
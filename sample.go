@@ -0,0 +1,85 @@
+package broadcast
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// ToRoomSample sends a message to n subscriptions within a room picked uniformly at random,
+// e.g. to canary a message. Every instance picks n of its own subscriptions. If the room
+// has n or fewer subscriptions, all of them receive the message.
+func (b *broadcaster) ToRoomSample(data interface{}, room string, n int) error {
+	if n <= 0 {
+		return errors.New("sample size must be positive")
+	}
+
+	return b.publish(&Message{Data: data, Rooms: []string{room}, Sample: n})
+}
+
+// ToRoomPercent sends a message to the given percentage of the subscriptions within a room
+// picked uniformly at random, e.g. to shed load in a very large room. Every instance picks
+// from its own subscriptions and rounds the number of recipients to the nearest integer.
+func (b *broadcaster) ToRoomPercent(data interface{}, room string, percent float64) error {
+	if err := validatePercent(percent); err != nil {
+		return err
+	}
+
+	return b.publish(&Message{Data: data, Rooms: []string{room}, SamplePercent: percent})
+}
+
+func (n *namespace) ToRoomSample(data interface{}, room string, size int) error {
+	if size <= 0 {
+		return errors.New("sample size must be positive")
+	}
+
+	return n.broadcaster.publish(n.message(&Message{Data: data, Rooms: []string{room}, Sample: size}))
+}
+
+func (n *namespace) ToRoomPercent(data interface{}, room string, percent float64) error {
+	if err := validatePercent(percent); err != nil {
+		return err
+	}
+
+	return n.broadcaster.publish(n.message(&Message{Data: data, Rooms: []string{room}, SamplePercent: percent}))
+}
+
+func validatePercent(percent float64) error {
+	if percent <= 0 || percent > 100 {
+		return errors.New("percent must be greater than 0 and at most 100")
+	}
+
+	return nil
+}
+
+// sample returns the recipients of a sampled message picked from the given subscriptions.
+// Subscriptions that are excluded from the message are not picked.
+func (b *broadcaster) sample(msg *Message, subs []*Subscription) []*Subscription {
+	if msg.Sample <= 0 && msg.SamplePercent <= 0 {
+		return subs
+	}
+
+	candidates := make([]*Subscription, 0, len(subs))
+	for _, s := range subs {
+		if !b.isExcluded(s, msg) {
+			candidates = append(candidates, s)
+		}
+	}
+
+	n := msg.Sample
+	if msg.SamplePercent > 0 {
+		n = int(math.Round(float64(len(candidates)) * msg.SamplePercent / 100))
+	}
+
+	if n >= len(candidates) {
+		return candidates
+	}
+
+	// A partial Fisher-Yates shuffle moves n random subscriptions to the front.
+	for i := 0; i < n; i++ {
+		j := i + rand.Intn(len(candidates)-i)
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	}
+
+	return candidates[:n]
+}
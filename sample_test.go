@@ -0,0 +1,64 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcaster_ToRoomSample(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	for i := 0; i < 10; i++ {
+		b.JoinRoom(counter.subscribe(b), "test-room")
+	}
+
+	b.ToRoomSample("data", "test-room", 3)
+
+	if counter.total() != 3 || len(counter.calls) != 3 {
+		t.Fatalf("%d subscriptions received %d messages; want 3 subscriptions with one each", len(counter.calls), counter.total())
+	}
+}
+
+func TestBroadcaster_ToRoomSample_ShouldSkipExcludedSubscriptions(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	for i := 0; i < 10; i++ {
+		b.JoinRoom(counter.subscribe(b), "test-room")
+	}
+	excluded := b.Subscribe(func(_ interface{}) {
+		t.Fatalf("excluded subscription should not be picked")
+	})
+	b.JoinRoom(excluded, "test-room", "muted")
+
+	b.(*broadcaster).publish(&Message{Data: "data", Rooms: []string{"test-room"}, Except: []string{"muted"}, Sample: 10})
+
+	if counter.total() != 10 {
+		t.Fatalf("subscriptions received %d messages; want 10", counter.total())
+	}
+}
+
+func TestBroadcaster_ToRoomPercent(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	counter := newCallCounter()
+	for i := 0; i < 10; i++ {
+		b.JoinRoom(counter.subscribe(b), "test-room")
+	}
+
+	b.ToRoomPercent("data", "test-room", 40)
+
+	if counter.total() != 4 {
+		t.Fatalf("subscriptions received %d messages; want 4", counter.total())
+	}
+}
+
+func TestBroadcaster_ToRoomSample_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := b.ToRoomSample("data", "test-room", 0); err == nil {
+		t.Fatalf("ToRoomSample should fail with a non-positive size")
+	}
+
+	if err := b.ToRoomPercent("data", "test-room", 150); err == nil {
+		t.Fatalf("ToRoomPercent should fail with a percent above 100")
+	}
+}
@@ -0,0 +1,37 @@
+package broadcast
+
+// ToRoom targets the subscriptions within the given rooms, see Send.
+// A subscription that is part of several of the rooms receives the message once.
+func ToRoom(rooms ...string) SendOption {
+	return func(msg *Message) {
+		msg.Rooms = append(msg.Rooms, rooms...)
+	}
+}
+
+// ToSubscriber targets the subscriptions with the given IDs, see Send.
+func ToSubscriber(ids ...string) SendOption {
+	return func(msg *Message) {
+		msg.Subscribers = append(msg.Subscribers, ids...)
+	}
+}
+
+// Send sends a message configured with send options, e.g.
+//
+//	b.Send(data, ToRoom("chat"), Except("muted"), WithMessageTTL(time.Minute), WithPriority(PriorityHigh))
+//
+// Without ToRoom or ToSubscriber the message is sent to all subscriptions like with ToAll.
+// New ways to send a message are added as send options, so Send covers what the other
+// To... methods do and keeps working as they grow.
+func (b *broadcaster) Send(data interface{}, options ...SendOption) error {
+	return b.publish(sendMessage(data, options...))
+}
+
+func (n *namespace) Send(data interface{}, options ...SendOption) error {
+	return n.broadcaster.publish(n.message(sendMessage(data, options...)))
+}
+
+func sendMessage(data interface{}, options ...SendOption) *Message {
+	msg := newMessage(data, options...)
+	msg.ToAll = len(msg.Rooms) == 0 && len(msg.Subscribers) == 0
+	return msg
+}
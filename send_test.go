@@ -0,0 +1,60 @@
+package broadcast
+
+import (
+	"testing"
+)
+
+func TestBroadcaster_Send_ShouldSendToAllWithoutTarget(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	b.Subscribe(func(_ interface{}) { calls++ })
+	excluded := b.Subscribe(func(_ interface{}) { calls++ })
+	b.JoinRoom(excluded, "muted")
+
+	if err := b.Send("data", Except("muted")); err != nil {
+		t.Fatalf("Send() returned %v", err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Send() delivered %d times; want 1", calls)
+	}
+}
+
+func TestBroadcaster_Send_ShouldTargetRoomsAndSubscribers(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	received := map[string]int{}
+	subscribe := func(name string) *Subscription {
+		return b.Subscribe(func(_ interface{}) { received[name]++ })
+	}
+	a, c, d := subscribe("a"), subscribe("c"), subscribe("d")
+	subscribe("other")
+	b.JoinRoom(a, "room-a")
+	b.JoinRoom(c, "room-a", "room-b")
+
+	b.Send("data", ToRoom("room-a", "room-b"))
+	b.Send("data", ToSubscriber(d.ID()))
+
+	if len(received) != 3 || received["a"] != 1 || received["c"] != 1 || received["d"] != 1 {
+		t.Fatalf("Send() delivered %v; want a, c and d once", received)
+	}
+}
+
+func TestNamespace_Send(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	n := namespaceOf(t, b, "tenant")
+	inside, outside := 0, 0
+	s := n.Subscribe(func(_ interface{}) { inside++ })
+	n.JoinRoom(s, "chat")
+	o := b.Subscribe(func(_ interface{}) { outside++ })
+	b.JoinRoom(o, "chat")
+
+	n.Send("data", ToRoom("chat"))
+	n.Send("data")
+
+	if inside != 2 || outside != 0 {
+		t.Fatalf("Send() delivered %d times inside and %d times outside the namespace; want 2 and 0", inside, outside)
+	}
+}
@@ -0,0 +1,28 @@
+package broadcast
+
+// From excludes the sending subscription from the recipients of the message,
+// see ToRoomFrom.
+func From(sub *Subscription) SendOption {
+	return func(msg *Message) {
+		if sub != nil {
+			msg.Sender = sub.id
+		}
+	}
+}
+
+// ToRoomFrom sends a message to all subscriptions within a room except the sending
+// subscription and the subscriptions that are part of the rooms specified with "except".
+// The ID of the sender is carried in the message, so if the Dispatcher implements
+// MessageDispatcher, the sender doesn't receive the message back from another instance either.
+func (b *broadcaster) ToRoomFrom(sub *Subscription, data interface{}, room string, except ...string) error {
+	return b.ToRoomWithOptions(data, room, From(sub), Except(except...))
+}
+
+func (n *namespace) ToRoomFrom(sub *Subscription, data interface{}, room string, except ...string) error {
+	return n.ToRoomWithOptions(data, room, From(sub), Except(except...))
+}
+
+// isSender reports whether the subscription sent the message.
+func isSender(sub *Subscription, msg *Message) bool {
+	return len(msg.Sender) > 0 && msg.Sender == sub.id
+}
@@ -0,0 +1,68 @@
+package broadcast
+
+import (
+	"testing"
+)
+
+func TestBroadcaster_ToRoomFrom_ShouldExcludeSender(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	senderCalled, otherCalled := false, false
+	sender := b.Subscribe(func(_ interface{}) { senderCalled = true })
+	other := b.Subscribe(func(_ interface{}) { otherCalled = true })
+	b.JoinRoom(sender, "chat")
+	b.JoinRoom(other, "chat")
+
+	if err := b.ToRoomFrom(sender, "data", "chat"); err != nil {
+		t.Fatalf("ToRoomFrom() returned %v", err)
+	}
+
+	if senderCalled {
+		t.Fatalf("ToRoomFrom() sent the message back to the sender")
+	}
+
+	if !otherCalled {
+		t.Fatalf("ToRoomFrom() did not send the message to the other subscription")
+	}
+}
+
+func TestBroadcaster_ToRoomFrom_ShouldCarrySenderAcrossDispatcher(t *testing.T) {
+	dispatcher := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	sender := b.Subscribe(func(_ interface{}) { calls++ })
+	b.JoinRoom(sender, "chat")
+
+	b.ToRoomFrom(sender, "data", "chat")
+	msg := <-dispatcher.dispatched
+
+	if msg.Sender != sender.ID() {
+		t.Fatalf("Dispatched message has sender %q; want %q", msg.Sender, sender.ID())
+	}
+
+	echo := *msg
+	echo.Origin = "other-instance"
+	dispatcher.received(&echo)
+
+	if calls != 0 {
+		t.Fatalf("Sender received its own message %d times; want 0", calls)
+	}
+}
+
+func TestNamespace_ToRoomFrom_ShouldExcludeSender(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	n := namespaceOf(t, b, "tenant")
+	senderCalled, otherCalled := false, false
+	sender := n.Subscribe(func(_ interface{}) { senderCalled = true })
+	other := n.Subscribe(func(_ interface{}) { otherCalled = true })
+	n.JoinRoom(sender, "chat")
+	n.JoinRoom(other, "chat")
+
+	n.ToRoomFrom(sender, "data", "chat")
+
+	if senderCalled || !otherCalled {
+		t.Fatalf("ToRoomFrom() delivered to sender %v and other %v; want false and true", senderCalled, otherCalled)
+	}
+}
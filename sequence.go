@@ -0,0 +1,50 @@
+package broadcast
+
+import "sync/atomic"
+
+// LastSeq returns the sequence number of the last message delivered to a room on this
+// instance. Every message sent to or received for a room gets the next sequence number
+// of the room in Message.Sequences, so subscriptions using SubscribeMessage can detect
+// missed messages by gaps and catch up with Replay. Without WithOrderedDelivery messages
+// may arrive out of order. Sequences restart when an empty room is removed.
+func (b *broadcaster) LastSeq(room string) uint64 {
+	r := b.rooms.get(room)
+	if r == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&r.seq)
+}
+
+func (n *namespace) LastSeq(room string) uint64 {
+	return n.broadcaster.LastSeq(n.room(room))
+}
+
+// Sequence returns the sequence number of the message in a room or zero if the
+// message was not sent to the room, see LastSeq.
+func (m *Message) Sequence(room string) uint64 {
+	return m.Sequences[room]
+}
+
+// sequence stamps a message with the next sequence number of every existing target room.
+// Messages sent to subscriptions are not sequenced.
+func (b *broadcaster) sequence(msg *Message) {
+	if len(msg.Subscribers) > 0 {
+		return
+	}
+
+	var sequences map[string]uint64
+	for _, name := range b.targetRooms(msg) {
+		r := b.rooms.get(name)
+		if r == nil {
+			continue
+		}
+
+		if sequences == nil {
+			sequences = make(map[string]uint64)
+		}
+		sequences[name] = atomic.AddUint64(&r.seq, 1)
+	}
+
+	msg.Sequences = sequences
+}
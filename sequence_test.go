@@ -0,0 +1,53 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcaster_LastSeq(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	sequences := []map[string]uint64{}
+	s := b.SubscribeMessage(func(msg *Message) {
+		sequences = append(sequences, msg.Sequences)
+	})
+	b.JoinRoom(s, "room-a", "room-b")
+
+	b.ToRoom("data", "room-a")
+	b.ToRoom("data", "room-a")
+	b.ToRooms("data", []string{"room-a", "room-b", "missing"})
+	b.ToAll("data")
+
+	if len(sequences) != 4 || sequences[1]["room-a"] != 2 || sequences[2]["room-a"] != 3 || sequences[2]["room-b"] != 1 || sequences[3]["default"] != 1 {
+		t.Fatalf("Messages have the sequences %v; want increasing sequences per room", sequences)
+	}
+
+	if _, ok := sequences[2]["missing"]; ok {
+		t.Fatalf("Message has a sequence for a missing room")
+	}
+
+	if seq := b.LastSeq("room-a"); seq != 3 {
+		t.Fatalf("LastSeq(room-a) = %d; want 3", seq)
+	}
+
+	if seq := b.LastSeq("missing"); seq != 0 {
+		t.Fatalf("LastSeq(missing) = %d; want 0", seq)
+	}
+}
+
+func TestBroadcaster_LastSeq_WithReceivedMessages(t *testing.T) {
+	dispatcher := mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	b, cancel, _ := New(WithDispatcher(&dispatcher), WithSynchronousDelivery())
+	defer cancel()
+	var received *Message
+	s := b.SubscribeMessage(func(msg *Message) {
+		received = msg
+	})
+	b.JoinRoom(s, "room")
+
+	b.ToRoom("data", "room")
+	<-dispatcher.dispatched
+	dispatcher.received(&Message{Data: "data", Rooms: []string{"room"}, Sequences: map[string]uint64{"room": 7}})
+
+	if received.Sequence("room") != 2 || b.LastSeq("room") != 2 {
+		t.Fatalf("Received message has the sequence %d; want the next local sequence 2", received.Sequence("room"))
+	}
+}
@@ -0,0 +1,191 @@
+package broadcast
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Snapshot is the room membership of a broadcaster, see Broadcaster.Export.
+// It only holds names, IDs and metadata, so it can be encoded e.g. as JSON.
+type Snapshot struct {
+	Subscriptions []SubscriptionSnapshot
+	// Rooms holds the metadata of rooms, see CreateRoom.
+	Rooms []RoomSnapshot
+}
+
+// SubscriptionSnapshot describes a subscription and the rooms, trees and groups it joined.
+type SubscriptionSnapshot struct {
+	ID        string
+	Meta      SubMeta
+	Namespace string
+	Rooms     []string
+	Trees     []string
+	Groups    []string
+}
+
+// RoomSnapshot describes a room with metadata.
+type RoomSnapshot struct {
+	Name string
+	Meta map[string]string
+}
+
+// Export returns the subscriptions with the rooms, trees and groups they joined and the
+// metadata of all rooms, sorted by ID and name. Names of namespaced rooms include the namespace.
+func (b *broadcaster) Export() Snapshot {
+	subs := map[string]*SubscriptionSnapshot{}
+	entry := func(s *Subscription) *SubscriptionSnapshot {
+		e := subs[s.id]
+		if e == nil {
+			e = &SubscriptionSnapshot{ID: s.id, Meta: s.Meta(), Namespace: s.namespace}
+			subs[s.id] = e
+		}
+		return e
+	}
+
+	snapshot := Snapshot{Subscriptions: []SubscriptionSnapshot{}, Rooms: []RoomSnapshot{}}
+	b.rooms.each(func(name string, r *room) bool {
+		for _, s := range r.snapshot() {
+			e := entry(s)
+			e.Rooms = append(e.Rooms, name)
+		}
+
+		if info := r.info(name); len(info.Meta) > 0 {
+			snapshot.Rooms = append(snapshot.Rooms, RoomSnapshot{Name: name, Meta: info.Meta})
+		}
+		return true
+	})
+
+	b.mux.RLock()
+	for name, tree := range b.trees {
+		for _, s := range tree.snapshot() {
+			e := entry(s)
+			e.Trees = append(e.Trees, name)
+		}
+	}
+
+	for name, g := range b.groups {
+		for _, s := range g.members.snapshot() {
+			e := entry(s)
+			e.Groups = append(e.Groups, name)
+		}
+	}
+	b.mux.RUnlock()
+
+	for _, e := range subs {
+		sort.Strings(e.Rooms)
+		sort.Strings(e.Trees)
+		sort.Strings(e.Groups)
+		snapshot.Subscriptions = append(snapshot.Subscriptions, *e)
+	}
+
+	sort.Slice(snapshot.Subscriptions, func(i, j int) bool {
+		return snapshot.Subscriptions[i].ID < snapshot.Subscriptions[j].ID
+	})
+	sort.Slice(snapshot.Rooms, func(i, j int) bool {
+		return snapshot.Rooms[i].Name < snapshot.Rooms[j].Name
+	})
+
+	return snapshot
+}
+
+// Restore recreates the subscriptions of a snapshot with their IDs, rooms, trees and groups
+// and the metadata of its rooms, e.g. after a restart. resubscribe is called with the ID of
+// every subscription and returns its callback, or nil if the subscription should not be restored.
+// The Authorizer is asked whether every subscription may join its rooms, trees and groups, and restored
+// subscriptions are announced to the subscribe hook. Restore returns ErrDuplicateSubscriptionID if a subscription
+// with the ID of a restored one exists, the error of the Authorizer, or the error of a subscription that is
+// rejected because of limits, see WithMaxSubscriptions and WithRoomCapacity. The failed subscription is
+// not restored, the subscriptions restored before it are kept.
+func (b *broadcaster) Restore(snapshot Snapshot, resubscribe func(id string) func(interface{})) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	for _, r := range snapshot.Rooms {
+		b.CreateRoom(r.Name, r.Meta)
+	}
+
+	for _, s := range snapshot.Subscriptions {
+		callback := resubscribe(s.ID)
+		if callback == nil {
+			continue
+		}
+
+		if err := b.restore(s, callback); err != nil {
+			return fmt.Errorf("subscription %s: %w", s.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// restore recreates a subscription of a snapshot. If it can't join its rooms,
+// it leaves the rooms it joined and is rejected like a new subscription.
+func (b *broadcaster) restore(s SubscriptionSnapshot, callback func(interface{})) error {
+	sub := b.newSubscription(callback)
+	sub.id = s.ID
+	sub.namespace = s.Namespace
+	sub.broadcaster = b
+	home := b.defaultRoomName
+	if len(s.Namespace) > 0 {
+		n := b.namespace(s.Namespace)
+		sub.broadcaster = n
+		home = n.room(b.defaultRoomName)
+	}
+	if len(s.Meta) > 0 {
+		sub.meta = s.Meta
+	}
+
+	if err := b.authorizeRestore(sub, home, s); err != nil {
+		return err
+	}
+
+	if err := b.claim(s.ID); err != nil {
+		return err
+	}
+
+	if err := b.admit(); err != nil {
+		b.reject(sub, err)
+		return err
+	}
+
+	if err := b.joinRoom(sub, s.Rooms...); err != nil {
+		b.LeaveRoom(sub, s.Rooms...)
+		b.measureSubscriptions(-1)
+		b.reject(sub, err)
+		return err
+	}
+	b.joinTree(sub, s.Trees...)
+	b.joinGroup(sub, s.Groups...)
+
+	if b.subscribeHook != nil {
+		b.subscribeHook(sub)
+	}
+
+	return nil
+}
+
+// authorizeRestore asks the Authorizer whether a restored subscription may join its rooms,
+// trees and groups. Like with Subscribe, joining the default room needs no authorization.
+func (b *broadcaster) authorizeRestore(sub *Subscription, home string, s SubscriptionSnapshot) error {
+	rooms := make([]string, 0, len(s.Rooms)+len(s.Trees)+len(s.Groups))
+	for _, r := range s.Rooms {
+		if r != home {
+			rooms = append(rooms, r)
+		}
+	}
+	rooms = append(rooms, s.Trees...)
+	rooms = append(rooms, s.Groups...)
+
+	return b.authorizeJoin(sub, rooms)
+}
+
+// Export returns the membership of the whole broadcaster, not only the namespace.
+func (n *namespace) Export() Snapshot {
+	return n.broadcaster.Export()
+}
+
+// Restore restores the membership of the whole broadcaster, not only the namespace.
+func (n *namespace) Restore(snapshot Snapshot, resubscribe func(id string) func(interface{})) error {
+	return n.broadcaster.Restore(snapshot, resubscribe)
+}
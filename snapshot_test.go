@@ -0,0 +1,151 @@
+package broadcast
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBroadcaster_Export(t *testing.T) {
+	b := createTestBroadcaster()
+	b.CreateRoom("chat", map[string]string{"topic": "go"})
+	s, _ := b.SubscribeWithID("user-1", func(_ interface{}) {})
+	b.JoinRoom(s, "chat")
+	b.JoinTree(s, "game")
+	b.JoinGroup(s, "workers")
+
+	snapshot := b.Export()
+
+	want := Snapshot{
+		Subscriptions: []SubscriptionSnapshot{{
+			ID:     "user-1",
+			Meta:   SubMeta{},
+			Rooms:  []string{"chat", "default"},
+			Trees:  []string{"game"},
+			Groups: []string{"workers"},
+		}},
+		Rooms: []RoomSnapshot{{Name: "chat", Meta: map[string]string{"topic": "go"}}},
+	}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Fatalf("Export() = %+v; want %+v", snapshot, want)
+	}
+}
+
+func TestBroadcaster_Restore(t *testing.T) {
+	source := createTestBroadcaster()
+	source.CreateRoom("chat", map[string]string{"topic": "go"})
+	s := source.SubscribeWithOptions(func(_ interface{}) {}, WithMeta("user", "1"))
+	source.JoinRoom(s, "chat")
+	namespaceOf(t, source, "tenant").Subscribe(func(_ interface{}) {})
+	dropped := source.Subscribe(func(_ interface{}) {})
+	snapshot := source.Export()
+
+	b := createTestBroadcaster()
+	err := b.Restore(snapshot, func(id string) func(interface{}) {
+		if id == dropped.ID() {
+			return nil
+		}
+		return func(_ interface{}) {}
+	})
+
+	if err != nil {
+		t.Fatalf("Restore returned %v", err)
+	}
+
+	restored := b.Export()
+	if len(restored.Subscriptions) != 2 || !reflect.DeepEqual(restored.Rooms, snapshot.Rooms) {
+		t.Fatalf("Export() after Restore = %+v; want all subscriptions but %s and the room metadata", restored, dropped.ID())
+	}
+
+	for _, sub := range snapshot.Subscriptions {
+		if sub.ID == dropped.ID() {
+			continue
+		}
+		if !containsSnapshot(restored.Subscriptions, sub) {
+			t.Fatalf("Restore didn't restore %+v", sub)
+		}
+	}
+}
+
+func TestBroadcaster_Restore_WithExistingID(t *testing.T) {
+	b := createTestBroadcaster()
+	b.SubscribeWithID("user-1", func(_ interface{}) {})
+
+	err := b.Restore(b.Export(), func(id string) func(interface{}) {
+		return func(_ interface{}) {}
+	})
+
+	if !errors.Is(err, ErrDuplicateSubscriptionID) {
+		t.Fatalf("Restore returned %v; want %v", err, ErrDuplicateSubscriptionID)
+	}
+}
+
+func TestBroadcaster_Restore_WithFullRoom(t *testing.T) {
+	b := createTestBroadcaster()
+	WithRoomCapacity("chat", 1)(b)
+	member := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(member, "chat")
+	snapshot := Snapshot{Subscriptions: []SubscriptionSnapshot{{ID: "user-1", Rooms: []string{"default", "chat"}}}}
+
+	err := b.Restore(snapshot, func(id string) func(interface{}) {
+		return func(_ interface{}) {}
+	})
+
+	if !errors.Is(err, ErrRoomFull) {
+		t.Fatalf("Restore returned %v; want %v", err, ErrRoomFull)
+	}
+
+	if b.Stats().Subscriptions != 1 || b.CountSubscribers("default") != 1 {
+		t.Fatalf("Restore should roll back a subscription that can't join its rooms")
+	}
+
+	if _, err := b.SubscribeWithID("user-1", func(_ interface{}) {}); err != nil {
+		t.Fatalf("SubscribeWithID after a failed Restore returned %v; want nil", err)
+	}
+}
+
+func TestBroadcaster_Restore_WithAuthorizer(t *testing.T) {
+	b := createTestBroadcaster()
+	WithAuthorizer(&joinAuthorizer{allowed: "chat"})(b)
+	snapshot := Snapshot{Subscriptions: []SubscriptionSnapshot{{ID: "user-1", Rooms: []string{"chat", "default"}, Groups: []string{"admins"}}}}
+
+	err := b.Restore(snapshot, func(id string) func(interface{}) {
+		return func(_ interface{}) {}
+	})
+
+	if err == nil || b.Stats().Subscriptions != 0 || b.CountSubscribers("chat") != 0 {
+		t.Fatalf("Restore returned %v; want the error of the Authorizer and no subscription", err)
+	}
+}
+
+func TestBroadcaster_Restore_ShouldCallHooks(t *testing.T) {
+	b := createTestBroadcaster()
+	var subscribed, unsubscribed []string
+	WithSubscribeHook(func(sub *Subscription) {
+		subscribed = append(subscribed, sub.ID())
+	})(b)
+	WithUnsubscribeHook(func(sub *Subscription) {
+		unsubscribed = append(unsubscribed, sub.ID())
+	})(b)
+	snapshot := Snapshot{Subscriptions: []SubscriptionSnapshot{{ID: "user-1", Rooms: []string{"default"}}}}
+	b.Restore(snapshot, func(id string) func(interface{}) {
+		return func(_ interface{}) {}
+	})
+	restored := b.rooms.get("default").lookup("user-1")
+
+	b.Unsubscribe(restored)
+
+	if !reflect.DeepEqual(subscribed, []string{"user-1"}) || !reflect.DeepEqual(unsubscribed, []string{"user-1"}) {
+		t.Fatalf("hooks were called with %v and %v; want user-1 once each", subscribed, unsubscribed)
+	}
+}
+
+func containsSnapshot(subs []SubscriptionSnapshot, sub SubscriptionSnapshot) bool {
+	for _, s := range subs {
+		if reflect.DeepEqual(s, sub) {
+			return true
+		}
+	}
+
+	return false
+}
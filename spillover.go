@@ -0,0 +1,85 @@
+package broadcast
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrPoolSaturated is returned when a message is sent while the pending task queue of the
+// pool is full and the spillover policy is SpilloverReject, see WithPoolQueueLimit.
+var ErrPoolSaturated = errors.New("pool is saturated")
+
+// unlimitedPending is the pending task limit of a pool without WithPoolQueueLimit.
+const unlimitedPending int32 = -1
+
+// SpilloverPolicy defines what happens to a task when the pool is saturated and
+// its pending task queue is full, see WithPoolQueueLimit.
+type SpilloverPolicy int
+
+const (
+	// SpilloverInline runs the task on the calling go routine.
+	SpilloverInline SpilloverPolicy = iota
+	// SpilloverDrop discards the task. Discarded deliveries are counted as dropped messages.
+	SpilloverDrop
+	// SpilloverReject makes sends fail with ErrPoolSaturated while the queue is full.
+	// Tasks of sends that were accepted before run on the calling go routine.
+	SpilloverReject
+)
+
+// WithPoolQueueLimit limits how many tasks can wait for a pool go routine once all go
+// routines are busy and their queues are full. Without a limit, every sender blocks until
+// a go routine takes its task, so bursts grow the number of blocked go routines without
+// bound. The policy decides what happens to tasks over the limit. A limit of zero applies
+// the policy as soon as the pool is saturated. The limit also applies to room pools.
+func WithPoolQueueLimit(limit int, policy SpilloverPolicy) Option {
+	return func(b *broadcaster) error {
+		if limit < 0 {
+			return errors.New("pool queue limit cannot be negative")
+		}
+
+		if policy < SpilloverInline || policy > SpilloverReject {
+			return errors.New("unknown spillover policy")
+		}
+
+		b.pool.maxPending = int32(limit)
+		b.pool.spillover = policy
+		return nil
+	}
+}
+
+// spill handles a task over the pending limit according to the policy
+// and reports whether the task ran.
+func (p *pool) spill(task func(), policy SpilloverPolicy) bool {
+	if policy == SpilloverDrop {
+		if p.drop != nil {
+			p.drop()
+		}
+		return false
+	}
+
+	task()
+	return true
+}
+
+// saturated reports whether the pending task queue of the pool is full.
+func (p *pool) saturated() bool {
+	return p.maxPending != unlimitedPending && atomic.LoadInt32(&p.pending) >= p.maxPending
+}
+
+// rejectSaturated returns ErrPoolSaturated if sends are rejected because the pool is saturated.
+func (b *broadcaster) rejectSaturated() error {
+	if b.pool.spillover == SpilloverReject && b.pool.saturated() {
+		return ErrPoolSaturated
+	}
+
+	return nil
+}
+
+// spilled counts a delivery discarded by SpilloverDrop.
+func (b *broadcaster) spilled() {
+	atomic.AddUint64(&b.counters.dropped, 1)
+
+	if b.metrics != nil {
+		b.metrics.MessagesDropped(1)
+	}
+}
@@ -0,0 +1,112 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+// saturate blocks the single go routine of the pool and fills its queue.
+// Closing the returned channel releases the pool.
+func saturate(p *pool) chan struct{} {
+	release := make(chan struct{})
+	p.do(func() { <-release })
+	for i := 0; i < workerQueueSize; i++ {
+		p.do(func() {})
+	}
+
+	return release
+}
+
+func TestWithPoolQueueLimit_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithPoolQueueLimit(-1, SpilloverInline)(b); err == nil {
+		t.Fatalf("WithPoolQueueLimit(-1); expected an error")
+	}
+
+	if err := WithPoolQueueLimit(1, SpilloverPolicy(7))(b); err == nil {
+		t.Fatalf("WithPoolQueueLimit with an unknown policy; expected an error")
+	}
+}
+
+func TestPool_do_WithSpilloverInline(t *testing.T) {
+	p := createTestPool()
+	p.maxPending = 0
+	release := saturate(p)
+	defer close(release)
+
+	ran := false
+	scheduled := p.do(func() { ran = true })
+
+	if !scheduled || !ran {
+		t.Fatalf("do() = %v and the task ran %v; want the task to run on the caller", scheduled, ran)
+	}
+}
+
+func TestPool_do_WithSpilloverDrop(t *testing.T) {
+	p := createTestPool()
+	p.maxPending = 0
+	p.spillover = SpilloverDrop
+	drops := 0
+	p.drop = func() { drops++ }
+	release := saturate(p)
+	defer close(release)
+
+	if p.do(func() {}) || drops != 1 {
+		t.Fatalf("do() of a saturated pool should drop the task and count it; got %d drops", drops)
+	}
+
+	if !p.doOrRun(func() {}) {
+		t.Fatalf("doOrRun() of a saturated pool should run the task")
+	}
+}
+
+func TestPool_do_WithPendingLimit(t *testing.T) {
+	p := createTestPool()
+	p.maxPending = 1
+	p.spillover = SpilloverDrop
+	release := saturate(p)
+
+	waiting := make(chan bool)
+	go func() {
+		waiting <- p.do(func() {})
+	}()
+	for !p.saturated() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if p.do(func() {}) {
+		t.Fatalf("do() over the pending limit should drop the task")
+	}
+
+	close(release)
+	if !<-waiting {
+		t.Fatalf("do() within the pending limit should wait for the pool")
+	}
+}
+
+func TestBroadcaster_WithPoolQueueLimit_Reject(t *testing.T) {
+	created, cancel, _ := New(WithPoolSize(1), WithPoolQueueLimit(1, SpilloverReject))
+	defer cancel()
+	b := created.(*broadcaster)
+	release := saturate(b.pool)
+	waiting := make(chan bool)
+	go func() {
+		waiting <- b.pool.do(func() {})
+	}()
+	for !b.pool.saturated() {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := b.ToAll("data")
+	close(release)
+	<-waiting
+
+	if err != ErrPoolSaturated {
+		t.Fatalf("ToAll() with a saturated pool returned %v; want ErrPoolSaturated", err)
+	}
+
+	if err := b.ToAll("data"); err != nil {
+		t.Fatalf("ToAll() after the pool recovered returned %v", err)
+	}
+}
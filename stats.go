@@ -0,0 +1,172 @@
+package broadcast
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// BroadcasterStats is a snapshot of the state of a broadcaster.
+type BroadcasterStats struct {
+	// Rooms is the number of rooms with at least one subscription.
+	Rooms int
+	// Subscriptions is the number of active subscriptions.
+	Subscriptions int
+	// RoomSubscribers is the number of subscriptions per room with at least one subscription.
+	RoomSubscribers map[string]int
+	// Sent is the number of messages sent by this instance.
+	Sent uint64
+	// Delivered is the number of completed subscription callbacks.
+	Delivered uint64
+	// Dropped is the number of messages not delivered to a subscription, see Subscription.Dropped.
+	Dropped uint64
+	// PoolWorkers is the number of running pool go routines.
+	PoolWorkers int
+	// PoolBusy is the number of pool go routines running a task.
+	PoolBusy int
+	// PoolSize is the maximum number of pool go routines.
+	PoolSize int
+	// PoolQueued is the number of tasks waiting in the queues of busy pool go routines.
+	PoolQueued int
+	// PoolPending is the number of tasks waiting for a pool go routine because all queues are full,
+	// counted only with WithPoolQueueLimit.
+	PoolPending int
+	// QueueDepths is the number of messages waiting in the buffer
+	// of every subscription that has one, by subscription ID.
+	QueueDepths map[string]int
+}
+
+// SubscriptionStats is a snapshot of the activity of a subscription.
+type SubscriptionStats struct {
+	ID string
+	// Delivered is the number of messages the subscription received.
+	Delivered uint64
+	// Dropped is the number of messages not delivered to the subscription, see Subscription.Dropped.
+	Dropped uint64
+	// LastDelivery is the time of the last delivery or, without deliveries, the time the subscription was created.
+	LastDelivery time.Time
+	// AverageLatency is the average duration of the callback calls, including failed ones.
+	AverageLatency time.Duration
+}
+
+// counters count the messages handled by a broadcaster.
+// They come first in the broadcaster for 64-bit alignment.
+type counters struct {
+	sent          uint64
+	delivered     uint64
+	dropped       uint64
+	subscriptions int64
+	// pending counts sends and deliveries in progress, see Drain.
+	pending int64
+}
+
+// Stats returns a snapshot of the rooms, subscriptions, message counters and pool of the broadcaster.
+func (b *broadcaster) Stats() BroadcasterStats {
+	stats := b.roomStats(func(name string) (string, bool) {
+		return name, true
+	})
+	stats.Subscriptions = int(atomic.LoadInt64(&b.counters.subscriptions))
+
+	return stats
+}
+
+// roomStats returns the stats of the rooms that the given function renames and accepts.
+func (b *broadcaster) roomStats(accept func(name string) (string, bool)) BroadcasterStats {
+	stats := BroadcasterStats{
+		RoomSubscribers: make(map[string]int),
+		Sent:            atomic.LoadUint64(&b.counters.sent),
+		Delivered:       atomic.LoadUint64(&b.counters.delivered),
+		Dropped:         atomic.LoadUint64(&b.counters.dropped),
+		PoolWorkers:     len(b.pool.tickets),
+		PoolBusy:        int(atomic.LoadInt32(&b.pool.busy)),
+		PoolSize:        cap(b.pool.tickets),
+		PoolQueued:      b.pool.queued(),
+		PoolPending:     int(atomic.LoadInt32(&b.pool.pending)),
+		QueueDepths:     make(map[string]int),
+	}
+
+	b.rooms.each(func(name string, r *room) bool {
+		name, ok := accept(name)
+		if !ok {
+			return true
+		}
+
+		subs := r.snapshot()
+		if len(subs) == 0 {
+			return true
+		}
+
+		stats.Rooms++
+		stats.RoomSubscribers[name] = len(subs)
+		for _, s := range subs {
+			if s.queue != nil {
+				stats.QueueDepths[s.id] = len(s.queue.items)
+			}
+		}
+		return true
+	})
+
+	return stats
+}
+
+// SubscriptionStats returns the stats of the subscription with the given ID
+// and reports whether the subscription exists.
+func (b *broadcaster) SubscriptionStats(id string) (SubscriptionStats, bool) {
+	subs := b.subscriptions([]string{id})
+	if len(subs) == 0 {
+		return SubscriptionStats{}, false
+	}
+
+	return subs[0].stats(), true
+}
+
+func (n *namespace) SubscriptionStats(id string) (SubscriptionStats, bool) {
+	subs := n.broadcaster.subscriptions([]string{id})
+	if len(subs) == 0 || !n.owns(subs[0]) {
+		return SubscriptionStats{}, false
+	}
+
+	return subs[0].stats(), true
+}
+
+func (s *Subscription) stats() SubscriptionStats {
+	stats := SubscriptionStats{
+		ID:           s.id,
+		Delivered:    atomic.LoadUint64(&s.delivered),
+		Dropped:      atomic.LoadUint64(&s.dropped),
+		LastDelivery: time.Unix(0, atomic.LoadInt64(&s.lastDelivery)),
+	}
+
+	if calls := atomic.LoadUint64(&s.calls); calls > 0 {
+		stats.AverageLatency = time.Duration(uint64(atomic.LoadInt64(&s.latency)) / calls)
+	}
+
+	return stats
+}
+
+// delivered records a successful delivery to the subscription.
+func (b *broadcaster) delivered(s *Subscription) {
+	atomic.AddUint64(&s.delivered, 1)
+	atomic.StoreInt64(&s.lastDelivery, b.clock.Now().UnixNano())
+}
+
+// measureCallback records the duration of a callback call of the subscription.
+func (b *broadcaster) measureCallback(s *Subscription, latency time.Duration) {
+	atomic.AddUint64(&s.calls, 1)
+	atomic.AddInt64(&s.latency, int64(latency))
+}
+
+// Stats returns the stats of the rooms and subscriptions of the namespace.
+// Message counters and the pool are shared by all namespaces.
+func (n *namespace) Stats() BroadcasterStats {
+	stats := n.broadcaster.roomStats(func(name string) (string, bool) {
+		if !strings.HasPrefix(name, n.prefix) {
+			return "", false
+		}
+
+		return strings.TrimPrefix(name, n.prefix), true
+	})
+	stats.Subscriptions = stats.RoomSubscribers[n.broadcaster.defaultRoomName]
+
+	return stats
+}
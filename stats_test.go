@@ -0,0 +1,104 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_Stats(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery(), WithSubscriberBuffer(10, OverflowDropNewest))
+	defer cancel()
+	s1 := b.Subscribe(func(_ interface{}) {})
+	s2 := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s1, "chat")
+	namespaceOf(t, b, "tenant").Subscribe(func(_ interface{}) {})
+
+	b.ToRoom("data", "chat")
+	b.ToAll("data")
+
+	stats := b.Stats()
+
+	if stats.Subscriptions != 3 || stats.Rooms != 3 {
+		t.Fatalf("Stats() has %d subscriptions and %d rooms; want 3 and 3", stats.Subscriptions, stats.Rooms)
+	}
+
+	if stats.RoomSubscribers["default"] != 2 || stats.RoomSubscribers["chat"] != 1 {
+		t.Fatalf("Stats() has room subscribers %v; want 2 in default and 1 in chat", stats.RoomSubscribers)
+	}
+
+	if stats.Sent != 2 || stats.Delivered != 3 || stats.Dropped != 0 {
+		t.Fatalf("Stats() has %d sent, %d delivered and %d dropped; want 2, 3 and 0", stats.Sent, stats.Delivered, stats.Dropped)
+	}
+
+	if stats.PoolSize != int(defaultPoolSize) || stats.PoolWorkers == 0 {
+		t.Fatalf("Stats() has %d pool workers of %d; want at least one of %d", stats.PoolWorkers, stats.PoolSize, defaultPoolSize)
+	}
+
+	if depth, ok := stats.QueueDepths[s2.ID()]; !ok || depth != 0 {
+		t.Fatalf("Stats() has queue depths %v; want an empty queue for %s", stats.QueueDepths, s2.ID())
+	}
+}
+
+func TestNamespace_Stats(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	b.Subscribe(func(_ interface{}) {})
+	tenant := namespaceOf(t, b, "tenant")
+	s := tenant.Subscribe(func(_ interface{}) {})
+	tenant.JoinRoom(s, "chat")
+
+	stats := tenant.Stats()
+
+	if stats.Subscriptions != 1 || stats.Rooms != 2 || stats.RoomSubscribers["chat"] != 1 {
+		t.Fatalf("Stats() has %d subscriptions and room subscribers %v; want 1 in default and chat", stats.Subscriptions, stats.RoomSubscribers)
+	}
+}
+
+func TestBroadcaster_SubscriptionStats(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) { time.Sleep(time.Millisecond) })
+	created := time.Now()
+
+	b.ToAll("data")
+	b.ToAll("data")
+
+	stats, ok := b.SubscriptionStats(s.ID())
+	if !ok {
+		t.Fatalf("SubscriptionStats(%s) did not find the subscription", s.ID())
+	}
+
+	if stats.ID != s.ID() || stats.Delivered != 2 || stats.Dropped != 0 {
+		t.Fatalf("SubscriptionStats() = %+v; want 2 delivered and 0 dropped", stats)
+	}
+
+	if stats.LastDelivery.Before(created) {
+		t.Fatalf("SubscriptionStats() has last delivery %v; want after %v", stats.LastDelivery, created)
+	}
+
+	if stats.AverageLatency < time.Millisecond {
+		t.Fatalf("SubscriptionStats() has average latency %v; want at least 1ms", stats.AverageLatency)
+	}
+
+	if _, ok := b.SubscriptionStats("unknown"); ok {
+		t.Fatalf("SubscriptionStats(unknown) found a subscription")
+	}
+}
+
+func TestNamespace_SubscriptionStats(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	n := namespaceOf(t, b, "tenant")
+	s := n.Subscribe(func(_ interface{}) {})
+	other := b.Subscribe(func(_ interface{}) {})
+
+	n.ToAll("data")
+
+	if stats, ok := n.SubscriptionStats(s.ID()); !ok || stats.Delivered != 1 {
+		t.Fatalf("SubscriptionStats(%s) = %+v, %v; want 1 delivered", s.ID(), stats, ok)
+	}
+
+	if _, ok := n.SubscriptionStats(other.ID()); ok {
+		t.Fatalf("SubscriptionStats() found a subscription of another namespace")
+	}
+}
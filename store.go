@@ -0,0 +1,110 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Store persists the messages sent to rooms so they can be replayed.
+// Implementations can be backed by Redis Streams, BoltDB, SQL or any other storage.
+type Store interface {
+	// Append stores a message sent to a room.
+	Append(room string, msg *Message) error
+	// Range calls fn with the messages of a room that were sent after since,
+	// from oldest to newest, until fn returns false.
+	Range(room string, since time.Time, fn func(msg *Message) bool) error
+	// Trim removes the messages of a room that were sent before the given time.
+	Trim(room string, before time.Time) error
+}
+
+// WithStore sets the Store every message sent to a room is appended to.
+// Messages sent to all subscriptions are stored under the name of the default room.
+// Requests and replies are not stored.
+func WithStore(store Store) Option {
+	return func(b *broadcaster) error {
+		if store == nil {
+			return errors.New("store cannot be nil")
+		}
+
+		b.store = store
+		return nil
+	}
+}
+
+// WithStoreErrorHandler sets a function that is called when appending a message
+// to the Store or trimming it fails. By default such errors are ignored.
+func WithStoreErrorHandler(handler func(err error)) Option {
+	return func(b *broadcaster) error {
+		if handler == nil {
+			return errors.New("store error handler cannot be nil")
+		}
+
+		b.storeErrorHandler = handler
+		return nil
+	}
+}
+
+// MemoryStore is a Store that keeps all messages in memory until they are trimmed.
+type MemoryStore struct {
+	mux   *sync.RWMutex
+	rooms map[string][]*Message
+}
+
+// NewMemoryStore creates a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mux:   &sync.RWMutex{},
+		rooms: make(map[string][]*Message),
+	}
+}
+
+// Append stores a message sent to a room.
+func (s *MemoryStore) Append(room string, msg *Message) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.rooms[room] = append(s.rooms[room], msg)
+	return nil
+}
+
+// Range calls fn with the messages of a room that were sent after since,
+// from oldest to newest, until fn returns false.
+func (s *MemoryStore) Range(room string, since time.Time, fn func(msg *Message) bool) error {
+	s.mux.RLock()
+	msgs := s.rooms[room]
+	s.mux.RUnlock()
+
+	for _, msg := range msgs {
+		if !msg.Timestamp.After(since) {
+			continue
+		}
+
+		if !fn(msg) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Trim removes the messages of a room that were sent before the given time.
+func (s *MemoryStore) Trim(room string, before time.Time) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	kept := []*Message{}
+	for _, msg := range s.rooms[room] {
+		if !msg.Timestamp.Before(before) {
+			kept = append(kept, msg)
+		}
+	}
+
+	if len(kept) == 0 {
+		delete(s.rooms, room)
+		return nil
+	}
+
+	s.rooms[room] = kept
+	return nil
+}
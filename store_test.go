@@ -0,0 +1,109 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithStore_WithNilStore(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithStore(nil)(b); err == nil {
+		t.Fatalf("WithStore(nil); expected an error")
+	}
+}
+
+func TestMemoryStore_Range(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.Append("room", &Message{ID: "a", Timestamp: now.Add(-time.Minute)})
+	store.Append("room", &Message{ID: "b", Timestamp: now})
+	store.Append("room", &Message{ID: "c", Timestamp: now.Add(time.Second)})
+	store.Append("other-room", &Message{ID: "d", Timestamp: now})
+	got := []string{}
+
+	store.Range("room", now.Add(-time.Second), func(msg *Message) bool {
+		got = append(got, msg.ID)
+		return len(got) < 1
+	})
+
+	if len(got) != 1 || got[0] != "b" {
+		t.Fatalf("Range returned %v; want [b]", got)
+	}
+}
+
+func TestMemoryStore_Trim(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.Append("room", &Message{ID: "a", Timestamp: now.Add(-time.Minute)})
+	store.Append("room", &Message{ID: "b", Timestamp: now})
+
+	store.Trim("room", now.Add(-time.Second))
+	got := []string{}
+	store.Range("room", time.Time{}, func(msg *Message) bool {
+		got = append(got, msg.ID)
+		return true
+	})
+
+	if len(got) != 1 || got[0] != "b" {
+		t.Fatalf("Trim should remove older messages; got %v", got)
+	}
+}
+
+func TestBroadcaster_WithStore_ShouldAppendMessages(t *testing.T) {
+	store := NewMemoryStore()
+	b, cancel, _ := New(WithStore(store), WithSynchronousDelivery())
+	defer cancel()
+
+	b.ToRoom(1, "room")
+	b.ToAll(2)
+	count := 0
+	store.Range("room", time.Time{}, func(_ *Message) bool {
+		count++
+		return true
+	})
+	store.Range("default", time.Time{}, func(_ *Message) bool {
+		count++
+		return true
+	})
+
+	if count != 2 {
+		t.Fatalf("store contains %v messages; want 2", count)
+	}
+}
+
+func TestBroadcaster_WithStoreErrorHandler(t *testing.T) {
+	want := errors.New("append failed")
+	var got error
+	b, cancel, _ := New(
+		WithStore(&failingStore{err: want}),
+		WithStoreErrorHandler(func(err error) {
+			got = err
+		}),
+		WithSynchronousDelivery(),
+	)
+	defer cancel()
+
+	b.ToRoom(1, "room")
+
+	if got != want {
+		t.Fatalf("store error handler called with %v; want %v", got, want)
+	}
+}
+
+type failingStore struct {
+	err error
+}
+
+func (s *failingStore) Append(room string, msg *Message) error {
+	return s.err
+}
+
+func (s *failingStore) Range(room string, since time.Time, fn func(msg *Message) bool) error {
+	return s.err
+}
+
+func (s *failingStore) Trim(room string, before time.Time) error {
+	return s.err
+}
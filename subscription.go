@@ -1,16 +1,87 @@
 package broadcast
 
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrSubscriptionClosed is returned when a subscription that was unsubscribed
+// or rejected tries to join a room, room tree or group.
+var ErrSubscriptionClosed = errors.New("subscription is closed")
+
 // Subscription represents a receiver of messages.
 type Subscription struct {
-	id       string
-	callback func(interface{})
+	dropped      uint64
+	delivered    uint64
+	calls        uint64
+	latency      int64
+	lastDelivery int64
+	strikes      int32
+	slow         int32
+	inFlight     int32
+	panics       int32
+	closed       int32
+	id           string
+	callback     func(interface{})
+	handler      func(*Message)
+	ackCallback  func(interface{}) error
+	queue        *queue
+	limiter      *rateLimiter
+	conflator    *conflator
+	meta         SubMeta
+	namespace    string
+	broadcaster  Broadcaster
+}
+
+func (s *Subscription) send(msg *Message) error {
+	if s.ackCallback != nil {
+		return s.ackCallback(msg.Data)
+	}
+
+	if s.handler != nil {
+		s.handler(msg)
+		return nil
+	}
+
+	s.callback(msg.Data)
+	return nil
 }
 
-func (s *Subscription) send(data interface{}) {
-	s.callback(data)
+func (s *Subscription) isClosed() bool {
+	return atomic.LoadInt32(&s.closed) == 1
 }
 
 // ID returns the unique identifier of the subscription.
 func (s *Subscription) ID() string {
 	return s.id
 }
+
+// Dropped returns the number of messages that were not delivered to the subscription
+// because its buffer was full, it was flagged as a slow consumer or the message expired.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close unsubscribes the subscription from the broadcaster that created it.
+func (s *Subscription) Close() {
+	if s.broadcaster != nil {
+		s.broadcaster.Unsubscribe(s)
+	}
+}
+
+// Leave removes the subscription from rooms of the broadcaster that created it.
+// Room names are relative to the namespace the subscription was created in.
+func (s *Subscription) Leave(rooms ...string) {
+	if s.broadcaster != nil {
+		s.broadcaster.LeaveRoom(s, rooms...)
+	}
+}
+
+// Rooms returns the rooms the subscription is part of, see Broadcaster.RoomsOf.
+func (s *Subscription) Rooms() []string {
+	if s.broadcaster == nil {
+		return []string{}
+	}
+
+	return s.broadcaster.RoomsOf(s)
+}
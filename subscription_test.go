@@ -1,6 +1,7 @@
 package broadcast
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/rs/xid"
@@ -14,13 +15,28 @@ func TestSubscription_send(t *testing.T) {
 	}
 	want := "data"
 
-	subscription.send(want)
+	subscription.send(&Message{Data: want})
 
 	if want != got {
 		t.Fatalf("send called with %v; want %v", got, want)
 	}
 }
 
+func TestSubscription_send_WithHandler(t *testing.T) {
+	subscription := createSubscriptionTestData()
+	var got *Message
+	subscription.handler = func(msg *Message) {
+		got = msg
+	}
+	want := &Message{Data: "data"}
+
+	subscription.send(want)
+
+	if want != got {
+		t.Fatalf("send called handler with %v; want %v", got, want)
+	}
+}
+
 func TestSubscription_ID(t *testing.T) {
 	subscription := createSubscriptionTestData()
 	want := subscription.id
@@ -40,3 +56,43 @@ func createSubscriptionTestData() *Subscription {
 
 	return &subscription
 }
+
+func TestSubscription_Close(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	s := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s, "room")
+
+	s.Close()
+
+	if !s.isClosed() || b.CountSubscribers("room") != 0 || b.CountSubscribers("default") != 0 {
+		t.Fatalf("Close() did not unsubscribe the subscription")
+	}
+}
+
+func TestSubscription_Leave(t *testing.T) {
+	b, cancel, _ := New()
+	defer cancel()
+	tenant := namespaceOf(t, b, "tenant")
+	s := tenant.Subscribe(func(_ interface{}) {})
+	tenant.JoinRoom(s, "a", "b")
+
+	s.Leave("a")
+
+	rooms := s.Rooms()
+	sort.Strings(rooms)
+	if len(rooms) != 2 || rooms[0] != "b" || rooms[1] != "default" {
+		t.Fatalf("Rooms() after Leave(a) returned %v; want [b default]", rooms)
+	}
+}
+
+func TestSubscription_Rooms_WithoutBroadcaster(t *testing.T) {
+	s := createSubscriptionTestData()
+
+	s.Close()
+	s.Leave("room")
+
+	if rooms := s.Rooms(); len(rooms) != 0 {
+		t.Fatalf("Rooms() returned %v; want no rooms", rooms)
+	}
+}
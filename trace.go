@@ -0,0 +1,82 @@
+package broadcast
+
+import "errors"
+
+// Span is a unit of work started by a Tracer.
+type Span interface {
+	// End completes the span, err is the error of the traced work or nil.
+	End(err error)
+}
+
+// Tracer starts spans for the flow of messages through a broadcaster. It is implemented
+// by adapters for tracing systems, e.g. one that extracts the parent context from the
+// carrier with an OpenTelemetry propagator, starts the span and injects its context
+// into a new carrier.
+type Tracer interface {
+	// Start starts a span with the given name that is a child of the trace context
+	// in msg.Trace, which is nil for a new trace. It returns the span and a carrier
+	// holding the trace context of the span. The message must not be modified.
+	Start(name string, msg *Message) (Span, map[string]string)
+}
+
+// Span names used by the broadcaster.
+const (
+	// SpanSend covers sending a message until it is dispatched and scheduled for local delivery.
+	SpanSend = "broadcast.send"
+	// SpanDispatch covers passing a message to the Dispatcher.
+	SpanDispatch = "broadcast.dispatch"
+	// SpanReceive covers a message received through the Dispatcher until it is scheduled for local delivery.
+	SpanReceive = "broadcast.receive"
+	// SpanDeliver covers running a subscription callback on the pool.
+	SpanDeliver = "broadcast.deliver"
+)
+
+// WithTracer sets the Tracer that traces sending, dispatching, receiving and delivering messages.
+// The trace context is passed along in Message.Trace, so a Dispatcher that transfers
+// the field traces a broadcast across instances.
+func WithTracer(tracer Tracer) Option {
+	return func(b *broadcaster) error {
+		if tracer == nil {
+			return errors.New("tracer cannot be nil")
+		}
+
+		b.tracer = tracer
+		return nil
+	}
+}
+
+// WithTraceContext makes the message part of the trace whose context the carrier holds,
+// e.g. the trace of the request that sends the message.
+func WithTraceContext(carrier map[string]string) SendOption {
+	return func(msg *Message) {
+		msg.Trace = carrier
+	}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(_ error) {}
+
+// startSpan starts a span that is a child of the trace context of the message
+// and returns it with its trace context.
+func (b *broadcaster) startSpan(name string, msg *Message) (Span, map[string]string) {
+	if b.tracer == nil {
+		return noopSpan{}, msg.Trace
+	}
+
+	return b.tracer.Start(name, msg)
+}
+
+// trace starts a span and makes it the trace context of the message.
+func (b *broadcaster) trace(name string, msg *Message) Span {
+	span, carrier := b.startSpan(name, msg)
+	msg.Trace = carrier
+	return span
+}
+
+// traceSend starts the send span. The message is stamped first, middleware may
+// have replaced the message stamped before it ran, so the span sees the message ID.
+func (b *broadcaster) traceSend(msg *Message) Span {
+	b.stamp(msg)
+	return b.trace(SpanSend, msg)
+}
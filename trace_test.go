@@ -0,0 +1,138 @@
+package broadcast
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWithTracer_WithNilTracer(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithTracer(nil)(b); err == nil {
+		t.Fatalf("WithTracer(nil); expected an error")
+	}
+}
+
+func TestBroadcaster_WithTracer_ShouldPropagateAcrossInstances(t *testing.T) {
+	tracer := newRecordingTracer()
+	sender := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	receiver := &mockMessageDispatcher{}
+	b1, cancel1, _ := New(WithTracer(tracer), WithDispatcher(sender), WithSynchronousDelivery())
+	defer cancel1()
+	b2, cancel2, _ := New(WithTracer(tracer), WithDispatcher(receiver), WithSynchronousDelivery())
+	defer cancel2()
+	b2.Subscribe(func(_ interface{}) {})
+
+	b1.ToAllWithOptions("data", WithTraceContext(map[string]string{"span": "request"}))
+	receiver.received(<-sender.dispatched)
+
+	want := []string{
+		SpanSend + " < request",
+		SpanDispatch + " < " + SpanSend,
+		SpanReceive + " < " + SpanDispatch,
+		SpanDeliver + " < " + SpanReceive,
+	}
+	spans := tracer.spans()
+	if len(spans) != len(want) {
+		t.Fatalf("Tracer recorded spans %v; want %v", spans, want)
+	}
+
+	for i := range want {
+		if spans[i] != want[i] {
+			t.Fatalf("Tracer recorded spans %v; want %v", spans, want)
+		}
+	}
+}
+
+func TestBroadcaster_WithTracer_ShouldEndDeliverySpanWithError(t *testing.T) {
+	tracer := newRecordingTracer()
+	b, cancel, _ := New(WithTracer(tracer), WithSynchronousDelivery(), WithRedelivery(1, 0))
+	defer cancel()
+	b.SubscribeAck(func(_ interface{}) error {
+		return errors.New("failed")
+	})
+
+	b.ToAll("data")
+
+	tracer.mux.Lock()
+	defer tracer.mux.Unlock()
+	if tracer.errors[SpanDeliver] == nil {
+		t.Fatalf("Delivery span ended without the error of the callback")
+	}
+}
+
+func TestBroadcaster_WithTracer_ShouldStartSpansWithMessageID(t *testing.T) {
+	tracer := newRecordingTracer()
+	receiver := &mockMessageDispatcher{dispatched: make(chan *Message, 1)}
+	replace := func(next SendFunc) SendFunc {
+		return func(msg *Message) error {
+			return next(&Message{Data: msg.Data, ToAll: msg.ToAll})
+		}
+	}
+	b, cancel, _ := New(WithTracer(tracer), WithDispatcher(receiver), WithMiddleware(replace), WithSynchronousDelivery())
+	defer cancel()
+
+	b.ToAll("data")
+	receiver.received(&Message{Data: "data", ToAll: true})
+
+	tracer.mux.Lock()
+	defer tracer.mux.Unlock()
+	for _, name := range []string{SpanSend, SpanReceive} {
+		if len(tracer.ids[name]) == 0 {
+			t.Fatalf("%s span started without the message ID", name)
+		}
+	}
+}
+
+// recordingTracer records spans as "name < parent name".
+type recordingTracer struct {
+	mux      sync.Mutex
+	next     int
+	names    map[string]string
+	recorded []string
+	errors   map[string]error
+	ids      map[string]string
+}
+
+func newRecordingTracer() *recordingTracer {
+	return &recordingTracer{
+		names:  map[string]string{"request": "request"},
+		errors: make(map[string]error),
+		ids:    make(map[string]string),
+	}
+}
+
+func (r *recordingTracer) Start(name string, msg *Message) (Span, map[string]string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.next++
+	id := strconv.Itoa(r.next)
+	r.names[id] = name
+	r.recorded = append(r.recorded, name+" < "+r.names[msg.Trace["span"]])
+	r.ids[name] = msg.ID
+
+	return &recordingSpan{tracer: r, name: name}, map[string]string{"span": id}
+}
+
+func (r *recordingTracer) spans() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]string{}, r.recorded...)
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	name   string
+}
+
+func (s *recordingSpan) End(err error) {
+	s.tracer.mux.Lock()
+	defer s.tracer.mux.Unlock()
+
+	if err != nil {
+		s.tracer.errors[s.name] = err
+	}
+}
@@ -0,0 +1,108 @@
+package broadcast
+
+// Transformer returns the data that subscriptions of a room receive instead of the data of a message.
+type Transformer func(data interface{}) interface{}
+
+// SetRoomTransformer sets a function that transforms the data of messages before they are
+// delivered to the subscriptions within a room, e.g. to localize or redact them. It is called
+// once per message and room, with data that is shared and must not be modified. A subscription
+// that is part of several target rooms receives the data of the first of them that has a
+// transformer. Messages sent to all subscribers are transformed by the transformer of the
+// default room, requests are not transformed. Retained and replayed messages are transformed
+// by the transformer of the room they are sent from. A nil transformer removes the transformer of the room.
+func (b *broadcaster) SetRoomTransformer(room string, transform Transformer) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if transform == nil {
+		delete(b.transformers, room)
+		return
+	}
+
+	if b.transformers == nil {
+		b.transformers = make(map[string]Transformer)
+	}
+
+	b.transformers[room] = transform
+}
+
+func (n *namespace) SetRoomTransformer(room string, transform Transformer) {
+	n.broadcaster.SetRoomTransformer(n.room(room), transform)
+}
+
+// transformed is a message transformed for the subscriptions of a room.
+type transformed struct {
+	room *room
+	msg  *Message
+}
+
+// transform returns the transformed messages for the target rooms of a message that have a transformer.
+func (b *broadcaster) transform(msg *Message) []transformed {
+	if len(msg.ReplyTo) > 0 {
+		return nil
+	}
+
+	b.mux.RLock()
+	empty := len(b.transformers) == 0
+	b.mux.RUnlock()
+
+	if empty {
+		return nil
+	}
+
+	names := b.targetRooms(msg)
+
+	type target struct {
+		room      *room
+		transform Transformer
+	}
+	targets := []target{}
+	b.mux.RLock()
+	for _, name := range names {
+		if t, r := b.transformers[name], b.rooms.get(name); t != nil && r != nil {
+			targets = append(targets, target{room: r, transform: t})
+		}
+	}
+	b.mux.RUnlock()
+
+	result := make([]transformed, 0, len(targets))
+	for _, t := range targets {
+		m := *msg
+		m.Data = t.transform(msg.Data)
+		result = append(result, transformed{room: t.room, msg: &m})
+	}
+
+	return result
+}
+
+// transformFor returns the message transformed for the subscriptions of a single room,
+// e.g. for a retained or replayed message, or the message if the room has no transformer.
+func (b *broadcaster) transformFor(room string, msg *Message) *Message {
+	if len(msg.ReplyTo) > 0 {
+		return msg
+	}
+
+	b.mux.RLock()
+	t := b.transformers[room]
+	b.mux.RUnlock()
+
+	if t == nil {
+		return msg
+	}
+
+	m := *msg
+	m.Data = t(msg.Data)
+	return &m
+}
+
+// messageFor returns the transformed message of the first room the subscription
+// is part of or the original message.
+func messageFor(s *Subscription, msg *Message, transforms []transformed) *Message {
+	for _, t := range transforms {
+		if t.room.lookup(s.id) != nil {
+			return t.msg
+		}
+	}
+
+	return msg
+}
@@ -0,0 +1,98 @@
+package broadcast
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_SetRoomTransformer(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	var calls int32
+	b.SetRoomTransformer("shout", func(data interface{}) interface{} {
+		atomic.AddInt32(&calls, 1)
+		return strings.ToUpper(data.(string))
+	})
+	var loud, quiet interface{}
+	s1 := b.Subscribe(func(data interface{}) {
+		loud = data
+	})
+	b.JoinRoom(s1, "shout")
+	s2 := b.Subscribe(func(data interface{}) {
+		quiet = data
+	})
+	b.JoinRoom(s2, "talk")
+	s3 := b.Subscribe(func(_ interface{}) {})
+	b.JoinRoom(s3, "shout")
+
+	b.ToRooms("hello", []string{"shout", "talk"})
+
+	if loud != "HELLO" || quiet != "hello" {
+		t.Fatalf("Subscriptions received %v and %v; want HELLO and hello", loud, quiet)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("Transformer was called %d times; want 1", n)
+	}
+}
+
+func TestBroadcaster_SetRoomTransformer_WithNilTransformer(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	b.SetRoomTransformer("default", func(_ interface{}) interface{} {
+		return "transformed"
+	})
+	var got interface{}
+	b.Subscribe(func(data interface{}) {
+		got = data
+	})
+
+	b.SetRoomTransformer("default", nil)
+	b.ToAll("data")
+
+	if got != "data" {
+		t.Fatalf("Subscription received %v after the transformer was removed; want data", got)
+	}
+}
+
+func TestBroadcaster_SetRoomTransformer_RetainedMessage(t *testing.T) {
+	b, cancel, _ := New(WithRetainLast("secret"))
+	defer cancel()
+	b.SetRoomTransformer("secret", func(_ interface{}) interface{} {
+		return "REDACTED"
+	})
+	b.ToRoom("classified", "secret")
+	received := make(chan interface{}, 1)
+	s := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	b.JoinRoom(s, "secret")
+
+	if got := <-received; got != "REDACTED" {
+		t.Fatalf("JoinRoom sent retained message %v; want REDACTED", got)
+	}
+}
+
+func TestBroadcaster_SetRoomTransformer_Replay(t *testing.T) {
+	b, cancel, _ := New(WithHistory(10, 0))
+	defer cancel()
+	b.SetRoomTransformer("secret", func(_ interface{}) interface{} {
+		return "REDACTED"
+	})
+	b.ToRoom("classified", "secret")
+	received := make(chan interface{}, 1)
+	s := b.Subscribe(func(data interface{}) {
+		received <- data
+	})
+
+	if n, err := b.Replay(s, "secret", time.Time{}); n != 1 || err != nil {
+		t.Fatalf("Replay() = %v, %v; want 1, nil", n, err)
+	}
+
+	if got := <-received; got != "REDACTED" {
+		t.Fatalf("Replay sent %v; want REDACTED", got)
+	}
+}
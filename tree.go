@@ -0,0 +1,99 @@
+package broadcast
+
+import (
+	"errors"
+	"strings"
+)
+
+const defaultRoomSeparator = "/"
+
+// WithRoomSeparator sets the separator between the levels of hierarchical room names
+// like "sports/football/league1". Default is "/".
+func WithRoomSeparator(separator string) Option {
+	return func(b *broadcaster) error {
+		if len(separator) == 0 {
+			return errors.New("room separator cannot be empty")
+		}
+
+		b.roomSeparator = separator
+		return nil
+	}
+}
+
+// Cascade sends the message to the target rooms and all of their child rooms,
+// e.g. a message sent to "sports" also reaches "sports/football/league1".
+func Cascade() SendOption {
+	return func(msg *Message) {
+		msg.Cascade = true
+	}
+}
+
+// JoinTree adds a subscription to one or multiple room trees. The subscription receives
+// messages sent to the root room of a tree and to any of its child rooms, including
+// rooms created after the call. Tree membership is not reported by RoomsOf.
+// Subsequent calls with the same tree and subscription have no effect.
+// The Authorizer is asked whether the subscription may join the root room of every tree,
+// if it rejects any of them, the subscription joins none of the trees and the error is returned.
+func (b *broadcaster) JoinTree(sub *Subscription, rooms ...string) error {
+	if b.isClosed() {
+		return ErrBroadcasterClosed
+	}
+
+	if sub.isClosed() {
+		return ErrSubscriptionClosed
+	}
+
+	if err := b.authorizeJoin(sub, rooms); err != nil {
+		return err
+	}
+
+	b.joinTree(sub, rooms...)
+	return nil
+}
+
+func (b *broadcaster) joinTree(sub *Subscription, rooms ...string) {
+	for _, r := range rooms {
+		b.mux.Lock()
+		tree := b.trees[r]
+		if tree == nil {
+			tree = newRoom()
+			b.trees[r] = tree
+		}
+		b.mux.Unlock()
+
+		tree.addSubscription(sub)
+	}
+}
+
+// LeaveTree removes a subscription from one or multiple room trees.
+// It doesn't remove the subscription from rooms it joined with JoinRoom.
+func (b *broadcaster) LeaveTree(sub *Subscription, rooms ...string) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	for _, r := range rooms {
+		if tree := b.trees[r]; tree != nil {
+			tree.removeSubscription(sub)
+		}
+	}
+}
+
+// ancestors returns the room and all of its parent rooms.
+func (b *broadcaster) ancestors(room string) []string {
+	rooms := []string{room}
+
+	for {
+		i := strings.LastIndex(room, b.roomSeparator)
+		if i <= 0 {
+			return rooms
+		}
+
+		room = room[:i]
+		rooms = append(rooms, room)
+	}
+}
+
+// isDescendant reports whether room is a child room of parent at any depth.
+func (b *broadcaster) isDescendant(room, parent string) bool {
+	return strings.HasPrefix(room, parent+b.roomSeparator)
+}
@@ -0,0 +1,127 @@
+package broadcast
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWithRoomSeparator(t *testing.T) {
+	b := createTestBroadcaster()
+
+	WithRoomSeparator(":")(b)
+
+	if b.roomSeparator != ":" {
+		t.Fatalf("WithRoomSeparator(\":\"); got separator %v", b.roomSeparator)
+	}
+}
+
+func TestWithRoomSeparator_WithEmptySeparator(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithRoomSeparator("")(b); err == nil {
+		t.Fatalf("WithRoomSeparator(\"\"); expected an error")
+	}
+}
+
+func TestBroadcaster_ancestors(t *testing.T) {
+	b := createTestBroadcaster()
+
+	got := b.ancestors("sports/football/league1")
+
+	if len(got) != 3 || got[0] != "sports/football/league1" || got[1] != "sports/football" || got[2] != "sports" {
+		t.Fatalf("ancestors returned %v", got)
+	}
+}
+
+func TestBroadcaster_ToRoomWithOptions_Cascade(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	received := recordRooms(b, "sports", "sports/football/league1", "sportswear", "music")
+
+	b.ToRoomWithOptions(struct{}{}, "sports", Cascade())
+
+	if received.count("sports") != 1 || received.count("sports/football/league1") != 1 {
+		t.Fatalf("Cascade should send data to the room and its child rooms; got %v", received.rooms)
+	}
+
+	if received.count("sportswear") != 0 || received.count("music") != 0 {
+		t.Fatalf("Cascade should not send data to rooms outside of the tree; got %v", received.rooms)
+	}
+}
+
+func TestBroadcaster_ToRoom_ShouldNotCascadeByDefault(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	received := recordRooms(b, "sports", "sports/football")
+
+	b.ToRoom(struct{}{}, "sports")
+
+	if received.count("sports/football") != 0 {
+		t.Fatalf("ToRoom should not send data to child rooms")
+	}
+}
+
+func TestBroadcaster_JoinTree(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	calls := 0
+	subscription := b.Subscribe(func(_ interface{}) {
+		calls++
+	})
+	b.JoinTree(subscription, "sports")
+	b.JoinRoom(subscription, "sports/football")
+
+	b.ToRoom(struct{}{}, "sports/football")
+	b.ToRoom(struct{}{}, "sports/tennis/open")
+	b.ToRoom(struct{}{}, "music")
+
+	if calls != 2 {
+		t.Fatalf("subscription in a tree received %v messages; want 2", calls)
+	}
+}
+
+func TestBroadcaster_LeaveTree(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	called := false
+	subscription := b.Subscribe(func(_ interface{}) {
+		called = true
+	})
+	b.JoinTree(subscription, "sports")
+
+	b.LeaveTree(subscription, "sports")
+	b.ToRoom(struct{}{}, "sports/football")
+
+	if called {
+		t.Fatalf("LeaveTree didn't remove subscription from the tree")
+	}
+}
+
+type roomRecorder struct {
+	mux   sync.Mutex
+	rooms map[string]int
+}
+
+func (r *roomRecorder) count(room string) int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.rooms[room]
+}
+
+// recordRooms subscribes one subscription per room and counts the messages each of them receives.
+func recordRooms(b Broadcaster, rooms ...string) *roomRecorder {
+	recorder := &roomRecorder{rooms: make(map[string]int)}
+
+	for _, room := range rooms {
+		r := room
+		subscription := b.Subscribe(func(_ interface{}) {
+			recorder.mux.Lock()
+			defer recorder.mux.Unlock()
+			recorder.rooms[r]++
+		})
+		b.JoinRoom(subscription, r)
+	}
+
+	return recorder
+}
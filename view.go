@@ -0,0 +1,165 @@
+package broadcast
+
+import (
+	"context"
+	"time"
+)
+
+// Publisher is the send-only part of a Broadcaster, see Broadcaster.Publisher.
+type Publisher interface {
+	Send(data interface{}, options ...SendOption) error
+	ToAll(data interface{}, except ...string) error
+	ToAllWithOptions(data interface{}, options ...SendOption) error
+	ToAllCtx(ctx context.Context, data interface{}, except ...string) error
+	ToAllSync(ctx context.Context, data interface{}, except ...string) (int, error)
+	ToRoom(data interface{}, room string, except ...string) error
+	ToRoomWithOptions(data interface{}, room string, options ...SendOption) error
+	ToRoomCtx(ctx context.Context, data interface{}, room string, except ...string) error
+	ToRoomSync(ctx context.Context, data interface{}, room string, except ...string) (int, error)
+	ToRooms(data interface{}, rooms []string, except ...string) error
+	ToRoomPattern(data interface{}, pattern string, except ...string) error
+	ToSubscriber(data interface{}, id string) error
+	Request(ctx context.Context, data interface{}, room string) (interface{}, error)
+	Gather(ctx context.Context, data interface{}, room string) ([]Reply, error)
+}
+
+// Subscriber is the subscribe-only part of a Broadcaster, see Broadcaster.SubscriberAPI.
+type Subscriber interface {
+	Subscribe(func(interface{})) *Subscription
+	SubscribeAck(func(interface{}) error) *Subscription
+	SubscribeMessage(func(*Message)) *Subscription
+	SubscribeContext(func(ctx context.Context, data interface{})) *Subscription
+	SubscribeWithOptions(callback func(interface{}), options ...SubscribeOption) *Subscription
+	Unsubscribe(*Subscription)
+	JoinRoom(s *Subscription, rooms ...string) error
+	LeaveRoom(s *Subscription, rooms ...string)
+	RoomsOf(s *Subscription) []string
+	Replay(s *Subscription, room string, since time.Time) (int, error)
+}
+
+// Publisher returns a handle that can only send messages, e.g. for components that must not
+// subscribe or manage rooms. The handle can't be converted back into the Broadcaster.
+func (b *broadcaster) Publisher() Publisher {
+	return &publisher{broadcaster: b}
+}
+
+// SubscriberAPI returns a handle that can only create subscriptions and manage their rooms,
+// e.g. for components that must not send messages. The handle can't be converted back into the Broadcaster.
+func (b *broadcaster) SubscriberAPI() Subscriber {
+	return &subscriber{broadcaster: b}
+}
+
+func (n *namespace) Publisher() Publisher {
+	return &publisher{broadcaster: n}
+}
+
+func (n *namespace) SubscriberAPI() Subscriber {
+	return &subscriber{broadcaster: n}
+}
+
+// publisher forwards the send operations to a broadcaster. The broadcaster is not
+// embedded, so the publisher doesn't implement any other operations.
+type publisher struct {
+	broadcaster Broadcaster
+}
+
+func (p *publisher) Send(data interface{}, options ...SendOption) error {
+	return p.broadcaster.Send(data, options...)
+}
+
+func (p *publisher) ToAll(data interface{}, except ...string) error {
+	return p.broadcaster.ToAll(data, except...)
+}
+
+func (p *publisher) ToAllWithOptions(data interface{}, options ...SendOption) error {
+	return p.broadcaster.ToAllWithOptions(data, options...)
+}
+
+func (p *publisher) ToAllCtx(ctx context.Context, data interface{}, except ...string) error {
+	return p.broadcaster.ToAllCtx(ctx, data, except...)
+}
+
+func (p *publisher) ToAllSync(ctx context.Context, data interface{}, except ...string) (int, error) {
+	return p.broadcaster.ToAllSync(ctx, data, except...)
+}
+
+func (p *publisher) ToRoom(data interface{}, room string, except ...string) error {
+	return p.broadcaster.ToRoom(data, room, except...)
+}
+
+func (p *publisher) ToRoomWithOptions(data interface{}, room string, options ...SendOption) error {
+	return p.broadcaster.ToRoomWithOptions(data, room, options...)
+}
+
+func (p *publisher) ToRoomCtx(ctx context.Context, data interface{}, room string, except ...string) error {
+	return p.broadcaster.ToRoomCtx(ctx, data, room, except...)
+}
+
+func (p *publisher) ToRoomSync(ctx context.Context, data interface{}, room string, except ...string) (int, error) {
+	return p.broadcaster.ToRoomSync(ctx, data, room, except...)
+}
+
+func (p *publisher) ToRooms(data interface{}, rooms []string, except ...string) error {
+	return p.broadcaster.ToRooms(data, rooms, except...)
+}
+
+func (p *publisher) ToRoomPattern(data interface{}, pattern string, except ...string) error {
+	return p.broadcaster.ToRoomPattern(data, pattern, except...)
+}
+
+func (p *publisher) ToSubscriber(data interface{}, id string) error {
+	return p.broadcaster.ToSubscriber(data, id)
+}
+
+func (p *publisher) Request(ctx context.Context, data interface{}, room string) (interface{}, error) {
+	return p.broadcaster.Request(ctx, data, room)
+}
+
+func (p *publisher) Gather(ctx context.Context, data interface{}, room string) ([]Reply, error) {
+	return p.broadcaster.Gather(ctx, data, room)
+}
+
+// subscriber forwards the subscribe operations to a broadcaster, see publisher.
+type subscriber struct {
+	broadcaster Broadcaster
+}
+
+func (s *subscriber) Subscribe(callback func(interface{})) *Subscription {
+	return s.broadcaster.Subscribe(callback)
+}
+
+func (s *subscriber) SubscribeAck(callback func(interface{}) error) *Subscription {
+	return s.broadcaster.SubscribeAck(callback)
+}
+
+func (s *subscriber) SubscribeMessage(callback func(*Message)) *Subscription {
+	return s.broadcaster.SubscribeMessage(callback)
+}
+
+func (s *subscriber) SubscribeContext(callback func(ctx context.Context, data interface{})) *Subscription {
+	return s.broadcaster.SubscribeContext(callback)
+}
+
+func (s *subscriber) SubscribeWithOptions(callback func(interface{}), options ...SubscribeOption) *Subscription {
+	return s.broadcaster.SubscribeWithOptions(callback, options...)
+}
+
+func (s *subscriber) Unsubscribe(sub *Subscription) {
+	s.broadcaster.Unsubscribe(sub)
+}
+
+func (s *subscriber) JoinRoom(sub *Subscription, rooms ...string) error {
+	return s.broadcaster.JoinRoom(sub, rooms...)
+}
+
+func (s *subscriber) LeaveRoom(sub *Subscription, rooms ...string) {
+	s.broadcaster.LeaveRoom(sub, rooms...)
+}
+
+func (s *subscriber) RoomsOf(sub *Subscription) []string {
+	return s.broadcaster.RoomsOf(sub)
+}
+
+func (s *subscriber) Replay(sub *Subscription, room string, since time.Time) (int, error) {
+	return s.broadcaster.Replay(sub, room, since)
+}
@@ -0,0 +1,50 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcaster_Publisher(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	var received interface{}
+	s := b.Subscribe(func(data interface{}) {
+		received = data
+	})
+	b.JoinRoom(s, "room")
+	publisher := b.Publisher()
+
+	publisher.ToRoom("data", "room")
+
+	if received != "data" {
+		t.Fatalf("Subscription received %v; want the message sent by the publisher", received)
+	}
+
+	if _, ok := publisher.(Broadcaster); ok {
+		t.Fatalf("Publisher can be converted into a Broadcaster")
+	}
+}
+
+func TestBroadcaster_SubscriberAPI(t *testing.T) {
+	b, cancel, _ := New(WithSynchronousDelivery())
+	defer cancel()
+	namespace := namespaceOf(t, b, "tenant")
+	subscriber := namespace.SubscriberAPI()
+	var received interface{}
+	s := subscriber.Subscribe(func(data interface{}) {
+		received = data
+	})
+	subscriber.JoinRoom(s, "room")
+
+	namespace.Publisher().ToRoom("data", "room")
+
+	if received != "data" {
+		t.Fatalf("Subscription received %v; want the message sent to its namespaced room", received)
+	}
+
+	if rooms := subscriber.RoomsOf(s); len(rooms) != 2 {
+		t.Fatalf("RoomsOf() = %v; want the default room and room", rooms)
+	}
+
+	if _, ok := subscriber.(Publisher); ok {
+		t.Fatalf("Subscriber can be converted into a Publisher")
+	}
+}
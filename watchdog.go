@@ -0,0 +1,128 @@
+package broadcast
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// SlowConsumerPolicy defines what happens to a subscription whose callback
+// consistently takes longer than the slow consumer threshold.
+type SlowConsumerPolicy int
+
+const (
+	// SlowConsumerWarn only invokes the slow consumer hook.
+	SlowConsumerWarn SlowConsumerPolicy = iota
+	// SlowConsumerSkip drops messages sent to the subscription while one of its
+	// callbacks is still running, until a delivery completes within the threshold again.
+	SlowConsumerSkip
+	// SlowConsumerUnsubscribe removes the subscription from all rooms.
+	SlowConsumerUnsubscribe
+)
+
+// WithSlowConsumerDetection measures how long subscription callbacks take.
+// A subscription whose callback exceeds the threshold on the given number of consecutive
+// deliveries is flagged as slow and handled according to the policy.
+// Slow consumer detection is disabled by default.
+func WithSlowConsumerDetection(threshold time.Duration, strikes int, policy SlowConsumerPolicy) Option {
+	return func(b *broadcaster) error {
+		if threshold <= 0 {
+			return errors.New("slow consumer threshold must be positive")
+		}
+
+		if strikes <= 0 {
+			return errors.New("slow consumer strikes must be positive")
+		}
+
+		if policy < SlowConsumerWarn || policy > SlowConsumerUnsubscribe {
+			return errors.New("unknown slow consumer policy")
+		}
+
+		b.watchdog = &watchdog{
+			threshold: threshold,
+			strikes:   int32(strikes),
+			policy:    policy,
+		}
+		return nil
+	}
+}
+
+// WithSlowConsumerHook sets a function that is called whenever a subscription is flagged as slow,
+// with how long the callback that exceeded the threshold had been running. It is called as soon
+// as the threshold passes, so callbacks that never return are reported too. It has no effect unless slow consumer detection is enabled.
+func WithSlowConsumerHook(hook func(s *Subscription, latency time.Duration)) Option {
+	return func(b *broadcaster) error {
+		if hook == nil {
+			return errors.New("slow consumer hook cannot be nil")
+		}
+
+		b.slowConsumerHook = hook
+		return nil
+	}
+}
+
+// watch starts measuring a callback of the subscription and returns a function that
+// stops the measurement with the latency of the callback. The callback is observed
+// when it exceeds the threshold instead of when it returns, so a hung callback is reported.
+func (b *broadcaster) watch(s *Subscription, start time.Time) func(latency time.Duration) {
+	if b.watchdog == nil {
+		return func(time.Duration) {}
+	}
+
+	timer := b.clock.AfterFunc(b.watchdog.threshold, func() {
+		b.observeCallback(s, b.clock.Now().Sub(start))
+	})
+
+	return func(latency time.Duration) {
+		if timer.Stop() {
+			b.observeCallback(s, latency)
+		}
+	}
+}
+
+// observeCallback handles a subscription that was just flagged as slow.
+func (b *broadcaster) observeCallback(s *Subscription, latency time.Duration) {
+	if !b.watchdog.observe(s, latency) {
+		return
+	}
+
+	if b.slowConsumerHook != nil {
+		b.slowConsumerHook(s, latency)
+	}
+
+	if b.watchdog.policy == SlowConsumerUnsubscribe {
+		go b.Unsubscribe(s)
+	}
+}
+
+type watchdog struct {
+	threshold time.Duration
+	strikes   int32
+	policy    SlowConsumerPolicy
+}
+
+// skip reports whether a message should not be delivered because
+// the subscription was flagged as slow and is still busy.
+func (w *watchdog) skip(s *Subscription) bool {
+	return w.policy == SlowConsumerSkip &&
+		atomic.LoadInt32(&s.slow) == 1 &&
+		atomic.LoadInt32(&s.inFlight) > 0
+}
+
+// observe records the latency of a delivery and reports whether
+// the subscription has just been flagged as slow.
+func (w *watchdog) observe(s *Subscription, latency time.Duration) bool {
+	if latency < w.threshold {
+		atomic.StoreInt32(&s.strikes, 0)
+		atomic.StoreInt32(&s.slow, 0)
+		return false
+	}
+
+	if atomic.AddInt32(&s.strikes, 1) < w.strikes {
+		return false
+	}
+
+	atomic.StoreInt32(&s.strikes, 0)
+	atomic.StoreInt32(&s.slow, 1)
+	return true
+}
@@ -0,0 +1,149 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithSlowConsumerDetection(t *testing.T) {
+	b := createTestBroadcaster()
+
+	WithSlowConsumerDetection(time.Second, 3, SlowConsumerSkip)(b)
+
+	if b.watchdog == nil || b.watchdog.threshold != time.Second || b.watchdog.strikes != 3 {
+		t.Fatalf("WithSlowConsumerDetection should enable the watchdog")
+	}
+}
+
+func TestWithSlowConsumerDetection_WithInvalidArguments(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if err := WithSlowConsumerDetection(0, 1, SlowConsumerWarn)(b); err == nil {
+		t.Fatalf("WithSlowConsumerDetection with zero threshold; expected an error")
+	}
+
+	if err := WithSlowConsumerDetection(time.Second, 0, SlowConsumerWarn)(b); err == nil {
+		t.Fatalf("WithSlowConsumerDetection with zero strikes; expected an error")
+	}
+
+	if err := WithSlowConsumerDetection(time.Second, 1, SlowConsumerPolicy(99))(b); err == nil {
+		t.Fatalf("WithSlowConsumerDetection with unknown policy; expected an error")
+	}
+}
+
+func TestWatchdog_observe(t *testing.T) {
+	w := &watchdog{threshold: time.Millisecond, strikes: 2}
+	s := createSubscriptionTestData()
+
+	if w.observe(s, time.Second) {
+		t.Fatalf("observe should not flag subscription before reaching strikes")
+	}
+
+	if !w.observe(s, time.Second) {
+		t.Fatalf("observe should flag subscription after consecutive slow deliveries")
+	}
+}
+
+func TestWatchdog_observe_FastDeliveryResetsStrikes(t *testing.T) {
+	w := &watchdog{threshold: time.Millisecond, strikes: 2}
+	s := createSubscriptionTestData()
+
+	w.observe(s, time.Second)
+	w.observe(s, time.Microsecond)
+
+	if w.observe(s, time.Second) {
+		t.Fatalf("a fast delivery should reset strikes")
+	}
+}
+
+func TestBroadcaster_SlowConsumerHook(t *testing.T) {
+	done := make(chan struct{})
+	var got *Subscription
+	b, cancel, _ := New(
+		WithSlowConsumerDetection(time.Millisecond, 1, SlowConsumerWarn),
+		WithSlowConsumerHook(func(s *Subscription, _ time.Duration) {
+			got = s
+			close(done)
+		}),
+	)
+	defer cancel()
+	subscription := b.Subscribe(func(_ interface{}) {
+		<-time.After(time.Millisecond * 10)
+	})
+
+	b.ToAll(struct{}{})
+	waitOrTimeout(done)
+
+	if got != subscription {
+		t.Fatalf("slow consumer hook was not called with the slow subscription")
+	}
+}
+
+func TestBroadcaster_SlowConsumerUnsubscribe(t *testing.T) {
+	b, cancel, _ := New(
+		WithSlowConsumerDetection(time.Millisecond, 1, SlowConsumerUnsubscribe),
+	)
+	defer cancel()
+	subscription := b.Subscribe(func(_ interface{}) {
+		<-time.After(time.Millisecond * 10)
+	})
+
+	b.ToAll(struct{}{})
+	<-time.After(time.Millisecond * 200)
+
+	if rooms := b.RoomsOf(subscription); len(rooms) != 0 {
+		t.Fatalf("slow subscription should be unsubscribed; still in %v", rooms)
+	}
+}
+
+func TestBroadcaster_SlowConsumerSkip(t *testing.T) {
+	b, cancel, _ := New(
+		WithSlowConsumerDetection(time.Millisecond, 1, SlowConsumerSkip),
+	)
+	defer cancel()
+	subscription := b.Subscribe(func(data interface{}) {
+		if data == 2 {
+			<-time.After(time.Millisecond * 300)
+			return
+		}
+		<-time.After(time.Millisecond * 10)
+	})
+	b.ToAll(1)
+	<-time.After(time.Millisecond * 100)
+	b.ToAll(2)
+	<-time.After(time.Millisecond * 50)
+
+	b.ToAll(3)
+	<-time.After(time.Millisecond * 50)
+
+	if got := subscription.Dropped(); got != 1 {
+		t.Fatalf("Dropped() = %v; want 1", got)
+	}
+}
+
+func TestBroadcaster_SlowConsumerHook_WhileCallbackRuns(t *testing.T) {
+	flagged := make(chan time.Duration, 1)
+	release := make(chan struct{})
+	defer close(release)
+	b, cancel, _ := New(
+		WithSlowConsumerDetection(time.Millisecond*10, 1, SlowConsumerWarn),
+		WithSlowConsumerHook(func(_ *Subscription, latency time.Duration) {
+			flagged <- latency
+		}),
+	)
+	defer cancel()
+	b.Subscribe(func(_ interface{}) {
+		<-release
+	})
+
+	b.ToAll(struct{}{})
+
+	select {
+	case latency := <-flagged:
+		if latency < time.Millisecond*10 {
+			t.Fatalf("slow consumer hook was called with %v; want at least the threshold", latency)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("slow consumer hook was not called while the callback was hung")
+	}
+}
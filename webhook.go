@@ -0,0 +1,366 @@
+package broadcast
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Headers of the requests sent by webhook subscriptions.
+const (
+	WebhookIDHeader        = "X-Broadcast-ID"
+	WebhookSignatureHeader = "X-Broadcast-Signature"
+)
+
+const (
+	defaultWebhookAttempts    = 3
+	defaultWebhookDelay       = time.Second
+	defaultWebhookConcurrency = 4
+	defaultWebhookTimeout     = time.Second * 10
+	// maxWebhookBackoff caps the doubling retry delay, unless the initial delay is longer.
+	maxWebhookBackoff = time.Minute
+)
+
+// WebhookOption changes how a webhook subscription delivers messages, see SubscribeWebhook.
+type WebhookOption func(w *webhook) error
+
+// WithWebhookClient sets the HTTP client that sends the requests.
+// Default is a client with a timeout of 10 seconds.
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(w *webhook) error {
+		if client == nil {
+			return errors.New("webhook client cannot be nil")
+		}
+
+		w.client = client
+		return nil
+	}
+}
+
+// WithWebhookSecret signs every request with HMAC-SHA256 of its body using the secret.
+// The signature is sent hex encoded with a "sha256=" prefix in the X-Broadcast-Signature header.
+func WithWebhookSecret(secret []byte) WebhookOption {
+	return func(w *webhook) error {
+		if len(secret) == 0 {
+			return errors.New("webhook secret cannot be empty")
+		}
+
+		w.secret = secret
+		return nil
+	}
+}
+
+// WithWebhookRetries sets how many times a message is posted before it is given up and
+// how long to wait before the first retry. The delay doubles after every attempt up to a minute,
+// or up to the initial delay if it is longer. Default is 3 attempts starting with 1 second.
+func WithWebhookRetries(maxAttempts int, delay time.Duration) WebhookOption {
+	return func(w *webhook) error {
+		if maxAttempts <= 0 {
+			return errors.New("max attempts must be positive")
+		}
+
+		if delay < 0 {
+			return errors.New("retry delay cannot be negative")
+		}
+
+		w.maxAttempts = maxAttempts
+		w.delay = delay
+		return nil
+	}
+}
+
+// WithWebhookConcurrency limits how many requests of the subscription are in flight at the same time.
+// Further deliveries are queued without blocking the go routine delivering the message
+// and are posted as requests complete. Default is 4.
+func WithWebhookConcurrency(limit int) WebhookOption {
+	return func(w *webhook) error {
+		if limit <= 0 {
+			return errors.New("webhook concurrency must be positive")
+		}
+
+		w.concurrency = limit
+		return nil
+	}
+}
+
+// WithWebhookHeader adds a header to every request, e.g. for authentication.
+func WithWebhookHeader(key, value string) WebhookOption {
+	return func(w *webhook) error {
+		w.header.Add(key, value)
+		return nil
+	}
+}
+
+// WithWebhookErrorHandler sets a function that is called with messages that could not be
+// posted after all attempts and the error of the last attempt. By default they are discarded.
+func WithWebhookErrorHandler(handler func(msg *Message, err error)) WebhookOption {
+	return func(w *webhook) error {
+		if handler == nil {
+			return errors.New("webhook error handler cannot be nil")
+		}
+
+		w.errorHandler = handler
+		return nil
+	}
+}
+
+// webhook posts the messages of a subscription to an HTTP endpoint.
+type webhook struct {
+	url          string
+	client       *http.Client
+	secret       []byte
+	header       http.Header
+	maxAttempts  int
+	delay        time.Duration
+	concurrency  int
+	errorHandler func(msg *Message, err error)
+	clock        Clock
+	done         chan struct{}
+	sub          *Subscription
+	// mux guards the number of running requests and the requests waiting for one of them.
+	mux     *sync.Mutex
+	running int
+	waiting []func()
+}
+
+// webhookBody is the JSON body of a webhook request.
+type webhookBody struct {
+	ID        string      `json:"id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Rooms     []string    `json:"rooms,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// webhookError is the error of a request answered with a status other than 2xx.
+type webhookError struct {
+	status int
+}
+
+func (e *webhookError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.status)
+}
+
+// SubscribeWebhook creates a subscription that posts every message it receives as JSON to
+// the URL. The body holds the message ID, timestamp, rooms and data and the message ID is
+// also sent in the X-Broadcast-ID header, so receivers can detect retries. Requests that
+// fail or are answered with 429 or a 5xx status are retried in the background, other
+// statuses outside of 2xx are given up immediately.
+func (b *broadcaster) SubscribeWebhook(endpoint string, options ...WebhookOption) (*Subscription, error) {
+	w, err := b.newWebhook(endpoint, options)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := b.newSubscription(nil)
+	w.sub = sub
+	sub.handler = w.handle
+	if err := b.subscribed(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (n *namespace) SubscribeWebhook(endpoint string, options ...WebhookOption) (*Subscription, error) {
+	w, err := n.broadcaster.newWebhook(endpoint, options)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := n.broadcaster.newSubscription(nil)
+	w.sub = sub
+	sub.handler = w.handle
+	if err := n.enter(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+func (b *broadcaster) newWebhook(endpoint string, options []WebhookOption) (*webhook, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.New("webhook URL must use http or https")
+	}
+
+	w := &webhook{
+		url:         endpoint,
+		client:      &http.Client{Timeout: defaultWebhookTimeout},
+		header:      http.Header{},
+		maxAttempts: defaultWebhookAttempts,
+		delay:       defaultWebhookDelay,
+		concurrency: defaultWebhookConcurrency,
+		mux:         &sync.Mutex{},
+		clock:       b.clock,
+		done:        b.done,
+	}
+
+	for _, option := range options {
+		if err := option(w); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// handle posts a message and schedules a retry if the attempt failed.
+func (w *webhook) handle(msg *Message) {
+	body, err := json.Marshal(webhookBody{ID: msg.ID, Timestamp: msg.Timestamp, Rooms: msg.Rooms, Data: msg.Data})
+	if err != nil {
+		w.fail(msg, err)
+		return
+	}
+
+	w.attempt(msg, body, 1)
+}
+
+func (w *webhook) attempt(msg *Message, body []byte, attempt int) {
+	w.run(func() {
+		w.send(msg, body, attempt)
+	})
+}
+
+// run calls fn right away if fewer than the concurrency limit of requests are running.
+// Otherwise fn is queued and called on a new go routine once a running request completes,
+// so a slow endpoint never blocks the go routine delivering the message.
+func (w *webhook) run(fn func()) {
+	w.mux.Lock()
+	if w.running >= w.concurrency {
+		w.waiting = append(w.waiting, fn)
+		w.mux.Unlock()
+		return
+	}
+	w.running++
+	w.mux.Unlock()
+
+	fn()
+	w.next()
+}
+
+// next passes the slot of a completed request to the first waiting request or frees it.
+func (w *webhook) next() {
+	w.mux.Lock()
+	if len(w.waiting) == 0 {
+		w.running--
+		w.mux.Unlock()
+		return
+	}
+
+	fn := w.waiting[0]
+	w.waiting[0] = nil
+	w.waiting = w.waiting[1:]
+	w.mux.Unlock()
+
+	go func() {
+		fn()
+		w.next()
+	}()
+}
+
+// send posts a message and schedules a retry if the attempt failed.
+func (w *webhook) send(msg *Message, body []byte, attempt int) {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	if w.sub.isClosed() {
+		return
+	}
+
+	err := w.post(msg, body)
+	if err == nil {
+		return
+	}
+
+	if attempt >= w.maxAttempts || !retryable(err) || msg.canceled() {
+		w.fail(msg, err)
+		return
+	}
+
+	w.clock.AfterFunc(w.backoff(attempt), func() {
+		w.attempt(msg, body, attempt+1)
+	})
+}
+
+// backoff returns the delay after the given failed attempt.
+func (w *webhook) backoff(attempt int) time.Duration {
+	limit := maxWebhookBackoff
+	if w.delay > limit {
+		limit = w.delay
+	}
+
+	delay := w.delay
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+func (w *webhook) post(msg *Message, body []byte) error {
+	req, err := http.NewRequestWithContext(msg.Context(), http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for key, values := range w.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, msg.ID)
+
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &webhookError{status: resp.StatusCode}
+	}
+
+	return nil
+}
+
+func (w *webhook) fail(msg *Message, err error) {
+	if w.errorHandler != nil {
+		w.errorHandler(msg, err)
+	}
+}
+
+// retryable reports whether a failed request is worth another attempt.
+func retryable(err error) bool {
+	var status *webhookError
+	if !errors.As(err, &status) {
+		return true
+	}
+
+	return status.status == http.StatusTooManyRequests || status.status >= 500
+}
@@ -0,0 +1,204 @@
+package broadcast
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBroadcaster_SubscribeWebhook(t *testing.T) {
+	secret := []byte("secret")
+	requests := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	defer server.Close()
+	b := createTestBroadcaster()
+	sub, err := b.SubscribeWebhook(server.URL, WithWebhookSecret(secret), WithWebhookHeader("Authorization", "token"))
+	if err != nil {
+		t.Fatalf("SubscribeWebhook() returned %v", err)
+	}
+	b.JoinRoom(sub, "room")
+
+	b.ToRoom(map[string]string{"key": "value"}, "room")
+	r := <-requests
+	body := <-bodies
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if r.Header.Get(WebhookSignatureHeader) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+		t.Fatalf("Request has the signature %q; want the HMAC of the body", r.Header.Get(WebhookSignatureHeader))
+	}
+
+	if r.Method != http.MethodPost || r.Header.Get("Authorization") != "token" || len(r.Header.Get(WebhookIDHeader)) == 0 {
+		t.Fatalf("Request is %s with headers %v; want a POST with the ID and custom header", r.Method, r.Header)
+	}
+
+	var decoded webhookBody
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("Request body %s is not JSON: %v", body, err)
+	}
+
+	if decoded.ID != r.Header.Get(WebhookIDHeader) || len(decoded.Rooms) != 1 || decoded.Rooms[0] != "room" || decoded.Data.(map[string]interface{})["key"] != "value" {
+		t.Fatalf("Request body is %+v; want the ID, room and data of the message", decoded)
+	}
+}
+
+func TestBroadcaster_SubscribeWebhook_Retries(t *testing.T) {
+	var calls int32
+	succeeded := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		close(succeeded)
+	}))
+	defer server.Close()
+	b := createTestBroadcaster()
+	b.SubscribeWebhook(server.URL, WithWebhookRetries(3, 0))
+
+	b.ToAll("data")
+
+	select {
+	case <-succeeded:
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Webhook made %d requests; want a successful third attempt", atomic.LoadInt32(&calls))
+	}
+}
+
+func TestBroadcaster_SubscribeWebhook_GivesUp(t *testing.T) {
+	for status, attempts := range map[int]int32{http.StatusInternalServerError: 3, http.StatusBadRequest: 1} {
+		var calls int32
+		code := status
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(code)
+		}))
+		failed := make(chan error, 1)
+		b := createTestBroadcaster()
+		b.SubscribeWebhook(server.URL, WithWebhookRetries(3, 0), WithWebhookErrorHandler(func(_ *Message, err error) {
+			failed <- err
+		}))
+
+		b.ToAll("data")
+
+		select {
+		case err := <-failed:
+			if atomic.LoadInt32(&calls) != attempts {
+				t.Fatalf("Webhook gave up status %d with %v after %d requests; want %d", status, err, atomic.LoadInt32(&calls), attempts)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("Webhook didn't give up status %d", status)
+		}
+		server.Close()
+	}
+}
+
+func TestBroadcaster_SubscribeWebhook_Concurrency(t *testing.T) {
+	var inFlight, max int32
+	release := make(chan struct{})
+	done := make(chan struct{}, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		if n > atomic.LoadInt32(&max) {
+			atomic.StoreInt32(&max, n)
+		}
+		<-release
+		atomic.AddInt32(&inFlight, -1)
+		done <- struct{}{}
+	}))
+	defer server.Close()
+	b := createTestBroadcaster()
+	b.SubscribeWebhook(server.URL, WithWebhookConcurrency(1))
+
+	for i := 0; i < 3; i++ {
+		b.ToAll(i)
+	}
+	close(release)
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+
+	if atomic.LoadInt32(&max) != 1 {
+		t.Fatalf("Webhook had %d requests in flight; want 1", atomic.LoadInt32(&max))
+	}
+}
+
+func TestWebhook_run_ShouldQueueWithoutBlocking(t *testing.T) {
+	w := &webhook{concurrency: 1, mux: &sync.Mutex{}}
+	started, release := make(chan struct{}), make(chan struct{})
+	go w.run(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	queued, returned := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		w.run(func() {
+			close(queued)
+		})
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("run blocked while the webhook had no free slot")
+	}
+
+	select {
+	case <-queued:
+		t.Fatal("queued request ran while the webhook had no free slot")
+	default:
+	}
+
+	close(release)
+	<-queued
+}
+
+func TestWebhook_backoff(t *testing.T) {
+	tests := []struct {
+		delay   time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{time.Second, 1, time.Second},
+		{time.Second, 3, time.Second * 4},
+		{time.Second, 100, maxWebhookBackoff},
+		{time.Hour, 5, time.Hour},
+		{0, 10, 0},
+	}
+
+	for _, test := range tests {
+		w := &webhook{delay: test.delay}
+
+		if got := w.backoff(test.attempt); got != test.want {
+			t.Fatalf("backoff(%d) with delay %v = %v; want %v", test.attempt, test.delay, got, test.want)
+		}
+	}
+}
+
+func TestBroadcaster_SubscribeWebhook_WithInvalidURL(t *testing.T) {
+	b := createTestBroadcaster()
+
+	if _, err := b.SubscribeWebhook("ftp://example.com"); err == nil {
+		t.Fatalf("SubscribeWebhook() with an ftp URL should fail")
+	}
+
+	if _, err := b.SubscribeWebhook("http://example.com", WithWebhookConcurrency(0)); err == nil {
+		t.Fatalf("SubscribeWebhook() with an invalid option should fail")
+	}
+}